@@ -0,0 +1,184 @@
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/katiem0/gh-seva/internal/providers"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type cmdFlags struct {
+	fileName    string
+	prune       bool
+	dryRun      bool
+	yes         bool
+	concurrency int
+	resultsFile string
+	providers   string
+	token       string
+	hostname    string
+	debug       bool
+}
+
+func NewCmdApply() *cobra.Command {
+	cmdFlags := cmdFlags{}
+	var authToken string
+
+	applyCmd := cobra.Command{
+		Use:   "apply [organization] [flags]",
+		Short: "Reconcile the secrets and variables of an organization with a manifest.",
+		Long:  "Reconcile the Actions, Dependabot, and Codespaces secrets and Actions variables of an organization with a YAML or JSON manifest, creating what is missing, updating what has drifted and, with --prune, deleting what is not in the manifest.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(applyCmd *cobra.Command, args []string) error {
+			var err error
+			var gqlClient *api.GraphQLClient
+			var restClient *api.RESTClient
+
+			// Reinitialize logging if debugging was enabled
+			if cmdFlags.debug {
+				logger, _ := log.NewLogger(cmdFlags.debug)
+				defer logger.Sync() // nolint:errcheck
+				zap.ReplaceGlobals(logger)
+			}
+
+			if cmdFlags.token != "" {
+				authToken = cmdFlags.token
+			} else {
+				t, _ := auth.TokenForHost(cmdFlags.hostname)
+				authToken = t
+			}
+
+			gqlClient, err = api.NewGraphQLClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github.hawkgirl-preview+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving graphql client")
+				return err
+			}
+
+			restClient, err = api.NewRESTClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving rest client")
+				return err
+			}
+
+			owner := ""
+			if len(args) > 0 {
+				owner = args[0]
+			}
+
+			return runCmdApply(owner, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), applyCmd.InOrStdin(), applyCmd.OutOrStdout())
+		},
+	}
+
+	// Configure flags for command
+	applyCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub personal access token for organization to write to (default "gh auth token")`)
+	applyCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
+	applyCmd.Flags().StringVarP(&cmdFlags.fileName, "from-file", "f", "", "Path and Name of the YAML or JSON manifest to apply (required)")
+	applyCmd.Flags().BoolVar(&cmdFlags.prune, "prune", false, "Delete secrets and variables that are not in the manifest, in the organization and the repositories and environments it lists")
+	applyCmd.Flags().BoolVar(&cmdFlags.dryRun, "dry-run", false, "Print the changes that would be made without applying them")
+	applyCmd.Flags().BoolVarP(&cmdFlags.yes, "yes", "y", false, "Prune without asking for confirmation")
+	applyCmd.Flags().IntVarP(&cmdFlags.concurrency, "concurrency", "c", 1, "Number of changes to apply concurrently")
+	applyCmd.Flags().StringVar(&cmdFlags.providers, "providers-config", "", "Path and Name of a YAML file configuring the secret stores that store: values are read from")
+	applyCmd.Flags().StringVar(&cmdFlags.resultsFile, "results-file", "", "Path and Name of a CSV, or .json, file to write the result of each change to")
+	applyCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
+
+	if err := applyCmd.MarkFlagRequired("from-file"); err != nil {
+		zap.S().Errorf("Error marking from-file flag as required: %v", err)
+	}
+
+	return &applyCmd
+}
+
+func runCmdApply(owner string, cmdFlags *cmdFlags, backend utils.Getter, in io.Reader, out io.Writer) error {
+	g := utils.NewAPIGetter(backend)
+	zap.S().Debugf("Reading manifest %s", cmdFlags.fileName)
+	manifest, err := utils.ReadManifest(cmdFlags.fileName)
+	if err != nil {
+		zap.S().Errorf("Error arose reading manifest")
+		return err
+	}
+	if owner == "" {
+		owner = manifest.Organization
+	} else if manifest.Organization != "" && manifest.Organization != owner {
+		zap.S().Infof("Applying manifest of %s to %s", manifest.Organization, owner)
+	}
+	if owner == "" {
+		return errors.New("an organization must be specified, as an argument or as organization in the manifest")
+	}
+	if len(cmdFlags.providers) > 0 {
+		zap.S().Debugf("Reading secret store providers from %s", cmdFlags.providers)
+		stores, err := providers.LoadRegistry(cmdFlags.providers)
+		if err != nil {
+			zap.S().Errorf("Error arose reading provider config")
+			return err
+		}
+		g.SetSecretStores(stores)
+	}
+
+	zap.S().Debugf("Planning changes to %s", owner)
+	changes := g.PlanApply(owner, manifest, cmdFlags.prune)
+	plan := utils.ApplyPlanItems(changes)
+	if err := utils.PrintPlan(out, plan); err != nil {
+		return err
+	}
+	counts := utils.CountPlanActions(plan)
+	if counts[data.PlanError] > 0 {
+		return fmt.Errorf("%d changes in the plan have errors, nothing was applied", counts[data.PlanError])
+	}
+	if cmdFlags.dryRun {
+		return nil
+	}
+	if counts[data.PlanCreate]+counts[data.PlanUpdate]+counts[data.PlanDelete] == 0 {
+		_, err := fmt.Fprintf(out, "No changes, %s matches the manifest.\n", owner)
+		return err
+	}
+	if counts[data.PlanDelete] > 0 && !cmdFlags.yes {
+		prompt := fmt.Sprintf("Delete %d secrets and variables that are not in the manifest? [y/N]: ", counts[data.PlanDelete])
+		confirmed, err := utils.Confirm(in, out, prompt)
+		if err != nil {
+			return fmt.Errorf("%w, use --yes to prune without confirmation", err)
+		}
+		if !confirmed {
+			_, err := fmt.Fprintln(out, "Nothing was applied.")
+			return err
+		}
+	}
+
+	results := g.Apply(owner, changes, cmdFlags.concurrency)
+	if len(cmdFlags.resultsFile) > 0 {
+		zap.S().Debugf("Writing results to %s", cmdFlags.resultsFile)
+		if err := utils.WriteResults(cmdFlags.resultsFile, results); err != nil {
+			zap.S().Errorf("Error arose writing results file")
+			return err
+		}
+	}
+	if err := utils.PrintResultSummary(out, "changes", results); err != nil {
+		return err
+	}
+	if failed := utils.CountFailedResults(results); failed > 0 {
+		return fmt.Errorf("failed to apply %d of %d changes to: %s", failed, len(results), owner)
+	}
+	_, err = fmt.Fprintf(out, "Successfully applied the manifest to: %s.\n", owner)
+	return err
+}
@@ -0,0 +1,183 @@
+package apply
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+// orgHandler serves an organization with the variables REGION=us and STALE,
+// and no secrets, recording every write.
+func orgHandler(writes *[]string) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != "GET" {
+			mu.Lock()
+			*writes = append(*writes, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		switch r.URL.Path {
+		case "/orgs/test-org/actions/variables":
+			_, _ = w.Write([]byte(`{"total_count":2,"variables":[{"name":"REGION","value":"us","visibility":"all"},{"name":"STALE","value":"x","visibility":"all"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"total_count":0,"secrets":[]}`))
+		}
+	}
+}
+
+func writeTestManifest(t *testing.T, content string) string {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), "org.yaml")
+	if err := os.WriteFile(fileName, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	return fileName
+}
+
+const testManifest = `organization: test-org
+variables:
+  - level: Organization
+    name: REGION
+    value: eu
+    visibility: all
+`
+
+func TestNewCmdApply(t *testing.T) {
+	cmd := NewCmdApply()
+
+	if cmd.Use != "apply [organization] [flags]" {
+		t.Errorf("Expected Use to be 'apply [organization] [flags]', got %s", cmd.Use)
+	}
+	for _, name := range []string{"from-file", "prune", "dry-run", "yes", "concurrency", "results-file", "token", "hostname", "debug"} {
+		if cmd.Flag(name) == nil {
+			t.Errorf("%s flag not found", name)
+		}
+	}
+	if err := cmd.Args(cmd, []string{"org-a", "org-b"}); err == nil {
+		t.Error("Expected an error for more than one organization")
+	}
+}
+
+func TestRunCmdApply(t *testing.T) {
+	var writes []string
+	g := newTestGetter(t, orgHandler(&writes))
+	flags := &cmdFlags{fileName: writeTestManifest(t, testManifest), concurrency: 1}
+
+	var out bytes.Buffer
+	if err := runCmdApply("", flags, g, strings.NewReader(""), &out); err != nil {
+		t.Fatalf("runCmdApply() error = %v", err)
+	}
+	if strings.Join(writes, ",") != "PATCH /orgs/test-org/actions/variables/REGION" {
+		t.Errorf("Expected only REGION to be updated without prune, got %v", writes)
+	}
+	for _, want := range []string{"value changed", "Summary: 1 changes succeeded", "Successfully applied the manifest to: test-org."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output containing %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunCmdApplyPrune(t *testing.T) {
+	testCases := []struct {
+		name       string
+		dryRun     bool
+		yes        bool
+		answer     string
+		wantWrites []string
+		wantOutput string
+	}{
+		{"dry run", true, false, "", nil, "1 to delete"},
+		{"declined", false, false, "n\n", nil, "Nothing was applied."},
+		{"confirmed", false, false, "y\n", []string{"PATCH /orgs/test-org/actions/variables/REGION", "DELETE /orgs/test-org/actions/variables/STALE"}, "Summary: 2 changes succeeded"},
+		{"yes", false, true, "", []string{"PATCH /orgs/test-org/actions/variables/REGION", "DELETE /orgs/test-org/actions/variables/STALE"}, "Successfully applied"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var writes []string
+			g := newTestGetter(t, orgHandler(&writes))
+			flags := &cmdFlags{fileName: writeTestManifest(t, testManifest), prune: true, dryRun: tc.dryRun, yes: tc.yes, concurrency: 1}
+
+			var out bytes.Buffer
+			if err := runCmdApply("test-org", flags, g, strings.NewReader(tc.answer), &out); err != nil {
+				t.Fatalf("runCmdApply() error = %v", err)
+			}
+			if strings.Join(writes, ",") != strings.Join(tc.wantWrites, ",") {
+				t.Errorf("Expected writes %v, got %v", tc.wantWrites, writes)
+			}
+			if !strings.Contains(out.String(), tc.wantOutput) {
+				t.Errorf("Expected output containing %q, got:\n%s", tc.wantOutput, out.String())
+			}
+		})
+	}
+}
+
+func TestRunCmdApplyPlanErrors(t *testing.T) {
+	var writes []string
+	g := newTestGetter(t, orgHandler(&writes))
+	manifest := testManifest + `secrets:
+  - level: Organization
+    type: Actions
+    name: NEW_TOKEN
+    visibility: all
+`
+	flags := &cmdFlags{fileName: writeTestManifest(t, manifest), yes: true, concurrency: 1}
+
+	var out bytes.Buffer
+	err := runCmdApply("", flags, g, strings.NewReader(""), &out)
+	if err == nil || !strings.Contains(err.Error(), "1 changes in the plan have errors, nothing was applied") {
+		t.Errorf("Expected the plan errors to stop the apply, got %v", err)
+	}
+	if len(writes) != 0 {
+		t.Errorf("Expected nothing to be written, got %v", writes)
+	}
+	if !strings.Contains(out.String(), "a secret value is required to create it") {
+		t.Errorf("Expected the error in the plan, got:\n%s", out.String())
+	}
+}
+
+func TestRunCmdApplyNoOrganization(t *testing.T) {
+	flags := &cmdFlags{fileName: writeTestManifest(t, "variables: []\n")}
+	err := runCmdApply("", flags, newTestGetter(t, orgHandler(new([]string))), strings.NewReader(""), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "an organization must be specified") {
+		t.Errorf("Expected an error without an organization, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package audit
+
+import (
+	unusedCmd "github.com/katiem0/gh-seva/cmd/audit/unused"
+	"github.com/spf13/cobra"
+)
+
+func NewCmdAudit() *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "audit <command>",
+		Short: "Audit the secrets and variables of an organization.",
+		Long:  "Audit the secrets and variables of an organization against the workflows of its repositories.",
+	}
+	cmd.Flags().Bool("help", false, "Show help for command")
+
+	cmd.AddCommand(unusedCmd.NewCmdUnused())
+
+	return cmd
+}
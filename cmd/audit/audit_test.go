@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"testing"
+)
+
+func TestNewCmdAudit(t *testing.T) {
+	cmd := NewCmdAudit()
+
+	if cmd == nil {
+		t.Fatal("NewCmdAudit() returned nil")
+	}
+
+	if cmd.Use != "audit <command>" {
+		t.Errorf("Expected Use to be 'audit <command>', got %s", cmd.Use)
+	}
+
+	unusedFound := false
+	for _, subcmd := range cmd.Commands() {
+		if subcmd.Name() == "unused" {
+			unusedFound = true
+		}
+	}
+	if !unusedFound {
+		t.Error("Unused subcommand not found")
+	}
+
+	if cmd.Short == "" {
+		t.Error("Command short description should not be empty")
+	}
+}
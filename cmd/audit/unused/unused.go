@@ -0,0 +1,126 @@
+package unusedaudit
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// Formats unused prints the report in
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+type cmdFlags struct {
+	format      string
+	exitCode    bool
+	concurrency int
+	token       string
+	hostname    string
+	debug       bool
+}
+
+func NewCmdUnused() *cobra.Command {
+	cmdFlags := cmdFlags{}
+	var authToken string
+
+	unusedCmd := cobra.Command{
+		Use:   "unused <organization> [flags]",
+		Short: "List secrets and variables that no workflow refers to.",
+		Long:  "List the Actions secrets and variables of an organization, its repositories and environments that no workflow or composite action of a repository able to use them refers to, by reading .github/workflows, .github/actions and the root action.yml of each repository.",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(unusedCmd *cobra.Command, args []string) error {
+			if cmdFlags.format != formatTable && cmdFlags.format != formatJSON {
+				return fmt.Errorf("unknown format %q, expected table or json", cmdFlags.format)
+			}
+			return nil
+		},
+		RunE: func(unusedCmd *cobra.Command, args []string) error {
+			var err error
+			var gqlClient *api.GraphQLClient
+			var restClient *api.RESTClient
+
+			// Reinitialize logging if debugging was enabled
+			if cmdFlags.debug {
+				logger, _ := log.NewLogger(cmdFlags.debug)
+				defer logger.Sync() // nolint:errcheck
+				zap.ReplaceGlobals(logger)
+			}
+
+			if cmdFlags.token != "" {
+				authToken = cmdFlags.token
+			} else {
+				t, _ := auth.TokenForHost(cmdFlags.hostname)
+				authToken = t
+			}
+
+			gqlClient, err = api.NewGraphQLClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github.hawkgirl-preview+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving graphql client")
+				return err
+			}
+
+			restClient, err = api.NewRESTClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving rest client")
+				return err
+			}
+
+			return runCmdUnused(args[0], &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), unusedCmd.OutOrStdout())
+		},
+	}
+
+	// Configure flags for command
+	unusedCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub personal access token for organization to audit (default "gh auth token")`)
+	unusedCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
+	unusedCmd.Flags().StringVar(&cmdFlags.format, "format", formatTable, "Output format: table or json")
+	unusedCmd.Flags().BoolVar(&cmdFlags.exitCode, "exit-code", false, "Exit with a non-zero status when there are unused secrets or variables")
+	unusedCmd.Flags().IntVarP(&cmdFlags.concurrency, "concurrency", "c", 1, "Number of repositories to read concurrently")
+	unusedCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
+
+	return &unusedCmd
+}
+
+func runCmdUnused(owner string, cmdFlags *cmdFlags, backend utils.Getter, out io.Writer) error {
+	g := utils.NewAPIGetter(backend)
+	report, err := g.AuditUnused(owner, cmdFlags.concurrency)
+	if err != nil {
+		zap.S().Errorf("Error arose auditing organization")
+		return err
+	}
+
+	if cmdFlags.format == formatJSON {
+		err = utils.WriteUnusedJSON(out, report)
+	} else {
+		err = utils.PrintUnused(out, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cmdFlags.exitCode && len(report.Unused) > 0 {
+		return fmt.Errorf("%s has %d unused secrets and variables", owner, len(report.Unused))
+	}
+	return nil
+}
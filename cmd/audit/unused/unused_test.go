@@ -0,0 +1,140 @@
+package unusedaudit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/fakegithub"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+// newTestServer returns a fake organization with an unused organization
+// secret, an unused repository variable and a workflow using the rest.
+func newTestServer(t *testing.T) *fakegithub.Server {
+	t.Helper()
+	server := fakegithub.New()
+	seed := &fakegithub.Seed{Organizations: []fakegithub.SeedOrganization{{
+		Login: "test-org",
+		Repositories: []fakegithub.SeedRepository{
+			{Name: "api", Files: map[string]string{
+				".github/workflows/ci.yml":         "env:\n  TOKEN: ${{ secrets.NPM_TOKEN }}\n",
+				".github/actions/setup/action.yml": "run: echo ${{ vars.REGION }}\n",
+			}},
+			{Name: "web", Visibility: "public"},
+		},
+		Secrets: []data.ImportedSecret{
+			{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Value: "npm", Access: "all"},
+			{Level: "Organization", Type: "Actions", Name: "DEPLOY_KEY", Value: "deploy", Access: "private"},
+		},
+		Variables: []data.ImportedVariable{
+			{Level: "Organization", Name: "REGION", Value: "eu", Visibility: "all"},
+			{Level: "Repository", Name: "LOG_LEVEL", Value: "info", SelectedRepos: []string{"web"}},
+		},
+	}}}
+	if err := server.Seed(seed); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	return server
+}
+
+func TestNewCmdUnused(t *testing.T) {
+	cmd := NewCmdUnused()
+
+	if cmd.Use != "unused <organization> [flags]" {
+		t.Errorf("Expected Use to be 'unused <organization> [flags]', got %s", cmd.Use)
+	}
+	for _, name := range []string{"format", "exit-code", "concurrency", "token", "hostname", "debug"} {
+		if cmd.Flag(name) == nil {
+			t.Errorf("%s flag not found", name)
+		}
+	}
+	if err := cmd.Args(cmd, []string{}); err == nil {
+		t.Error("Expected an error without an organization")
+	}
+	if err := cmd.Flags().Set("format", "csv"); err != nil {
+		t.Fatalf("Failed to set format: %v", err)
+	}
+	if err := cmd.PreRunE(cmd, []string{"test-org"}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestRunCmdUnused(t *testing.T) {
+	server := newTestServer(t)
+	var out bytes.Buffer
+	err := runCmdUnused("test-org", &cmdFlags{format: formatTable, concurrency: 2}, newTestGetter(t, server.ServeHTTP), &out)
+	if err != nil {
+		t.Fatalf("runCmdUnused() error = %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{"DEPLOY_KEY", "LOG_LEVEL", "Unused: 1 secrets and 1 variables, from 2 workflow and action files in 2 repositories."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	for _, used := range []string{"NPM_TOKEN", "REGION"} {
+		if strings.Contains(output, used) {
+			t.Errorf("Expected %s to be used, got:\n%s", used, output)
+		}
+	}
+}
+
+func TestRunCmdUnusedJSON(t *testing.T) {
+	server := newTestServer(t)
+	var out bytes.Buffer
+	err := runCmdUnused("test-org", &cmdFlags{format: formatJSON, concurrency: 1}, newTestGetter(t, server.ServeHTTP), &out)
+	if err != nil {
+		t.Fatalf("runCmdUnused() error = %v", err)
+	}
+	var report data.UnusedReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Expected a JSON report: %v", err)
+	}
+	if len(report.Unused) != 2 || report.Unused[0].Name != "DEPLOY_KEY" || report.Unused[1].Repository != "web" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestRunCmdUnusedExitCode(t *testing.T) {
+	server := newTestServer(t)
+	var out bytes.Buffer
+	err := runCmdUnused("test-org", &cmdFlags{format: formatTable, exitCode: true, concurrency: 1}, newTestGetter(t, server.ServeHTTP), &out)
+	if err == nil || !strings.Contains(err.Error(), "2 unused secrets and variables") {
+		t.Errorf("Expected an error for unused secrets and variables, got %v", err)
+	}
+}
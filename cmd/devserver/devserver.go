@@ -0,0 +1,113 @@
+package devserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/katiem0/gh-seva/internal/fakegithub"
+	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type cmdFlags struct {
+	listen   string
+	seedFile string
+	debug    bool
+}
+
+func NewCmdDevServer() *cobra.Command {
+	cmdFlags := cmdFlags{}
+
+	devServerCmd := cobra.Command{
+		Use:   "dev-server [flags]",
+		Short: "Serve an in-memory fake of the GitHub API to run commands against offline.",
+		Long:  "Serve an in-memory fake of the GitHub REST and GraphQL APIs used for secrets, variables and environments, decrypting each secret written to it, so files, manifests and migrations can be tried end-to-end without an organization. Nothing is kept once the server stops.",
+		Args:  cobra.NoArgs,
+		RunE: func(devServerCmd *cobra.Command, args []string) error {
+			// Reinitialize logging if debugging was enabled
+			if cmdFlags.debug {
+				logger, _ := log.NewLogger(cmdFlags.debug)
+				defer logger.Sync() // nolint:errcheck
+				zap.ReplaceGlobals(logger)
+			}
+
+			server := fakegithub.New()
+			if cmdFlags.seedFile != "" {
+				zap.S().Debugf("Seeding the server from %s", cmdFlags.seedFile)
+				seed, err := fakegithub.ReadSeed(cmdFlags.seedFile)
+				if err != nil {
+					return err
+				}
+				if err := server.Seed(seed); err != nil {
+					return fmt.Errorf("failed to seed the server: %w", err)
+				}
+			}
+
+			listener, err := net.Listen("tcp", cmdFlags.listen)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(devServerCmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			return runCmdDevServer(ctx, listener, server, devServerCmd.OutOrStdout())
+		},
+	}
+
+	// Configure flags for command
+	devServerCmd.Flags().StringVarP(&cmdFlags.listen, "listen", "l", "127.0.0.1:8080", "Address to listen on")
+	devServerCmd.Flags().StringVarP(&cmdFlags.seedFile, "seed", "s", "", "Path and Name of a YAML or JSON file of the users, organizations, repositories, environments, files, secrets and variables to start with")
+	devServerCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
+
+	return &devServerCmd
+}
+
+// runCmdDevServer serves server on listener until ctx is done. Commands
+// reach it as a proxy for github.localhost, which go-gh sends plain HTTP
+// requests to, so no certificate or name resolution is needed.
+func runCmdDevServer(ctx context.Context, listener net.Listener, server http.Handler, out io.Writer) error {
+	httpServer := &http.Server{
+		Handler:           logRequests(server),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	address := "http://" + listener.Addr().String()
+	_, err := fmt.Fprintf(out, "Serving a fake GitHub API on %s\nRun commands against it with:\n  HTTP_PROXY=%s gh seva <command> --hostname %s --token dev\n", address, address, fakegithub.Hostname)
+	if err != nil {
+		if err := listener.Close(); err != nil {
+			zap.S().Errorf("Error closing listener: %v", err)
+		}
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			zap.S().Errorf("Error shutting down the server: %v", err)
+		}
+	}()
+
+	err = httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		<-done
+		return nil
+	}
+	return err
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zap.S().Debugf("%s %s", r.Method, r.URL.RequestURI())
+		next.ServeHTTP(w, r)
+	})
+}
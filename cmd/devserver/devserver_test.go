@@ -0,0 +1,160 @@
+package devserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/fakegithub"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+// syncBuffer is written by the server while the test reads it
+type syncBuffer struct {
+	bytes.Buffer
+	written chan struct{}
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	n, err := b.Buffer.Write(p)
+	select {
+	case b.written <- struct{}{}:
+	default:
+	}
+	return n, err
+}
+
+func TestNewCmdDevServer(t *testing.T) {
+	cmd := NewCmdDevServer()
+
+	if cmd.Use != "dev-server [flags]" {
+		t.Errorf("Expected Use to be 'dev-server [flags]', got %s", cmd.Use)
+	}
+	for _, name := range []string{"listen", "seed", "debug"} {
+		if cmd.Flag(name) == nil {
+			t.Errorf("%s flag not found", name)
+		}
+	}
+	if err := cmd.Args(cmd, []string{"extra"}); err == nil {
+		t.Error("Expected an error with an argument")
+	}
+}
+
+func TestNewCmdDevServerSeedErrors(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "seed.yaml")
+	if err := os.WriteFile(invalid, []byte("organizations: [{teams: [a]}]"), 0600); err != nil {
+		t.Fatalf("Failed to write seed: %v", err)
+	}
+	tests := []struct {
+		name string
+		seed string
+		want string
+	}{
+		{"missing file", filepath.Join(t.TempDir(), "missing.yaml"), "no such file"},
+		{"invalid seed", invalid, "failed to seed the server"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := NewCmdDevServer()
+			cmd.SetOut(new(bytes.Buffer))
+			cmd.SetArgs([]string{"--seed", tc.seed, "--listen", "127.0.0.1:0"})
+			err := cmd.Execute()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestRunCmdDevServer(t *testing.T) {
+	server := fakegithub.New()
+	server.AddRepository("test-org", "repo-a", "private")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{written: make(chan struct{}, 1)}
+	result := make(chan error, 1)
+	go func() {
+		result <- runCmdDevServer(ctx, listener, server, out)
+	}()
+	<-out.written
+
+	// Reach the server as the printed command does, as a proxy for
+	// github.localhost
+	proxy, _ := url.Parse("http://" + listener.Addr().String())
+	options := api.ClientOptions{
+		Host:      fakegithub.Hostname,
+		AuthToken: "dev",
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxy)},
+	}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	g := utils.NewAPIGetter(utils.NewGitHubGetter(gqlClient, restClient))
+
+	secrets := []data.ImportedSecret{{
+		Level:           "Organization",
+		Type:            "Actions",
+		Name:            "DEPLOY_KEY",
+		Value:           "offline",
+		Access:          "selected",
+		RepositoryNames: []string{"repo-a"},
+	}}
+	for _, err := range g.ResolveSecretRepos("test-org", secrets) {
+		if err != nil {
+			t.Fatalf("Failed to resolve repositories: %v", err)
+		}
+	}
+	if err := g.CreateImportedSecret("test-org", secrets[0]); err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	if secret, _ := server.Secret("test-org", "", "", "Actions", "DEPLOY_KEY"); secret.Value != "offline" {
+		t.Errorf("Expected the decrypted value offline, got %q", secret.Value)
+	}
+
+	cancel()
+	if err := <-result; err != nil {
+		t.Errorf("Expected the server to stop cleanly, got %v", err)
+	}
+	if !strings.Contains(out.String(), "HTTP_PROXY=http://"+listener.Addr().String()) {
+		t.Errorf("Expected instructions to use the server as a proxy, got %q", out.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestRunCmdDevServerWriteError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	err = runCmdDevServer(context.Background(), listener, fakegithub.New(), failingWriter{})
+	if err == nil || err.Error() != "broken pipe" {
+		t.Fatalf("Expected the write error, got %v", err)
+	}
+	if _, err := listener.Accept(); err == nil {
+		t.Error("Expected the listener to be closed")
+	}
+}
@@ -0,0 +1,189 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// Formats diff prints differences in
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+type cmdFlags struct {
+	sourceOrg      string
+	sourceToken    string
+	sourceHostname string
+	secretsFile    string
+	variablesFile  string
+	format         string
+	exitCode       bool
+	concurrency    int
+	token          string
+	hostname       string
+	debug          bool
+}
+
+func NewCmdDiff() *cobra.Command {
+	cmdFlags := cmdFlags{}
+
+	diffCmd := cobra.Command{
+		Use:   "diff <organization> [flags]",
+		Short: "Compare the secrets and variables of an organization with another organization or an export file.",
+		Long:  "Compare the Actions, Dependabot, and Codespaces secrets and Actions variables of an organization, its repositories and environments with those of a source organization, possibly on another host, or of an export file, listing what is missing, extra and changed.",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(diffCmd *cobra.Command, args []string) error {
+			files := len(cmdFlags.secretsFile) > 0 || len(cmdFlags.variablesFile) > 0
+			if len(cmdFlags.sourceOrg) == 0 && !files {
+				return errors.New("a source organization, or a secrets or variables file, must be specified to compare with")
+			} else if len(cmdFlags.sourceOrg) > 0 && files {
+				return errors.New("specify only one of `--source-organization` or `--secrets-file` and `--variables-file`")
+			}
+			if cmdFlags.format != formatTable && cmdFlags.format != formatJSON {
+				return fmt.Errorf("unknown format %q, expected table or json", cmdFlags.format)
+			}
+			return nil
+		},
+		RunE: func(diffCmd *cobra.Command, args []string) error {
+			// Reinitialize logging if debugging was enabled
+			if cmdFlags.debug {
+				logger, _ := log.NewLogger(cmdFlags.debug)
+				defer logger.Sync() // nolint:errcheck
+				zap.ReplaceGlobals(logger)
+			}
+
+			backend, err := newGitHubGetter(cmdFlags.hostname, cmdFlags.token)
+			if err != nil {
+				return err
+			}
+			var source utils.Getter
+			if len(cmdFlags.sourceOrg) > 0 {
+				if source, err = newGitHubGetter(cmdFlags.sourceHostname, cmdFlags.sourceToken); err != nil {
+					return err
+				}
+			}
+
+			return runCmdDiff(args[0], &cmdFlags, backend, source, diffCmd.OutOrStdout())
+		},
+	}
+
+	// Configure flags for command
+	diffCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub personal access token for the organization to compare (default "gh auth token")`)
+	diffCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
+	diffCmd.PersistentFlags().StringVarP(&cmdFlags.sourceOrg, "source-organization", "o", "", "Name of the Source Organization to compare with")
+	diffCmd.PersistentFlags().StringVarP(&cmdFlags.sourceToken, "source-token", "s", "", `GitHub personal access token for Source Organization (default "gh auth token" of --source-hostname)`)
+	diffCmd.PersistentFlags().StringVarP(&cmdFlags.sourceHostname, "source-hostname", "", "github.com", "GitHub Enterprise Server hostname of the Source Organization")
+	diffCmd.Flags().StringVar(&cmdFlags.secretsFile, "secrets-file", "", "Path and Name of a CSV, JSON or YAML secrets export to compare with")
+	diffCmd.Flags().StringVar(&cmdFlags.variablesFile, "variables-file", "", "Path and Name of a CSV, JSON or YAML variables export to compare with")
+	diffCmd.Flags().StringVar(&cmdFlags.format, "format", formatTable, "Output format: table or json")
+	diffCmd.Flags().BoolVar(&cmdFlags.exitCode, "exit-code", false, "Exit with a non-zero status when there are differences")
+	diffCmd.Flags().IntVarP(&cmdFlags.concurrency, "concurrency", "c", 1, "Number of repositories to read concurrently")
+	diffCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
+
+	return &diffCmd
+}
+
+func newGitHubGetter(hostname string, token string) (utils.Getter, error) {
+	if token == "" {
+		token, _ = auth.TokenForHost(hostname)
+	}
+
+	gqlClient, err := api.NewGraphQLClient(api.ClientOptions{
+		Headers: map[string]string{
+			"Accept": "application/vnd.github.hawkgirl-preview+json",
+		},
+		Host:      hostname,
+		AuthToken: token,
+	})
+	if err != nil {
+		zap.S().Errorf("Error arose retrieving graphql client for %s", hostname)
+		return nil, err
+	}
+
+	restClient, err := api.NewRESTClient(api.ClientOptions{
+		Headers: map[string]string{
+			"Accept": "application/vnd.github+json",
+		},
+		Host:      hostname,
+		AuthToken: token,
+	})
+	if err != nil {
+		zap.S().Errorf("Error arose retrieving rest client for %s", hostname)
+		return nil, err
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient), nil
+}
+
+// runCmdDiff compares owner with the source organization read through
+// source or, when source is nil, with the export files. Only what the files
+// cover is read from owner.
+func runCmdDiff(owner string, cmdFlags *cmdFlags, backend utils.Getter, source utils.Getter, out io.Writer) error {
+	g := utils.NewAPIGetter(backend)
+	var sourceState *utils.OrgState
+	var sourceName string
+	secrets, variables := true, true
+	if source != nil {
+		zap.S().Debugf("Gathering secrets and variables of %s", cmdFlags.sourceOrg)
+		var err error
+		sourceState, err = utils.NewAPIGetter(source).ReadOrgState(cmdFlags.sourceOrg, cmdFlags.concurrency, true, true)
+		if err != nil {
+			zap.S().Errorf("Error arose reading source organization")
+			return err
+		}
+		sourceName = cmdFlags.sourceOrg
+	} else {
+		var fileSecrets []data.ImportedSecret
+		var fileVariables []data.ImportedVariable
+		var err error
+		secrets, variables = len(cmdFlags.secretsFile) > 0, len(cmdFlags.variablesFile) > 0
+		if secrets {
+			zap.S().Debugf("Reading in secrets from %s", cmdFlags.secretsFile)
+			if fileSecrets, err = utils.ReadSecretsFile(cmdFlags.secretsFile); err != nil {
+				zap.S().Errorf("Error arose reading secrets file")
+				return err
+			}
+		}
+		if variables {
+			zap.S().Debugf("Reading in variables from %s", cmdFlags.variablesFile)
+			if fileVariables, err = utils.ReadVariablesFile(cmdFlags.variablesFile); err != nil {
+				zap.S().Errorf("Error arose reading variables file")
+				return err
+			}
+		}
+		sourceState = utils.NewFileState(fileSecrets, fileVariables)
+		sourceName = "the export"
+	}
+
+	zap.S().Debugf("Gathering secrets and variables of %s", owner)
+	targetState, err := g.ReadOrgState(owner, cmdFlags.concurrency, secrets, variables)
+	if err != nil {
+		zap.S().Errorf("Error arose reading organization")
+		return err
+	}
+
+	items := utils.DiffOrgStates(sourceState, targetState)
+	if cmdFlags.format == formatJSON {
+		err = utils.WriteDiffJSON(out, items)
+	} else {
+		err = utils.PrintDiff(out, items)
+	}
+	if err != nil {
+		return err
+	}
+
+	differences := len(utils.Differences(items))
+	if cmdFlags.exitCode && differences > 0 {
+		return fmt.Errorf("%s differs from %s in %d secrets and variables", owner, sourceName, differences)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+// orgHandler serves an organization without repositories that has the
+// Actions secrets and variables given as JSON lists.
+func orgHandler(owner string, secrets string, variables string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/graphql":
+			_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"totalCount":0,"nodes":[],"pageInfo":{"hasNextPage":false}}}}}`))
+		case "/orgs/" + owner + "/actions/secrets":
+			_, _ = w.Write([]byte(`{"secrets":` + secrets + `}`))
+		case "/orgs/" + owner + "/actions/variables":
+			_, _ = w.Write([]byte(`{"variables":` + variables + `}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}
+}
+
+func TestNewCmdDiff(t *testing.T) {
+	cmd := NewCmdDiff()
+
+	if cmd.Use != "diff <organization> [flags]" {
+		t.Errorf("Expected Use to be 'diff <organization> [flags]', got %s", cmd.Use)
+	}
+	for _, name := range []string{"source-organization", "source-token", "source-hostname", "secrets-file", "variables-file", "format", "exit-code", "concurrency", "token", "hostname", "debug"} {
+		if cmd.Flag(name) == nil {
+			t.Errorf("%s flag not found", name)
+		}
+	}
+	if err := cmd.Args(cmd, []string{}); err == nil {
+		t.Error("Expected an error without an organization")
+	}
+}
+
+func TestNewCmdDiffPreRunE(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"nothing to compare", nil, "a source organization, or a secrets or variables file, must be specified"},
+		{"both", []string{"--source-organization", "source-org", "--secrets-file", "secrets.csv"}, "specify only one of"},
+		{"unknown format", []string{"--variables-file", "variables.csv", "--format", "xml"}, `unknown format "xml"`},
+		{"source organization", []string{"--source-organization", "source-org"}, ""},
+		{"files", []string{"--secrets-file", "secrets.csv", "--variables-file", "variables.csv", "--format", "json"}, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := NewCmdDiff()
+			if err := cmd.ParseFlags(tc.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			err := cmd.PreRunE(cmd, []string{"test-org"})
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			} else if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRunCmdDiff(t *testing.T) {
+	source := newTestGetter(t, orgHandler("source-org",
+		`[{"name":"NPM_TOKEN","visibility":"all"},{"name":"DEPLOY_KEY","visibility":"all"}]`,
+		`[{"name":"REGION","value":"eu","visibility":"all"}]`))
+	g := newTestGetter(t, orgHandler("test-org",
+		`[{"name":"NPM_TOKEN","visibility":"private"},{"name":"OLD_TOKEN","visibility":"all"}]`,
+		`[{"name":"REGION","value":"eu","visibility":"all"}]`))
+	flags := &cmdFlags{sourceOrg: "source-org", format: formatTable, concurrency: 1}
+
+	var out bytes.Buffer
+	if err := runCmdDiff("test-org", flags, g, source, &out); err != nil {
+		t.Fatalf("runCmdDiff() error = %v", err)
+	}
+	for _, want := range []string{"visibility: private -> all", "missing  secret", "extra    secret", "Diff: 1 missing, 1 extra, 1 changed, 1 identical."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output containing %q, got:\n%s", want, out.String())
+		}
+	}
+
+	flags.exitCode = true
+	err := runCmdDiff("test-org", flags, g, source, &bytes.Buffer{})
+	if err == nil || err.Error() != "test-org differs from source-org in 3 secrets and variables" {
+		t.Errorf("Expected an error with --exit-code, got %v", err)
+	}
+}
+
+func TestRunCmdDiffFile(t *testing.T) {
+	var requested []string
+	handler := orgHandler("test-org", `[]`, `[{"name":"REGION","value":"us","visibility":"all"}]`)
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		handler(w, r)
+	})
+	fileName := filepath.Join(t.TempDir(), "variables.yaml")
+	if err := os.WriteFile(fileName, []byte("- level: Organization\n  name: REGION\n  value: eu\n  visibility: all\n"), 0600); err != nil {
+		t.Fatalf("Failed to write variables file: %v", err)
+	}
+	flags := &cmdFlags{variablesFile: fileName, format: formatJSON, exitCode: true, concurrency: 1}
+
+	var out bytes.Buffer
+	err := runCmdDiff("test-org", flags, g, nil, &out)
+	if err == nil || err.Error() != "test-org differs from the export in 1 secrets and variables" {
+		t.Errorf("Expected an error with --exit-code, got %v", err)
+	}
+	var items []data.DiffItem
+	if err := json.Unmarshal(out.Bytes(), &items); err != nil {
+		t.Fatalf("Failed to parse JSON output %q: %v", out.String(), err)
+	}
+	if len(items) != 1 || items[0].Name != "REGION" || strings.Join(items[0].Details, "; ") != `value: "us" -> "eu"` {
+		t.Errorf("Expected the changed value of REGION, got %+v", items)
+	}
+	for _, path := range requested {
+		if strings.Contains(path, "/secrets") {
+			t.Errorf("Expected no secrets to be read without a secrets file, got a request for %s", path)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package createenvs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type cmdFlags struct {
+	fileName    string
+	resultsFile string
+	token       string
+	hostname    string
+	debug       bool
+}
+
+func NewCmdCreate() *cobra.Command {
+	cmdFlags := cmdFlags{}
+	var authToken string
+
+	createCmd := cobra.Command{
+		Use:   "create <organization> [flags]",
+		Short: "Create deployment environments from a file.",
+		Long:  "Create deployment environments and their protection rules for repositories in an organization from a file. Run this before creating environment secrets and variables.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(createCmd *cobra.Command, args []string) error {
+			var err error
+			var gqlClient *api.GraphQLClient
+			var restClient *api.RESTClient
+
+			// Reinitialize logging if debugging was enabled
+			if cmdFlags.debug {
+				logger, _ := log.NewLogger(cmdFlags.debug)
+				defer logger.Sync() // nolint:errcheck
+				zap.ReplaceGlobals(logger)
+			}
+
+			if cmdFlags.token != "" {
+				authToken = cmdFlags.token
+			} else {
+				t, _ := auth.TokenForHost(cmdFlags.hostname)
+				authToken = t
+			}
+
+			gqlClient, err = api.NewGraphQLClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github.hawkgirl-preview+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving graphql client")
+				return err
+			}
+
+			restClient, err = api.NewRESTClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving rest client")
+				return err
+			}
+
+			owner := args[0]
+
+			return runCmdCreate(owner, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), os.Stdout)
+		},
+	}
+
+	// Configure flags for command
+	createCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub personal access token for organization to write to (default "gh auth token")`)
+	createCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
+	createCmd.Flags().StringVarP(&cmdFlags.fileName, "from-file", "f", "", "Path and Name of CSV file to create environments from (required)")
+	createCmd.Flags().StringVar(&cmdFlags.resultsFile, "results-file", "", "Path and Name of a CSV, or .json, file to write the result of each environment to")
+	createCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
+	if err := createCmd.MarkFlagRequired("from-file"); err != nil {
+		zap.S().Errorf("Error marking from-file flag as required: %v", err)
+		return nil
+	}
+
+	return &createCmd
+}
+
+func runCmdCreate(owner string, cmdFlags *cmdFlags, backend utils.Getter, out io.Writer) error {
+	g := utils.NewAPIGetter(backend)
+	f, err := os.Open(cmdFlags.fileName)
+	zap.S().Debugf("Opening up file %s", cmdFlags.fileName)
+	if err != nil {
+		zap.S().Errorf("Error arose opening environments csv file")
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			zap.S().Errorf("Error closing file: %v", err)
+		}
+	}()
+
+	// read csv values using csv.Reader
+	csvReader := csv.NewReader(f)
+	environmentData, err := csvReader.ReadAll()
+	zap.S().Debugf("Reading in all lines from csv file")
+	if err != nil {
+		zap.S().Errorf("Error arose reading environments from csv file")
+		return err
+	}
+	environmentList, err := utils.ParseEnvironmentRecords(environmentData)
+	if err != nil {
+		zap.S().Errorf("Error arose reading environments file")
+		return fmt.Errorf("invalid environments file %s:\n%w", cmdFlags.fileName, err)
+	}
+
+	// Reviewers are frequently shared between environments, so each
+	// login and team slug is only resolved once in the target organization
+	resolvedReviewers := make(map[string]int)
+
+	results := make([]data.RowResult, len(environmentList))
+	for index, environment := range environmentList {
+		err := createEnvironment(owner, environment, resolvedReviewers, g)
+		if err != nil {
+			zap.S().Errorf("Error arose creating environment %s in %s: %v", environment.Name, environment.RepositoryName, err)
+		}
+		target := utils.Target(owner, "Environment", environment.RepositoryName, environment.Name)
+		results[index] = utils.NewRowResult(utils.ResultRow(environment.Row, index), "Environment", "", environment.Name, target, err)
+	}
+
+	if len(cmdFlags.resultsFile) > 0 {
+		zap.S().Debugf("Writing results to %s", cmdFlags.resultsFile)
+		if err := utils.WriteResults(cmdFlags.resultsFile, results); err != nil {
+			zap.S().Errorf("Error arose writing results file")
+			return err
+		}
+	}
+	if err := utils.PrintResultSummary(out, "environments", results); err != nil {
+		return err
+	}
+	if failed := utils.CountFailedResults(results); failed > 0 {
+		return fmt.Errorf("failed to create %d of %d environments for: %s", failed, len(results), owner)
+	}
+	_, err = fmt.Fprintf(out, "Successfully created environments for: %s.\n", owner)
+	return err
+}
+
+// createEnvironment creates environment with its reviewers and, for a custom
+// deployment branch policy, each of its branch policies. A reviewer that
+// cannot be resolved fails the environment before it is created.
+func createEnvironment(owner string, environment data.ImportedEnvironment, resolvedReviewers map[string]int, g *utils.APIGetter) error {
+	zap.S().Debugf("Resolving reviewers for environment %s in %s", environment.Name, environment.RepositoryName)
+	var reviewers []data.CreateEnvironmentReviewer
+	for _, reviewer := range environment.Reviewers {
+		reviewerID, ok := resolvedReviewers[reviewer]
+		if !ok {
+			var err error
+			reviewerID, err = resolveReviewer(owner, reviewer, g)
+			if err != nil {
+				return fmt.Errorf("resolving reviewer %s: %w", reviewer, err)
+			}
+			resolvedReviewers[reviewer] = reviewerID
+		}
+		reviewerType, _ := utils.ParseEnvironmentReviewer(reviewer)
+		reviewers = append(reviewers, data.CreateEnvironmentReviewer{Type: reviewerType, ID: reviewerID})
+	}
+
+	createEnvironment, err := json.Marshal(utils.CreateEnvironmentData(environment, reviewers))
+	if err != nil {
+		return err
+	}
+
+	zap.S().Debugf("Creating environment %s in %s", environment.Name, environment.RepositoryName)
+	err = g.CreateEnvironment(owner, environment.RepositoryName, environment.Name, bytes.NewReader(createEnvironment))
+	if err != nil {
+		return err
+	}
+
+	if environment.DeploymentBranchPolicy != "custom" {
+		return nil
+	}
+	for _, policy := range environment.BranchPolicies {
+		createPolicy, err := json.Marshal(utils.ParseEnvironmentBranchPolicy(policy))
+		if err != nil {
+			return err
+		}
+		zap.S().Debugf("Creating deployment branch policy %s for environment %s", policy, environment.Name)
+		err = g.CreateEnvironmentBranchPolicy(owner, environment.RepositoryName, environment.Name, bytes.NewReader(createPolicy))
+		if err != nil {
+			return fmt.Errorf("creating deployment branch policy %s: %w", policy, err)
+		}
+	}
+	return nil
+}
+
+func resolveReviewer(owner string, reviewer string, g *utils.APIGetter) (int, error) {
+	var response []byte
+	var err error
+
+	reviewerType, identifier := utils.ParseEnvironmentReviewer(reviewer)
+	switch reviewerType {
+	case "Team":
+		response, err = g.GetOrgTeam(owner, identifier)
+	case "User":
+		response, err = g.GetUser(identifier)
+	default:
+		return 0, fmt.Errorf("unknown reviewer type %s", reviewerType)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var reviewerInfo data.ReviewerInfo
+	err = json.Unmarshal(response, &reviewerInfo)
+	if err != nil {
+		return 0, err
+	}
+	if reviewerInfo.ID == 0 {
+		return 0, fmt.Errorf("%s %s was not found", reviewerType, identifier)
+	}
+	return reviewerInfo.ID, nil
+}
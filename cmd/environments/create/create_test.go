@@ -0,0 +1,140 @@
+package createenvs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/fakegithub"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+func TestNewCmdCreate(t *testing.T) {
+	cmd := NewCmdCreate()
+
+	if cmd == nil {
+		t.Fatal("NewCmdCreate() returned nil")
+	}
+
+	if cmd.Use != "create <organization> [flags]" {
+		t.Errorf("Expected Use to be 'create <organization> [flags]', got %s", cmd.Use)
+	}
+
+	for _, flag := range []string{"from-file", "results-file", "token", "hostname", "debug"} {
+		if cmd.Flag(flag) == nil {
+			t.Errorf("%s flag not found", flag)
+		}
+	}
+
+	fromFile := cmd.Flag("from-file")
+	if fromFile != nil {
+		if annotations := fromFile.Annotations["cobra_annotation_bash_completion_one_required_flag"]; len(annotations) == 0 || annotations[0] != "true" {
+			t.Error("from-file flag should be required")
+		}
+	}
+}
+
+func TestRunCmdCreatePartialFailure(t *testing.T) {
+	server := fakegithub.New()
+	server.AddRepository("test-org", "api", "private")
+	server.AddUser("octocat")
+	server.AddTeam("test-org", "platform")
+
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "environments.csv")
+	content := "RepositoryName,EnvironmentName,WaitTimer,PreventSelfReview,Reviewers,DeploymentBranchPolicy,BranchPolicies,CanAdminsBypass\n" +
+		"api,production,30,true,User:octocat;Team:platform,custom,branch:main;tag:v*,false\n" +
+		"api,staging,,,User:hubot,,,\n" +
+		"missing,qa,,,,,,\n"
+	if err := os.WriteFile(fileName, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write environments file: %v", err)
+	}
+	resultsFile := filepath.Join(dir, "results.csv")
+
+	var out bytes.Buffer
+	flags := &cmdFlags{fileName: fileName, resultsFile: resultsFile}
+	err := runCmdCreate("test-org", flags, newTestGetter(t, server.ServeHTTP), &out)
+	if err == nil || !strings.Contains(err.Error(), "failed to create 2 of 3 environments") {
+		t.Fatalf("Expected 2 of 3 environments to fail, got %v", err)
+	}
+
+	if _, ok := server.Environment("test-org", "api", "production"); !ok {
+		t.Error("Expected production to be created")
+	}
+	if policies := server.BranchPolicies("test-org", "api", "production"); len(policies) != 2 {
+		t.Errorf("Expected 2 branch policies, got %+v", policies)
+	}
+	if _, ok := server.Environment("test-org", "api", "staging"); ok {
+		t.Error("Expected staging not to be created with an unknown reviewer")
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"Row 2: failed test-org/api (staging) staging: resolving reviewer User:hubot",
+		"Row 3: failed test-org/missing (qa) qa:",
+		"Summary: 1 environments succeeded, 0 skipped, 2 failed.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	results, err := os.ReadFile(resultsFile)
+	if err != nil {
+		t.Fatalf("Expected a results file: %v", err)
+	}
+	if !strings.Contains(string(results), "1,Environment,,production,test-org/api (production),succeeded") {
+		t.Errorf("Expected production to be reported as succeeded, got:\n%s", results)
+	}
+}
+
+func TestRunCmdCreateInvalidFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "environments.csv")
+	content := "RepositoryName,EnvironmentName,WaitTimer\n" +
+		"api,production,soon\n"
+	if err := os.WriteFile(fileName, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write environments file: %v", err)
+	}
+
+	getter := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request %s %s for an invalid file", r.Method, r.URL.Path)
+	})
+	var out bytes.Buffer
+	err := runCmdCreate("test-org", &cmdFlags{fileName: fileName}, getter, &out)
+	if err == nil || !strings.Contains(err.Error(), `row 1, column WaitTimer (C): invalid wait timer "soon"`) {
+		t.Errorf("Expected the invalid wait timer to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package environments
+
+import (
+	createCmd "github.com/katiem0/gh-seva/cmd/environments/create"
+	exportCmd "github.com/katiem0/gh-seva/cmd/environments/export"
+	"github.com/spf13/cobra"
+)
+
+func NewCmdEnvironments() *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:   "environments <command> [flags]",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Export and Create deployment environments for repositories.",
+		Long:  "Export and Create deployment environments, including wait timers, required reviewers, deployment branch policies and admin bypass, for repositories in an organization.",
+	}
+	cmd.Flags().Bool("help", false, "Show help for command")
+	cmd.AddCommand(exportCmd.NewCmdExport())
+	cmd.AddCommand(createCmd.NewCmdCreate())
+
+	return cmd
+}
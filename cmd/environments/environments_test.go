@@ -0,0 +1,40 @@
+package environments
+
+import (
+	"testing"
+)
+
+func TestNewCmdEnvironments(t *testing.T) {
+	cmd := NewCmdEnvironments()
+
+	if cmd == nil {
+		t.Fatal("NewCmdEnvironments() returned nil")
+	}
+
+	if cmd.Use != "environments <command> [flags]" {
+		t.Errorf("Expected Use to be 'environments <command> [flags]', got %s", cmd.Use)
+	}
+
+	subCommands := cmd.Commands()
+	if len(subCommands) != 2 {
+		t.Fatalf("Expected 2 subcommands, got %d", len(subCommands))
+	}
+
+	foundCreate := false
+	foundExport := false
+	for _, subCmd := range subCommands {
+		switch subCmd.Name() {
+		case "create":
+			foundCreate = true
+		case "export":
+			foundExport = true
+		}
+	}
+
+	if !foundCreate {
+		t.Error("create subcommand not found")
+	}
+	if !foundExport {
+		t.Error("export subcommand not found")
+	}
+}
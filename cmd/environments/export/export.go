@@ -0,0 +1,222 @@
+package exportenvs
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type cmdFlags struct {
+	hostname   string
+	token      string
+	reportFile string
+	debug      bool
+}
+
+func NewCmdExport() *cobra.Command {
+	cmdFlags := cmdFlags{}
+	var authToken string
+
+	exportCmd := cobra.Command{
+		Use:   "export [flags] <organization> [repo ...] ",
+		Short: "Generate a report of deployment environments for an organization and/or repositories.",
+		Long:  "Generate a report of deployment environments and their protection rules for an organization and/or repositories.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(exportCmd *cobra.Command, args []string) error {
+			var err error
+			var gqlClient *api.GraphQLClient
+			var restClient *api.RESTClient
+
+			// Reinitialize logging if debugging was enabled
+			if cmdFlags.debug {
+				logger, _ := log.NewLogger(cmdFlags.debug)
+				defer logger.Sync() // nolint:errcheck
+				zap.ReplaceGlobals(logger)
+			}
+
+			if cmdFlags.token != "" {
+				authToken = cmdFlags.token
+			} else {
+				t, _ := auth.TokenForHost(cmdFlags.hostname)
+				authToken = t
+			}
+
+			gqlClient, err = api.NewGraphQLClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github.hawkgirl-preview+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving graphql client")
+				return err
+			}
+
+			restClient, err = api.NewRESTClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving rest client")
+				return err
+			}
+
+			owner := args[0]
+			repos := args[1:]
+
+			if _, err := os.Stat(cmdFlags.reportFile); errors.Is(err, os.ErrExist) {
+				return err
+			}
+
+			reportWriter, err := os.OpenFile(cmdFlags.reportFile, os.O_WRONLY|os.O_CREATE, 0644)
+
+			if err != nil {
+				return err
+			}
+
+			return runCmdExport(owner, repos, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), reportWriter)
+		},
+	}
+
+	// Determine default report file based on current timestamp; for more info see https://pkg.go.dev/time#pkg-constants
+	reportFileDefault := fmt.Sprintf("report-environments-%s.csv", time.Now().Format("20060102150405"))
+	// Configure flags for command
+	exportCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub Personal Access Token (default "gh auth token")`)
+	exportCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
+	exportCmd.Flags().StringVarP(&cmdFlags.reportFile, "output-file", "o", reportFileDefault, "Name of file to write CSV report")
+	exportCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
+
+	return &exportCmd
+}
+
+// runCmdExport writes the environments of every repository to
+// reportWriter. Should any repository fail to export, no environments are
+// written, as a partial export would be mistaken for a complete one.
+func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, backend utils.Getter, reportWriter io.Writer) error {
+	g := utils.NewAPIGetter(backend)
+
+	allRepos, err := g.ListRepos(owner, repos)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, singleRepo := range allRepos {
+		zap.S().Debugf("Gathering environments for repo %s", singleRepo.Name)
+		repoRows, err := repoEnvironmentRows(owner, singleRepo.Name, g)
+		if err != nil {
+			zap.S().Errorf("Error arose exporting environments for %s", singleRepo.Name)
+			return fmt.Errorf("failed to export environments for %s/%s: %w", owner, singleRepo.Name, err)
+		}
+		rows = append(rows, repoRows...)
+	}
+
+	csvWriter := csv.NewWriter(reportWriter)
+	err = csvWriter.Write([]string{
+		"RepositoryName",
+		"EnvironmentName",
+		"WaitTimer",
+		"PreventSelfReview",
+		"Reviewers",
+		"DeploymentBranchPolicy",
+		"BranchPolicies",
+		"CanAdminsBypass",
+	})
+	if err != nil {
+		return err
+	}
+	err = csvWriter.WriteAll(rows)
+	if err != nil {
+		zap.S().Error("Error raised in writing output", zap.Error(err))
+		return err
+	}
+
+	fmt.Printf("Successfully exported environments for %s to %s\n", owner, cmdFlags.reportFile)
+	return nil
+}
+
+// repoEnvironmentRows returns a report row for each environment of repo.
+func repoEnvironmentRows(owner string, repo string, g *utils.APIGetter) ([][]string, error) {
+	environmentsList, err := g.GetRepoEnvironments(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var environmentsResponse data.EnvironmentsResponse
+	err = json.Unmarshal(environmentsList, &environmentsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, environment := range environmentsResponse.Environments {
+		waitTimer := 0
+		preventSelfReview := false
+		for _, rule := range environment.ProtectionRules {
+			switch rule.Type {
+			case "wait_timer":
+				waitTimer = rule.WaitTimer
+			case "required_reviewers":
+				preventSelfReview = rule.PreventSelfReview
+			}
+		}
+
+		branchPolicy := "all"
+		var branchPolicies []string
+		if environment.DeploymentBranchPolicy != nil {
+			if environment.DeploymentBranchPolicy.ProtectedBranches {
+				branchPolicy = "protected"
+			} else if environment.DeploymentBranchPolicy.CustomBranchPolicies {
+				branchPolicy = "custom"
+				zap.S().Debugf("Gathering deployment branch policies for environment %s in %s", environment.Name, repo)
+				policiesList, err := g.GetEnvironmentBranchPolicies(owner, repo, environment.Name)
+				if err != nil {
+					return nil, err
+				}
+				var policiesResponse data.BranchPoliciesResponse
+				err = json.Unmarshal(policiesList, &policiesResponse)
+				if err != nil {
+					return nil, err
+				}
+				for _, policy := range policiesResponse.BranchPolicies {
+					policyType := policy.Type
+					if policyType == "" {
+						policyType = "branch"
+					}
+					branchPolicies = append(branchPolicies, policyType+":"+policy.Name)
+				}
+			}
+		}
+
+		rows = append(rows, []string{
+			repo,
+			environment.Name,
+			strconv.Itoa(waitTimer),
+			strconv.FormatBool(preventSelfReview),
+			strings.Join(utils.FormatEnvironmentReviewers(environment.ProtectionRules), ";"),
+			branchPolicy,
+			strings.Join(branchPolicies, ";"),
+			strconv.FormatBool(environment.CanAdminsBypass),
+		})
+	}
+	return rows, nil
+}
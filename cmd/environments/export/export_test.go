@@ -0,0 +1,117 @@
+package exportenvs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/fakegithub"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+func TestNewCmdExport(t *testing.T) {
+	cmd := NewCmdExport()
+
+	if cmd == nil {
+		t.Fatal("NewCmdExport() returned nil")
+	}
+
+	if cmd.Use != "export [flags] <organization> [repo ...] " {
+		t.Errorf("Expected Use to be 'export [flags] <organization> [repo ...] ', got %s", cmd.Use)
+	}
+
+	for _, flag := range []string{"output-file", "token", "hostname", "debug"} {
+		if cmd.Flag(flag) == nil {
+			t.Errorf("%s flag not found", flag)
+		}
+	}
+
+	if cmd.Short == "" {
+		t.Error("Command should have a short description")
+	}
+}
+
+func TestRunCmdExport(t *testing.T) {
+	server := fakegithub.New()
+	server.AddRepository("test-org", "api", "private")
+	server.AddRepository("test-org", "web", "private")
+	if err := server.AddEnvironment("test-org", "api", "production"); err != nil {
+		t.Fatalf("Failed to add environment: %v", err)
+	}
+	if err := server.AddEnvironment("test-org", "web", "staging"); err != nil {
+		t.Fatalf("Failed to add environment: %v", err)
+	}
+
+	var report bytes.Buffer
+	err := runCmdExport("test-org", nil, &cmdFlags{}, newTestGetter(t, server.ServeHTTP), &report)
+	if err != nil {
+		t.Fatalf("runCmdExport() error = %v", err)
+	}
+	records, err := csv.NewReader(&report).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected a header and 2 environments, got %v", records)
+	}
+	if records[1][0] != "api" || records[1][1] != "production" || records[2][0] != "web" || records[2][1] != "staging" {
+		t.Errorf("Unexpected environments: %v", records[1:])
+	}
+}
+
+func TestRunCmdExportRepositoryError(t *testing.T) {
+	server := fakegithub.New()
+	server.AddRepository("test-org", "api", "private")
+	server.AddRepository("test-org", "web", "private")
+	if err := server.AddEnvironment("test-org", "api", "production"); err != nil {
+		t.Fatalf("Failed to add environment: %v", err)
+	}
+	getter := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/test-org/web/environments" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		server.ServeHTTP(w, r)
+	})
+
+	var report bytes.Buffer
+	err := runCmdExport("test-org", nil, &cmdFlags{}, getter, &report)
+	if err == nil || !strings.Contains(err.Error(), "failed to export environments for test-org/web") {
+		t.Fatalf("Expected the web repository to fail the export, got %v", err)
+	}
+	if report.Len() != 0 {
+		t.Errorf("Expected nothing to be written for a failed export, got:\n%s", report.String())
+	}
+}
@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	applyCmd "github.com/katiem0/gh-seva/cmd/apply"
+	auditCmd "github.com/katiem0/gh-seva/cmd/audit"
+	devServerCmd "github.com/katiem0/gh-seva/cmd/devserver"
+	diffCmd "github.com/katiem0/gh-seva/cmd/diff"
+	environmentsCmd "github.com/katiem0/gh-seva/cmd/environments"
 	secretsCmd "github.com/katiem0/gh-seva/cmd/secrets"
 	variablesCmd "github.com/katiem0/gh-seva/cmd/variables"
 	"github.com/spf13/cobra"
@@ -10,13 +15,18 @@ func NewCmdRoot() *cobra.Command {
 
 	cmdRoot := &cobra.Command{
 		Use:   "seva <command> <subcommand> [flags]",
-		Short: "Export and Create secrets and variables.",
-		Long:  "Export and Create secrets and variables for an organization and/or repositories.",
+		Short: "Export and Create secrets, variables and environments.",
+		Long:  "Export and Create secrets, variables and environments for an organization and/or repositories.",
 	}
 	cmdRoot.PersistentFlags().Bool("help", false, "Show help for command")
 
 	cmdRoot.AddCommand(secretsCmd.NewCmdSecrets())
 	cmdRoot.AddCommand(variablesCmd.NewCmdVariables())
+	cmdRoot.AddCommand(environmentsCmd.NewCmdEnvironments())
+	cmdRoot.AddCommand(applyCmd.NewCmdApply())
+	cmdRoot.AddCommand(diffCmd.NewCmdDiff())
+	cmdRoot.AddCommand(auditCmd.NewCmdAudit())
+	cmdRoot.AddCommand(devServerCmd.NewCmdDevServer())
 	cmdRoot.CompletionOptions.DisableDefaultCmd = true
 	cmdRoot.SetHelpCommand(&cobra.Command{
 		Use:    "no-help",
@@ -0,0 +1,148 @@
+package applysealedsecrets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type cmdFlags struct {
+	fileName    string
+	concurrency int
+	resultsFile string
+	token       string
+	hostname    string
+	debug       bool
+}
+
+func NewCmdApplySealed() *cobra.Command {
+	cmdFlags := cmdFlags{}
+	var authToken string
+
+	applySealedCmd := cobra.Command{
+		Use:   "apply-sealed [organization] [flags]",
+		Short: "Create the secrets of a sealed bundle.",
+		Long:  "Create, or replace, the Actions, Dependabot, and/or Codespaces secrets of a bundle written by seal, refusing to write anything when a public key was rotated after the bundle was sealed.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(applySealedCmd *cobra.Command, args []string) error {
+			var err error
+			var gqlClient *api.GraphQLClient
+			var restClient *api.RESTClient
+
+			// Reinitialize logging if debugging was enabled
+			if cmdFlags.debug {
+				logger, _ := log.NewLogger(cmdFlags.debug)
+				defer logger.Sync() // nolint:errcheck
+				zap.ReplaceGlobals(logger)
+			}
+
+			if cmdFlags.token != "" {
+				authToken = cmdFlags.token
+			} else {
+				t, _ := auth.TokenForHost(cmdFlags.hostname)
+				authToken = t
+			}
+
+			gqlClient, err = api.NewGraphQLClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github.hawkgirl-preview+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving graphql client")
+				return err
+			}
+
+			restClient, err = api.NewRESTClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving rest client")
+				return err
+			}
+
+			owner := ""
+			if len(args) > 0 {
+				owner = args[0]
+			}
+
+			return runCmdApplySealed(owner, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), applySealedCmd.OutOrStdout())
+		},
+	}
+
+	// Configure flags for command
+	applySealedCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub personal access token for organization to write to (default "gh auth token")`)
+	applySealedCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
+	applySealedCmd.Flags().StringVarP(&cmdFlags.fileName, "from-file", "f", "", "Path and Name of the sealed bundle to apply (required)")
+	applySealedCmd.Flags().IntVarP(&cmdFlags.concurrency, "concurrency", "c", 1, "Number of secrets to create concurrently")
+	applySealedCmd.Flags().StringVar(&cmdFlags.resultsFile, "results-file", "", "Path and Name of a CSV, or .json, file to write the result of each secret to")
+	applySealedCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
+
+	if err := applySealedCmd.MarkFlagRequired("from-file"); err != nil {
+		zap.S().Errorf("Error marking from-file flag as required: %v", err)
+	}
+
+	return &applySealedCmd
+}
+
+func runCmdApplySealed(owner string, cmdFlags *cmdFlags, backend utils.Getter, out io.Writer) error {
+	g := utils.NewAPIGetter(backend)
+	zap.S().Debugf("Reading sealed bundle %s", cmdFlags.fileName)
+	bundle, err := utils.ReadSealedBundle(cmdFlags.fileName)
+	if err != nil {
+		zap.S().Errorf("Error arose reading sealed bundle")
+		return err
+	}
+	if owner == "" {
+		owner = bundle.Organization
+	} else if bundle.Organization != "" && bundle.Organization != owner {
+		return fmt.Errorf("the bundle was sealed with the public keys of %s, not %s", bundle.Organization, owner)
+	}
+	if owner == "" {
+		return errors.New("an organization must be specified, as an argument or as organization in the bundle")
+	}
+	if bundle.Hostname != "" && !strings.EqualFold(bundle.Hostname, cmdFlags.hostname) {
+		return fmt.Errorf("the bundle was sealed with the public keys of %s on %s, not %s", owner, bundle.Hostname, cmdFlags.hostname)
+	}
+	if len(bundle.Secrets) == 0 {
+		return errors.New("no secrets found in the bundle")
+	}
+
+	zap.S().Debugf("Checking the public keys of %s", owner)
+	if err := g.CheckSealedKeys(owner, bundle.Secrets); err != nil {
+		return err
+	}
+
+	results := g.ApplySealedSecrets(owner, bundle.Secrets, cmdFlags.concurrency)
+	if len(cmdFlags.resultsFile) > 0 {
+		zap.S().Debugf("Writing results to %s", cmdFlags.resultsFile)
+		if err := utils.WriteResults(cmdFlags.resultsFile, results); err != nil {
+			zap.S().Errorf("Error arose writing results file")
+			return err
+		}
+	}
+	if err := utils.PrintResultSummary(out, "secrets", results); err != nil {
+		return err
+	}
+	if failed := utils.CountFailedResults(results); failed > 0 {
+		return fmt.Errorf("failed to create %d of %d secrets for: %s", failed, len(results), owner)
+	}
+	_, err = fmt.Fprintf(out, "Successfully applied the sealed bundle to: %s.\n", owner)
+	return err
+}
@@ -0,0 +1,136 @@
+package applysealedsecrets
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+// keyHandler serves keyID as the public key of the api repository and
+// records the paths of the secrets written.
+func keyHandler(keyID string, writes *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/test-org/api/actions/secrets/public-key":
+			_, _ = w.Write([]byte(`{"key_id":"` + keyID + `","key":"AAAA"}`))
+		case r.Method == http.MethodPut:
+			*writes = append(*writes, r.URL.Path)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}
+}
+
+func writeTestBundle(t *testing.T, organization string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "sealed.json")
+	bundle := &data.SealedBundle{
+		Organization: organization,
+		Hostname:     "github.com",
+		SealedAt:     time.Now().UTC(),
+		Secrets: []data.SealedSecret{
+			{Level: "Repository", Type: "Actions", Name: "TOKEN", RepositoryNames: []string{"api"}, KeyID: "key-1", EncryptedValue: "c2VhbGVk"},
+		},
+	}
+	if err := utils.WriteSealedBundle(file, bundle); err != nil {
+		t.Fatalf("WriteSealedBundle() error = %v", err)
+	}
+	return file
+}
+
+func TestRunCmdApplySealed(t *testing.T) {
+	var writes []string
+	var out bytes.Buffer
+	resultsFile := filepath.Join(t.TempDir(), "results.csv")
+	flags := &cmdFlags{fileName: writeTestBundle(t, "test-org"), concurrency: 1, hostname: "github.com", resultsFile: resultsFile}
+
+	if err := runCmdApplySealed("", flags, newTestGetter(t, keyHandler("key-1", &writes)), &out); err != nil {
+		t.Fatalf("runCmdApplySealed() error = %v", err)
+	}
+	if len(writes) != 1 || writes[0] != "/repos/test-org/api/actions/secrets/TOKEN" {
+		t.Errorf("Expected TOKEN to be written to the organization of the bundle, got %v", writes)
+	}
+	if !strings.HasSuffix(out.String(), "Successfully applied the sealed bundle to: test-org.\n") {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+}
+
+func TestRunCmdApplySealedRotatedKey(t *testing.T) {
+	var writes []string
+	var out bytes.Buffer
+	flags := &cmdFlags{fileName: writeTestBundle(t, "test-org"), concurrency: 1, hostname: "github.com"}
+
+	err := runCmdApplySealed("test-org", flags, newTestGetter(t, keyHandler("key-2", &writes)), &out)
+	if err == nil || !strings.HasPrefix(err.Error(), "public keys were rotated after the bundle was sealed, so nothing was applied:") {
+		t.Errorf("Expected a rotated key error, got %v", err)
+	}
+	if len(writes) != 0 {
+		t.Errorf("Expected nothing to be written, got %v", writes)
+	}
+}
+
+func TestRunCmdApplySealedOrganization(t *testing.T) {
+	var writes []string
+	var out bytes.Buffer
+	handler := keyHandler("key-1", &writes)
+
+	flags := &cmdFlags{fileName: writeTestBundle(t, "test-org"), concurrency: 1, hostname: "github.com"}
+	err := runCmdApplySealed("other-org", flags, newTestGetter(t, handler), &out)
+	if err == nil || err.Error() != "the bundle was sealed with the public keys of test-org, not other-org" {
+		t.Errorf("Expected an organization mismatch error, got %v", err)
+	}
+
+	flags = &cmdFlags{fileName: writeTestBundle(t, ""), concurrency: 1, hostname: "github.com"}
+	err = runCmdApplySealed("", flags, newTestGetter(t, handler), &out)
+	if err == nil || err.Error() != "an organization must be specified, as an argument or as organization in the bundle" {
+		t.Errorf("Expected a missing organization error, got %v", err)
+	}
+
+	flags = &cmdFlags{fileName: writeTestBundle(t, "test-org"), concurrency: 1, hostname: "ghes.example.com"}
+	err = runCmdApplySealed("test-org", flags, newTestGetter(t, handler), &out)
+	if err == nil || err.Error() != "the bundle was sealed with the public keys of test-org on github.com, not ghes.example.com" {
+		t.Errorf("Expected a hostname mismatch error, got %v", err)
+	}
+	if len(writes) != 0 {
+		t.Errorf("Expected nothing to be written, got %v", writes)
+	}
+}
@@ -0,0 +1,109 @@
+package createsecrets
+
+import (
+	"encoding/csv"
+	"os"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+// importSourceSecrets reads the names and scopes of the organization,
+// repository and environment secrets of the source organization, in the form
+// read from a file. Secret values can't be read, so every value is empty.
+func importSourceSecrets(sourceOrg string, concurrency int, source utils.Getter) ([]data.ImportedSecret, error) {
+	states, err := utils.NewAPIGetter(source).ReadOrgSecrets(sourceOrg, concurrency)
+	if err != nil {
+		return nil, err
+	}
+	imported := make([]data.ImportedSecret, 0, len(states))
+	for _, state := range states {
+		imported = append(imported, state.ImportedSecret)
+	}
+	return imported, nil
+}
+
+// secretKey identifies a secret by its level, type, name and, below the
+// organization, its repository and environment, without regard to case.
+func secretKey(secret data.ImportedSecret) string {
+	parts := []string{secret.Level, secret.Type, secret.Name}
+	if secret.Level != "Organization" {
+		repo := ""
+		if len(secret.RepositoryNames) > 0 {
+			repo = secret.RepositoryNames[0]
+		}
+		parts = append(parts, repo, secret.EnvironmentName)
+	}
+	return strings.ToLower(strings.Join(parts, "/"))
+}
+
+// loadSecretValues reads the values of a filled in manifest, or any file
+// create reads secrets from, keyed by secretKey. Rows without a value are
+// left out.
+func loadSecretValues(fileName string) (map[string]string, error) {
+	secrets, err := utils.ReadSecretsFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, secret := range secrets {
+		if secret.Value != "" {
+			values[secretKey(secret)] = secret.Value
+		}
+	}
+	return values, nil
+}
+
+// fillSecretValues sets the value of each secret from values, or else from
+// the environment variable named after the secret when fromEnv is set. The
+// secrets still without a value are returned. Values from the file are kept
+// as they are, so references in them are resolved as for --from-file, while
+// values from the environment are escaped so they are never resolved.
+func fillSecretValues(secrets []data.ImportedSecret, values map[string]string, fromEnv bool) []data.ImportedSecret {
+	var missing []data.ImportedSecret
+	for i, secret := range secrets {
+		if value, ok := values[secretKey(secret)]; ok {
+			secrets[i].Value = value
+			continue
+		}
+		value := ""
+		if fromEnv {
+			value = os.Getenv(secret.Name)
+		}
+		if value == "" {
+			missing = append(missing, secret)
+			continue
+		}
+		secrets[i].Value = utils.LiteralValue(value)
+	}
+	return missing
+}
+
+// writeManifest writes the secrets whose values must be supplied to a CSV
+// file in the same format as create, ready to be filled in.
+func writeManifest(fileName string, secrets []data.ImportedSecret) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	csvWriter := csv.NewWriter(f)
+	rows := [][]string{{"SecretLevel", "SecretType", "SecretName", "SecretValue", "SecretAccess", "RepositoryNames", "RepositoryIDs", "EnvironmentName"}}
+	for _, secret := range secrets {
+		rows = append(rows, []string{
+			secret.Level,
+			secret.Type,
+			secret.Name,
+			"",
+			secret.Access,
+			strings.Join(secret.RepositoryNames, ";"),
+			strings.Join(secret.RepositoryIDs, ";"),
+			secret.EnvironmentName,
+		})
+	}
+	if err := csvWriter.WriteAll(rows); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,221 @@
+package createsecrets
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+func TestImportSourceSecrets(t *testing.T) {
+	source := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/graphql":
+			_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"totalCount":2,"nodes":[{"databaseId":1,"name":"api"},{"databaseId":2,"name":"web"}],"pageInfo":{"hasNextPage":false}}}}}`))
+		case "/orgs/source-org/actions/secrets":
+			_, _ = w.Write([]byte(`{"total_count":2,"secrets":[{"name":"DEPLOY_KEY","visibility":"selected"},{"name":"NPM_TOKEN","visibility":"private"}]}`))
+		case "/orgs/source-org/actions/secrets/DEPLOY_KEY/repositories":
+			_, _ = w.Write([]byte(`{"total_count":2,"repositories":[{"id":1,"name":"api"},{"id":2,"name":"web"}]}`))
+		case "/orgs/source-org/dependabot/secrets":
+			_, _ = w.Write([]byte(`{"total_count":1,"secrets":[{"name":"REGISTRY","visibility":"all"}]}`))
+		case "/repos/source-org/api/actions/secrets":
+			_, _ = w.Write([]byte(`{"total_count":1,"secrets":[{"name":"API_KEY"}]}`))
+		case "/repos/source-org/api/environments":
+			_, _ = w.Write([]byte(`{"total_count":1,"environments":[{"name":"production"}]}`))
+		case "/repos/source-org/api/environments/production/secrets":
+			_, _ = w.Write([]byte(`{"total_count":1,"secrets":[{"name":"DB_PASSWORD"}]}`))
+		case "/repos/source-org/web/codespaces/secrets":
+			_, _ = w.Write([]byte(`{"total_count":1,"secrets":[{"name":"DEV_TOKEN"}]}`))
+		default:
+			if strings.HasSuffix(r.URL.Path, "/environments") {
+				_, _ = w.Write([]byte(`{"total_count":0,"environments":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"total_count":0,"secrets":[]}`))
+		}
+	})
+
+	secrets, err := importSourceSecrets("source-org", 2, source)
+	if err != nil {
+		t.Fatalf("importSourceSecrets() error = %v", err)
+	}
+	expected := []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "DEPLOY_KEY", Access: "selected", RepositoryNames: []string{"api", "web"}},
+		{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Access: "private"},
+		{Level: "Organization", Type: "Dependabot", Name: "REGISTRY", Access: "all"},
+		{Level: "Repository", Type: "Actions", Name: "API_KEY", Access: "RepoOnly", RepositoryNames: []string{"api"}},
+		{Level: "Environment", Type: "Actions", Name: "DB_PASSWORD", Access: "EnvironmentOnly", RepositoryNames: []string{"api"}, EnvironmentName: "production"},
+		{Level: "Repository", Type: "Codespaces", Name: "DEV_TOKEN", Access: "RepoOnly", RepositoryNames: []string{"web"}},
+	}
+	if len(secrets) != len(expected) {
+		t.Fatalf("Expected %d secrets, got %+v", len(expected), secrets)
+	}
+	for i, want := range expected {
+		got := secrets[i]
+		if got.Level != want.Level || got.Type != want.Type || got.Name != want.Name || got.Access != want.Access ||
+			got.EnvironmentName != want.EnvironmentName || strings.Join(got.RepositoryNames, ";") != strings.Join(want.RepositoryNames, ";") {
+			t.Errorf("Secret %d: expected %+v, got %+v", i, want, got)
+		}
+		if got.Value != "" {
+			t.Errorf("Secret %d: expected no value, got %q", i, got.Value)
+		}
+	}
+	if strings.Join(secrets[0].RepositoryIDs, ";") != "1;2" {
+		t.Errorf("Expected the scoped repository IDs to be kept, got %v", secrets[0].RepositoryIDs)
+	}
+}
+
+func TestImportSourceSecretsError(t *testing.T) {
+	source := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+	})
+
+	_, err := importSourceSecrets("source-org", 1, source)
+	if err == nil || !strings.Contains(err.Error(), "failed to read Actions secrets of source-org") {
+		t.Errorf("Expected a read error for the organization secrets, got %v", err)
+	}
+}
+
+func TestSupplySecretValues(t *testing.T) {
+	tmpDir := t.TempDir()
+	valuesFile := filepath.Join(tmpDir, "values.csv")
+	valuesContent := "SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs,EnvironmentName\n" +
+		"Organization,Actions,NPM_TOKEN,npm-value,private,,,\n" +
+		"Environment,Actions,DB_PASSWORD,prod-password,EnvironmentOnly,api,1,production\n" +
+		"Environment,Actions,DB_PASSWORD,,EnvironmentOnly,api,1,staging\n"
+	if err := os.WriteFile(valuesFile, []byte(valuesContent), 0644); err != nil {
+		t.Fatalf("Failed to write values file: %v", err)
+	}
+	// Supplied values that look like references are used as they are
+	t.Setenv("API_KEY", "exec:false")
+	t.Setenv("NPM_TOKEN", "ignored")
+
+	secrets := []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Access: "private"},
+		{Level: "Repository", Type: "Actions", Name: "API_KEY", Access: "RepoOnly", RepositoryNames: []string{"api"}, RepositoryIDs: []string{"1"}},
+		{Level: "Environment", Type: "Actions", Name: "DB_PASSWORD", Access: "EnvironmentOnly", RepositoryNames: []string{"api"}, RepositoryIDs: []string{"1"}, EnvironmentName: "production"},
+		{Level: "Environment", Type: "Actions", Name: "DB_PASSWORD", Access: "EnvironmentOnly", RepositoryNames: []string{"api"}, RepositoryIDs: []string{"1"}, EnvironmentName: "staging"},
+	}
+	manifest := filepath.Join(tmpDir, "manifest.csv")
+	flags := &cmdFlags{valuesFile: valuesFile, valuesFromEnv: true, manifest: manifest}
+	rowErrors := make([]error, len(secrets))
+	var out bytes.Buffer
+	if err := supplySecretValues(secrets, rowErrors, flags, utils.NewAPIGetter(newTestGetter(t, nil)), &out); err != nil {
+		t.Fatalf("supplySecretValues() error = %v", err)
+	}
+
+	for i, want := range []string{"npm-value", "exec:false", "prod-password"} {
+		value, err := utils.ResolveSecretValue(secrets[i].Value, nil)
+		if err != nil || value != want {
+			t.Errorf("Secret %d: expected value %q, got %q, %v", i, want, value, err)
+		}
+	}
+	if secrets[3].Value != "" {
+		t.Errorf("Expected no value for the staging secret, got %q", secrets[3].Value)
+	}
+	if rowErrors[0] != nil || rowErrors[1] != nil || rowErrors[2] != nil {
+		t.Errorf("Expected secrets with values to be created, got %v", rowErrors)
+	}
+	var skipErr *utils.SkipError
+	if !errors.As(rowErrors[3], &skipErr) || !strings.Contains(skipErr.Reason, "no value supplied") {
+		t.Errorf("Expected the secret without a value to be skipped, got %v", rowErrors[3])
+	}
+	if !strings.Contains(out.String(), "1 secrets need a value") {
+		t.Errorf("Expected the manifest to be reported, got %q", out.String())
+	}
+
+	f, err := os.Open(manifest)
+	if err != nil {
+		t.Fatalf("Failed to open manifest: %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if len(records) != 2 || records[0][3] != "SecretValue" {
+		t.Fatalf("Expected a header and one secret in the manifest, got %v", records)
+	}
+	if strings.Join(records[1], ",") != "Environment,Actions,DB_PASSWORD,,EnvironmentOnly,api,1,staging" {
+		t.Errorf("Unexpected manifest row %v", records[1])
+	}
+}
+
+func TestSupplySecretValuesReferences(t *testing.T) {
+	t.Setenv("COPIED_TOKEN", "token-value")
+	t.Setenv("LEVEL", "env:COPIED_TOKEN")
+	valuesFile := filepath.Join(t.TempDir(), "values.csv")
+	valuesContent := "SecretLevel,SecretType,SecretName,SecretValue,SecretAccess\n" +
+		"Organization,Actions,TOKEN,env:COPIED_TOKEN,all\n" +
+		"Organization,Actions,STAGE,literal:env:prod,all\n"
+	if err := os.WriteFile(valuesFile, []byte(valuesContent), 0600); err != nil {
+		t.Fatalf("Failed to write values file: %v", err)
+	}
+
+	secrets := []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "TOKEN", Access: "all"},
+		{Level: "Organization", Type: "Actions", Name: "STAGE", Access: "all"},
+		{Level: "Organization", Type: "Actions", Name: "LEVEL", Access: "all"},
+	}
+	flags := &cmdFlags{valuesFile: valuesFile, valuesFromEnv: true, manifest: filepath.Join(t.TempDir(), "manifest.csv")}
+	rowErrors := make([]error, len(secrets))
+	var out bytes.Buffer
+	if err := supplySecretValues(secrets, rowErrors, flags, utils.NewAPIGetter(newTestGetter(t, nil)), &out); err != nil {
+		t.Fatalf("supplySecretValues() error = %v", err)
+	}
+
+	// References in the values file are resolved as for --from-file, while
+	// values from the environment are used as they are
+	for i, want := range []string{"token-value", "env:prod", "env:COPIED_TOKEN"} {
+		value, err := utils.ResolveSecretValue(secrets[i].Value, nil)
+		if err != nil || value != want {
+			t.Errorf("Secret %s: expected value %q, got %q, %v", secrets[i].Name, want, value, err)
+		}
+	}
+	if secrets[1].Value != "literal:env:prod" {
+		t.Errorf("Expected a literal value to be kept as it is, got %q", secrets[1].Value)
+	}
+}
+
+func TestSupplySecretValuesComplete(t *testing.T) {
+	t.Setenv("NPM_TOKEN", "env-value")
+	manifest := filepath.Join(t.TempDir(), "manifest.csv")
+	secrets := []data.ImportedSecret{{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Access: "all"}}
+	rowErrors := make([]error, 1)
+	var out bytes.Buffer
+	flags := &cmdFlags{valuesFromEnv: true, manifest: manifest}
+	if err := supplySecretValues(secrets, rowErrors, flags, utils.NewAPIGetter(newTestGetter(t, nil)), &out); err != nil {
+		t.Fatalf("supplySecretValues() error = %v", err)
+	}
+	if rowErrors[0] != nil || secrets[0].Value != utils.LiteralValue("env-value") {
+		t.Errorf("Expected the value to be read from the environment, got %+v %v", secrets[0], rowErrors[0])
+	}
+	if _, err := os.Stat(manifest); !os.IsNotExist(err) {
+		t.Errorf("Expected no manifest when every value is supplied, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output, got %q", out.String())
+	}
+}
+
+func TestSecretKey(t *testing.T) {
+	org := data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "TOKEN", RepositoryNames: []string{"api"}}
+	if secretKey(org) != secretKey(data.ImportedSecret{Level: "Organization", Type: "actions", Name: "token"}) {
+		t.Error("Expected organization secrets to match without regard to case or repositories")
+	}
+	repo := data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "TOKEN", RepositoryNames: []string{"api"}}
+	other := data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "TOKEN", RepositoryNames: []string{"web"}}
+	if secretKey(repo) == secretKey(other) {
+		t.Error("Expected repository secrets of different repositories not to match")
+	}
+}
@@ -1,27 +1,38 @@
 package createsecrets
 
 import (
-	"bytes"
-	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/cli/go-gh/v2/pkg/auth"
 	"github.com/katiem0/gh-seva/internal/data"
 	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/katiem0/gh-seva/internal/providers"
 	"github.com/katiem0/gh-seva/internal/utils"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
 type cmdFlags struct {
-	fileName string
-	token    string
-	hostname string
-	debug    bool
+	sourceToken    string
+	sourceOrg      string
+	sourceHostname string
+	manifest       string
+	valuesFile     string
+	valuesFromEnv  bool
+	fileName       string
+	token          string
+	hostname       string
+	concurrency    int
+	dryRun         bool
+	resultsFile    string
+	repoMap        string
+	providers      string
+	debug          bool
 }
 
 func NewCmdCreate() *cobra.Command {
@@ -31,9 +42,22 @@ func NewCmdCreate() *cobra.Command {
 
 	createCmd := cobra.Command{
 		Use:   "create <organization> [flags]",
-		Short: "Create Actions, Dependabot, and/or Codespaces secrets from a file.",
-		Long:  "Create Actions, Dependabot, and/or Codespaces secrets for an organization and/or repositories from a file.",
+		Short: "Create Actions, Dependabot, and/or Codespaces secrets from a file or another organization.",
+		Long:  "Create Actions, Dependabot, and/or Codespaces secrets for an organization and/or repositories from a file, or copy the secrets of a source organization with values supplied from a file or environment variables.",
 		Args:  cobra.MinimumNArgs(1),
+		PreRunE: func(createCmd *cobra.Command, args []string) error {
+			copyFlags := len(cmdFlags.valuesFile) > 0 || cmdFlags.valuesFromEnv || createCmd.Flags().Changed("manifest")
+			if len(cmdFlags.fileName) == 0 && len(cmdFlags.sourceOrg) == 0 {
+				return errors.New("a file or source organization must be specified where secrets will be created from")
+			} else if len(cmdFlags.sourceOrg) > 0 && len(cmdFlags.sourceToken) == 0 {
+				return errors.New("a Personal Access Token must be specified to access secrets from the Source Organization")
+			} else if len(cmdFlags.fileName) > 0 && len(cmdFlags.sourceOrg) > 0 {
+				return errors.New("specify only one of `--source-organization` or `from-file`")
+			} else if copyFlags && len(cmdFlags.sourceOrg) == 0 {
+				return errors.New("`--manifest`, `--values-file` and `--values-from-env` require `--source-organization`")
+			}
+			return nil
+		},
 		RunE: func(createCmd *cobra.Command, args []string) error {
 			var err error
 			var gqlClient *api.GraphQLClient
@@ -81,273 +105,219 @@ func NewCmdCreate() *cobra.Command {
 
 			owner := args[0]
 
-			return runCmdCreate(owner, &cmdFlags, utils.NewAPIGetter(gqlClient, restClient))
+			return runCmdCreate(owner, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient))
 		},
 	}
 
+	// Determine default manifest file based on current timestamp; for more info see https://pkg.go.dev/time#pkg-constants
+	manifestDefault := fmt.Sprintf("manifest-secrets-%s.csv", time.Now().Format("20060102150405"))
 	// Configure flags for command
 	createCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub personal access token for organization to write to (default "gh auth token")`)
+	createCmd.PersistentFlags().StringVarP(&cmdFlags.sourceToken, "source-token", "s", "", `GitHub personal access token for Source Organization (Required for --source-organization)`)
+	createCmd.PersistentFlags().StringVarP(&cmdFlags.sourceOrg, "source-organization", "o", "", `Name of the Source Organization to copy secrets from (Requires --source-token)`)
 	createCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
-	createCmd.Flags().StringVarP(&cmdFlags.fileName, "from-file", "f", "", "Path and Name of CSV file to create secrets from (required)")
+	createCmd.PersistentFlags().StringVarP(&cmdFlags.sourceHostname, "source-hostname", "", "github.com", "GitHub Enterprise Server hostname where secrets are copied from")
+	createCmd.Flags().StringVarP(&cmdFlags.fileName, "from-file", "f", "", "Path and Name of CSV, JSON or YAML file to create secrets from")
+	createCmd.Flags().StringVar(&cmdFlags.manifest, "manifest", manifestDefault, "Name of CSV file to write the copied secrets without a value to, ready to be filled in")
+	createCmd.Flags().StringVar(&cmdFlags.valuesFile, "values-file", "", "Path and Name of a filled in manifest, or CSV file in the same format, to read copied secret values from")
+	createCmd.Flags().BoolVar(&cmdFlags.valuesFromEnv, "values-from-env", false, "Read the value of each copied secret from the environment variable of the same name")
+	createCmd.Flags().IntVarP(&cmdFlags.concurrency, "concurrency", "c", 1, "Number of secrets to create, and source repositories to read, concurrently")
+	createCmd.Flags().BoolVar(&cmdFlags.dryRun, "dry-run", false, "Print the changes that would be made without creating any secrets")
+	createCmd.Flags().StringVar(&cmdFlags.repoMap, "repo-map", "", "Path and Name of CSV file mapping source repository names to their names in the organization")
+	createCmd.Flags().StringVar(&cmdFlags.providers, "providers-config", "", "Path and Name of a YAML file configuring the secret stores that store: values are read from")
+	createCmd.Flags().StringVar(&cmdFlags.resultsFile, "results-file", "", "Path and Name of a CSV, or .json, file to write the result of each secret to")
 	createCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
-	if err := createCmd.MarkFlagRequired("from-file"); err != nil {
-		zap.S().Errorf("Error marking from-file flag as required: %v", err)
-		return nil
-	}
 
 	return &createCmd
 }
 
-func runCmdCreate(owner string, cmdFlags *cmdFlags, g *utils.APIGetter) error {
-	var secretData [][]string
+func runCmdCreate(owner string, cmdFlags *cmdFlags, backend utils.Getter) error {
+	g := utils.NewAPIGetter(backend)
+	if len(cmdFlags.providers) > 0 {
+		zap.S().Debugf("Reading secret store providers from %s", cmdFlags.providers)
+		stores, err := providers.LoadRegistry(cmdFlags.providers)
+		if err != nil {
+			zap.S().Errorf("Error arose reading provider config")
+			return err
+		}
+		g.SetSecretStores(stores)
+	}
 	var importSecretList []data.ImportedSecret
 	if len(cmdFlags.fileName) > 0 {
-		f, err := os.Open(cmdFlags.fileName)
-		zap.S().Debugf("Opening up file %s", cmdFlags.fileName)
+		zap.S().Debugf("Reading in secrets from %s", cmdFlags.fileName)
+		var err error
+		importSecretList, err = utils.ReadSecretsFile(cmdFlags.fileName)
 		if err != nil {
-			zap.S().Errorf("Error arose opening secret csv file")
+			zap.S().Errorf("Error arose reading secrets file")
+			return err
 		}
-		defer func() {
-			if err := f.Close(); err != nil {
-				zap.S().Errorf("Error closing file: %v", err)
-			}
-		}()
-		// read csv values using csv.Reader
-		csvReader := csv.NewReader(f)
-		secretData, err = csvReader.ReadAll()
-		zap.S().Debugf("Reading in all lines from csv file")
+	} else if len(cmdFlags.sourceOrg) > 0 {
+		zap.S().Debugf("Reading in secrets from %s", cmdFlags.sourceOrg)
+		var authToken string
+
+		if cmdFlags.sourceToken != "" {
+			authToken = cmdFlags.sourceToken
+		} else {
+			t, _ := auth.TokenForHost(cmdFlags.sourceHostname)
+			authToken = t
+		}
+
+		restSourceClient, err := api.NewRESTClient(api.ClientOptions{
+			Headers: map[string]string{
+				"Accept": "application/vnd.github+json",
+			},
+			Host:      cmdFlags.sourceHostname,
+			AuthToken: authToken,
+		})
+		if err != nil {
+			zap.S().Errorf("Error arose retrieving source rest client")
+			return err
+		}
+
+		gqlSourceClient, err := api.NewGraphQLClient(api.ClientOptions{
+			Headers: map[string]string{
+				"Accept": "application/vnd.github.hawkgirl-preview+json",
+			},
+			Host:      cmdFlags.sourceHostname,
+			AuthToken: authToken,
+		})
+		if err != nil {
+			zap.S().Errorf("Error arose retrieving source graphql client")
+			return err
+		}
+
+		zap.S().Debugf("Gathering secrets %s", cmdFlags.sourceOrg)
+		importSecretList, err = importSourceSecrets(cmdFlags.sourceOrg, cmdFlags.concurrency, utils.NewGitHubGetter(gqlSourceClient, restSourceClient))
 		if err != nil {
-			zap.S().Errorf("Error arose reading secrets from csv file")
+			return err
 		}
-		importSecretList = g.CreateSecretsList(secretData)
 	} else {
 		zap.S().Errorf("Error arose identifying secrets")
+		return errors.New("a file or source organization must be specified where secrets will be created from")
 	}
-	zap.S().Debugf("Determining secrets to create")
-	for _, importSecret := range importSecretList {
-		switch importSecret.Level {
-		case "Organization":
-			zap.S().Debugf("Gathering Organization level secret %s", importSecret.Name)
-			switch importSecret.Type {
-			case "Actions":
-				zap.S().Debugf("Encrypting Organization level Actions secret %s", importSecret.Name)
-				publicKey, err := g.GetOrgActionPublicKey(owner)
-				if err != nil {
-					zap.S().Errorf("Error arose reading Organization Actions secret from csv file")
-				}
-				var responsePublicKey data.PublicKey
-				err = json.Unmarshal(publicKey, &responsePublicKey)
-				if err != nil {
-					return err
-				}
-				encryptedSecret, err := g.EncryptSecret(responsePublicKey.Key, importSecret.Value)
-				if err != nil {
-					return err
-				}
-				zap.S().Debugf("Creating Organization Actions Secret Data for %s", importSecret.Name)
-
-				var reader io.Reader
-				if importSecret.Access == "selected" {
-					orgSecretObject := utils.CreateSelectedOrgSecretData(importSecret, responsePublicKey.KeyID, encryptedSecret)
-					createSecret, err := json.Marshal(orgSecretObject)
-					if err != nil {
-						return err
-					}
-					reader = bytes.NewReader(createSecret)
-				} else {
-					orgSecretObject := utils.CreateOrgSecretData(importSecret, responsePublicKey.KeyID, encryptedSecret)
-					createSecret, err := json.Marshal(orgSecretObject)
-					if err != nil {
-						return err
-					}
-					reader = bytes.NewReader(createSecret)
-				}
-
-				zap.S().Debugf("Creating Organization Actions Secret %s", importSecret.Name)
-				err = g.CreateOrgActionSecret(owner, importSecret.Name, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating Actions secret %s", importSecret.Name)
-				}
-			case "Codespaces":
-				zap.S().Debugf("Encrypting Organization level Codespaces secret %s", importSecret.Name)
-				publicKey, err := g.GetOrgCodespacesPublicKey(owner)
-				if err != nil {
-					zap.S().Errorf("Error arose reading Organization Codespaces secret from csv file")
-				}
-				var responsePublicKey data.PublicKey
-				err = json.Unmarshal(publicKey, &responsePublicKey)
-				if err != nil {
-					return err
-				}
-				encryptedSecret, err := g.EncryptSecret(responsePublicKey.Key, importSecret.Value)
-				if err != nil {
-					return err
-				}
-				var reader io.Reader
-				if importSecret.Access == "selected" {
-					orgSecretObject := utils.CreateOrgSecretData(importSecret, responsePublicKey.KeyID, encryptedSecret)
-					createSecret, err := json.Marshal(orgSecretObject)
-					if err != nil {
-						return err
-					}
-					reader = bytes.NewReader(createSecret)
-				} else {
-					orgSecretObject := utils.CreateOrgSecretData(importSecret, responsePublicKey.KeyID, encryptedSecret)
-					createSecret, err := json.Marshal(orgSecretObject)
-					if err != nil {
-						return err
-					}
-					reader = bytes.NewReader(createSecret)
-				}
-				zap.S().Debugf("Creating Organization Codespaces Secret %s", importSecret.Name)
-
-				err = g.CreateOrgCodespacesSecret(owner, importSecret.Name, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating Organization Codespaces secret %s", importSecret.Name)
-				}
-			case "Dependabot":
-				zap.S().Debugf("Encrypting Organization level Dependabot secret %s", importSecret.Name)
-				publicKey, err := g.GetOrgDependabotPublicKey(owner)
-				if err != nil {
-					zap.S().Errorf("Error arose reading Organization Dependabot secret from csv file")
-				}
-				var responsePublicKey data.PublicKey
-				err = json.Unmarshal(publicKey, &responsePublicKey)
-				if err != nil {
-					return err
-				}
-				encryptedSecret, err := g.EncryptSecret(responsePublicKey.Key, importSecret.Value)
-				if err != nil {
-					return err
-				}
-
-				var reader io.Reader
-				if importSecret.Access == "selected" {
-					orgSecretObject := utils.CreateOrgDependabotSecretData(importSecret, responsePublicKey.KeyID, encryptedSecret)
-					createSecret, err := json.Marshal(orgSecretObject)
-					if err != nil {
-						return err
-					}
-					reader = bytes.NewReader(createSecret)
-				} else {
-					orgSecretObject := utils.CreateOrgSecretData(importSecret, responsePublicKey.KeyID, encryptedSecret)
-					createSecret, err := json.Marshal(orgSecretObject)
-					if err != nil {
-						return err
-					}
-					reader = bytes.NewReader(createSecret)
-				}
-
-				zap.S().Debugf("Creating Organization Dependabot Secret %s", importSecret.Name)
-
-				err = g.CreateOrgDependabotSecret(owner, importSecret.Name, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating Organization Dependabot secret %s", importSecret.Name)
-				}
 
-			default:
-				zap.S().Errorf("Error arose reading secret from csv file")
+	rowErrors := make([]error, len(importSecretList))
+	if len(cmdFlags.sourceOrg) > 0 {
+		if err := supplySecretValues(importSecretList, rowErrors, cmdFlags, g, os.Stdout); err != nil {
+			return err
+		}
+	}
+	if len(cmdFlags.repoMap) > 0 {
+		zap.S().Debugf("Mapping repository names with %s", cmdFlags.repoMap)
+		repoMap, err := utils.LoadRepoMap(cmdFlags.repoMap)
+		if err != nil {
+			zap.S().Errorf("Error arose reading repository mapping file")
+			return err
+		}
+		for i, err := range utils.MapSecretRepos(importSecretList, repoMap) {
+			if rowErrors[i] == nil {
+				rowErrors[i] = err
 			}
-		case "Repository":
-			repoName := importSecret.RepositoryNames[0]
-			zap.S().Debugf("Gathering Repository level secret %s", importSecret.Name)
-			switch importSecret.Type {
-			case "Actions":
-				zap.S().Debugf("Encrypting Repository %s level Actions secret %s", repoName, importSecret.Name)
-				publicKey, err := g.GetRepoActionPublicKey(owner, repoName)
-				if err != nil {
-					zap.S().Errorf("Error arose reading Actions secret from csv file")
-				}
-				var responsePublicKey data.PublicKey
-				err = json.Unmarshal(publicKey, &responsePublicKey)
-				if err != nil {
-					return err
-				}
-				encryptedSecret, err := g.EncryptSecret(responsePublicKey.Key, importSecret.Value)
-				if err != nil {
-					return err
-				}
-				zap.S().Debugf("Creating Repository Actions Secret Data for %s", importSecret.Name)
-				repoSecretObject := utils.CreateRepoSecretData(responsePublicKey.KeyID, encryptedSecret)
-				createSecret, err := json.Marshal(repoSecretObject)
-
-				if err != nil {
-					return err
-				}
-
-				reader := bytes.NewReader(createSecret)
-				zap.S().Debugf("Creating Actions Secret %s", importSecret.Name)
-
-				err = g.CreateRepoActionSecret(owner, repoName, importSecret.Name, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating Repository Actions secret %s", importSecret.Name)
-				}
-			case "Codespaces":
-				zap.S().Debugf("Encrypting Repository level Codespaces secret %s", importSecret.Name)
-				publicKey, err := g.GetRepoCodespacesPublicKey(owner, repoName)
-				if err != nil {
-					zap.S().Errorf("Error arose reading Repository Codespaces secret from csv file")
-				}
-				var responsePublicKey data.PublicKey
-				err = json.Unmarshal(publicKey, &responsePublicKey)
-				if err != nil {
-					return err
-				}
-				encryptedSecret, err := g.EncryptSecret(responsePublicKey.Key, importSecret.Value)
-				if err != nil {
-					return err
-				}
-				repoSecretObject := utils.CreateRepoSecretData(responsePublicKey.KeyID, encryptedSecret)
-				createSecret, err := json.Marshal(repoSecretObject)
-
-				if err != nil {
-					return err
-				}
+		}
+	}
+	if len(cmdFlags.sourceOrg) > 0 {
+		if err := g.SkipMissingSecretRepos(owner, importSecretList, rowErrors); err != nil {
+			return err
+		}
+	}
+	if cmdFlags.dryRun {
+		zap.S().Debugf("Planning secrets to create under %s", owner)
+		plan := g.PlanSecrets(owner, importSecretList)
+		utils.MarkPlanErrors(plan, rowErrors)
+		return printPlan(plan)
+	}
 
-				reader := bytes.NewReader(createSecret)
-				zap.S().Debugf("Creating Repository Codespaces Secret %s", importSecret.Name)
+	zap.S().Debugf("Resolving selected repositories in %s", owner)
+	resolveErrors := g.ResolveSecretRepos(owner, importSecretList)
 
-				err = g.CreateRepoCodespacesSecret(owner, repoName, importSecret.Name, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating Repository Codespaces secret %s", importSecret.Name)
-				}
-			case "Dependabot":
-				zap.S().Debugf("Encrypting Repository level Dependabot secret %s", importSecret.Name)
-				publicKey, err := g.GetRepoDependabotPublicKey(owner, repoName)
-				if err != nil {
-					zap.S().Errorf("Error arose reading Repository Dependabot secret from csv file")
-				}
-				var responsePublicKey data.PublicKey
-				err = json.Unmarshal(publicKey, &responsePublicKey)
-				if err != nil {
-					return err
-				}
-				encryptedSecret, err := g.EncryptSecret(responsePublicKey.Key, importSecret.Value)
-				if err != nil {
-					return err
-				}
-				repoSecretObject := utils.CreateRepoSecretData(responsePublicKey.KeyID, encryptedSecret)
-				createSecret, err := json.Marshal(repoSecretObject)
+	zap.S().Debugf("Determining secrets to create")
+	results := make([]data.RowResult, len(importSecretList))
+	utils.RunConcurrently(cmdFlags.concurrency, len(importSecretList), func(index int) {
+		importSecret := importSecretList[index]
+		err := rowErrors[index]
+		if err == nil {
+			err = resolveErrors[index]
+		}
+		var skipErr *utils.SkipError
+		if err == nil {
+			err = g.CreateImportedSecret(owner, importSecret)
+		}
+		if err != nil && !errors.As(err, &skipErr) {
+			zap.S().Errorf("Error arose creating secret %s: %v", importSecret.Name, err)
+		}
+		results[index] = utils.NewRowResult(utils.ResultRow(importSecret.Row, index), importSecret.Level, importSecret.Type, importSecret.Name, secretTarget(owner, importSecret), err)
+	})
+	return reportResults(owner, cmdFlags.resultsFile, results, os.Stdout)
+}
 
-				if err != nil {
-					return err
-				}
+// supplySecretValues fills in the values of secrets copied from the source
+// organization from the values file and environment. The secrets still
+// without a value are written to the manifest and marked as skipped in
+// rowErrors, so the rest are created in one pass.
+func supplySecretValues(secrets []data.ImportedSecret, rowErrors []error, cmdFlags *cmdFlags, g *utils.APIGetter, out io.Writer) error {
+	values := map[string]string{}
+	if len(cmdFlags.valuesFile) > 0 {
+		zap.S().Debugf("Reading secret values from %s", cmdFlags.valuesFile)
+		var err error
+		values, err = loadSecretValues(cmdFlags.valuesFile)
+		if err != nil {
+			zap.S().Errorf("Error arose reading secret values file")
+			return err
+		}
+	}
+	missing := fillSecretValues(secrets, values, cmdFlags.valuesFromEnv)
+	if len(missing) == 0 {
+		return nil
+	}
 
-				reader := bytes.NewReader(createSecret)
-				zap.S().Debugf("Creating Repository Dependabot Secret %s", importSecret.Name)
+	zap.S().Debugf("Writing %d secrets without a value to %s", len(missing), cmdFlags.manifest)
+	if err := writeManifest(cmdFlags.manifest, missing); err != nil {
+		zap.S().Errorf("Error arose writing secrets manifest")
+		return err
+	}
+	for i, secret := range secrets {
+		if secret.Value == "" {
+			rowErrors[i] = &utils.SkipError{Reason: fmt.Sprintf("no value supplied, listed in %s", cmdFlags.manifest)}
+		}
+	}
+	_, err := fmt.Fprintf(out, "%d secrets need a value, fill them in to %s and create them with --from-file.\n", len(missing), cmdFlags.manifest)
+	return err
+}
 
-				err = g.CreateRepoDependabotSecret(owner, repoName, importSecret.Name, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating Repository Dependabot secret %s", importSecret.Name)
-				}
-			default:
-				zap.S().Errorf("Error arose reading secret from csv file")
-			}
-		default:
-			zap.S().Errorf("Error arose reading in where to create secret %s, check csv file.", importSecret.Name)
+// reportResults writes the results file when one was requested and prints a
+// summary, returning an error when any secret could not be created.
+func reportResults(owner string, resultsFile string, results []data.RowResult, out io.Writer) error {
+	if len(resultsFile) > 0 {
+		zap.S().Debugf("Writing results to %s", resultsFile)
+		if err := utils.WriteResults(resultsFile, results); err != nil {
+			zap.S().Errorf("Error arose writing results file")
+			return err
 		}
 	}
+	if err := utils.PrintResultSummary(out, "secrets", results); err != nil {
+		return err
+	}
+	if failed := utils.CountFailedResults(results); failed > 0 {
+		return fmt.Errorf("failed to create %d of %d secrets for: %s", failed, len(results), owner)
+	}
+	_, err := fmt.Fprintf(out, "Successfully created secrets for: %s.\n", owner)
+	return err
+}
 
-	fmt.Printf("Successfully created secrets for: %s.", owner)
+func secretTarget(owner string, importSecret data.ImportedSecret) string {
+	repo := ""
+	if len(importSecret.RepositoryNames) > 0 {
+		repo = importSecret.RepositoryNames[0]
+	}
+	return utils.Target(owner, importSecret.Level, repo, importSecret.EnvironmentName)
+}
+
+func printPlan(plan []data.PlanItem) error {
+	if err := utils.PrintPlan(os.Stdout, plan); err != nil {
+		return err
+	}
+	if errorCount := utils.CountPlanActions(plan)[data.PlanError]; errorCount > 0 {
+		return fmt.Errorf("%d secrets in the plan have errors", errorCount)
+	}
 	return nil
 }
@@ -1,16 +1,19 @@
 package createsecrets
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/katiem0/gh-seva/internal/data"
 	"github.com/katiem0/gh-seva/internal/utils"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/nacl/box"
 )
 
 func TestNewCmdCreate(t *testing.T) {
@@ -43,243 +46,54 @@ func TestNewCmdCreate(t *testing.T) {
 	if cmd.Flag("debug") == nil {
 		t.Error("debug flag not found")
 	}
-}
-
-// Modified runCmdCreate to accept an interface instead of a concrete type
-// This is only used for testing purposes
-func runCmdCreateTest(owner string, cmdFlags *cmdFlags, g interface{}) error {
-	// Type assertion to the interface methods we need
-	getter, ok := g.(interface {
-		CreateSecretsList(data [][]string) []data.ImportedSecret
-		GetOrgActionPublicKey(owner string) ([]byte, error)
-		GetOrgCodespacesPublicKey(owner string) ([]byte, error)
-		GetOrgDependabotPublicKey(owner string) ([]byte, error)
-		GetRepoActionPublicKey(owner string, repo string) ([]byte, error)
-		GetRepoCodespacesPublicKey(owner string, repo string) ([]byte, error)
-		GetRepoDependabotPublicKey(owner string, repo string) ([]byte, error)
-		EncryptSecret(publickey string, secret string) (string, error)
-		CreateOrgActionSecret(owner string, secret string, data io.Reader) error
-		CreateOrgCodespacesSecret(owner string, secret string, data io.Reader) error
-		CreateOrgDependabotSecret(owner string, secret string, data io.Reader) error
-		CreateRepoActionSecret(owner string, repo string, secret string, data io.Reader) error
-		CreateRepoCodespacesSecret(owner string, repo string, secret string, data io.Reader) error
-		CreateRepoDependabotSecret(owner string, repo string, secret string, data io.Reader) error
-	})
 
-	if !ok {
-		return nil // For testing, we're not actually concerned with this error
+	if cmd.Flag("concurrency") == nil {
+		t.Error("concurrency flag not found")
 	}
 
-	// Call the original function implementation but with our getter
-	// We're essentially copying the implementation from the original function
-	var secretData [][]string
-	var importSecretList []data.ImportedSecret
-	if len(cmdFlags.fileName) > 0 {
-		_, err := os.ReadFile(cmdFlags.fileName)
-		if err != nil {
-			return err
-		}
-
-		// Parse CSV data (simplified for test)
-		secretData = [][]string{
-			{"SecretLevel", "SecretType", "SecretName", "SecretValue", "SecretAccess", "RepositoryNames", "RepositoryIDs"},
-			{"Organization", "Actions", "TEST_SECRET", "test-value", "all", "", ""},
-		}
-
-		importSecretList = getter.CreateSecretsList(secretData)
-
-		// Process just the first secret for testing
-		if len(importSecretList) > 0 {
-			secret := importSecretList[0]
-			if secret.Level == "Organization" && secret.Type == "Actions" {
-				publicKey, _ := getter.GetOrgActionPublicKey(owner)
-				var responsePublicKey data.PublicKey
-				err := json.Unmarshal(publicKey, &responsePublicKey)
-				if err != nil {
-					return err
-				}
-				// Store the encrypted secret in a variable and use it
-				encryptedSecret, _ := getter.EncryptSecret(responsePublicKey.Key, secret.Value)
-				// Use encryptedSecret in a meaningful way to avoid the "declared and not used" warning
-				if encryptedSecret != "" {
-					err := getter.CreateOrgActionSecret(owner, secret.Name, nil)
-					if err != nil {
-						return err
-					}
-				}
-			}
-		}
+	if cmd.Flag("dry-run") == nil {
+		t.Error("dry-run flag not found")
 	}
-
-	return nil
-}
-
-// Custom adapter to make MockAPIGetter compatible with runCmdCreate
-type testAPIGetter struct {
-	mock *utils.MockAPIGetter
-}
-
-// Implement the necessary methods from APIGetter
-func (t *testAPIGetter) CreateSecretsList(data [][]string) []data.ImportedSecret {
-	return t.mock.CreateSecretsList(data)
-}
-
-func (t *testAPIGetter) GetOrgActionPublicKey(owner string) ([]byte, error) {
-	return t.mock.GetOrgActionPublicKey(owner)
-}
-
-func (t *testAPIGetter) GetOrgCodespacesPublicKey(owner string) ([]byte, error) {
-	return t.mock.GetOrgCodespacesPublicKey(owner)
-}
-
-func (t *testAPIGetter) GetOrgDependabotPublicKey(owner string) ([]byte, error) {
-	return t.mock.GetOrgDependabotPublicKey(owner)
-}
-
-func (t *testAPIGetter) GetRepoActionPublicKey(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoActionPublicKey(owner, repo)
-}
-
-func (t *testAPIGetter) GetRepoCodespacesPublicKey(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoCodespacesPublicKey(owner, repo)
-}
-
-func (t *testAPIGetter) GetRepoDependabotPublicKey(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoDependabotPublicKey(owner, repo)
-}
-
-func (t *testAPIGetter) EncryptSecret(publickey string, secret string) (string, error) {
-	return t.mock.EncryptSecret(publickey, secret)
-}
-
-func (t *testAPIGetter) CreateOrgActionSecret(owner string, secret string, data io.Reader) error {
-	return t.mock.CreateOrgActionSecret(owner, secret, data)
-}
-
-func (t *testAPIGetter) CreateOrgCodespacesSecret(owner string, secret string, data io.Reader) error {
-	return t.mock.CreateOrgCodespacesSecret(owner, secret, data)
-}
-
-func (t *testAPIGetter) CreateOrgDependabotSecret(owner string, secret string, data io.Reader) error {
-	return t.mock.CreateOrgDependabotSecret(owner, secret, data)
-}
-
-func (t *testAPIGetter) CreateRepoActionSecret(owner string, repo string, secret string, data io.Reader) error {
-	return t.mock.CreateRepoActionSecret(owner, repo, secret, data)
-}
-
-func (t *testAPIGetter) CreateRepoCodespacesSecret(owner string, repo string, secret string, data io.Reader) error {
-	return t.mock.CreateRepoCodespacesSecret(owner, repo, secret, data)
-}
-
-func (t *testAPIGetter) CreateRepoDependabotSecret(owner string, repo string, secret string, data io.Reader) error {
-	return t.mock.CreateRepoDependabotSecret(owner, repo, secret, data)
-}
-
-func (t *testAPIGetter) GetReposList(owner string, endCursor *string) (*data.ReposQuery, error) {
-	return t.mock.GetReposList(owner, endCursor)
-}
-
-func (t *testAPIGetter) GetRepo(owner string, name string) (*data.RepoSingleQuery, error) {
-	return t.mock.GetRepo(owner, name)
-}
-
-func (t *testAPIGetter) GetOrgActionSecrets(owner string) ([]byte, error) {
-	return t.mock.GetOrgActionSecrets(owner)
-}
-
-func (t *testAPIGetter) GetRepoActionSecrets(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoActionSecrets(owner, repo)
-}
-
-func (t *testAPIGetter) GetScopedOrgActionSecrets(owner string, secret string) ([]byte, error) {
-	return t.mock.GetScopedOrgActionSecrets(owner, secret)
-}
-
-func (t *testAPIGetter) GetOrgDependabotSecrets(owner string) ([]byte, error) {
-	return t.mock.GetOrgDependabotSecrets(owner)
-}
-
-func (t *testAPIGetter) GetRepoDependabotSecrets(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoDependabotSecrets(owner, repo)
-}
-
-func (t *testAPIGetter) GetScopedOrgDependabotSecrets(owner string, secret string) ([]byte, error) {
-	return t.mock.GetScopedOrgDependabotSecrets(owner, secret)
-}
-
-func (t *testAPIGetter) GetOrgCodespacesSecrets(owner string) ([]byte, error) {
-	return t.mock.GetOrgCodespacesSecrets(owner)
-}
-
-func (t *testAPIGetter) GetRepoCodespacesSecrets(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoCodespacesSecrets(owner, repo)
 }
 
-func (t *testAPIGetter) GetScopedOrgCodespacesSecrets(owner string, secret string) ([]byte, error) {
-	return t.mock.GetScopedOrgCodespacesSecrets(owner, secret)
-}
-
-func (t *testAPIGetter) GetOrgActionVariables(owner string) ([]byte, error) {
-	return t.mock.GetOrgActionVariables(owner)
-}
-
-func (t *testAPIGetter) GetRepoActionVariables(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoActionVariables(owner, repo)
-}
-
-func (t *testAPIGetter) GetScopedOrgActionVariables(owner string, variable string) ([]byte, error) {
-	return t.mock.GetScopedOrgActionVariables(owner, variable)
-}
-
-func setupMockGetter() (*utils.MockAPIGetter, *testAPIGetter) {
+// setupMockGetter returns a mock backend serving a generated public key for
+// every level and type, with the repositories in repoIDs.
+func setupMockGetter(t *testing.T, repoIDs map[string]int) *utils.MockAPIGetter {
+	t.Helper()
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
 	mockGetter := utils.NewMockAPIGetter()
-
-	// Mock public key response for all types
-	publicKey := data.PublicKey{
+	mockGetter.PublicKeyData, _ = json.Marshal(data.PublicKey{
 		KeyID: "test-key-id",
-		Key:   "dGVzdC1wdWJsaWMta2V5", // base64 encoded
-	}
-	publicKeyBytes, _ := json.Marshal(publicKey)
-	mockGetter.PublicKeyData = publicKeyBytes
-	mockGetter.EncryptedSecret = "encrypted-test-value"
+		Key:   base64.StdEncoding.EncodeToString(publicKey[:]),
+	})
+	mockGetter.RepoIDs = repoIDs
+	return mockGetter
+}
 
-	// Create our adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
+func writeSecretsFile(t *testing.T, content string) string {
+	t.Helper()
+	csvFile := filepath.Join(t.TempDir(), "test-secrets.csv")
+	if err := os.WriteFile(csvFile, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to create test CSV file: %v", err)
 	}
-
-	return mockGetter, testGetter
+	return csvFile
 }
 
 func TestRunCmdCreate(t *testing.T) {
-	// Create a temporary CSV file
-	tmpDir := t.TempDir()
-	csvFile := filepath.Join(tmpDir, "test-secrets.csv")
-	csvContent := `SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs
-Organization,Actions,TEST_SECRET,test-value,all,,`
+	csvFile := writeSecretsFile(t, `SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs
+Organization,Actions,TEST_SECRET,test-value,all,,`)
+	mockGetter := setupMockGetter(t, nil)
 
-	err := os.WriteFile(csvFile, []byte(csvContent), 0644)
+	err := runCmdCreate("testorg", &cmdFlags{fileName: csvFile, hostname: "github.com"}, mockGetter)
 	if err != nil {
-		t.Fatalf("Failed to create test CSV file: %v", err)
-	}
-
-	// Create mock API getter
-	_, testGetter := setupMockGetter()
-
-	// Create command flags
-	flags := &cmdFlags{
-		fileName: csvFile,
-		hostname: "github.com",
-		debug:    false,
+		t.Fatalf("runCmdCreate() error = %v", err)
 	}
-
-	// Execute with our adapter, using the test version that accepts an interface
-	err = runCmdCreateTest("testorg", flags, testGetter)
-
-	// Verify
-	if err != nil {
-		t.Errorf("runCmdCreate() error = %v", err)
+	expected := []string{"orgs/testorg/actions/secrets/TEST_SECRET"}
+	if !reflect.DeepEqual(mockGetter.Created, expected) {
+		t.Errorf("Expected %v to be created, got %v", expected, mockGetter.Created)
 	}
 }
 
@@ -288,65 +102,31 @@ func TestRunCmdCreateOrgSecretTypes(t *testing.T) {
 		name       string
 		secretType string
 		access     string
+		wantPath   string
 	}{
-		{"Org Actions Secret", "Actions", "all"},
-		{"Org Codespaces Secret", "Codespaces", "all"},
-		{"Org Dependabot Secret", "Dependabot", "all"},
-		{"Org Actions Secret with Private Access", "Actions", "private"},
-		{"Org Actions Secret with Selected Access", "Actions", "selected"},
+		{"Org Actions Secret", "Actions", "all", "orgs/testorg/actions/secrets/TEST_SECRET"},
+		{"Org Codespaces Secret", "Codespaces", "all", "orgs/testorg/codespaces/secrets/TEST_SECRET"},
+		{"Org Dependabot Secret", "Dependabot", "all", "orgs/testorg/dependabot/secrets/TEST_SECRET"},
+		{"Org Actions Secret with Private Access", "Actions", "private", "orgs/testorg/actions/secrets/TEST_SECRET"},
+		{"Org Actions Secret with Selected Access", "Actions", "selected", "orgs/testorg/actions/secrets/TEST_SECRET"},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			// Create a temporary CSV file
-			tmpDir := t.TempDir()
-			csvFile := filepath.Join(tmpDir, "test-secrets.csv")
-
-			var csvContent string
+			repos := ""
 			if tc.access == "selected" {
-				csvContent = `SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs
-Organization,` + tc.secretType + `,TEST_SECRET,test-value,` + tc.access + `,repo1;repo2,1234;5678`
-			} else {
-				csvContent = `SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs
-Organization,` + tc.secretType + `,TEST_SECRET,test-value,` + tc.access + `,,`
+				repos = "repo1;repo2"
 			}
+			csvFile := writeSecretsFile(t, `SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs
+Organization,`+tc.secretType+`,TEST_SECRET,test-value,`+tc.access+`,`+repos+`,`)
+			mockGetter := setupMockGetter(t, map[string]int{"repo1": 1234, "repo2": 5678})
 
-			err := os.WriteFile(csvFile, []byte(csvContent), 0644)
+			err := runCmdCreate("testorg", &cmdFlags{fileName: csvFile, hostname: "github.com"}, mockGetter)
 			if err != nil {
-				t.Fatalf("Failed to create test CSV file: %v", err)
+				t.Fatalf("runCmdCreate() error = %v", err)
 			}
-
-			// Create mock API getter
-			mockGetter, testGetter := setupMockGetter()
-
-			// Setup mock data for selected repos if needed
-			if tc.access == "selected" {
-				mockGetter.ImportedSecrets = []data.ImportedSecret{
-					{
-						Level:           "Organization",
-						Type:            tc.secretType,
-						Name:            "TEST_SECRET",
-						Value:           "test-value",
-						Access:          "selected",
-						RepositoryNames: []string{"repo1", "repo2"},
-						RepositoryIDs:   []string{"1234", "5678"},
-					},
-				}
-			}
-
-			// Create command flags
-			flags := &cmdFlags{
-				fileName: csvFile,
-				hostname: "github.com",
-				debug:    false,
-			}
-
-			// Execute with our adapter, using the test version that accepts an interface
-			err = runCmdCreateTest("testorg", flags, testGetter)
-
-			// Verify
-			if err != nil {
-				t.Errorf("runCmdCreate() error = %v", err)
+			if !reflect.DeepEqual(mockGetter.Created, []string{tc.wantPath}) {
+				t.Errorf("Expected %s to be created, got %v", tc.wantPath, mockGetter.Created)
 			}
 		})
 	}
@@ -356,76 +136,48 @@ func TestRunCmdCreateRepoSecrets(t *testing.T) {
 	tests := []struct {
 		name       string
 		secretType string
-		repoName   string
-		repoID     string
+		wantPath   string
 	}{
-		{"Repo Actions Secret", "Actions", "test-repo", "12345"},
-		{"Repo Codespaces Secret", "Codespaces", "test-repo", "12345"},
-		{"Repo Dependabot Secret", "Dependabot", "test-repo", "12345"},
+		{"Repo Actions Secret", "Actions", "repos/testorg/test-repo/actions/secrets/TEST_REPO_SECRET"},
+		{"Repo Codespaces Secret", "Codespaces", "repos/testorg/test-repo/codespaces/secrets/TEST_REPO_SECRET"},
+		{"Repo Dependabot Secret", "Dependabot", "repos/testorg/test-repo/dependabot/secrets/TEST_REPO_SECRET"},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			// Create a temporary CSV file
-			tmpDir := t.TempDir()
-			csvFile := filepath.Join(tmpDir, "test-secrets.csv")
-			csvContent := `SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs
-Repository,` + tc.secretType + `,TEST_REPO_SECRET,test-value,RepoOnly,` + tc.repoName + `,` + tc.repoID
+			csvFile := writeSecretsFile(t, `SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs
+Repository,`+tc.secretType+`,TEST_REPO_SECRET,test-value,RepoOnly,test-repo,12345`)
+			mockGetter := setupMockGetter(t, nil)
 
-			err := os.WriteFile(csvFile, []byte(csvContent), 0644)
+			err := runCmdCreate("testorg", &cmdFlags{fileName: csvFile, hostname: "github.com"}, mockGetter)
 			if err != nil {
-				t.Fatalf("Failed to create test CSV file: %v", err)
-			}
-
-			// Create mock API getter
-			mockGetter, testGetter := setupMockGetter()
-
-			// Setup mock data for repository secrets
-			mockGetter.ImportedSecrets = []data.ImportedSecret{
-				{
-					Level:           "Repository",
-					Type:            tc.secretType,
-					Name:            "TEST_REPO_SECRET",
-					Value:           "test-value",
-					Access:          "RepoOnly",
-					RepositoryNames: []string{tc.repoName},
-					RepositoryIDs:   []string{tc.repoID},
-				},
+				t.Fatalf("runCmdCreate() error = %v", err)
 			}
-
-			// Create command flags
-			flags := &cmdFlags{
-				fileName: csvFile,
-				hostname: "github.com",
-				debug:    false,
-			}
-
-			// Execute with our adapter, using the test version that accepts an interface
-			err = runCmdCreateTest("testorg", flags, testGetter)
-
-			// Verify
-			if err != nil {
-				t.Errorf("runCmdCreate() error = %v", err)
+			if !reflect.DeepEqual(mockGetter.Created, []string{tc.wantPath}) {
+				t.Errorf("Expected %s to be created, got %v", tc.wantPath, mockGetter.Created)
 			}
 		})
 	}
 }
 
-func TestRunCmdCreateFileError(t *testing.T) {
-	// Create mock API getter
-	_, testGetter := setupMockGetter()
+func TestRunCmdCreateMissingRepo(t *testing.T) {
+	csvFile := writeSecretsFile(t, `SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs
+Organization,Actions,TEST_SECRET,test-value,selected,repo1;missing,`)
+	mockGetter := setupMockGetter(t, map[string]int{"repo1": 1234})
 
-	// Create command flags with non-existent file
-	flags := &cmdFlags{
-		fileName: "non-existent-file.csv",
-		hostname: "github.com",
-		debug:    false,
+	err := runCmdCreate("testorg", &cmdFlags{fileName: csvFile, hostname: "github.com"}, mockGetter)
+	if err == nil {
+		t.Fatal("Expected an error for a repository that does not exist, got nil")
 	}
+	if len(mockGetter.Created) != 0 {
+		t.Errorf("Expected nothing to be created, got %v", mockGetter.Created)
+	}
+}
 
-	// Execute with our adapter, using the test version that accepts an interface
-	err := runCmdCreateTest("testorg", flags, testGetter)
+func TestRunCmdCreateFileError(t *testing.T) {
+	mockGetter := setupMockGetter(t, nil)
 
-	// Verify error is returned
+	err := runCmdCreate("testorg", &cmdFlags{fileName: "non-existent-file.csv", hostname: "github.com"}, mockGetter)
 	if err == nil {
 		t.Error("Expected error for non-existent file, got nil")
 	}
@@ -462,48 +214,11 @@ func TestCmdRunE(t *testing.T) {
 func TestFlagRequirements(t *testing.T) {
 	cmd := NewCmdCreate()
 
-	// from-file flag should be required
+	// from-file flag should exist, with PreRunE requiring it or a source organization
 	fromFileFlag := cmd.Flag("from-file")
 	if fromFileFlag == nil {
 		t.Fatal("from-file flag not found")
 	}
 	// This log entry documents our conclusion from code inspection
-	t.Log("The from-file flag is marked as required in the NewCmdCreate() function")
-}
-
-func TestOutputFormat(t *testing.T) {
-	// Create a temporary CSV file
-	tmpDir := t.TempDir()
-	csvFile := filepath.Join(tmpDir, "test-secrets.csv")
-	csvContent := `SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs
-Organization,Actions,TEST_SECRET,test-value,all,,`
-
-	err := os.WriteFile(csvFile, []byte(csvContent), 0644)
-	if err != nil {
-		t.Fatalf("Failed to create test CSV file: %v", err)
-	}
-
-	// We don't actually use the output buffer in this test, so we'll remove it
-	// to avoid the "declared and not used" error
-
-	// Create mock API getter and use only the testGetter to avoid the unused variable error
-	_, testGetter := setupMockGetter()
-
-	// Create command flags
-	flags := &cmdFlags{
-		fileName: csvFile,
-		hostname: "github.com",
-		debug:    false,
-	}
-
-	// Execute
-	err = runCmdCreateTest("testorg", flags, testGetter)
-
-	// Verify no error
-	if err != nil {
-		t.Errorf("runCmdCreate() error = %v", err)
-	}
-
-	// In a real implementation, you'd verify the output text
-	// For now, we'll just check that our function completes without error
+	t.Log("Either the from-file or source-organization flag is required by PreRunE in the NewCmdCreate() function")
 }
@@ -0,0 +1,70 @@
+package createsecrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestRunCmdCreateSecretStores(t *testing.T) {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyResponse := `{"key_id":"key-1","key":"` + base64.StdEncoding.EncodeToString(publicKey[:]) + `"}`
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"secrets.csv":    "SecretLevel,SecretType,SecretName,SecretValue,SecretAccess\nOrganization,Actions,DB_PASSWORD,store:aws:prod/db#password,all\n",
+		"aws.json":       `[{"Name":"prod/db","SecretString":"{\"username\":\"app\",\"password\":\"pa55\"}"}]`,
+		"providers.yaml": "providers:\n  - name: aws\n    type: aws-secrets-file\n    path: " + filepath.Join(tmpDir, "aws.json") + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	var encrypted string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/public-key") {
+			_, _ = w.Write([]byte(keyResponse))
+			return
+		}
+		var body map[string]string
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &body)
+		encrypted = body["encrypted_value"]
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	flags := &cmdFlags{fileName: filepath.Join(tmpDir, "secrets.csv"), providers: filepath.Join(tmpDir, "providers.yaml"), concurrency: 1}
+	if err := runCmdCreate("test-org", flags, newTestGetter(t, handler)); err != nil {
+		t.Fatalf("runCmdCreate() error = %v", err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(encrypted)
+	value, ok := box.OpenAnonymous(nil, sealed, publicKey, privateKey)
+	if !ok || string(value) != "pa55" {
+		t.Errorf("Expected the value from the secret store to be encrypted, got %q", value)
+	}
+
+	flags.providers = ""
+	err = runCmdCreate("test-org", flags, newTestGetter(t, handler))
+	if err == nil || !strings.Contains(err.Error(), "failed to create 1 of 1 secrets") {
+		t.Errorf("Expected the secret to fail without a provider config, got %v", err)
+	}
+
+	flags.providers = filepath.Join(tmpDir, "aws.json")
+	if err := runCmdCreate("test-org", flags, newTestGetter(t, handler)); err == nil || !strings.Contains(err.Error(), "failed to read provider config") {
+		t.Errorf("Expected an error for an invalid provider config, got %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package createsecrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/fakegithub"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"golang.org/x/crypto/nacl/box"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+func TestRunCmdCreateResults(t *testing.T) {
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyResponse := `{"key_id":"key-1","key":"` + base64.StdEncoding.EncodeToString(publicKey[:]) + `"}`
+
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "secrets.csv")
+	csvContent := "SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs,EnvironmentName\n" +
+		"Organization,Actions,ORG_SECRET,value,all,,,\n" +
+		"Repository,Dependabot,REPO_SECRET,value,RepoOnly,locked-repo,1,\n" +
+		"Environment,Actions,ENV_SECRET,value,EnvironmentOnly,archived-repo,1,prod\n"
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0600); err != nil {
+		t.Fatalf("Failed to create test CSV file: %v", err)
+	}
+
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/repos/test-org/locked-repo/"):
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"Must have admin rights to Repository."}`))
+		case strings.HasSuffix(r.URL.Path, "/public-key"):
+			_, _ = w.Write([]byte(keyResponse))
+		case strings.HasPrefix(r.URL.Path, "/repos/test-org/archived-repo/"):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"message":"Repository was archived so is read-only."}`))
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+
+	resultsFile := filepath.Join(tmpDir, "results.json")
+	err = runCmdCreate("test-org", &cmdFlags{fileName: csvFile, concurrency: 2, resultsFile: resultsFile}, g)
+	if err == nil || !strings.Contains(err.Error(), "failed to create 2 of 3 secrets") {
+		t.Fatalf("Expected an error counting 2 failed secrets, got %v", err)
+	}
+
+	content, err := os.ReadFile(resultsFile)
+	if err != nil {
+		t.Fatalf("Failed to read results file: %v", err)
+	}
+	var results []data.RowResult
+	if err := json.Unmarshal(content, &results); err != nil {
+		t.Fatalf("Failed to parse results file: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	expected := []data.RowResult{
+		{Row: 1, Level: "Organization", Type: "Actions", Name: "ORG_SECRET", Target: "test-org", Result: data.ResultSucceeded},
+		{Row: 2, Level: "Repository", Type: "Dependabot", Name: "REPO_SECRET", Target: "test-org/locked-repo", Result: data.ResultFailed, HTTPStatus: http.StatusForbidden},
+		{Row: 3, Level: "Environment", Type: "Actions", Name: "ENV_SECRET", Target: "test-org/archived-repo (prod)", Result: data.ResultFailed, HTTPStatus: http.StatusUnprocessableEntity},
+	}
+	for i, want := range expected {
+		got := results[i]
+		errMessage := got.Error
+		got.Error = ""
+		if got != want {
+			t.Errorf("Result %d: expected %+v, got %+v", i, want, got)
+		}
+		if (want.Result == data.ResultFailed) != (errMessage != "") {
+			t.Errorf("Result %d: unexpected error message %q", i, errMessage)
+		}
+	}
+}
+
+func TestReportResults(t *testing.T) {
+	succeeded := []data.RowResult{
+		utils.NewRowResult(1, "Organization", "Actions", "ORG_SECRET", "test-org", nil),
+	}
+	var out bytes.Buffer
+	if err := reportResults("test-org", "", succeeded, &out); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Summary: 1 secrets succeeded, 0 skipped, 0 failed.") {
+		t.Errorf("Expected a summary, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "Successfully created secrets for: test-org.") {
+		t.Errorf("Expected a success message, got:\n%s", out.String())
+	}
+
+	failed := append(succeeded, utils.NewRowResult(2, "Repository", "Actions", "REPO_SECRET", "test-org/repo1",
+		&api.HTTPError{StatusCode: http.StatusNotFound, Message: "Not Found"}))
+	resultsFile := filepath.Join(t.TempDir(), "results.csv")
+	out.Reset()
+	err := reportResults("test-org", resultsFile, failed, &out)
+	if err == nil || !strings.Contains(err.Error(), "failed to create 1 of 2 secrets") {
+		t.Errorf("Expected a failure count error, got %v", err)
+	}
+	if strings.Contains(out.String(), "Successfully created") {
+		t.Errorf("Expected no success message, got:\n%s", out.String())
+	}
+	content, err := os.ReadFile(resultsFile)
+	if err != nil {
+		t.Fatalf("Failed to read results file: %v", err)
+	}
+	if !strings.Contains(string(content), "2,Repository,Actions,REPO_SECRET,test-org/repo1,failed,404,") {
+		t.Errorf("Expected the failed row in the results file, got:\n%s", content)
+	}
+}
+
+func TestRunCmdCreateRepoMap(t *testing.T) {
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyResponse := `{"key_id":"key-1","key":"` + base64.StdEncoding.EncodeToString(publicKey[:]) + `"}`
+
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "secrets.csv")
+	csvContent := "SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs,EnvironmentName\n" +
+		"Repository,Actions,REPO_SECRET,value,RepoOnly,api,1,\n" +
+		"Environment,Actions,ENV_SECRET,value,EnvironmentOnly,docs,2,prod\n"
+	mapFile := filepath.Join(tmpDir, "mapping.csv")
+	mapContent := "github_source_org,github_source_repo,github_target_org,github_target_repo\n" +
+		"source-org,api,test-org,platform-api\n"
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0600); err != nil {
+		t.Fatalf("Failed to create test CSV file: %v", err)
+	}
+	if err := os.WriteFile(mapFile, []byte(mapContent), 0600); err != nil {
+		t.Fatalf("Failed to create mapping file: %v", err)
+	}
+
+	var created []string
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/public-key") {
+			_, _ = w.Write([]byte(keyResponse))
+			return
+		}
+		created = append(created, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	resultsFile := filepath.Join(tmpDir, "results.json")
+	err = runCmdCreate("test-org", &cmdFlags{fileName: csvFile, concurrency: 1, repoMap: mapFile, resultsFile: resultsFile}, g)
+	if err == nil || !strings.Contains(err.Error(), "failed to create 1 of 2 secrets") {
+		t.Fatalf("Expected the unmapped row to fail, got %v", err)
+	}
+	if strings.Join(created, ",") != "/repos/test-org/platform-api/actions/secrets/REPO_SECRET" {
+		t.Errorf("Expected only the mapped secret to be created, got %v", created)
+	}
+
+	content, err := os.ReadFile(resultsFile)
+	if err != nil {
+		t.Fatalf("Failed to read results file: %v", err)
+	}
+	var results []data.RowResult
+	if err := json.Unmarshal(content, &results); err != nil {
+		t.Fatalf("Failed to parse results file: %v", err)
+	}
+	if results[0].Target != "test-org/platform-api" || results[0].Result != data.ResultSucceeded {
+		t.Errorf("Expected the mapped secret to succeed, got %+v", results[0])
+	}
+	if results[1].Result != data.ResultFailed || results[1].Error != "no repository mapping for: docs" {
+		t.Errorf("Expected the unmapped secret to fail, got %+v", results[1])
+	}
+}
+
+func TestRunCmdCreateInvalidFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "secrets.csv")
+	csvContent := "SecretName,SecretType,SecretLevel,SecretValue\n" +
+		"ORG_SECRET,Actions,Organization,value\n" +
+		"REPO_SECRET,Actions,Repository,value\n"
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0600); err != nil {
+		t.Fatalf("Failed to create test CSV file: %v", err)
+	}
+
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request %s %s for an invalid file", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	err := runCmdCreate("test-org", &cmdFlags{fileName: csvFile, concurrency: 1}, g)
+	if err == nil {
+		t.Fatal("Expected an error for an invalid file, got nil")
+	}
+	for _, want := range []string{
+		`row 1, column SecretAccess: unknown visibility ""`,
+		"row 2, column RepositoryNames: a repository name is required for a Repository level secret",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %v", want, err)
+		}
+	}
+}
+
+func TestRunCmdCreatePublicKeyCache(t *testing.T) {
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyResponse := `{"key_id":"key-1","key":"` + base64.StdEncoding.EncodeToString(publicKey[:]) + `"}`
+
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "secrets.csv")
+	csvContent := "SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs\n"
+	for i := 0; i < 20; i++ {
+		csvContent += fmt.Sprintf("Organization,Actions,ORG_SECRET_%d,value,all,,\n", i)
+		csvContent += fmt.Sprintf("Repository,Actions,REPO_SECRET_%d,value,,api,\n", i)
+	}
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0600); err != nil {
+		t.Fatalf("Failed to create test CSV file: %v", err)
+	}
+
+	var mu sync.Mutex
+	keyReads := map[string]int{}
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/public-key") {
+			mu.Lock()
+			keyReads[r.URL.Path]++
+			mu.Unlock()
+			_, _ = w.Write([]byte(keyResponse))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if err := runCmdCreate("test-org", &cmdFlags{fileName: csvFile, concurrency: 4}, g); err != nil {
+		t.Fatalf("runCmdCreate() error = %v", err)
+	}
+	expected := map[string]int{
+		"/orgs/test-org/actions/secrets/public-key":      1,
+		"/repos/test-org/api/actions/secrets/public-key": 1,
+	}
+	if !reflect.DeepEqual(keyReads, expected) {
+		t.Errorf("Expected each public key to be read once, got %v", keyReads)
+	}
+}
+
+func TestRunCmdCreateFakeGitHub(t *testing.T) {
+	server := fakegithub.New()
+	repoA := server.AddRepository("test-org", "repo-a", "private")
+	server.AddRepository("test-org", "repo-b", "private")
+	if err := server.AddEnvironment("test-org", "repo-b", "production"); err != nil {
+		t.Fatalf("Failed to add environment: %v", err)
+	}
+
+	csvFile := writeSecretsFile(t, "SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs,EnvironmentName\n"+
+		"Organization,Dependabot,NPM_TOKEN,npm-value,selected,repo-a,,\n"+
+		"Repository,Codespaces,API_KEY,api-value,,repo-b,,\n"+
+		"Environment,Actions,DEPLOY_KEY,\"deploy, value\",,repo-b,,production\n")
+	err := runCmdCreate("test-org", &cmdFlags{fileName: csvFile, concurrency: 2}, newTestGetter(t, server.ServeHTTP))
+	if err != nil {
+		t.Fatalf("runCmdCreate() error = %v", err)
+	}
+
+	npm, _ := server.Secret("test-org", "", "", "Dependabot", "NPM_TOKEN")
+	if npm.Value != "npm-value" || !reflect.DeepEqual(npm.SelectedRepositoryIDs, []int{repoA}) {
+		t.Errorf("Unexpected organization secret: %+v", npm)
+	}
+	if apiKey, _ := server.Secret("test-org", "repo-b", "", "Codespaces", "API_KEY"); apiKey.Value != "api-value" {
+		t.Errorf("Expected API_KEY to decrypt to api-value, got %q", apiKey.Value)
+	}
+	if deploy, _ := server.Secret("test-org", "repo-b", "production", "Actions", "DEPLOY_KEY"); deploy.Value != "deploy, value" {
+		t.Errorf("Expected DEPLOY_KEY to decrypt to %q, got %q", "deploy, value", deploy.Value)
+	}
+}
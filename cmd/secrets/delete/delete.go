@@ -0,0 +1,120 @@
+package deletesecrets
+
+import (
+	"io"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type cmdFlags struct {
+	utils.DeleteFlags
+	app      string
+	token    string
+	hostname string
+	debug    bool
+}
+
+func NewCmdDelete() *cobra.Command {
+	cmdFlags := cmdFlags{}
+	var authToken string
+
+	deleteCmd := cobra.Command{
+		Use:   "delete <organization> [flags]",
+		Short: "Delete Actions, Dependabot, and/or Codespaces secrets.",
+		Long:  "Delete Actions, Dependabot, and/or Codespaces secrets for an organization, repositories and environments from a file, or a single secret specified with flags.",
+		Args:  cobra.MinimumNArgs(1),
+		PreRunE: func(deleteCmd *cobra.Command, args []string) error {
+			return cmdFlags.Validate("secret")
+		},
+		RunE: func(deleteCmd *cobra.Command, args []string) error {
+			var err error
+			var gqlClient *api.GraphQLClient
+			var restClient *api.RESTClient
+
+			// Reinitialize logging if debugging was enabled
+			if cmdFlags.debug {
+				logger, _ := log.NewLogger(cmdFlags.debug)
+				defer logger.Sync() // nolint:errcheck
+				zap.ReplaceGlobals(logger)
+			}
+
+			if cmdFlags.token != "" {
+				authToken = cmdFlags.token
+			} else {
+				t, _ := auth.TokenForHost(cmdFlags.hostname)
+				authToken = t
+			}
+
+			gqlClient, err = api.NewGraphQLClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github.hawkgirl-preview+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving graphql client")
+				return err
+			}
+
+			restClient, err = api.NewRESTClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving rest client")
+				return err
+			}
+
+			owner := args[0]
+
+			return runCmdDelete(owner, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), deleteCmd.InOrStdin(), deleteCmd.OutOrStdout())
+		},
+	}
+
+	// Configure flags for command
+	deleteCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub personal access token for organization to delete from (default "gh auth token")`)
+	deleteCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
+	utils.AddDeleteFlags(&deleteCmd, &cmdFlags.DeleteFlags, "secret")
+	deleteCmd.Flags().StringVarP(&cmdFlags.app, "app", "a", "actions", "Application of the secret to delete: {actions|codespaces|dependabot}")
+	deleteCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
+
+	return &deleteCmd
+}
+
+func runCmdDelete(owner string, cmdFlags *cmdFlags, backend utils.Getter, in io.Reader, out io.Writer) error {
+	secrets := []data.ImportedSecret{secretFromFlags(cmdFlags)}
+	if len(cmdFlags.FileName) > 0 {
+		zap.S().Debugf("Reading in secrets from %s", cmdFlags.FileName)
+		var err error
+		secrets, err = utils.ReadSecretScopesFile(cmdFlags.FileName)
+		if err != nil {
+			zap.S().Errorf("Error arose reading secrets file")
+			return err
+		}
+	}
+	return utils.NewAPIGetter(backend).DeleteSecrets(owner, secrets, cmdFlags.Yes, in, out)
+}
+
+// secretFromFlags builds the secret to delete from the level, app, repo and
+// environment flags.
+func secretFromFlags(cmdFlags *cmdFlags) data.ImportedSecret {
+	return data.ImportedSecret{
+		Level:           cmdFlags.Level,
+		Type:            cmdFlags.app,
+		Name:            cmdFlags.Name,
+		RepositoryNames: []string{cmdFlags.Repo},
+		EnvironmentName: cmdFlags.Environment,
+	}
+}
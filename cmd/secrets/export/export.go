@@ -1,12 +1,12 @@
 package exportsecrets
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -21,11 +21,13 @@ import (
 )
 
 type cmdFlags struct {
-	app        string
-	hostname   string
-	token      string
-	reportFile string
-	debug      bool
+	app         string
+	hostname    string
+	token       string
+	reportFile  string
+	format      string
+	concurrency int
+	debug       bool
 }
 
 func NewCmdExport() *cobra.Command {
@@ -86,6 +88,13 @@ func NewCmdExport() *cobra.Command {
 			owner := args[0]
 			repos := args[1:]
 
+			if err := utils.ValidateFormat(cmdFlags.format); err != nil {
+				return err
+			}
+			if !exportCmd.Flags().Changed("output-file") {
+				cmdFlags.reportFile = utils.FormatFileName(cmdFlags.reportFile, cmdFlags.format)
+			}
+
 			if _, err := os.Stat(cmdFlags.reportFile); errors.Is(err, os.ErrExist) {
 				return err
 			}
@@ -96,7 +105,7 @@ func NewCmdExport() *cobra.Command {
 				return err
 			}
 
-			return runCmdExport(owner, repos, &cmdFlags, utils.NewAPIGetter(gqlClient, restClient), reportWriter)
+			return runCmdExport(owner, repos, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), reportWriter)
 		},
 	}
 
@@ -108,20 +117,28 @@ func NewCmdExport() *cobra.Command {
 	exportCmd.PersistentFlags().StringVarP(&cmdFlags.app, "app", "a", appDefault, "List secrets for a specific application or all: {all|actions|codespaces|dependabot}")
 	exportCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub Personal Access Token (default "gh auth token")`)
 	exportCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
-	exportCmd.Flags().StringVarP(&cmdFlags.reportFile, "output-file", "o", reportFileDefault, "Name of file to write CSV report")
+	exportCmd.Flags().StringVarP(&cmdFlags.reportFile, "output-file", "o", reportFileDefault, "Name of file to write report")
+	exportCmd.Flags().StringVar(&cmdFlags.format, "format", utils.FormatCSV, "Format of the report: {csv|json|yaml}")
+	exportCmd.Flags().IntVarP(&cmdFlags.concurrency, "concurrency", "c", 1, "Number of repositories to gather secrets for concurrently")
 	exportCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
 	//cmd.MarkPersistentFlagRequired("app")
 
 	return &exportCmd
 }
 
-func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.APIGetter, reportWriter io.Writer) error {
-	var reposCursor *string
-	var allRepos []data.RepoInfo
+func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, backend utils.Getter, reportWriter io.Writer) error {
+	g := utils.NewAPIGetter(backend)
 
-	csvWriter := csv.NewWriter(reportWriter)
+	format := cmdFlags.format
+	if format == "" {
+		format = utils.FormatCSV
+	}
+	reportRecords, err := utils.NewSecretsWriter(reportWriter, format)
+	if err != nil {
+		return err
+	}
 
-	err := csvWriter.Write([]string{
+	err = reportRecords.Write([]string{
 		"SecretLevel",
 		"SecretType",
 		"SecretName",
@@ -129,44 +146,16 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 		"SecretAccess",
 		"RepositoryNames",
 		"RepositoryIDs",
+		"EnvironmentName",
 	})
 
 	if err != nil {
 		return err
 	}
 
-	if len(repos) > 0 {
-		zap.S().Infof("Processing repos: %s", repos)
-
-		for _, repo := range repos {
-
-			zap.S().Debugf("Processing %s/%s", owner, repo)
-
-			repoQuery, err := g.GetRepo(owner, repo)
-			if err != nil {
-				return err
-			}
-			allRepos = append(allRepos, repoQuery.Repository)
-		}
-
-	} else {
-		// Prepare writer for outputting report
-		for {
-			zap.S().Debugf("Processing list of repositories for %s", owner)
-			reposQuery, err := g.GetReposList(owner, reposCursor)
-
-			if err != nil {
-				return err
-			}
-
-			allRepos = append(allRepos, reposQuery.Organization.Repositories.Nodes...)
-
-			reposCursor = &reposQuery.Organization.Repositories.PageInfo.EndCursor
-
-			if !reposQuery.Organization.Repositories.PageInfo.HasNextPage {
-				break
-			}
-		}
+	allRepos, err := g.ListRepos(owner, repos)
+	if err != nil {
+		return err
 	}
 
 	// Writing to CSV Org level Actions secrets
@@ -202,7 +191,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					stringRepoId := strconv.Itoa(scopeSecret.ID)
 					concatRepoIds = append(concatRepoIds, stringRepoId)
 				}
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					"Actions",
 					orgSecret.Name,
@@ -210,6 +199,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					orgSecret.Visibility,
 					strings.Join(concatRepos, ";"),
 					strings.Join(concatRepoIds, ";"),
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
@@ -225,7 +215,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 						concatRepoIds = append(concatRepoIds, stringRepoId)
 					}
 				}
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					"Actions",
 					orgSecret.Name,
@@ -233,13 +223,14 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					orgSecret.Visibility,
 					strings.Join(concatRepos, ";"),
 					strings.Join(concatRepoIds, ";"),
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
 				}
 			default:
 				zap.S().Debugf("Gathering public Actions Secret %s for %s", orgSecret.Name, owner)
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					"Actions",
 					orgSecret.Name,
@@ -247,6 +238,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					orgSecret.Visibility,
 					"",
 					"",
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
@@ -289,7 +281,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					stringRepoId := strconv.Itoa(depScopeSecret.ID)
 					concatRepoIds = append(concatRepoIds, stringRepoId)
 				}
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					"Dependabot",
 					orgDepSecret.Name,
@@ -297,6 +289,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					orgDepSecret.Visibility,
 					strings.Join(concatRepos, ";"),
 					strings.Join(concatRepoIds, ";"),
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
@@ -312,7 +305,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 						concatRepoIds = append(concatRepoIds, stringRepoId)
 					}
 				}
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					"Dependabot",
 					orgDepSecret.Name,
@@ -320,13 +313,14 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					orgDepSecret.Visibility,
 					strings.Join(concatRepos, ";"),
 					strings.Join(concatRepoIds, ";"),
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
 				}
 			default:
 				zap.S().Debugf("Gathering public Dependabot Secret %s for %s", orgDepSecret.Name, owner)
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					"Dependabot",
 					orgDepSecret.Name,
@@ -334,6 +328,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					orgDepSecret.Visibility,
 					"",
 					"",
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
@@ -376,7 +371,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					stringRepoId := strconv.Itoa(codeScopeSecret.ID)
 					concatRepoIds = append(concatRepoIds, stringRepoId)
 				}
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					"Codespaces",
 					orgCodeSecret.Name,
@@ -384,6 +379,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					orgCodeSecret.Visibility,
 					strings.Join(concatRepos, ";"),
 					strings.Join(concatRepoIds, ";"),
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
@@ -399,7 +395,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 						concatRepoIds = append(concatRepoIds, stringRepoId)
 					}
 				}
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					"Codespaces",
 					orgCodeSecret.Name,
@@ -407,13 +403,14 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					orgCodeSecret.Visibility,
 					strings.Join(concatRepos, ";"),
 					strings.Join(concatRepoIds, ";"),
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
 				}
 			default:
 				zap.S().Debugf("Gathering public Codespaces Secret %s for %s", orgCodeSecret.Name, owner)
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					"Codespaces",
 					orgCodeSecret.Name,
@@ -421,6 +418,7 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					orgCodeSecret.Visibility,
 					"",
 					"",
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
@@ -429,95 +427,172 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 		}
 	}
 
-	// Writing to CSV repository level Secrets
-	for _, singleRepo := range allRepos {
-		zap.S().Debugf("Gathering Secrets for repo %s", singleRepo.Name)
-
-		// Writing to CSV repository level Actions secrets
-		if cmdFlags.app == "all" || cmdFlags.app == "actions" {
-			zap.S().Debugf("Gathering Actions Secrets for repo %s", singleRepo.Name)
-			repoActionSecretsList, err := g.GetRepoActionSecrets(owner, singleRepo.Name)
-			if err != nil {
-				return err
-			}
-			var repoActionResponseObject data.SecretsResponse
-			err = json.Unmarshal(repoActionSecretsList, &repoActionResponseObject)
-			if err != nil {
-				return err
-			}
-			for _, repoActionsSecret := range repoActionResponseObject.Secrets {
-				err = csvWriter.Write([]string{
-					"Repository",
-					"Actions",
-					repoActionsSecret.Name,
-					"",
-					"RepoOnly",
-					singleRepo.Name,
-					strconv.Itoa(singleRepo.DatabaseId),
-				})
-				if err != nil {
-					zap.S().Error("Error raised in writing output", zap.Error(err))
-				}
-			}
+	// Writing to CSV repository level Secrets, gathered concurrently and
+	// written in repository order so the report is deterministic
+	sort.SliceStable(allRepos, func(i, j int) bool {
+		return allRepos[i].Name < allRepos[j].Name
+	})
+	repoRows := make([][][]string, len(allRepos))
+	repoErrors := make([]error, len(allRepos))
+	utils.RunConcurrently(cmdFlags.concurrency, len(allRepos), func(index int) {
+		repoRows[index], repoErrors[index] = exportRepoSecrets(owner, allRepos[index], cmdFlags.app, g)
+	})
+	for index := range allRepos {
+		if repoErrors[index] != nil {
+			return repoErrors[index]
 		}
-		// Writing to CSV repository level Dependabot secrets
-		if cmdFlags.app == "all" || cmdFlags.app == "dependabot" {
-			zap.S().Debugf("Gathering Dependabot Secrets for repo %s", singleRepo.Name)
-			repoDepSecretsList, err := g.GetRepoDependabotSecrets(owner, singleRepo.Name)
+		sortSecretRows(repoRows[index])
+		for _, row := range repoRows[index] {
+			err = reportRecords.Write(row)
 			if err != nil {
-				return err
-			}
-			var repoDepResponseObject data.SecretsResponse
-			err = json.Unmarshal(repoDepSecretsList, &repoDepResponseObject)
-			if err != nil {
-				return err
-			}
-			for _, repoDepSecret := range repoDepResponseObject.Secrets {
-				err = csvWriter.Write([]string{
-					"Repository",
-					"Dependabot",
-					repoDepSecret.Name,
-					"",
-					"RepoOnly",
-					singleRepo.Name,
-					strconv.Itoa(singleRepo.DatabaseId),
-				})
-				if err != nil {
-					zap.S().Error("Error raised in writing output", zap.Error(err))
-				}
+				zap.S().Error("Error raised in writing output", zap.Error(err))
 			}
 		}
-		// Writing to CSV repository level Codespaces secrets
-		if cmdFlags.app == "all" || cmdFlags.app == "codespaces" {
-			zap.S().Debugf("Gathering Codespaces Secrets for repo %s", singleRepo.Name)
-			repoCodeSecretsList, err := g.GetRepoCodespacesSecrets(owner, singleRepo.Name)
+	}
+
+	reportRecords.Flush()
+	if err := reportRecords.Error(); err != nil {
+		zap.S().Error("Error raised in writing output", zap.Error(err))
+		return err
+	}
+	fmt.Printf("Successfully exported secrets for %s to %s\n", owner, cmdFlags.reportFile)
+	return nil
+
+}
+
+func exportRepoSecrets(owner string, singleRepo data.RepoInfo, app string, g *utils.APIGetter) ([][]string, error) {
+	var rows [][]string
+	zap.S().Debugf("Gathering Secrets for repo %s", singleRepo.Name)
+
+	// Gathering repository level Actions secrets
+	if app == "all" || app == "actions" {
+		zap.S().Debugf("Gathering Actions Secrets for repo %s", singleRepo.Name)
+		repoActionSecretsList, err := g.GetRepoActionSecrets(owner, singleRepo.Name)
+		if err != nil {
+			return nil, err
+		}
+		var repoActionResponseObject data.SecretsResponse
+		err = json.Unmarshal(repoActionSecretsList, &repoActionResponseObject)
+		if err != nil {
+			return nil, err
+		}
+		for _, repoActionsSecret := range repoActionResponseObject.Secrets {
+			rows = append(rows, []string{
+				"Repository",
+				"Actions",
+				repoActionsSecret.Name,
+				"",
+				"RepoOnly",
+				singleRepo.Name,
+				strconv.Itoa(singleRepo.DatabaseId),
+				"",
+			})
+		}
+	}
+	// Gathering repository level Dependabot secrets
+	if app == "all" || app == "dependabot" {
+		zap.S().Debugf("Gathering Dependabot Secrets for repo %s", singleRepo.Name)
+		repoDepSecretsList, err := g.GetRepoDependabotSecrets(owner, singleRepo.Name)
+		if err != nil {
+			return nil, err
+		}
+		var repoDepResponseObject data.SecretsResponse
+		err = json.Unmarshal(repoDepSecretsList, &repoDepResponseObject)
+		if err != nil {
+			return nil, err
+		}
+		for _, repoDepSecret := range repoDepResponseObject.Secrets {
+			rows = append(rows, []string{
+				"Repository",
+				"Dependabot",
+				repoDepSecret.Name,
+				"",
+				"RepoOnly",
+				singleRepo.Name,
+				strconv.Itoa(singleRepo.DatabaseId),
+				"",
+			})
+		}
+	}
+	// Gathering repository level Codespaces secrets
+	if app == "all" || app == "codespaces" {
+		zap.S().Debugf("Gathering Codespaces Secrets for repo %s", singleRepo.Name)
+		repoCodeSecretsList, err := g.GetRepoCodespacesSecrets(owner, singleRepo.Name)
+		if err != nil {
+			return nil, err
+		}
+		var repoCodeResponseObject data.SecretsResponse
+		err = json.Unmarshal(repoCodeSecretsList, &repoCodeResponseObject)
+		if err != nil {
+			return nil, err
+		}
+		for _, repoCodeSecret := range repoCodeResponseObject.Secrets {
+			rows = append(rows, []string{
+				"Repository",
+				"Codespaces",
+				repoCodeSecret.Name,
+				"",
+				"RepoOnly",
+				singleRepo.Name,
+				strconv.Itoa(singleRepo.DatabaseId),
+				"",
+			})
+		}
+	}
+	// Gathering environment level Actions secrets
+	if app == "all" || app == "actions" {
+		zap.S().Debugf("Gathering environments for repo %s", singleRepo.Name)
+		repoEnvironmentsList, err := g.GetRepoEnvironments(owner, singleRepo.Name)
+		if err != nil {
+			return nil, err
+		}
+		var repoEnvironmentsResponseObject data.EnvironmentsResponse
+		err = json.Unmarshal(repoEnvironmentsList, &repoEnvironmentsResponseObject)
+		if err != nil {
+			return nil, err
+		}
+		for _, environment := range repoEnvironmentsResponseObject.Environments {
+			zap.S().Debugf("Gathering Actions Secrets for environment %s in repo %s", environment.Name, singleRepo.Name)
+			envSecretsList, err := g.GetEnvironmentActionSecrets(owner, singleRepo.Name, environment.Name)
 			if err != nil {
-				zap.S().Error("Error raised in writing output", zap.Error(err))
+				return nil, err
 			}
-			var repoCodeResponseObject data.SecretsResponse
-			err = json.Unmarshal(repoCodeSecretsList, &repoCodeResponseObject)
+			var envResponseObject data.SecretsResponse
+			err = json.Unmarshal(envSecretsList, &envResponseObject)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			for _, repoCodeSecret := range repoCodeResponseObject.Secrets {
-				err = csvWriter.Write([]string{
-					"Repository",
-					"Codespaces",
-					repoCodeSecret.Name,
+			for _, envSecret := range envResponseObject.Secrets {
+				rows = append(rows, []string{
+					"Environment",
+					"Actions",
+					envSecret.Name,
 					"",
-					"RepoOnly",
+					"EnvironmentOnly",
 					singleRepo.Name,
 					strconv.Itoa(singleRepo.DatabaseId),
+					environment.Name,
 				})
-				if err != nil {
-					zap.S().Error("Error raised in writing output", zap.Error(err))
-				}
 			}
 		}
 	}
+	return rows, nil
+}
 
-	csvWriter.Flush()
-	fmt.Printf("Successfully exported secrets for %s to %s\n", owner, cmdFlags.reportFile)
-	return nil
-
+// Repository rows are ordered by level, environment, type and name, matching
+// the order they are written in for a single repository
+func sortSecretRows(rows [][]string) {
+	levelOrder := map[string]int{"Repository": 0, "Environment": 1}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if levelOrder[rows[i][0]] != levelOrder[rows[j][0]] {
+			return levelOrder[rows[i][0]] < levelOrder[rows[j][0]]
+		}
+		if rows[i][7] != rows[j][7] {
+			return rows[i][7] < rows[j][7]
+		}
+		if rows[i][1] != rows[j][1] {
+			return rows[i][1] < rows[j][1]
+		}
+		return rows[i][2] < rows[j][2]
+	})
 }
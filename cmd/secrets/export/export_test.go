@@ -2,11 +2,8 @@ package exportsecrets
 
 import (
 	"bytes"
-	"encoding/csv"
 	"encoding/json"
-	"fmt"
 	"io"
-	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -36,311 +33,16 @@ func TestNewCmdExport(t *testing.T) {
 		t.Error("output-file flag not found")
 	}
 
+	if cmd.Flag("concurrency") == nil {
+		t.Error("concurrency flag not found")
+	}
+
 	// Test short description
 	if cmd.Short == "" {
 		t.Error("Command should have a short description")
 	}
 }
 
-// Define an adapter that implements the required methods for testing
-type testAPIGetter struct {
-	mock *utils.MockAPIGetter
-}
-
-// Implement the necessary methods from APIGetter for secrets functionality
-func (t *testAPIGetter) GetReposList(owner string, endCursor *string) (*data.ReposQuery, error) {
-	return t.mock.GetReposList(owner, endCursor)
-}
-
-func (t *testAPIGetter) GetRepo(owner string, name string) (*data.RepoSingleQuery, error) {
-	return t.mock.GetRepo(owner, name)
-}
-
-func (t *testAPIGetter) GetOrgActionSecrets(owner string) ([]byte, error) {
-	return t.mock.GetOrgActionSecrets(owner)
-}
-
-func (t *testAPIGetter) GetRepoActionSecrets(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoActionSecrets(owner, repo)
-}
-
-func (t *testAPIGetter) GetScopedOrgActionSecrets(owner string, secret string) ([]byte, error) {
-	return t.mock.GetScopedOrgActionSecrets(owner, secret)
-}
-
-func (t *testAPIGetter) GetOrgDependabotSecrets(owner string) ([]byte, error) {
-	return t.mock.GetOrgDependabotSecrets(owner)
-}
-
-func (t *testAPIGetter) GetRepoDependabotSecrets(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoDependabotSecrets(owner, repo)
-}
-
-func (t *testAPIGetter) GetScopedOrgDependabotSecrets(owner string, secret string) ([]byte, error) {
-	return t.mock.GetScopedOrgDependabotSecrets(owner, secret)
-}
-
-func (t *testAPIGetter) GetOrgCodespacesSecrets(owner string) ([]byte, error) {
-	return t.mock.GetOrgCodespacesSecrets(owner)
-}
-
-func (t *testAPIGetter) GetRepoCodespacesSecrets(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoCodespacesSecrets(owner, repo)
-}
-
-func (t *testAPIGetter) GetScopedOrgCodespacesSecrets(owner string, secret string) ([]byte, error) {
-	return t.mock.GetScopedOrgCodespacesSecrets(owner, secret)
-}
-
-func runCmdExportTest(owner string, repos []string, cmdFlags *cmdFlags, g interface{}, reportWriter io.Writer) error {
-	// Use type assertion to get the required methods
-	getter, ok := g.(interface {
-		GetReposList(owner string, endCursor *string) (*data.ReposQuery, error)
-		GetRepo(owner string, name string) (*data.RepoSingleQuery, error)
-		GetOrgActionSecrets(owner string) ([]byte, error)
-		GetRepoActionSecrets(owner string, repo string) ([]byte, error)
-		GetScopedOrgActionSecrets(owner string, secret string) ([]byte, error)
-		GetOrgDependabotSecrets(owner string) ([]byte, error)
-		GetRepoDependabotSecrets(owner string, repo string) ([]byte, error)
-		GetScopedOrgDependabotSecrets(owner string, secret string) ([]byte, error)
-		GetOrgCodespacesSecrets(owner string) ([]byte, error)
-		GetRepoCodespacesSecrets(owner string, repo string) ([]byte, error)
-		GetScopedOrgCodespacesSecrets(owner string, secret string) ([]byte, error)
-	})
-
-	if !ok {
-		return nil // For testing, we're not concerned with this error
-	}
-
-	var reposCursor *string
-	var allRepos []data.RepoInfo
-
-	csvWriter := csv.NewWriter(reportWriter)
-
-	err := csvWriter.Write([]string{
-		"SecretLevel",
-		"SecretType",
-		"SecretName",
-		"SecretValue",
-		"SecretAccess",
-		"RepositoryNames",
-		"RepositoryIDs",
-	})
-	if err != nil {
-		return err
-	}
-
-	// Handle special error cases for testing
-	testGetter, isTestGetter := g.(*testAPIGetter)
-	if isTestGetter {
-		if testGetter.mock.GetRepoError && len(repos) > 0 {
-			return fmt.Errorf("mock GetRepo error")
-		}
-		if testGetter.mock.GetReposListError && len(repos) == 0 {
-			return fmt.Errorf("mock GetReposList error")
-		}
-		if testGetter.mock.GetOrgActionSecretsError && len(repos) == 0 && (strings.EqualFold(cmdFlags.app, "all") || strings.EqualFold(cmdFlags.app, "actions")) {
-			return fmt.Errorf("mock GetOrgActionSecrets error")
-		}
-	}
-
-	if len(repos) > 0 {
-		for _, repo := range repos {
-			repoQuery, err := getter.GetRepo(owner, repo)
-			if err != nil {
-				return err
-			}
-			allRepos = append(allRepos, repoQuery.Repository)
-		}
-	} else {
-		reposQuery, err := getter.GetReposList(owner, reposCursor)
-		if err != nil {
-			return err
-		}
-		allRepos = append(allRepos, reposQuery.Organization.Repositories.Nodes...)
-	}
-
-	// Writing to CSV Org level Actions secrets
-	if len(repos) == 0 && (strings.EqualFold(cmdFlags.app, "all") || strings.EqualFold(cmdFlags.app, "actions")) {
-		orgSecrets, err := getter.GetOrgActionSecrets(owner)
-		if err != nil {
-			return err
-		}
-
-		var oActionResponseObject data.SecretsResponse
-		err = json.Unmarshal(orgSecrets, &oActionResponseObject)
-		if err != nil {
-			return err
-		}
-
-		for _, orgSecret := range oActionResponseObject.Secrets {
-			err = csvWriter.Write([]string{
-				"Organization",
-				"Actions",
-				orgSecret.Name,
-				"",
-				orgSecret.Visibility,
-				"",
-				"",
-			})
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// Writing to CSV Org level Dependabot secrets
-	if len(repos) == 0 && (strings.EqualFold(cmdFlags.app, "all") || strings.EqualFold(cmdFlags.app, "dependabot")) {
-		orgDepSecrets, err := getter.GetOrgDependabotSecrets(owner)
-		if err != nil {
-			return err
-		}
-
-		var oDepResponseObject data.SecretsResponse
-		err = json.Unmarshal(orgDepSecrets, &oDepResponseObject)
-		if err != nil {
-			return err
-		}
-
-		for _, orgDepSecret := range oDepResponseObject.Secrets {
-			err = csvWriter.Write([]string{
-				"Organization",
-				"Dependabot",
-				orgDepSecret.Name,
-				"",
-				orgDepSecret.Visibility,
-				"",
-				"",
-			})
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// Writing to CSV Org level Codespaces secrets
-	if len(repos) == 0 && (strings.EqualFold(cmdFlags.app, "all") || strings.EqualFold(cmdFlags.app, "codespaces")) {
-		orgCodeSecrets, err := getter.GetOrgCodespacesSecrets(owner)
-		if err != nil {
-			return err
-		}
-
-		var oCodeResponseObject data.SecretsResponse
-		err = json.Unmarshal(orgCodeSecrets, &oCodeResponseObject)
-		if err != nil {
-			return err
-		}
-
-		for _, orgCodeSecret := range oCodeResponseObject.Secrets {
-			err = csvWriter.Write([]string{
-				"Organization",
-				"Codespaces",
-				orgCodeSecret.Name,
-				"",
-				orgCodeSecret.Visibility,
-				"",
-				"",
-			})
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// Writing to CSV repository level Secrets
-	for _, singleRepo := range allRepos {
-		// Writing to CSV repository level Actions secrets
-		if strings.EqualFold(cmdFlags.app, "all") || strings.EqualFold(cmdFlags.app, "actions") {
-			repoActionSecretsList, err := getter.GetRepoActionSecrets(owner, singleRepo.Name)
-			if err != nil {
-				return err
-			}
-
-			var repoActionResponseObject data.SecretsResponse
-			err = json.Unmarshal(repoActionSecretsList, &repoActionResponseObject)
-			if err != nil {
-				return err
-			}
-
-			for _, repoActionsSecret := range repoActionResponseObject.Secrets {
-				err = csvWriter.Write([]string{
-					"Repository",
-					"Actions",
-					repoActionsSecret.Name,
-					"",
-					"RepoOnly",
-					singleRepo.Name,
-					strconv.Itoa(singleRepo.DatabaseId),
-				})
-				if err != nil {
-					return err
-				}
-			}
-		}
-
-		// Writing to CSV repository level Dependabot secrets
-		if strings.EqualFold(cmdFlags.app, "all") || strings.EqualFold(cmdFlags.app, "dependabot") {
-			repoDepSecretsList, err := getter.GetRepoDependabotSecrets(owner, singleRepo.Name)
-			if err != nil {
-				return err
-			}
-
-			var repoDepResponseObject data.SecretsResponse
-			err = json.Unmarshal(repoDepSecretsList, &repoDepResponseObject)
-			if err != nil {
-				return err
-			}
-
-			for _, repoDepSecret := range repoDepResponseObject.Secrets {
-				err = csvWriter.Write([]string{
-					"Repository",
-					"Dependabot",
-					repoDepSecret.Name,
-					"",
-					"RepoOnly",
-					singleRepo.Name,
-					"12345", // Use a hardcoded value for testing
-				})
-				if err != nil {
-					return err
-				}
-			}
-		}
-
-		// Writing to CSV repository level Codespaces secrets
-		if strings.EqualFold(cmdFlags.app, "all") || strings.EqualFold(cmdFlags.app, "codespaces") {
-			repoCodeSecretsList, err := getter.GetRepoCodespacesSecrets(owner, singleRepo.Name)
-			if err != nil {
-				return err
-			}
-
-			var repoCodeResponseObject data.SecretsResponse
-			err = json.Unmarshal(repoCodeSecretsList, &repoCodeResponseObject)
-			if err != nil {
-				return err
-			}
-
-			for _, repoCodeSecret := range repoCodeResponseObject.Secrets {
-				err = csvWriter.Write([]string{
-					"Repository",
-					"Codespaces",
-					repoCodeSecret.Name,
-					"",
-					"RepoOnly",
-					singleRepo.Name,
-					"12345", // Use a hardcoded value for testing
-				})
-				if err != nil {
-					return err
-				}
-			}
-		}
-	}
-
-	csvWriter.Flush()
-	return nil
-}
-
 func TestRunCmdExport(t *testing.T) {
 	// Setup
 	owner := "testorg"
@@ -411,16 +113,10 @@ func TestRunCmdExport(t *testing.T) {
 	mockGetter.RepoDependabotSecretsData = secretsResponseBytes
 	mockGetter.RepoCodespacesSecretsData = secretsResponseBytes
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err != nil {
@@ -445,7 +141,7 @@ func TestRunCmdExportSpecificRepos(t *testing.T) {
 	owner := "testorg"
 	repos := []string{"testrepo"}
 	flags := &cmdFlags{
-		app:        "Actions",
+		app:        "actions",
 		reportFile: "test-specific-report.csv",
 	}
 
@@ -475,16 +171,10 @@ func TestRunCmdExportSpecificRepos(t *testing.T) {
 	secretsResponseBytes, _ := json.Marshal(secretsResponse)
 	mockGetter.RepoActionSecretsData = secretsResponseBytes
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err != nil {
@@ -533,25 +223,20 @@ func TestRunCmdExportDependabotRepoSecrets(t *testing.T) {
 	secretsResponseBytes, _ := json.Marshal(secretsResponse)
 	mockGetter.RepoDependabotSecretsData = secretsResponseBytes
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function - need to modify runCmdExportTest to handle dependabot repo secrets
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err != nil {
 		t.Errorf("runCmdExport() error = %v", err)
 	}
 
-	// For now this test will pass even though the output doesn't contain the secret
-	// because the implementation doesn't write dependabot repo secrets yet
-	// This test serves as documentation of what needs to be implemented
+	// Check if output contains the expected repository secret
+	if !strings.Contains(buf.String(), "Repository,Dependabot,REPO_DEP_SECRET") {
+		t.Errorf("Output does not contain expected repository secret data: %s", buf.String())
+	}
 }
 
 func TestRunCmdExportCodespacesRepoSecrets(t *testing.T) {
@@ -589,25 +274,20 @@ func TestRunCmdExportCodespacesRepoSecrets(t *testing.T) {
 	secretsResponseBytes, _ := json.Marshal(secretsResponse)
 	mockGetter.RepoCodespacesSecretsData = secretsResponseBytes
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function - need to modify runCmdExportTest to handle codespaces repo secrets
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err != nil {
 		t.Errorf("runCmdExport() error = %v", err)
 	}
 
-	// For now this test will pass even though the output doesn't contain the secret
-	// because the implementation doesn't write codespaces repo secrets yet
-	// This test serves as documentation of what needs to be implemented
+	// Check if output contains the expected repository secret
+	if !strings.Contains(buf.String(), "Repository,Codespaces,REPO_CODE_SECRET") {
+		t.Errorf("Output does not contain expected repository secret data: %s", buf.String())
+	}
 }
 
 func TestRunCmdExportGetRepoError(t *testing.T) {
@@ -623,16 +303,10 @@ func TestRunCmdExportGetRepoError(t *testing.T) {
 	mockGetter := utils.NewMockAPIGetter()
 	mockGetter.GetRepoError = true
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err == nil {
@@ -653,16 +327,10 @@ func TestRunCmdExportGetReposListError(t *testing.T) {
 	mockGetter := utils.NewMockAPIGetter()
 	mockGetter.GetReposListError = true
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err == nil {
@@ -715,16 +383,10 @@ func TestRunCmdExportGetOrgActionSecretsError(t *testing.T) {
 	}
 	mockGetter.GetOrgActionSecretsError = true
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err == nil {
@@ -778,16 +440,10 @@ func TestRunCmdExportInvalidOrgActionSecretsJSON(t *testing.T) {
 	// Mock invalid JSON for org action secrets
 	mockGetter.OrgActionSecretsData = []byte(`{invalid json`)
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err == nil {
@@ -840,16 +496,10 @@ func TestRunCmdExportCSVWriteError(t *testing.T) {
 		},
 	}
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create a writer that will fail on Write
 	failWriter := &errorWriter{}
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, failWriter)
+	err := runCmdExport(owner, repos, flags, mockGetter, failWriter)
 
 	// Verify
 	if err == nil {
@@ -921,16 +571,10 @@ func TestRunCmdExportDependabotOnly(t *testing.T) {
 	secretsResponseBytes, _ := json.Marshal(secretsResponse)
 	mockGetter.OrgDependabotSecretsData = secretsResponseBytes
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err != nil {
@@ -1002,16 +646,10 @@ func TestRunCmdExportCodespacesOnly(t *testing.T) {
 	secretsResponseBytes, _ := json.Marshal(secretsResponse)
 	mockGetter.OrgCodespacesSecretsData = secretsResponseBytes
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err != nil {
@@ -1025,21 +663,25 @@ func TestRunCmdExportCodespacesOnly(t *testing.T) {
 	}
 }
 
-func TestRunCmdExportTypeAssertionError(t *testing.T) {
-	// Setup
-	owner := "testorg"
-	repos := []string{}
-	flags := &cmdFlags{
-		app:        "all",
-		reportFile: "test-report.csv",
+func TestSortSecretRows(t *testing.T) {
+	rows := [][]string{
+		{"Environment", "Actions", "TOKEN", "", "EnvironmentOnly", "repo", "1", "prod"},
+		{"Repository", "Dependabot", "TOKEN", "", "RepoOnly", "repo", "1", ""},
+		{"Environment", "Actions", "TOKEN", "", "EnvironmentOnly", "repo", "1", "dev"},
+		{"Repository", "Actions", "ZETA", "", "RepoOnly", "repo", "1", ""},
+		{"Repository", "Actions", "ALPHA", "", "RepoOnly", "repo", "1", ""},
 	}
 
-	// Pass an incompatible type that will fail type assertion
-	var buf bytes.Buffer
-	err := runCmdExportTest(owner, repos, flags, "not-a-getter", &buf)
+	sortSecretRows(rows)
 
-	// Verify - this should not return an error as the test function ignores type assertion errors
-	if err != nil {
-		t.Errorf("runCmdExport() error = %v", err)
+	expected := []string{"Actions/ALPHA", "Actions/ZETA", "Dependabot/TOKEN", "dev/TOKEN", "prod/TOKEN"}
+	for i, row := range rows {
+		got := row[1] + "/" + row[2]
+		if row[0] == "Environment" {
+			got = row[7] + "/" + row[2]
+		}
+		if got != expected[i] {
+			t.Errorf("Row %d: expected %s, got %s", i, expected[i], got)
+		}
 	}
 }
@@ -0,0 +1,95 @@
+package exportsecrets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+func TestRunCmdExportFormats(t *testing.T) {
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/graphql":
+			_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"nodes":[{"databaseId":1,"name":"api","visibility":"private"}],"pageInfo":{"hasNextPage":false}}}}}`))
+		case "/orgs/test-org/actions/secrets":
+			_, _ = w.Write([]byte(`{"total_count":1,"secrets":[{"name":"DEPLOY_KEY","visibility":"selected"}]}`))
+		case "/orgs/test-org/actions/secrets/DEPLOY_KEY/repositories":
+			_, _ = w.Write([]byte(`{"total_count":1,"repositories":[{"id":1,"name":"api"}]}`))
+		case "/repos/test-org/api/environments":
+			_, _ = w.Write([]byte(`{"total_count":1,"environments":[{"name":"production"}]}`))
+		case "/repos/test-org/api/environments/production/secrets":
+			_, _ = w.Write([]byte(`{"total_count":1,"secrets":[{"name":"DB_PASSWORD"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"total_count":0,"secrets":[]}`))
+		}
+	})
+
+	for _, format := range []string{utils.FormatJSON, utils.FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			reportFile := filepath.Join(t.TempDir(), "report."+format)
+			f, err := os.Create(reportFile)
+			if err != nil {
+				t.Fatalf("Failed to create report: %v", err)
+			}
+			flags := &cmdFlags{app: "actions", reportFile: reportFile, format: format, concurrency: 1}
+			err = runCmdExport("test-org", nil, flags, g, f)
+			f.Close()
+			if err != nil {
+				t.Fatalf("runCmdExport() error = %v", err)
+			}
+
+			content, _ := os.ReadFile(reportFile)
+			if !strings.Contains(string(content), "selected_repository_ids") {
+				t.Errorf("Expected the fields of data.ImportedSecret, got:\n%s", content)
+			}
+			secrets, err := utils.ReadSecretsFile(reportFile)
+			if err != nil {
+				t.Fatalf("Expected the report to be read back, got %v", err)
+			}
+			if len(secrets) != 2 {
+				t.Fatalf("Expected 2 secrets, got %+v", secrets)
+			}
+			if secrets[0].Name != "DEPLOY_KEY" || secrets[0].Access != "selected" || secrets[0].RepositoryIDs[0] != "1" {
+				t.Errorf("Unexpected organization secret %+v", secrets[0])
+			}
+			if secrets[1].Level != "Environment" || secrets[1].EnvironmentName != "production" {
+				t.Errorf("Unexpected environment secret %+v", secrets[1])
+			}
+		})
+	}
+}
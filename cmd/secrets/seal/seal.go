@@ -0,0 +1,156 @@
+package sealsecrets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/log"
+	"github.com/katiem0/gh-seva/internal/providers"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type cmdFlags struct {
+	fileName    string
+	bundleFile  string
+	providers   string
+	concurrency int
+	token       string
+	hostname    string
+	debug       bool
+}
+
+func NewCmdSeal() *cobra.Command {
+	cmdFlags := cmdFlags{}
+	var authToken string
+
+	sealCmd := cobra.Command{
+		Use:   "seal <organization> [flags]",
+		Short: "Encrypt secrets into a sealed bundle to be applied later.",
+		Long:  "Encrypt the values of Actions, Dependabot, and/or Codespaces secrets from a file with the public keys of an organization, writing a bundle of only encrypted values that apply-sealed can write without ever seeing the values.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(sealCmd *cobra.Command, args []string) error {
+			var err error
+			var gqlClient *api.GraphQLClient
+			var restClient *api.RESTClient
+
+			// Reinitialize logging if debugging was enabled
+			if cmdFlags.debug {
+				logger, _ := log.NewLogger(cmdFlags.debug)
+				defer logger.Sync() // nolint:errcheck
+				zap.ReplaceGlobals(logger)
+			}
+
+			if cmdFlags.token != "" {
+				authToken = cmdFlags.token
+			} else {
+				t, _ := auth.TokenForHost(cmdFlags.hostname)
+				authToken = t
+			}
+
+			gqlClient, err = api.NewGraphQLClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github.hawkgirl-preview+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving graphql client")
+				return err
+			}
+
+			restClient, err = api.NewRESTClient(api.ClientOptions{
+				Headers: map[string]string{
+					"Accept": "application/vnd.github+json",
+				},
+				Host:      cmdFlags.hostname,
+				AuthToken: authToken,
+			})
+
+			if err != nil {
+				zap.S().Errorf("Error arose retrieving rest client")
+				return err
+			}
+
+			owner := args[0]
+
+			return runCmdSeal(owner, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), sealCmd.OutOrStdout())
+		},
+	}
+
+	bundleFileDefault := fmt.Sprintf("sealed-secrets-%s.json", time.Now().Format("20060102150405"))
+
+	// Configure flags for command
+	sealCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub personal access token able to read the public keys of the organization (default "gh auth token")`)
+	sealCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
+	sealCmd.Flags().StringVarP(&cmdFlags.fileName, "from-file", "f", "", "Path and Name of CSV, JSON or YAML file of the secrets to seal (required)")
+	sealCmd.Flags().StringVarP(&cmdFlags.bundleFile, "output-file", "o", bundleFileDefault, "Name of file to write the sealed bundle to")
+	sealCmd.Flags().StringVar(&cmdFlags.providers, "providers-config", "", "Path and Name of a YAML file configuring the secret stores that store: values are read from")
+	sealCmd.Flags().IntVarP(&cmdFlags.concurrency, "concurrency", "c", 1, "Number of secrets to seal concurrently")
+	sealCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
+
+	if err := sealCmd.MarkFlagRequired("from-file"); err != nil {
+		zap.S().Errorf("Error marking from-file flag as required: %v", err)
+	}
+
+	return &sealCmd
+}
+
+func runCmdSeal(owner string, cmdFlags *cmdFlags, backend utils.Getter, out io.Writer) error {
+	g := utils.NewAPIGetter(backend)
+	zap.S().Debugf("Reading in secrets from %s", cmdFlags.fileName)
+	secrets, err := utils.ReadSecretsFile(cmdFlags.fileName)
+	if err != nil {
+		zap.S().Errorf("Error arose reading secrets file")
+		return err
+	}
+	if len(secrets) == 0 {
+		return errors.New("no secrets found to seal")
+	}
+	if len(cmdFlags.providers) > 0 {
+		zap.S().Debugf("Reading secret store providers from %s", cmdFlags.providers)
+		stores, err := providers.LoadRegistry(cmdFlags.providers)
+		if err != nil {
+			zap.S().Errorf("Error arose reading provider config")
+			return err
+		}
+		g.SetSecretStores(stores)
+	}
+
+	sealed, errs := g.SealSecrets(owner, secrets, cmdFlags.concurrency)
+	failed := 0
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed++
+		if _, err := fmt.Fprintf(out, "Row %d: %s: %v\n", utils.ResultRow(secrets[i].Row, i), secrets[i].Name, err); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to seal %d of %d secrets, no bundle was written", failed, len(secrets))
+	}
+
+	bundle := &data.SealedBundle{
+		Organization: owner,
+		Hostname:     cmdFlags.hostname,
+		SealedAt:     time.Now().UTC(),
+		Secrets:      sealed,
+	}
+	zap.S().Debugf("Writing sealed bundle to %s", cmdFlags.bundleFile)
+	if err := utils.WriteSealedBundle(cmdFlags.bundleFile, bundle); err != nil {
+		zap.S().Errorf("Error arose writing sealed bundle")
+		return err
+	}
+	_, err = fmt.Fprintf(out, "Sealed %d secrets for %s to: %s.\n", len(sealed), owner, cmdFlags.bundleFile)
+	return err
+}
@@ -0,0 +1,125 @@
+package sealsecrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/utils"
+	"golang.org/x/crypto/nacl/box"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+// publicKeyHandler serves a public key for the api repository only, and
+// fails the test on anything but a read.
+func publicKeyHandler(t *testing.T) http.HandlerFunc {
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			t.Errorf("Unexpected %s %s while sealing", r.Method, r.URL.Path)
+		}
+		if r.URL.Path == "/repos/test-org/api/actions/secrets/public-key" {
+			_, _ = w.Write([]byte(`{"key_id":"key-1","key":"` + base64.StdEncoding.EncodeToString(publicKey[:]) + `"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	}
+}
+
+func TestRunCmdSeal(t *testing.T) {
+	dir := t.TempDir()
+	secretsFile := filepath.Join(dir, "secrets.csv")
+	content := "SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs\n" +
+		"Repository,Actions,TOKEN,plain-value,,api,\n"
+	if err := os.WriteFile(secretsFile, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write secrets file: %v", err)
+	}
+	bundleFile := filepath.Join(dir, "sealed.json")
+	var out bytes.Buffer
+
+	flags := &cmdFlags{fileName: secretsFile, bundleFile: bundleFile, concurrency: 1, hostname: "github.com"}
+	if err := runCmdSeal("test-org", flags, newTestGetter(t, publicKeyHandler(t)), &out); err != nil {
+		t.Fatalf("runCmdSeal() error = %v", err)
+	}
+	if want := "Sealed 1 secrets for test-org to: " + bundleFile + ".\n"; out.String() != want {
+		t.Errorf("Expected output %q, got %q", want, out.String())
+	}
+	bundle, err := utils.ReadSealedBundle(bundleFile)
+	if err != nil {
+		t.Fatalf("ReadSealedBundle() error = %v", err)
+	}
+	if bundle.Organization != "test-org" || bundle.Hostname != "github.com" || bundle.SealedAt.IsZero() {
+		t.Errorf("Expected the bundle to record where and when it was sealed, got %+v", bundle)
+	}
+	if len(bundle.Secrets) != 1 || bundle.Secrets[0].KeyID != "key-1" {
+		t.Errorf("Expected TOKEN to be sealed with key-1, got %+v", bundle.Secrets)
+	}
+	written, _ := os.ReadFile(bundleFile)
+	if strings.Contains(string(written), "plain-value") {
+		t.Errorf("Expected the bundle to hold no plaintext, got %s", written)
+	}
+}
+
+func TestRunCmdSealFailure(t *testing.T) {
+	dir := t.TempDir()
+	secretsFile := filepath.Join(dir, "secrets.csv")
+	content := "SecretLevel,SecretType,SecretName,SecretValue,SecretAccess,RepositoryNames,RepositoryIDs\n" +
+		"Repository,Actions,TOKEN,plain-value,,api,\n" +
+		"Repository,Actions,OTHER,plain-value,,missing,\n"
+	if err := os.WriteFile(secretsFile, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write secrets file: %v", err)
+	}
+	bundleFile := filepath.Join(dir, "sealed.json")
+	var out bytes.Buffer
+
+	flags := &cmdFlags{fileName: secretsFile, bundleFile: bundleFile, concurrency: 1, hostname: "github.com"}
+	err := runCmdSeal("test-org", flags, newTestGetter(t, publicKeyHandler(t)), &out)
+	if err == nil || err.Error() != "failed to seal 1 of 2 secrets, no bundle was written" {
+		t.Errorf("Expected a seal error, got %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Row 2: OTHER: failed to get the public key for test-org/missing:") {
+		t.Errorf("Expected the failed row to be reported, got %q", out.String())
+	}
+	if _, err := os.Stat(bundleFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no bundle to be written, got %v", err)
+	}
+}
@@ -1,8 +1,11 @@
 package secrets
 
 import (
+	applySealedCmd "github.com/katiem0/gh-seva/cmd/secrets/applysealed"
 	createCmd "github.com/katiem0/gh-seva/cmd/secrets/create"
+	deleteCmd "github.com/katiem0/gh-seva/cmd/secrets/delete"
 	exportCmd "github.com/katiem0/gh-seva/cmd/secrets/export"
+	sealCmd "github.com/katiem0/gh-seva/cmd/secrets/seal"
 	"github.com/spf13/cobra"
 )
 
@@ -11,12 +14,15 @@ func NewCmdSecrets() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "secrets <command> [flags]",
 		Args:  cobra.MinimumNArgs(1),
-		Short: "Export and Create secrets for an organization and/or repositories.",
-		Long:  "Export and Create Actions, Dependabot, and Codespaces secrets for an organization and/or repositories.",
+		Short: "Export, Create, Delete and Seal secrets for an organization and/or repositories.",
+		Long:  "Export, Create, Delete and Seal Actions, Dependabot, and Codespaces secrets for an organization and/or repositories.",
 	}
 	cmd.Flags().Bool("help", false, "Show help for command")
 	cmd.AddCommand(exportCmd.NewCmdExport())
 	cmd.AddCommand(createCmd.NewCmdCreate())
+	cmd.AddCommand(deleteCmd.NewCmdDelete())
+	cmd.AddCommand(sealCmd.NewCmdSeal())
+	cmd.AddCommand(applySealedCmd.NewCmdApplySealed())
 
 	return cmd
 }
@@ -19,9 +19,10 @@ func TestNewCmdSecrets(t *testing.T) {
 	// Test that subcommands are added
 	subcommands := cmd.Commands()
 
-	// Verify we have export and create subcommands
+	// Verify we have export, create and delete subcommands
 	exportFound := false
 	createFound := false
+	deleteFound := false
 
 	for _, subcmd := range subcommands {
 		if subcmd.Name() == "export" {
@@ -30,6 +31,9 @@ func TestNewCmdSecrets(t *testing.T) {
 		if subcmd.Name() == "create" {
 			createFound = true
 		}
+		if subcmd.Name() == "delete" {
+			deleteFound = true
+		}
 	}
 
 	if !exportFound {
@@ -40,6 +44,10 @@ func TestNewCmdSecrets(t *testing.T) {
 		t.Error("Create subcommand not found")
 	}
 
+	if !deleteFound {
+		t.Error("Delete subcommand not found")
+	}
+
 	// Test command short description
 	if cmd.Short == "" {
 		t.Error("Command short description should not be empty")
@@ -1,8 +1,6 @@
 package createvars
 
 import (
-	"bytes"
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,6 +23,11 @@ type cmdFlags struct {
 	fileName       string
 	token          string
 	hostname       string
+	concurrency    int
+	dryRun         bool
+	onConflict     string
+	resultsFile    string
+	repoMap        string
 	debug          bool
 }
 
@@ -45,6 +48,8 @@ func NewCmdCreate() *cobra.Command {
 				return errors.New("a Personal Access Token must be specified to access variables from the Source Organization")
 			} else if len(cmdFlags.fileName) > 0 && len(cmdFlags.sourceOrg) > 0 {
 				return errors.New("specify only one of `--source-organization` or `from-file`")
+			} else if cmdFlags.onConflict != utils.ConflictSkip && cmdFlags.onConflict != utils.ConflictUpdate && cmdFlags.onConflict != utils.ConflictFail {
+				return fmt.Errorf("invalid on-conflict policy %q, expected skip, update or fail", cmdFlags.onConflict)
 			}
 			return nil
 		},
@@ -93,9 +98,17 @@ func NewCmdCreate() *cobra.Command {
 				return err
 			}
 
+			var source utils.Getter
+			if len(cmdFlags.sourceOrg) > 0 {
+				source, err = newSourceGetter(&cmdFlags)
+				if err != nil {
+					return err
+				}
+			}
+
 			owner := args[0]
 
-			return runCmdCreate(owner, &cmdFlags, utils.NewAPIGetter(gqlClient, restClient))
+			return runCmdCreate(owner, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), source)
 		},
 	}
 
@@ -105,174 +118,257 @@ func NewCmdCreate() *cobra.Command {
 	createCmd.PersistentFlags().StringVarP(&cmdFlags.sourceOrg, "source-organization", "o", "", `Name of the Source Organization to copy variables from (Requires --source-token)`)
 	createCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
 	createCmd.PersistentFlags().StringVarP(&cmdFlags.sourceHostname, "source-hostname", "", "github.com", "GitHub Enterprise Server hostname where variables are copied from")
-	createCmd.Flags().StringVarP(&cmdFlags.fileName, "from-file", "f", "", "Path and Name of CSV file to create variables from")
+	createCmd.Flags().StringVarP(&cmdFlags.fileName, "from-file", "f", "", "Path and Name of CSV, JSON or YAML file to create variables from")
+	createCmd.Flags().IntVarP(&cmdFlags.concurrency, "concurrency", "c", 1, "Number of variables to create, and source repositories to read, concurrently")
+	createCmd.Flags().BoolVar(&cmdFlags.dryRun, "dry-run", false, "Print the changes that would be made without creating any variables")
+	createCmd.Flags().StringVar(&cmdFlags.resultsFile, "results-file", "", "Path and Name of a CSV, or .json, file to write the result of each variable to")
+	createCmd.Flags().StringVar(&cmdFlags.repoMap, "repo-map", "", "Path and Name of CSV file mapping source repository names to their names in the organization")
+	createCmd.Flags().StringVar(&cmdFlags.onConflict, "on-conflict", utils.ConflictUpdate, "How to handle a variable that already exists: {skip|update|fail}")
 	createCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
 
 	return &createCmd
 }
 
-func runCmdCreate(owner string, cmdFlags *cmdFlags, g *utils.APIGetter) error {
-	var variableData [][]string
+// runCmdCreate creates the variables of the file, or of the source
+// organization read through source, in owner. source is only used with
+// --source-organization.
+func runCmdCreate(owner string, cmdFlags *cmdFlags, backend utils.Getter, source utils.Getter) error {
+	g := utils.NewAPIGetter(backend)
 	var variablesList []data.ImportedVariable
 
 	if len(cmdFlags.fileName) > 0 {
-		f, err := os.Open(cmdFlags.fileName)
-		zap.S().Debugf("Opening up file %s", cmdFlags.fileName)
+		zap.S().Debugf("Reading in variables from %s", cmdFlags.fileName)
+		var err error
+		variablesList, err = utils.ReadVariablesFile(cmdFlags.fileName)
 		if err != nil {
-			zap.S().Errorf("Error arose opening variables csv file")
+			zap.S().Errorf("Error arose reading variables file")
+			return err
 		}
-		defer func() {
-			if err := f.Close(); err != nil {
-				zap.S().Errorf("Error closing file: %v", err)
-			}
-		}()
-
-		// read csv values using csv.Reader
-		csvReader := csv.NewReader(f)
-		variableData, err = csvReader.ReadAll()
-		zap.S().Debugf("Reading in all lines from csv file")
+	} else if len(cmdFlags.sourceOrg) > 0 {
+		zap.S().Debugf("Gathering variables %s", cmdFlags.sourceOrg)
+		variableResponse, err := source.GetOrgActionVariables(cmdFlags.sourceOrg)
 		if err != nil {
-			zap.S().Errorf("Error arose reading variables from csv file")
+			return err
 		}
-		variablesList = g.CreateVariableList(variableData)
-		zap.S().Debugf("Identifying Variable list to create under %s", owner)
-		zap.S().Debugf("Determining variables to create")
-		for _, variable := range variablesList {
-
-			switch variable.Level {
-			case "Organization":
-				zap.S().Debugf("Gathering Organization level variable %s", variable.Name)
-				var reader io.Reader
-				if variable.Visibility == "selected" {
-					importOrgVar := utils.CreateSelectedOrgVariableData(variable)
-					createVariable, err := json.Marshal(importOrgVar)
-
-					if err != nil {
-						return err
-					}
-
-					reader = bytes.NewReader(createVariable)
-				} else {
-					importOrgVar := utils.CreateOrgVariableData(variable)
-					createVariable, err := json.Marshal(importOrgVar)
-
-					if err != nil {
-						return err
-					}
-
-					reader = bytes.NewReader(createVariable)
-				}
-				zap.S().Debugf("Creating Variable %s under %s", variable.Name, owner)
-				err = g.CreateOrganizationVariable(owner, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating variable with %s", variable.Name)
-				}
-			case "Repository":
-				repoName := variable.SelectedRepos[0]
-				zap.S().Debugf("Gathering Repository level variable %s", variable.Name)
-				importRepoVar := utils.CreateRepoVariableData(variable)
-				createVariable, err := json.Marshal(importRepoVar)
-
-				if err != nil {
-					return err
-				}
-
-				reader := bytes.NewReader(createVariable)
-				zap.S().Debugf("Creating Variables under %s", repoName)
-				err = g.CreateRepoVariable(owner, repoName, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating variable with %s", variable.Name)
-				}
-			}
+		var response data.VariableResponse
+		err = json.Unmarshal(variableResponse, &response)
+		if err != nil {
+			return err
 		}
-	} else if len(cmdFlags.sourceOrg) > 0 {
-		zap.S().Debugf("Reading in variables from %s", cmdFlags.sourceOrg)
-		var authToken string
-		var restSourceClient *api.RESTClient
-
-		if cmdFlags.sourceToken != "" {
-			authToken = cmdFlags.sourceToken
-		} else {
-			t, _ := auth.TokenForHost(cmdFlags.sourceHostname)
-			authToken = t
+		variablesList, err = importSourceVariables(cmdFlags.sourceOrg, response.Variables, source)
+		if err != nil {
+			return err
 		}
 
-		restSourceClient, err := api.NewRESTClient(api.ClientOptions{
-			Headers: map[string]string{
-				"Accept": "application/vnd.github+json",
-			},
-			Host:      cmdFlags.sourceHostname,
-			AuthToken: authToken,
-		})
+		zap.S().Debugf("Gathering repository variables %s", cmdFlags.sourceOrg)
+		repoVariables, err := importSourceRepoVariables(cmdFlags.sourceOrg, cmdFlags.concurrency, source)
 		if err != nil {
-			zap.S().Errorf("Error arose retrieving source rest client")
 			return err
 		}
+		variablesList = append(variablesList, repoVariables...)
+	} else {
+		zap.S().Errorf("Error arose identifying variables")
+		return errors.New("a file or source organization must be specified where variables will be created from")
+	}
 
-		zap.S().Debugf("Gathering variables %s", cmdFlags.sourceOrg)
-
-		variableResponse, err := utils.GetSourceOrganizationVariables(cmdFlags.sourceOrg, utils.NewSourceAPIGetter(*restSourceClient))
+	rowErrors := make([]error, len(variablesList))
+	if len(cmdFlags.repoMap) > 0 {
+		zap.S().Debugf("Mapping repository names with %s", cmdFlags.repoMap)
+		repoMap, err := utils.LoadRepoMap(cmdFlags.repoMap)
 		if err != nil {
+			zap.S().Errorf("Error arose reading repository mapping file")
 			return err
 		}
-		var response data.VariableResponse
-		err = json.Unmarshal(variableResponse, &response)
-		if err != nil {
+		rowErrors = utils.MapVariableRepos(variablesList, repoMap)
+	}
+	if len(cmdFlags.sourceOrg) > 0 {
+		if err := g.SkipMissingVariableRepos(owner, variablesList, rowErrors); err != nil {
 			return err
 		}
-		for _, variable := range response.Variables {
-			if variable.Visibility == "selected" {
-				zap.S().Debugf("Creating Scoped Variables under %s", owner)
-				var orgVariable data.CreateOrgVariable
-				scoped_repo, err := utils.GetScopedSourceOrgActionVariables(cmdFlags.sourceOrg, variable.Name, utils.NewSourceAPIGetter(*restSourceClient))
-				if err != nil {
-					zap.S().Error("Error raised in writing output", zap.Error(err))
-				}
+	}
+	if cmdFlags.dryRun {
+		zap.S().Debugf("Planning variables to create under %s", owner)
+		plan := g.PlanVariables(owner, variablesList, cmdFlags.onConflict)
+		utils.MarkPlanErrors(plan, rowErrors)
+		return printPlan(plan)
+	}
+	zap.S().Debugf("Identifying Variable list to create under %s", owner)
+	return createVariables(owner, variablesList, rowErrors, cmdFlags, g)
+}
 
-				var responseOObject data.ScopedResponse
-				err = json.Unmarshal(scoped_repo, &responseOObject)
-				if err != nil {
-					return err
-				}
-				var concatRepoIds []int
-				for _, scopedVar := range responseOObject.Repositories {
-					concatRepoIds = append(concatRepoIds, scopedVar.ID)
-				}
-				orgVariable.SelectedReposIDs = concatRepoIds
-				orgVariable.Name = variable.Name
-				orgVariable.Value = variable.Value
-				orgVariable.Visibility = variable.Visibility
+// newSourceGetter returns a Getter for the source organization on
+// --source-hostname, authenticated with --source-token.
+func newSourceGetter(cmdFlags *cmdFlags) (utils.Getter, error) {
+	var authToken string
 
-				createOrgVariable, err := json.Marshal(orgVariable)
+	if cmdFlags.sourceToken != "" {
+		authToken = cmdFlags.sourceToken
+	} else {
+		t, _ := auth.TokenForHost(cmdFlags.sourceHostname)
+		authToken = t
+	}
 
-				if err != nil {
-					return err
-				}
-				reader := bytes.NewReader(createOrgVariable)
-				zap.S().Debugf("Creating Variables under %s", owner)
-				err = g.CreateOrganizationVariable(owner, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating variable with %s", variable.Name)
-				}
-			} else {
-				orgVariable := utils.CreateOrgSourceVariableData(variable)
-				createOrgVariable, err := json.Marshal(orgVariable)
+	restSourceClient, err := api.NewRESTClient(api.ClientOptions{
+		Headers: map[string]string{
+			"Accept": "application/vnd.github+json",
+		},
+		Host:      cmdFlags.sourceHostname,
+		AuthToken: authToken,
+	})
+	if err != nil {
+		zap.S().Errorf("Error arose retrieving source rest client")
+		return nil, err
+	}
 
-				if err != nil {
-					return err
-				}
-				reader := bytes.NewReader(createOrgVariable)
-				zap.S().Debugf("Creating Variable %s under %s", variable.Name, owner)
-				err = g.CreateOrganizationVariable(owner, reader)
-				if err != nil {
-					zap.S().Errorf("Error arose creating variable with %s", variable.Name)
-				}
-			}
+	gqlSourceClient, err := api.NewGraphQLClient(api.ClientOptions{
+		Headers: map[string]string{
+			"Accept": "application/vnd.github.hawkgirl-preview+json",
+		},
+		Host:      cmdFlags.sourceHostname,
+		AuthToken: authToken,
+	})
+	if err != nil {
+		zap.S().Errorf("Error arose retrieving source graphql client")
+		return nil, err
+	}
+
+	return utils.NewGitHubGetter(gqlSourceClient, restSourceClient), nil
+}
+
+// createVariables creates each variable, scoping organization variables to
+// the repositories of the same name in owner, and reports the results. Rows
+// with an error in rowErrors are reported as failed without being created.
+func createVariables(owner string, variablesList []data.ImportedVariable, rowErrors []error, cmdFlags *cmdFlags, g *utils.APIGetter) error {
+	zap.S().Debugf("Resolving selected repositories in %s", owner)
+	resolveErrors := g.ResolveVariableRepos(owner, variablesList)
+
+	zap.S().Debugf("Determining variables to create")
+	results := make([]data.RowResult, len(variablesList))
+	utils.RunConcurrently(cmdFlags.concurrency, len(variablesList), func(index int) {
+		variable := variablesList[index]
+		err := rowErrors[index]
+		if err == nil {
+			err = resolveErrors[index]
+		}
+		var skipErr *utils.SkipError
+		if err == nil {
+			err = g.WriteImportedVariable(owner, variable, cmdFlags.onConflict)
+		}
+		if err != nil && !errors.As(err, &skipErr) {
+			zap.S().Errorf("Error arose creating variable with %s: %v", variable.Name, err)
+		}
+		results[index] = utils.NewRowResult(utils.ResultRow(variable.Row, index), variable.Level, "Actions", variable.Name, variableTarget(owner, variable), err)
+	})
+	return reportResults(owner, cmdFlags.resultsFile, results, os.Stdout)
+}
+
+// reportResults writes the results file when one was requested and prints a
+// summary, returning an error when any variable could not be created.
+func reportResults(owner string, resultsFile string, results []data.RowResult, out io.Writer) error {
+	if len(resultsFile) > 0 {
+		zap.S().Debugf("Writing results to %s", resultsFile)
+		if err := utils.WriteResults(resultsFile, results); err != nil {
+			zap.S().Errorf("Error arose writing results file")
+			return err
 		}
-	} else {
-		zap.S().Errorf("Error arose identifying variables")
 	}
+	if err := utils.PrintResultSummary(out, "variables", results); err != nil {
+		return err
+	}
+	if failed := utils.CountFailedResults(results); failed > 0 {
+		return fmt.Errorf("failed to create %d of %d variables for: %s", failed, len(results), owner)
+	}
+	_, err := fmt.Fprintf(out, "Successfully created variables for: %s.\n", owner)
+	return err
+}
 
-	fmt.Printf("Successfully created variables for: %s.", owner)
+func variableTarget(owner string, variable data.ImportedVariable) string {
+	repo := ""
+	if len(variable.SelectedRepos) > 0 {
+		repo = variable.SelectedRepos[0]
+	}
+	return utils.Target(owner, variable.Level, repo, variable.EnvironmentName)
+}
+
+func printPlan(plan []data.PlanItem) error {
+	if err := utils.PrintPlan(os.Stdout, plan); err != nil {
+		return err
+	}
+	if errorCount := utils.CountPlanActions(plan)[data.PlanError]; errorCount > 0 {
+		return fmt.Errorf("%d variables in the plan have errors", errorCount)
+	}
 	return nil
 }
+
+// importSourceVariables converts the variables of the source organization to
+// the form read from a file, so they are planned and created in the same
+// way. Selected repositories are then matched by name in the target, as the
+// IDs of the source repositories mean nothing there.
+func importSourceVariables(sourceOrg string, variables []data.Variable, source utils.Getter) ([]data.ImportedVariable, error) {
+	imported := make([]data.ImportedVariable, 0, len(variables))
+	for _, variable := range variables {
+		importVariable := data.ImportedVariable{
+			Level:      "Organization",
+			Name:       variable.Name,
+			Value:      variable.Value,
+			Visibility: variable.Visibility,
+		}
+		if variable.Visibility == "selected" {
+			scopedRepo, err := source.GetScopedOrgActionVariables(sourceOrg, variable.Name)
+			if err != nil {
+				return nil, err
+			}
+			var responseObject data.ScopedResponse
+			if err := json.Unmarshal(scopedRepo, &responseObject); err != nil {
+				return nil, err
+			}
+			for _, repo := range responseObject.Repositories {
+				importVariable.SelectedRepos = append(importVariable.SelectedRepos, repo.Name)
+			}
+		}
+		imported = append(imported, importVariable)
+	}
+	return imported, nil
+}
+
+// importSourceRepoVariables reads the repository level variables of every
+// repository in the source organization, in the form read from a file.
+func importSourceRepoVariables(sourceOrg string, concurrency int, source utils.Getter) ([]data.ImportedVariable, error) {
+	allRepos, err := utils.NewAPIGetter(source).ListRepos(sourceOrg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	repoVariables := make([][]data.ImportedVariable, len(allRepos))
+	repoErrors := make([]error, len(allRepos))
+	utils.RunConcurrently(concurrency, len(allRepos), func(index int) {
+		repo := allRepos[index]
+		zap.S().Debugf("Gathering repo level variables for %s", repo.Name)
+		response, err := source.GetRepoActionVariables(sourceOrg, repo.Name)
+		var variablesResponse data.VariableResponse
+		if err == nil {
+			err = json.Unmarshal(response, &variablesResponse)
+		}
+		if err != nil {
+			repoErrors[index] = fmt.Errorf("failed to read variables of %s/%s: %w", sourceOrg, repo.Name, err)
+			return
+		}
+		for _, variable := range variablesResponse.Variables {
+			repoVariables[index] = append(repoVariables[index], data.ImportedVariable{
+				Level:         "Repository",
+				Name:          variable.Name,
+				Value:         variable.Value,
+				SelectedRepos: []string{repo.Name},
+			})
+		}
+	})
+
+	var imported []data.ImportedVariable
+	for index := range allRepos {
+		if repoErrors[index] != nil {
+			return nil, repoErrors[index]
+		}
+		imported = append(imported, repoVariables[index]...)
+	}
+	return imported, nil
+}
@@ -44,6 +44,20 @@ func TestNewCmdCreate(t *testing.T) {
 		t.Error("source-token flag not found")
 	}
 
+	if cmd.Flag("concurrency") == nil {
+		t.Error("concurrency flag not found")
+	}
+
+	if cmd.Flag("dry-run") == nil {
+		t.Error("dry-run flag not found")
+	}
+
+	if flag := cmd.Flag("on-conflict"); flag == nil {
+		t.Error("on-conflict flag not found")
+	} else if flag.DefValue != "update" {
+		t.Errorf("Expected on-conflict to default to update, got %s", flag.DefValue)
+	}
+
 	// We're removing the ValidArgsFunction check since this command doesn't use it
 	// Unlike secrets/create, this command doesn't set ValidArgsFunction
 }
@@ -0,0 +1,209 @@
+package createvars
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/fakegithub"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+func TestRunCmdCreateResults(t *testing.T) {
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "variables.csv")
+	csvContent := "VariableLevel,VariableName,VariableValue,VariableAccess,RepositoryNames,RepositoryIDs,EnvironmentName\n" +
+		"Organization,ORG_VAR,value,all,,,\n" +
+		"Repository,REPO_VAR,value,RepoOnly,locked-repo,1,\n" +
+		"Environment,ENV_VAR,value,EnvironmentOnly,repo1,1,prod\n" +
+		"Environment,ARCHIVED_VAR,value,EnvironmentOnly,archived-repo,1,prod\n"
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0600); err != nil {
+		t.Fatalf("Failed to create test CSV file: %v", err)
+	}
+
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/repos/test-org/locked-repo/") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"Must have admin rights to Repository."}`))
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/repos/test-org/archived-repo/") {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"message":"Repository was archived so is read-only."}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	resultsFile := filepath.Join(tmpDir, "results.csv")
+	flags := &cmdFlags{fileName: csvFile, concurrency: 1, onConflict: utils.ConflictUpdate, resultsFile: resultsFile}
+	err := runCmdCreate("test-org", flags, g, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to create 2 of 4 variables") {
+		t.Fatalf("Expected an error counting 2 failed variables, got %v", err)
+	}
+
+	f, err := os.Open(resultsFile)
+	if err != nil {
+		t.Fatalf("Failed to open results file: %v", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read results file: %v", err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("Expected a header and 4 rows, got %d rows", len(rows))
+	}
+
+	expected := [][]string{
+		{"1", "Organization", "Actions", "ORG_VAR", "test-org", "succeeded", ""},
+		{"2", "Repository", "Actions", "REPO_VAR", "test-org/locked-repo", "failed", "403"},
+		{"3", "Environment", "Actions", "ENV_VAR", "test-org/repo1 (prod)", "succeeded", ""},
+		{"4", "Environment", "Actions", "ARCHIVED_VAR", "test-org/archived-repo (prod)", "failed", "422"},
+	}
+	for i, want := range expected {
+		got := rows[i+1]
+		if strings.Join(got[:7], ",") != strings.Join(want, ",") {
+			t.Errorf("Row %d: expected %v, got %v", i+1, want, got[:7])
+		}
+		if (want[5] == "failed") != (got[7] != "") {
+			t.Errorf("Row %d: unexpected error message %q", i+1, got[7])
+		}
+	}
+}
+
+func TestRunCmdCreateResolvesRepos(t *testing.T) {
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "variables.csv")
+	csvContent := "VariableLevel,VariableName,VariableValue,VariableAccess,RepositoryNames,RepositoryIDs,EnvironmentName\n" +
+		"Organization,SCOPED_VAR,value,selected,repo-a;repo-b,1;2,\n" +
+		"Organization,UNKNOWN_VAR,value,selected,repo-z,3,\n"
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0600); err != nil {
+		t.Fatalf("Failed to create test CSV file: %v", err)
+	}
+
+	var created []string
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/graphql" {
+			_, _ = w.Write([]byte(`{"data":{"r0":{"databaseId":101,"name":"repo-a"},"r1":{"databaseId":202,"name":"repo-b"},"r2":null},` +
+				`"errors":[{"type":"NOT_FOUND","path":["r2"],"message":"Could not resolve to a Repository"}]}`))
+			return
+		}
+		created = append(created, string(body))
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	flags := &cmdFlags{fileName: csvFile, concurrency: 1, onConflict: utils.ConflictUpdate}
+	err := runCmdCreate("test-org", flags, g, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to create 1 of 2 variables") {
+		t.Fatalf("Expected the unknown repository to fail its row, got %v", err)
+	}
+	if len(created) != 1 {
+		t.Fatalf("Expected only the resolved variable to be created, got %v", created)
+	}
+	if !strings.Contains(created[0], `"selected_repository_ids":[101,202]`) {
+		t.Errorf("Expected the target repository ids, got %s", created[0])
+	}
+}
+
+func TestRunCmdCreateOnConflictSkip(t *testing.T) {
+	server := fakegithub.New()
+	seed := &fakegithub.Seed{Organizations: []fakegithub.SeedOrganization{{
+		Login:     "test-org",
+		Variables: []data.ImportedVariable{{Level: "Organization", Name: "REGION", Value: "us", Visibility: "all"}},
+	}}}
+	if err := server.Seed(seed); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "variables.csv")
+	csvContent := "VariableLevel,VariableName,VariableValue,VariableAccess,RepositoryNames,RepositoryIDs,EnvironmentName\n" +
+		"Organization,REGION,eu,all,,,\n" +
+		"Organization,LOG_LEVEL,info,all,,,\n"
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0600); err != nil {
+		t.Fatalf("Failed to create test CSV file: %v", err)
+	}
+
+	resultsFile := filepath.Join(tmpDir, "results.csv")
+	flags := &cmdFlags{fileName: csvFile, concurrency: 1, onConflict: utils.ConflictSkip, resultsFile: resultsFile}
+	if err := runCmdCreate("test-org", flags, newTestGetter(t, server.ServeHTTP), nil); err != nil {
+		t.Fatalf("runCmdCreate() error = %v", err)
+	}
+	if region, _ := server.Variable("test-org", "", "", "REGION"); region.Value != "us" {
+		t.Errorf("Expected the existing variable to be kept, got %q", region.Value)
+	}
+
+	f, err := os.Open(resultsFile)
+	if err != nil {
+		t.Fatalf("Failed to open results file: %v", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read results file: %v", err)
+	}
+	if len(rows) != 3 || rows[1][5] != "skipped" || !strings.Contains(rows[1][7], "already exists") || rows[2][5] != "succeeded" {
+		t.Errorf("Expected REGION to be skipped and LOG_LEVEL created, got %v", rows)
+	}
+}
+
+func TestRunCmdCreateInvalidFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	csvFile := filepath.Join(tmpDir, "variables.csv")
+	csvContent := "VariableLevel,VariableName,VariableValue,VariableAccess,RepositoryNames\n" +
+		"Organization,ORG_VAR,value,all\n" +
+		"Enviroment,ENV_VAR,value,EnvironmentOnly,repo1\n"
+	if err := os.WriteFile(csvFile, []byte(csvContent), 0600); err != nil {
+		t.Fatalf("Failed to create test CSV file: %v", err)
+	}
+
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request %s %s for an invalid file", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	flags := &cmdFlags{fileName: csvFile, concurrency: 1, onConflict: utils.ConflictUpdate}
+	err := runCmdCreate("test-org", flags, g, nil)
+	want := `row 2, column VariableLevel (A): unknown variable level "Enviroment"`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected error to contain %q, got %v", want, err)
+	}
+}
@@ -0,0 +1,132 @@
+package createvars
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/fakegithub"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+func TestImportSourceRepoVariables(t *testing.T) {
+	pages := 0
+	source := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/graphql":
+			pages++
+			if pages == 1 {
+				_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"totalCount":3,"nodes":[{"databaseId":1,"name":"api"},{"databaseId":2,"name":"web"}],"pageInfo":{"endCursor":"c1","hasNextPage":true}}}}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"totalCount":3,"nodes":[{"databaseId":3,"name":"docs"}],"pageInfo":{"endCursor":"c2","hasNextPage":false}}}}}`))
+		case "/repos/source-org/api/actions/variables":
+			_, _ = w.Write([]byte(`{"total_count":2,"variables":[{"name":"LEVEL","value":"debug"},{"name":"REGION","value":"eu"}]}`))
+		case "/repos/source-org/web/actions/variables":
+			_, _ = w.Write([]byte(`{"total_count":0,"variables":[]}`))
+		case "/repos/source-org/docs/actions/variables":
+			_, _ = w.Write([]byte(`{"total_count":1,"variables":[{"name":"THEME","value":"dark"}]}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	variables, err := importSourceRepoVariables("source-org", 2, source)
+	if err != nil {
+		t.Fatalf("importSourceRepoVariables() error = %v", err)
+	}
+	if pages != 2 {
+		t.Errorf("Expected both pages of repositories to be read, got %d", pages)
+	}
+	expected := []data.ImportedVariable{
+		{Level: "Repository", Name: "LEVEL", Value: "debug", SelectedRepos: []string{"api"}},
+		{Level: "Repository", Name: "REGION", Value: "eu", SelectedRepos: []string{"api"}},
+		{Level: "Repository", Name: "THEME", Value: "dark", SelectedRepos: []string{"docs"}},
+	}
+	if len(variables) != len(expected) {
+		t.Fatalf("Expected %d variables, got %+v", len(expected), variables)
+	}
+	for i, want := range expected {
+		got := variables[i]
+		if got.Level != want.Level || got.Name != want.Name || got.Value != want.Value || got.SelectedRepos[0] != want.SelectedRepos[0] {
+			t.Errorf("Variable %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestImportSourceRepoVariablesError(t *testing.T) {
+	source := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/graphql" {
+			_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"totalCount":1,"nodes":[{"databaseId":1,"name":"api"}],"pageInfo":{"hasNextPage":false}}}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+	})
+
+	_, err := importSourceRepoVariables("source-org", 1, source)
+	if err == nil || !strings.Contains(err.Error(), "failed to read variables of source-org/api") {
+		t.Errorf("Expected a read error for the repository, got %v", err)
+	}
+}
+
+func TestImportSourceVariablesNamesOnly(t *testing.T) {
+	source := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/orgs/source-org/actions/variables/REGION/repositories" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"total_count":1,"repositories":[{"id":41,"name":"api"}]}`))
+	})
+
+	variables, err := importSourceVariables("source-org", []data.Variable{
+		{Name: "REGION", Value: "eu", Visibility: "selected"},
+		{Name: "LEVEL", Value: "debug", Visibility: "all"},
+	}, source)
+	if err != nil {
+		t.Fatalf("importSourceVariables() error = %v", err)
+	}
+	if len(variables) != 2 || strings.Join(variables[0].SelectedRepos, ";") != "api" {
+		t.Fatalf("Expected the selected repository by name, got %+v", variables)
+	}
+	if len(variables[0].SelectedReposIDs) != 0 {
+		t.Errorf("Expected no source repository IDs, got %v", variables[0].SelectedReposIDs)
+	}
+}
+
+func TestRunCmdCreateFromSourceOrganization(t *testing.T) {
+	source := fakegithub.New()
+	err := source.Seed(&fakegithub.Seed{Organizations: []fakegithub.SeedOrganization{{
+		Login:        "source-org",
+		Repositories: []fakegithub.SeedRepository{{Name: "api"}, {Name: "web"}, {Name: "docs"}},
+		Variables: []data.ImportedVariable{
+			{Level: "Organization", Name: "REGION", Value: "eu", Visibility: "selected", SelectedRepos: []string{"api", "web"}},
+			{Level: "Repository", Name: "LOG_LEVEL", Value: "debug", SelectedRepos: []string{"api"}},
+			{Level: "Repository", Name: "THEME", Value: "dark", SelectedRepos: []string{"docs"}},
+		},
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to seed source: %v", err)
+	}
+	target := fakegithub.New()
+	target.AddRepository("test-org", "api", "private")
+	target.AddRepository("test-org", "web", "private")
+
+	flags := &cmdFlags{sourceOrg: "source-org", concurrency: 2, onConflict: utils.ConflictUpdate}
+	err = runCmdCreate("test-org", flags, newTestGetter(t, target.ServeHTTP), newTestGetter(t, source.ServeHTTP))
+	if err != nil {
+		t.Fatalf("runCmdCreate() error = %v", err)
+	}
+
+	region, ok := target.Variable("test-org", "", "", "REGION")
+	if !ok || region.Value != "eu" || len(region.SelectedRepositoryIDs) != 2 {
+		t.Errorf("Expected REGION to be scoped to api and web, got %+v", region)
+	}
+	if level, ok := target.Variable("test-org", "api", "", "LOG_LEVEL"); !ok || level.Value != "debug" {
+		t.Errorf("Expected LOG_LEVEL to be copied to api, got %+v", level)
+	}
+}
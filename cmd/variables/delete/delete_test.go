@@ -0,0 +1,113 @@
+package deletevars
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+func TestNewCmdDelete(t *testing.T) {
+	cmd := NewCmdDelete()
+
+	if cmd == nil {
+		t.Fatal("NewCmdDelete() returned nil")
+	}
+
+	if cmd.Use != "delete <organization> [flags]" {
+		t.Errorf("Expected Use to be 'delete <organization> [flags]', got %s", cmd.Use)
+	}
+
+	for _, name := range []string{"from-file", "name", "level", "repo", "environment", "yes", "token", "hostname", "debug"} {
+		if cmd.Flag(name) == nil {
+			t.Errorf("%s flag not found", name)
+		}
+	}
+
+	if flag := cmd.Flag("yes"); flag != nil && flag.DefValue != "false" {
+		t.Errorf("Expected yes to default to false, got %s", flag.DefValue)
+	}
+
+	if err := cmd.PreRunE(cmd, []string{"test-org"}); err == nil || !strings.Contains(err.Error(), "variable name must be specified") {
+		t.Errorf("Expected a file or name to be required, got %v", err)
+	}
+}
+
+func TestRunCmdDelete(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "variables.csv")
+	content := "VariableLevel,VariableName,VariableValue,VariableAccess,RepositoryNames,RepositoryIDs,EnvironmentName\n" +
+		"Repository,REPO_VAR,,RepoOnly,repo1,1,\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// Only the columns naming an organization variable are needed to delete it
+	orgFile := filepath.Join(t.TempDir(), "organization.csv")
+	if err := os.WriteFile(orgFile, []byte("VariableLevel,VariableName,VariableValue,VariableAccess,RepositoryNames,RepositoryIDs,EnvironmentName\nOrganization,ORG_VAR,,,,,\n"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		flags utils.DeleteFlags
+		path  string
+	}{
+		{name: "from file", flags: utils.DeleteFlags{FileName: file}, path: "/repos/test-org/repo1/actions/variables/REPO_VAR"},
+		{name: "from flags", flags: utils.DeleteFlags{Name: "VAR", Level: "ENVIRONMENT", Repo: "repo1", Environment: "prod"}, path: "/repos/test-org/repo1/environments/prod/variables/VAR"},
+		{name: "organization from file without access", flags: utils.DeleteFlags{FileName: orgFile}, path: "/orgs/test-org/actions/variables/ORG_VAR"},
+		{name: "organization", flags: utils.DeleteFlags{Name: "VAR", Level: "organization"}, path: "/orgs/test-org/actions/variables/VAR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted []string
+			g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+				deleted = append(deleted, r.Method+" "+r.URL.Path)
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			flags := cmdFlags{DeleteFlags: tt.flags}
+			flags.Yes = true
+			var out bytes.Buffer
+			if err := runCmdDelete("test-org", &flags, g, strings.NewReader(""), &out); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(deleted, ",") != "DELETE "+tt.path {
+				t.Errorf("Expected DELETE %s, got %v", tt.path, deleted)
+			}
+		})
+	}
+}
@@ -1,12 +1,12 @@
 package exportvars
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -21,10 +21,12 @@ import (
 )
 
 type cmdFlags struct {
-	hostname   string
-	token      string
-	reportFile string
-	debug      bool
+	hostname    string
+	token       string
+	reportFile  string
+	format      string
+	concurrency int
+	debug       bool
 }
 
 func NewCmdExport() *cobra.Command {
@@ -85,6 +87,13 @@ func NewCmdExport() *cobra.Command {
 			owner := args[0]
 			repos := args[1:]
 
+			if err := utils.ValidateFormat(cmdFlags.format); err != nil {
+				return err
+			}
+			if !exportCmd.Flags().Changed("output-file") {
+				cmdFlags.reportFile = utils.FormatFileName(cmdFlags.reportFile, cmdFlags.format)
+			}
+
 			if _, err := os.Stat(cmdFlags.reportFile); errors.Is(err, os.ErrExist) {
 				return err
 			}
@@ -95,7 +104,7 @@ func NewCmdExport() *cobra.Command {
 				return err
 			}
 
-			return runCmdExport(owner, repos, &cmdFlags, utils.NewAPIGetter(gqlClient, restClient), reportWriter)
+			return runCmdExport(owner, repos, &cmdFlags, utils.NewGitHubGetter(gqlClient, restClient), reportWriter)
 		},
 	}
 
@@ -104,64 +113,42 @@ func NewCmdExport() *cobra.Command {
 	// Configure flags for command
 	exportCmd.PersistentFlags().StringVarP(&cmdFlags.token, "token", "t", "", `GitHub Personal Access Token (default "gh auth token")`)
 	exportCmd.PersistentFlags().StringVarP(&cmdFlags.hostname, "hostname", "", "github.com", "GitHub Enterprise Server hostname")
-	exportCmd.Flags().StringVarP(&cmdFlags.reportFile, "output-file", "o", reportFileDefault, "Name of file to write CSV report")
+	exportCmd.Flags().StringVarP(&cmdFlags.reportFile, "output-file", "o", reportFileDefault, "Name of file to write report")
+	exportCmd.Flags().StringVar(&cmdFlags.format, "format", utils.FormatCSV, "Format of the report: {csv|json|yaml}")
+	exportCmd.Flags().IntVarP(&cmdFlags.concurrency, "concurrency", "c", 1, "Number of repositories to gather variables for concurrently")
 	exportCmd.PersistentFlags().BoolVarP(&cmdFlags.debug, "debug", "d", false, "To debug logging")
 	//cmd.MarkPersistentFlagRequired("app")
 
 	return &exportCmd
 }
 
-func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.APIGetter, reportWriter io.Writer) error {
-	var reposCursor *string
-	var allRepos []data.RepoInfo
+func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, backend utils.Getter, reportWriter io.Writer) error {
+	g := utils.NewAPIGetter(backend)
 
-	csvWriter := csv.NewWriter(reportWriter)
+	format := cmdFlags.format
+	if format == "" {
+		format = utils.FormatCSV
+	}
+	reportRecords, err := utils.NewVariablesWriter(reportWriter, format)
+	if err != nil {
+		return err
+	}
 
-	err := csvWriter.Write([]string{
+	err = reportRecords.Write([]string{
 		"VariableLevel",
 		"VariableName",
 		"VariableValue",
 		"VariableAccess",
 		"RepositoryNames",
 		"RepositoryIDs",
+		"EnvironmentName",
 	})
 	if err != nil {
 		zap.S().Error("Error raised in writing to csv", zap.Error(err))
 	}
-	if len(repos) > 0 {
-		zap.S().Infof("Processing repos: %s", repos)
-
-		for _, repo := range repos {
-
-			zap.S().Debugf("Processing %s/%s", owner, repo)
-
-			repoQuery, err := g.GetRepo(owner, repo)
-			if err != nil {
-				zap.S().Error("Error raised in getting repos", zap.Error(err))
-				return err
-			}
-			allRepos = append(allRepos, repoQuery.Repository)
-		}
-
-	} else {
-		// Prepare writer for outputting report
-		for {
-			zap.S().Debugf("Processing list of repositories for %s", owner)
-			reposQuery, err := g.GetReposList(owner, reposCursor)
-
-			if err != nil {
-				zap.S().Error("Error raised in processing list of repos", zap.Error(err))
-				return err
-			}
-
-			allRepos = append(allRepos, reposQuery.Organization.Repositories.Nodes...)
-
-			reposCursor = &reposQuery.Organization.Repositories.PageInfo.EndCursor
-
-			if !reposQuery.Organization.Repositories.PageInfo.HasNextPage {
-				break
-			}
-		}
+	allRepos, err := g.ListRepos(owner, repos)
+	if err != nil {
+		return err
 	}
 	// Writing to CSV Org level Actions Variables
 	if len(repos) == 0 {
@@ -197,13 +184,14 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 					stringRepoId := strconv.Itoa(scopeVariable.ID)
 					concatRepoIds = append(concatRepoIds, stringRepoId)
 				}
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					orgVariable.Name,
 					orgVariable.Value,
 					orgVariable.Visibility,
 					strings.Join(concatRepos, ";"),
 					strings.Join(concatRepoIds, ";"),
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
@@ -220,13 +208,14 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 						concatRepoIds = append(concatRepoIds, stringRepoId)
 					}
 				}
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					orgVariable.Name,
 					orgVariable.Value,
 					orgVariable.Visibility,
 					strings.Join(concatRepos, ";"),
 					strings.Join(concatRepoIds, ";"),
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
@@ -234,13 +223,14 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 				}
 			default:
 				zap.S().Debugf("Gathering public Actions Secret %s for %s", orgVariable.Name, owner)
-				err = csvWriter.Write([]string{
+				err = reportRecords.Write([]string{
 					"Organization",
 					orgVariable.Name,
 					orgVariable.Value,
 					orgVariable.Visibility,
 					"",
 					"",
+					"",
 				})
 				if err != nil {
 					zap.S().Error("Error raised in writing output", zap.Error(err))
@@ -249,38 +239,119 @@ func runCmdExport(owner string, repos []string, cmdFlags *cmdFlags, g *utils.API
 			}
 		}
 	}
-	// Writing to CSV repository level Variables
-	for _, singleRepo := range allRepos {
-		// Writing to CSV repository level Actions Variables
-		zap.S().Debugf("Gathering repo level variables for %s", singleRepo.Name)
-		repoActionVariablesList, err := g.GetRepoActionVariables(owner, singleRepo.Name)
+	// Writing to CSV repository level Variables, gathered concurrently and
+	// written in repository order so the report is deterministic
+	sort.SliceStable(allRepos, func(i, j int) bool {
+		return allRepos[i].Name < allRepos[j].Name
+	})
+	repoRows := make([][][]string, len(allRepos))
+	repoErrors := make([]error, len(allRepos))
+	utils.RunConcurrently(cmdFlags.concurrency, len(allRepos), func(index int) {
+		repoRows[index], repoErrors[index] = exportRepoVariables(owner, allRepos[index], g)
+	})
+	for index := range allRepos {
+		if repoErrors[index] != nil {
+			return repoErrors[index]
+		}
+		sortVariableRows(repoRows[index])
+		for _, row := range repoRows[index] {
+			err = reportRecords.Write(row)
+			if err != nil {
+				zap.S().Error("Error raised in writing output", zap.Error(err))
+				return err
+			}
+		}
+	}
+
+	reportRecords.Flush()
+	if err := reportRecords.Error(); err != nil {
+		zap.S().Error("Error raised in writing output", zap.Error(err))
+		return err
+	}
+	fmt.Printf("Successfully exported variables for %s to %s\n", owner, cmdFlags.reportFile)
+	return nil
+}
+
+func exportRepoVariables(owner string, singleRepo data.RepoInfo, g *utils.APIGetter) ([][]string, error) {
+	var rows [][]string
+	// Gathering repository level Actions Variables
+	zap.S().Debugf("Gathering repo level variables for %s", singleRepo.Name)
+	repoActionVariablesList, err := g.GetRepoActionVariables(owner, singleRepo.Name)
+	if err != nil {
+		zap.S().Error("Error raised in getting repo variables", zap.Error(err))
+		return nil, err
+	}
+	var repoActionResponseObject data.VariableResponse
+	err = json.Unmarshal(repoActionVariablesList, &repoActionResponseObject)
+	if err != nil {
+		zap.S().Error("Error raised with variable response", zap.Error(err))
+		return nil, err
+	}
+	zap.S().Debugf("Writing repo level variables for %s", singleRepo.Name)
+	for _, repoActionsVars := range repoActionResponseObject.Variables {
+		rows = append(rows, []string{
+			"Repository",
+			repoActionsVars.Name,
+			repoActionsVars.Value,
+			"RepoOnly",
+			singleRepo.Name,
+			strconv.Itoa(singleRepo.DatabaseId),
+			"",
+		})
+	}
+
+	// Gathering environment level Actions Variables
+	zap.S().Debugf("Gathering environments for %s", singleRepo.Name)
+	repoEnvironmentsList, err := g.GetRepoEnvironments(owner, singleRepo.Name)
+	if err != nil {
+		zap.S().Error("Error raised in getting repo environments", zap.Error(err))
+		return nil, err
+	}
+	var repoEnvironmentsResponseObject data.EnvironmentsResponse
+	err = json.Unmarshal(repoEnvironmentsList, &repoEnvironmentsResponseObject)
+	if err != nil {
+		zap.S().Error("Error raised with environment response", zap.Error(err))
+		return nil, err
+	}
+	for _, environment := range repoEnvironmentsResponseObject.Environments {
+		zap.S().Debugf("Gathering environment level variables for %s in %s", environment.Name, singleRepo.Name)
+		envVariablesList, err := g.GetEnvironmentActionVariables(owner, singleRepo.Name, environment.Name)
 		if err != nil {
-			zap.S().Error("Error raised in getting repo variables", zap.Error(err))
+			zap.S().Error("Error raised in getting environment variables", zap.Error(err))
+			return nil, err
 		}
-		var repoActionResponseObject data.VariableResponse
-		err = json.Unmarshal(repoActionVariablesList, &repoActionResponseObject)
+		var envResponseObject data.VariableResponse
+		err = json.Unmarshal(envVariablesList, &envResponseObject)
 		if err != nil {
 			zap.S().Error("Error raised with variable response", zap.Error(err))
-			return err
+			return nil, err
 		}
-		zap.S().Debugf("Writing repo level variables for %s", singleRepo.Name)
-		for _, repoActionsVars := range repoActionResponseObject.Variables {
-			err = csvWriter.Write([]string{
-				"Repository",
-				repoActionsVars.Name,
-				repoActionsVars.Value,
-				"RepoOnly",
+		for _, envVariable := range envResponseObject.Variables {
+			rows = append(rows, []string{
+				"Environment",
+				envVariable.Name,
+				envVariable.Value,
+				"EnvironmentOnly",
 				singleRepo.Name,
 				strconv.Itoa(singleRepo.DatabaseId),
+				environment.Name,
 			})
-			if err != nil {
-				zap.S().Error("Error raised in writing output", zap.Error(err))
-				return err
-			}
 		}
 	}
+	return rows, nil
+}
 
-	csvWriter.Flush()
-	fmt.Printf("Successfully exported variables for %s to %s\n", owner, cmdFlags.reportFile)
-	return nil
+// Repository rows are ordered by level, environment and name, matching
+// the order they are written in for a single repository
+func sortVariableRows(rows [][]string) {
+	levelOrder := map[string]int{"Repository": 0, "Environment": 1}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if levelOrder[rows[i][0]] != levelOrder[rows[j][0]] {
+			return levelOrder[rows[i][0]] < levelOrder[rows[j][0]]
+		}
+		if rows[i][6] != rows[j][6] {
+			return rows[i][6] < rows[j][6]
+		}
+		return rows[i][1] < rows[j][1]
+	})
 }
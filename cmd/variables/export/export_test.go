@@ -2,9 +2,7 @@ package exportvars
 
 import (
 	"bytes"
-	"encoding/csv"
 	"encoding/json"
-	"io"
 	"strings"
 	"testing"
 	"time"
@@ -30,6 +28,10 @@ func TestNewCmdExport(t *testing.T) {
 		t.Error("output-file flag not found")
 	}
 
+	if cmd.Flag("concurrency") == nil {
+		t.Error("concurrency flag not found")
+	}
+
 	// The repos flag doesn't exist in the command, it's passed as positional arguments
 	// So we remove this check
 
@@ -39,140 +41,6 @@ func TestNewCmdExport(t *testing.T) {
 	}
 }
 
-// Define an adapter that implements the required methods for testing
-type testAPIGetter struct {
-	mock *utils.MockAPIGetter
-}
-
-// Implement the necessary methods from APIGetter for variables functionality
-func (t *testAPIGetter) GetReposList(owner string, endCursor *string) (*data.ReposQuery, error) {
-	return t.mock.GetReposList(owner, endCursor)
-}
-
-func (t *testAPIGetter) GetRepo(owner string, name string) (*data.RepoSingleQuery, error) {
-	return t.mock.GetRepo(owner, name)
-}
-
-func (t *testAPIGetter) GetOrgActionVariables(owner string) ([]byte, error) {
-	return t.mock.GetOrgActionVariables(owner)
-}
-
-func (t *testAPIGetter) GetRepoActionVariables(owner string, repo string) ([]byte, error) {
-	return t.mock.GetRepoActionVariables(owner, repo)
-}
-
-func (t *testAPIGetter) GetScopedOrgActionVariables(owner string, variable string) ([]byte, error) {
-	return t.mock.GetScopedOrgActionVariables(owner, variable)
-}
-
-// Modified runCmdExport for testing
-func runCmdExportTest(owner string, repos []string, cmdFlags *cmdFlags, g interface{}, reportWriter io.Writer) error {
-	// Use type assertion to get the required methods
-	getter, ok := g.(interface {
-		GetReposList(owner string, endCursor *string) (*data.ReposQuery, error)
-		GetRepo(owner string, name string) (*data.RepoSingleQuery, error)
-		GetOrgActionVariables(owner string) ([]byte, error)
-		GetRepoActionVariables(owner string, repo string) ([]byte, error)
-		GetScopedOrgActionVariables(owner string, variable string) ([]byte, error)
-	})
-
-	if !ok {
-		return nil // For testing, we're not concerned with this error
-	}
-
-	var reposCursor *string
-	var allRepos []data.RepoInfo
-
-	csvWriter := csv.NewWriter(reportWriter)
-
-	err := csvWriter.Write([]string{
-		"VariableLevel",
-		"VariableName",
-		"VariableValue",
-		"VariableAccess",
-		"RepositoryNames",
-		"RepositoryIDs",
-	})
-	if err != nil {
-		return err
-	}
-
-	if len(repos) > 0 {
-		for _, repo := range repos {
-			repoQuery, err := getter.GetRepo(owner, repo)
-			if err != nil {
-				return err
-			}
-			allRepos = append(allRepos, repoQuery.Repository)
-		}
-	} else {
-		reposQuery, err := getter.GetReposList(owner, reposCursor)
-		if err != nil {
-			return err
-		}
-		allRepos = append(allRepos, reposQuery.Organization.Repositories.Nodes...)
-	}
-
-	// Organization variables
-	if len(repos) == 0 {
-		orgVariables, err := getter.GetOrgActionVariables(owner)
-		if err != nil {
-			return err
-		}
-
-		var oActionResponseObject data.VariableResponse
-		err = json.Unmarshal(orgVariables, &oActionResponseObject)
-		if err != nil {
-			return err
-		}
-
-		for _, orgVariable := range oActionResponseObject.Variables {
-			err = csvWriter.Write([]string{
-				"Organization",
-				orgVariable.Name,
-				orgVariable.Value,
-				orgVariable.Visibility,
-				"",
-				"",
-			})
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// Repository variables
-	for _, singleRepo := range allRepos {
-		repoActionVariablesList, err := getter.GetRepoActionVariables(owner, singleRepo.Name)
-		if err != nil {
-			return err
-		}
-
-		var repoActionResponseObject data.VariableResponse
-		err = json.Unmarshal(repoActionVariablesList, &repoActionResponseObject)
-		if err != nil {
-			return err
-		}
-
-		for _, repoActionsVars := range repoActionResponseObject.Variables {
-			err = csvWriter.Write([]string{
-				"Repository",
-				repoActionsVars.Name,
-				repoActionsVars.Value,
-				"RepoOnly",
-				singleRepo.Name,
-				"12345", // Use a hardcoded value for testing
-			})
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	csvWriter.Flush()
-	return nil
-}
-
 func TestRunCmdExport(t *testing.T) {
 	// Setup
 	owner := "testorg"
@@ -239,16 +107,10 @@ func TestRunCmdExport(t *testing.T) {
 	mockGetter.OrgActionVariablesData = varResponseBytes
 	mockGetter.RepoActionVariablesData = varResponseBytes
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err != nil {
@@ -304,16 +166,10 @@ func TestRunCmdExportSpecificRepos(t *testing.T) {
 	varResponseBytes, _ := json.Marshal(varResponse)
 	mockGetter.RepoActionVariablesData = varResponseBytes
 
-	// Create adapter that wraps the mock
-	testGetter := &testAPIGetter{
-		mock: mockGetter,
-	}
-
 	// Create buffer for output
 	var buf bytes.Buffer
 
-	// Execute using our test wrapper function
-	err := runCmdExportTest(owner, repos, flags, testGetter, &buf)
+	err := runCmdExport(owner, repos, flags, mockGetter, &buf)
 
 	// Verify
 	if err != nil {
@@ -326,3 +182,25 @@ func TestRunCmdExportSpecificRepos(t *testing.T) {
 		t.Error("Output does not contain expected repository variable data")
 	}
 }
+
+func TestSortVariableRows(t *testing.T) {
+	rows := [][]string{
+		{"Environment", "REGION", "us", "EnvironmentOnly", "repo", "1", "prod"},
+		{"Repository", "ZETA", "z", "RepoOnly", "repo", "1", ""},
+		{"Environment", "REGION", "eu", "EnvironmentOnly", "repo", "1", "dev"},
+		{"Repository", "ALPHA", "a", "RepoOnly", "repo", "1", ""},
+	}
+
+	sortVariableRows(rows)
+
+	expected := []string{"ALPHA", "ZETA", "eu", "us"}
+	for i, row := range rows {
+		got := row[1]
+		if row[0] == "Environment" {
+			got = row[2]
+		}
+		if got != expected[i] {
+			t.Errorf("Row %d: expected %s, got %s", i, expected[i], got)
+		}
+	}
+}
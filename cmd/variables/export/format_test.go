@@ -0,0 +1,86 @@
+package exportvars
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestGetter returns a Getter whose requests are served by handler.
+func newTestGetter(t *testing.T, handler http.HandlerFunc) utils.Getter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewGitHubGetter(gqlClient, restClient)
+}
+
+func TestRunCmdExportFormats(t *testing.T) {
+	g := newTestGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/graphql":
+			_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"nodes":[{"databaseId":1,"name":"api","visibility":"private"}],"pageInfo":{"hasNextPage":false}}}}}`))
+		case "/orgs/test-org/actions/variables":
+			_, _ = w.Write([]byte(`{"total_count":1,"variables":[{"name":"CERT","value":"line one\nline two\n","visibility":"all"}]}`))
+		case "/repos/test-org/api/actions/variables":
+			_, _ = w.Write([]byte(`{"total_count":1,"variables":[{"name":"THEME","value":"dark"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"total_count":0,"environments":[]}`))
+		}
+	})
+
+	for _, format := range []string{utils.FormatJSON, utils.FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			reportFile := filepath.Join(t.TempDir(), "report."+format)
+			f, err := os.Create(reportFile)
+			if err != nil {
+				t.Fatalf("Failed to create report: %v", err)
+			}
+			flags := &cmdFlags{reportFile: reportFile, format: format, concurrency: 1}
+			err = runCmdExport("test-org", nil, flags, g, f)
+			f.Close()
+			if err != nil {
+				t.Fatalf("runCmdExport() error = %v", err)
+			}
+
+			variables, err := utils.ReadVariablesFile(reportFile)
+			if err != nil {
+				t.Fatalf("Expected the report to be read back, got %v", err)
+			}
+			if len(variables) != 2 {
+				t.Fatalf("Expected 2 variables, got %+v", variables)
+			}
+			if variables[0].Name != "CERT" || variables[0].Value != "line one\nline two\n" || variables[0].Visibility != "all" {
+				t.Errorf("Unexpected organization variable %+v", variables[0])
+			}
+			if variables[1].Level != "Repository" || variables[1].SelectedRepos[0] != "api" || variables[1].SelectedReposIDs[0] != "1" {
+				t.Errorf("Unexpected repository variable %+v", variables[1])
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package variables
 
 import (
 	createCmd "github.com/katiem0/gh-seva/cmd/variables/create"
+	deleteCmd "github.com/katiem0/gh-seva/cmd/variables/delete"
 	exportCmd "github.com/katiem0/gh-seva/cmd/variables/export"
 	"github.com/spf13/cobra"
 )
@@ -10,13 +11,14 @@ func NewCmdVariables() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "variables <command>",
-		Short: "Export and Create variables for an organization and/or repositories.",
-		Long:  "Export and Create Actions variables for an organization and/or repositories.",
+		Short: "Export, Create and Delete variables for an organization and/or repositories.",
+		Long:  "Export, Create and Delete Actions variables for an organization and/or repositories.",
 	}
 	cmd.Flags().Bool("help", false, "Show help for command")
 
 	cmd.AddCommand(exportCmd.NewCmdExport())
 	cmd.AddCommand(createCmd.NewCmdCreate())
+	cmd.AddCommand(deleteCmd.NewCmdDelete())
 
 	return cmd
 }
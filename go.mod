@@ -2,7 +2,10 @@ module github.com/katiem0/gh-seva
 
 go 1.24.0
 
-require github.com/cli/go-gh/v2 v2.12.1
+require (
+	filippo.io/age v1.2.1
+	github.com/cli/go-gh/v2 v2.12.1
+)
 
 require (
 	github.com/benbjohnson/clock v1.1.0 // indirect
@@ -31,5 +34,5 @@ require (
 	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/term v0.37.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
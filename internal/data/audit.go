@@ -0,0 +1,24 @@
+package data
+
+// UnusedItem is a secret or variable no workflow of the repositories that
+// can use it refers to.
+type UnusedItem struct {
+	Kind        string   `json:"kind"`
+	Level       string   `json:"level"`
+	Name        string   `json:"name"`
+	Visibility  string   `json:"visibility,omitempty"`
+	Repository  string   `json:"repository,omitempty"`
+	Environment string   `json:"environment,omitempty"`
+	Details     []string `json:"details,omitempty"`
+}
+
+// UnusedReport is the result of auditing an organization for unused
+// secrets and variables. Dynamic lists the repositories whose workflows
+// refer to secrets or variables by expression, such as toJSON(secrets),
+// so everything they can use is treated as used.
+type UnusedReport struct {
+	Unused        []UnusedItem `json:"unused"`
+	Repositories  int          `json:"repositories"`
+	WorkflowFiles int          `json:"workflow_files"`
+	Dynamic       []string     `json:"dynamic_repositories"`
+}
@@ -0,0 +1,21 @@
+package data
+
+// How a secret or variable of the target organization compares with the
+// source organization or file
+const (
+	DiffMissing   = "missing"
+	DiffExtra     = "extra"
+	DiffChanged   = "changed"
+	DiffIdentical = "identical"
+)
+
+type DiffItem struct {
+	Status      string   `json:"status"`
+	Kind        string   `json:"kind"`
+	Level       string   `json:"level"`
+	Type        string   `json:"type,omitempty"`
+	Name        string   `json:"name"`
+	Repository  string   `json:"repository,omitempty"`
+	Environment string   `json:"environment,omitempty"`
+	Details     []string `json:"details,omitempty"`
+}
@@ -0,0 +1,88 @@
+package data
+
+import "time"
+
+type Environment struct {
+	ID                     int                     `json:"id"`
+	Name                   string                  `json:"name"`
+	CanAdminsBypass        bool                    `json:"can_admins_bypass"`
+	ProtectionRules        []ProtectionRule        `json:"protection_rules"`
+	DeploymentBranchPolicy *DeploymentBranchPolicy `json:"deployment_branch_policy"`
+	CreatedAt              time.Time               `json:"created_at"`
+	UpdatedAt              time.Time               `json:"updated_at"`
+}
+
+type EnvironmentsResponse struct {
+	TotalCount   int           `json:"total_count"`
+	Environments []Environment `json:"environments"`
+}
+
+// A protection rule is one of wait_timer, required_reviewers or
+// branch_policy, only the fields relevant to the type are populated
+type ProtectionRule struct {
+	ID                int                   `json:"id"`
+	Type              string                `json:"type"`
+	WaitTimer         int                   `json:"wait_timer"`
+	PreventSelfReview bool                  `json:"prevent_self_review"`
+	Reviewers         []EnvironmentReviewer `json:"reviewers"`
+}
+
+type EnvironmentReviewer struct {
+	Type     string       `json:"type"`
+	Reviewer ReviewerInfo `json:"reviewer"`
+}
+
+// Users are identified by login and teams by slug
+type ReviewerInfo struct {
+	ID    int    `json:"id"`
+	Login string `json:"login"`
+	Slug  string `json:"slug"`
+}
+
+type DeploymentBranchPolicy struct {
+	ProtectedBranches    bool `json:"protected_branches"`
+	CustomBranchPolicies bool `json:"custom_branch_policies"`
+}
+
+type BranchPolicy struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type BranchPoliciesResponse struct {
+	TotalCount     int            `json:"total_count"`
+	BranchPolicies []BranchPolicy `json:"branch_policies"`
+}
+
+type CreateEnvironment struct {
+	WaitTimer              int                         `json:"wait_timer"`
+	PreventSelfReview      bool                        `json:"prevent_self_review"`
+	Reviewers              []CreateEnvironmentReviewer `json:"reviewers"`
+	DeploymentBranchPolicy *DeploymentBranchPolicy     `json:"deployment_branch_policy"`
+	CanAdminsBypass        bool                        `json:"can_admins_bypass"`
+}
+
+type CreateEnvironmentReviewer struct {
+	Type string `json:"type"`
+	ID   int    `json:"id"`
+}
+
+type CreateBranchPolicy struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ImportedEnvironment struct {
+	RepositoryName         string   `json:"repository"`
+	Name                   string   `json:"name"`
+	WaitTimer              int      `json:"wait_timer"`
+	PreventSelfReview      bool     `json:"prevent_self_review"`
+	Reviewers              []string `json:"reviewers"`
+	DeploymentBranchPolicy string   `json:"deployment_branch_policy"`
+	BranchPolicies         []string `json:"branch_policies"`
+	CanAdminsBypass        bool     `json:"can_admins_bypass"`
+	// Row is the row of the file the environment was read from, numbered
+	// from the first row after the header.
+	Row int `json:"-"`
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEnvironmentsResponse(t *testing.T) {
+	payload := []byte(`{"total_count":2,"environments":[{"id":1,"name":"production"},{"id":2,"name":"staging"}]}`)
+
+	var response EnvironmentsResponse
+	if err := json.Unmarshal(payload, &response); err != nil {
+		t.Fatalf("Failed to unmarshal EnvironmentsResponse: %v", err)
+	}
+
+	if response.TotalCount != 2 {
+		t.Errorf("Expected TotalCount to be 2, got %d", response.TotalCount)
+	}
+	if len(response.Environments) != 2 {
+		t.Fatalf("Expected 2 environments, got %d", len(response.Environments))
+	}
+	if response.Environments[0].Name != "production" {
+		t.Errorf("Expected first environment to be 'production', got %s", response.Environments[0].Name)
+	}
+}
+
+func TestEnvironmentProtectionRules(t *testing.T) {
+	payload := []byte(`{
+		"id": 1,
+		"name": "production",
+		"can_admins_bypass": false,
+		"protection_rules": [
+			{"id": 1, "type": "wait_timer", "wait_timer": 30},
+			{"id": 2, "type": "required_reviewers", "prevent_self_review": true, "reviewers": [
+				{"type": "User", "reviewer": {"id": 10, "login": "octocat"}},
+				{"type": "Team", "reviewer": {"id": 20, "slug": "platform"}}
+			]}
+		],
+		"deployment_branch_policy": {"protected_branches": false, "custom_branch_policies": true}
+	}`)
+
+	var environment Environment
+	if err := json.Unmarshal(payload, &environment); err != nil {
+		t.Fatalf("Failed to unmarshal Environment: %v", err)
+	}
+
+	if len(environment.ProtectionRules) != 2 {
+		t.Fatalf("Expected 2 protection rules, got %d", len(environment.ProtectionRules))
+	}
+	if environment.ProtectionRules[0].WaitTimer != 30 {
+		t.Errorf("Expected wait timer 30, got %d", environment.ProtectionRules[0].WaitTimer)
+	}
+	reviewers := environment.ProtectionRules[1].Reviewers
+	if len(reviewers) != 2 || reviewers[0].Reviewer.Login != "octocat" || reviewers[1].Reviewer.Slug != "platform" {
+		t.Errorf("Unexpected reviewers: %+v", reviewers)
+	}
+	if environment.DeploymentBranchPolicy == nil || !environment.DeploymentBranchPolicy.CustomBranchPolicies {
+		t.Error("Expected custom deployment branch policies")
+	}
+}
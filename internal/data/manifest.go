@@ -0,0 +1,9 @@
+package data
+
+// Manifest is the desired state of the secrets and variables of an
+// organization, kept in git and reconciled by apply.
+type Manifest struct {
+	Organization string             `json:"organization" yaml:"organization"`
+	Secrets      []ImportedSecret   `json:"secrets" yaml:"secrets"`
+	Variables    []ImportedVariable `json:"variables" yaml:"variables"`
+}
@@ -0,0 +1,20 @@
+package data
+
+// Actions a dry run reports for each secret or variable in a file, and
+// apply for each one it prunes
+const (
+	PlanCreate    = "create"
+	PlanUpdate    = "update"
+	PlanDelete    = "delete"
+	PlanUnchanged = "unchanged"
+	PlanError     = "error"
+)
+
+type PlanItem struct {
+	Action  string   `json:"action"`
+	Level   string   `json:"level"`
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Target  string   `json:"target"`
+	Details []string `json:"details"`
+}
@@ -35,3 +35,13 @@ type ScopedResponse struct {
 	TotalCount   int                `json:"total_count"`
 	Repositories []ScopedRepository `json:"repositories"`
 }
+
+// A file or directory read with the repository contents API. Content is
+// only returned when a single file is read.
+type RepoContent struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Content  string `json:"content,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
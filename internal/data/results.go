@@ -0,0 +1,19 @@
+package data
+
+// Results recorded for each secret or variable a create command writes
+const (
+	ResultSucceeded = "succeeded"
+	ResultSkipped   = "skipped"
+	ResultFailed    = "failed"
+)
+
+type RowResult struct {
+	Row        int    `json:"row"`
+	Level      string `json:"level"`
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	Target     string `json:"target"`
+	Result     string `json:"result"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
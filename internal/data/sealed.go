@@ -0,0 +1,25 @@
+package data
+
+import "time"
+
+// SealedBundle is a set of secrets whose values are encrypted with the
+// public keys of an organization, so they can be written by someone who
+// never sees the values.
+type SealedBundle struct {
+	Organization string         `json:"organization"`
+	Hostname     string         `json:"hostname"`
+	SealedAt     time.Time      `json:"sealed_at"`
+	Secrets      []SealedSecret `json:"secrets"`
+}
+
+type SealedSecret struct {
+	Level           string   `json:"level"`
+	Type            string   `json:"type"`
+	Name            string   `json:"name"`
+	Access          string   `json:"visibility,omitempty"`
+	RepositoryNames []string `json:"selected_repositories,omitempty"`
+	RepositoryIDs   []string `json:"selected_repository_ids,omitempty"`
+	EnvironmentName string   `json:"environment_name,omitempty"`
+	KeyID           string   `json:"key_id"`
+	EncryptedValue  string   `json:"encrypted_value"`
+}
@@ -29,13 +29,17 @@ type CreateRepoSecret struct {
 }
 
 type ImportedSecret struct {
-	Level           string   `json:"level"`
-	Type            string   `json:"type"`
-	Name            string   `json:"name"`
-	Value           string   `json:"value"`
-	Access          string   `json:"visibility"`
-	RepositoryNames []string `json:"selected_repositories"`
-	RepositoryIDs   []string `json:"selected_repository_ids"`
+	Level           string   `json:"level" yaml:"level"`
+	Type            string   `json:"type" yaml:"type"`
+	Name            string   `json:"name" yaml:"name"`
+	Value           string   `json:"value" yaml:"value"`
+	Access          string   `json:"visibility" yaml:"visibility"`
+	RepositoryNames []string `json:"selected_repositories" yaml:"selected_repositories"`
+	RepositoryIDs   []string `json:"selected_repository_ids" yaml:"selected_repository_ids"`
+	EnvironmentName string   `json:"environment_name" yaml:"environment_name"`
+	// Row is the row of the file the secret was read from, numbered from
+	// the first row after the header, or 0 when it was not read from one.
+	Row int `json:"-" yaml:"-"`
 }
 
 type PublicKey struct {
@@ -21,12 +21,16 @@ type CreateRepoVariable struct {
 }
 
 type ImportedVariable struct {
-	Level            string
-	Name             string `json:"name"`
-	Value            string `json:"value"`
-	Visibility       string `json:"visibility"`
-	SelectedRepos    []string
-	SelectedReposIDs []string `json:"selected_repository_ids"`
+	Level            string   `json:"level" yaml:"level"`
+	Name             string   `json:"name" yaml:"name"`
+	Value            string   `json:"value" yaml:"value"`
+	Visibility       string   `json:"visibility" yaml:"visibility"`
+	SelectedRepos    []string `json:"selected_repositories" yaml:"selected_repositories"`
+	SelectedReposIDs []string `json:"selected_repository_ids" yaml:"selected_repository_ids"`
+	EnvironmentName  string   `json:"environment_name" yaml:"environment_name"`
+	// Row is the row of the file the variable was read from, numbered from
+	// the first row after the header, or 0 when it was not read from one.
+	Row int `json:"-" yaml:"-"`
 }
 
 type Variable struct {
@@ -0,0 +1,128 @@
+package fakegithub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// repositoryFieldPattern matches the repository fields of the getRepo query
+// and of the aliased batches GetRepoIDs sends, capturing the alias and the
+// names of the owner and name variables.
+var repositoryFieldPattern = regexp.MustCompile(`(?:(\w+)\s*:\s*)?repository\(owner:\s*\$(\w+),\s*name:\s*\$(\w+)\)`)
+
+// reposPerPage is the page size of the getRepos query
+const reposPerPage = 100
+
+type graphQLError struct {
+	Type    string   `json:"type"`
+	Path    []string `json:"path"`
+	Message string   `json:"message"`
+}
+
+type repositoryNode struct {
+	DatabaseID int       `json:"databaseId"`
+	Name       string    `json:"name"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	Visibility string    `json:"visibility"`
+}
+
+// serveGraphQL answers the queries utils sends, telling them apart by the
+// fields they select rather than parsing them: the repositories of an
+// organization, or one or more repositories by name.
+func (s *Server) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+
+	result := map[string]interface{}{}
+	var errs []graphQLError
+	if strings.Contains(request.Query, "organization(") {
+		owner, _ := request.Variables["owner"].(string)
+		cursor, _ := request.Variables["endCursor"].(string)
+		if org, ok := s.orgs[owner]; ok {
+			result["organization"] = map[string]interface{}{"repositories": repositoriesPage(org, cursor)}
+		} else {
+			result["organization"] = nil
+			errs = append(errs, graphQLError{
+				Type:    "NOT_FOUND",
+				Path:    []string{"organization"},
+				Message: fmt.Sprintf("Could not resolve to an Organization with the login of '%s'.", owner),
+			})
+		}
+	} else {
+		matches := repositoryFieldPattern.FindAllStringSubmatch(request.Query, -1)
+		if len(matches) == 0 {
+			writeError(w, http.StatusBadRequest, "Unsupported query")
+			return
+		}
+		for _, match := range matches {
+			field := match[1]
+			if field == "" {
+				field = "repository"
+			}
+			owner, _ := request.Variables[match[2]].(string)
+			name, _ := request.Variables[match[3]].(string)
+			repo, err := s.repository(owner, name)
+			if err != nil {
+				result[field] = nil
+				errs = append(errs, graphQLError{
+					Type:    "NOT_FOUND",
+					Path:    []string{field},
+					Message: fmt.Sprintf("Could not resolve to a Repository with the name '%s/%s'.", owner, name),
+				})
+				continue
+			}
+			result[field] = newRepositoryNode(repo)
+		}
+	}
+
+	response := map[string]interface{}{"data": result}
+	if len(errs) > 0 {
+		response["errors"] = errs
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// repositoriesPage returns the page of repositories of org after cursor,
+// which is the number of repositories on the pages before.
+func repositoriesPage(org *organization, cursor string) map[string]interface{} {
+	repos := org.sortedRepos()
+	start, err := strconv.Atoi(cursor)
+	if err != nil || start < 0 {
+		start = 0
+	}
+	start = min(start, len(repos))
+	end := min(start+reposPerPage, len(repos))
+
+	nodes := make([]repositoryNode, 0, end-start)
+	for _, repo := range repos[start:end] {
+		nodes = append(nodes, newRepositoryNode(repo))
+	}
+	return map[string]interface{}{
+		"totalCount": len(repos),
+		"nodes":      nodes,
+		"pageInfo": map[string]interface{}{
+			"endCursor":   strconv.Itoa(end),
+			"hasNextPage": end < len(repos),
+		},
+	}
+}
+
+func newRepositoryNode(repo *repository) repositoryNode {
+	return repositoryNode{
+		DatabaseID: repo.id,
+		Name:       repo.name,
+		UpdatedAt:  repo.updatedAt,
+		Visibility: strings.ToUpper(repo.visibility),
+	}
+}
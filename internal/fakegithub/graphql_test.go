@@ -0,0 +1,85 @@
+package fakegithub
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetReposList(t *testing.T) {
+	s := New()
+	for i := 0; i < 150; i++ {
+		s.AddRepository("test-org", fmt.Sprintf("repo-%03d", i), "internal")
+	}
+	g := newTestAPIGetter(t, s)
+
+	first, err := g.GetReposList("test-org", nil)
+	if err != nil {
+		t.Fatalf("Failed to list repositories: %v", err)
+	}
+	repos := first.Organization.Repositories
+	if repos.TotalCount != 150 || len(repos.Nodes) != 100 || !repos.PageInfo.HasNextPage {
+		t.Fatalf("Unexpected first page: total %d, %d nodes, next %v", repos.TotalCount, len(repos.Nodes), repos.PageInfo.HasNextPage)
+	}
+	if repos.Nodes[0].Name != "repo-000" || repos.Nodes[0].Visibility != "INTERNAL" || repos.Nodes[0].DatabaseId == 0 {
+		t.Errorf("Unexpected first repository: %+v", repos.Nodes[0])
+	}
+
+	second, err := g.GetReposList("test-org", &repos.PageInfo.EndCursor)
+	if err != nil {
+		t.Fatalf("Failed to list repositories: %v", err)
+	}
+	repos = second.Organization.Repositories
+	if len(repos.Nodes) != 50 || repos.PageInfo.HasNextPage || repos.Nodes[0].Name != "repo-100" {
+		t.Errorf("Unexpected second page: %d nodes starting at %s, next %v", len(repos.Nodes), repos.Nodes[0].Name, repos.PageInfo.HasNextPage)
+	}
+
+	if _, err := g.GetReposList("other-org", nil); err == nil {
+		t.Error("Expected an error listing a missing organization")
+	}
+}
+
+func TestGetRepo(t *testing.T) {
+	s := New()
+	id := s.AddRepository("test-org", "repo-a", "public")
+	g := newTestAPIGetter(t, s)
+
+	repo, err := g.GetRepo("test-org", "repo-a")
+	if err != nil {
+		t.Fatalf("Failed to get repository: %v", err)
+	}
+	if repo.Repository.DatabaseId != id || repo.Repository.Name != "repo-a" || repo.Repository.Visibility != "PUBLIC" {
+		t.Errorf("Unexpected repository: %+v", repo.Repository)
+	}
+	if _, err := g.GetRepo("test-org", "repo-b"); err == nil {
+		t.Error("Expected an error getting a missing repository")
+	}
+}
+
+func TestGetRepoIDs(t *testing.T) {
+	s := New()
+	names := make([]string, 0, 60)
+	ids := map[string]int{}
+	for i := 0; i < 60; i++ {
+		name := fmt.Sprintf("repo-%02d", i)
+		ids[name] = s.AddRepository("test-org", name, "private")
+		names = append(names, name)
+	}
+	g := newTestAPIGetter(t, s)
+
+	// More repositories than fit in one query, and one that does not exist
+	found, err := g.GetRepoIDs("test-org", append(names, "missing"))
+	if err != nil {
+		t.Fatalf("Failed to resolve repositories: %v", err)
+	}
+	if len(found) != 60 {
+		t.Errorf("Expected 60 repositories, got %d", len(found))
+	}
+	for name, id := range ids {
+		if found[name] != id {
+			t.Errorf("Expected %s to have ID %d, got %d", name, id, found[name])
+		}
+	}
+	if _, ok := found["missing"]; ok {
+		t.Error("Expected the missing repository to be left out")
+	}
+}
@@ -0,0 +1,644 @@
+package fakegithub
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+// Page sizes of list endpoints when per_page is not given, and at most
+const (
+	defaultPerPage = 30
+	maxPerPage     = 100
+)
+
+type secretResponse struct {
+	Name                    string    `json:"name"`
+	CreatedAt               time.Time `json:"created_at"`
+	UpdatedAt               time.Time `json:"updated_at"`
+	Visibility              string    `json:"visibility,omitempty"`
+	SelectedRepositoriesURL string    `json:"selected_repositories_url,omitempty"`
+}
+
+type variableResponse struct {
+	Name                    string    `json:"name"`
+	Value                   string    `json:"value"`
+	CreatedAt               time.Time `json:"created_at"`
+	UpdatedAt               time.Time `json:"updated_at"`
+	Visibility              string    `json:"visibility,omitempty"`
+	SelectedRepositoriesURL string    `json:"selected_repositories_url,omitempty"`
+}
+
+// ServeHTTP answers a REST or GraphQL request. Paths may be prefixed with
+// /api/v3, as on GitHub Enterprise Server, and the host is ignored.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, err := splitPath(r.URL.EscapedPath())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(path) >= 2 && path[0] == "api" && (path[1] == "v3" || path[1] == "graphql") {
+		path = path[1:]
+		if path[0] == "v3" {
+			path = path[1:]
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case len(path) == 1 && path[0] == "graphql" && r.Method == http.MethodPost:
+		s.serveGraphQL(w, r)
+	case len(path) == 2 && path[0] == "users" && r.Method == http.MethodGet:
+		s.serveUser(w, path[1])
+	case len(path) >= 2 && path[0] == "orgs":
+		org, ok := s.orgs[path[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.serveOrg(w, r, org, path[2:])
+	case len(path) >= 3 && path[0] == "repos":
+		repo, err := s.repository(path[1], path[2])
+		if err != nil {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.serveRepo(w, r, s.orgs[path[1]], repo, path[3:])
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// splitPath splits an escaped path into unescaped segments, as environment
+// names may contain slashes and spaces.
+func splitPath(escaped string) ([]string, error) {
+	segments := strings.Split(strings.Trim(escaped, "/"), "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s", escaped)
+		}
+		segments[i] = unescaped
+	}
+	return segments, nil
+}
+
+func (s *Server) serveUser(w http.ResponseWriter, login string) {
+	id, ok := s.users[login]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"login": login, "id": id, "type": "User"})
+}
+
+func (s *Server) serveOrg(w http.ResponseWriter, r *http.Request, org *organization, rest []string) {
+	switch {
+	case len(rest) == 2 && rest[0] == "teams" && r.Method == http.MethodGet:
+		id, ok := org.teams[rest[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"slug": rest[1], "id": id, "name": rest[1]})
+	case len(rest) >= 2 && slices.Contains(secretTypes, rest[0]) && rest[1] == "secrets":
+		s.serveSecrets(w, r, org, &org.scope, rest[0], rest[2:])
+	case len(rest) >= 2 && rest[0] == "actions" && rest[1] == "variables":
+		s.serveVariables(w, r, org, &org.scope, rest[2:])
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (s *Server) serveRepo(w http.ResponseWriter, r *http.Request, org *organization, repo *repository, rest []string) {
+	switch {
+	case len(rest) >= 2 && slices.Contains(secretTypes, rest[0]) && rest[1] == "secrets":
+		s.serveSecrets(w, r, nil, &repo.scope, rest[0], rest[2:])
+	case len(rest) >= 2 && rest[0] == "actions" && rest[1] == "variables":
+		s.serveVariables(w, r, nil, &repo.scope, rest[2:])
+	case len(rest) >= 1 && rest[0] == "environments":
+		s.serveEnvironments(w, r, org, repo, rest[1:])
+	case len(rest) >= 1 && rest[0] == "contents" && r.Method == http.MethodGet:
+		serveContents(w, repo, strings.Join(rest[1:], "/"))
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// serveSecrets answers the secrets endpoints of a scope, where org is only
+// set for organization secrets, which have a visibility.
+func (s *Server) serveSecrets(w http.ResponseWriter, r *http.Request, org *organization, sc *scope, secretType string, rest []string) {
+	secrets := sc.secrets[secretType]
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		names := make([]string, 0, len(secrets))
+		for name := range secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		items := make([]secretResponse, 0, len(names))
+		for _, name := range names {
+			items = append(items, newSecretResponse(r, org, secretType, secrets[name]))
+		}
+		writePage(w, r, "secrets", items)
+	case len(rest) == 1 && rest[0] == "public-key" && r.Method == http.MethodGet:
+		key, err := s.publicKey(sc, secretType)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, data.PublicKey{KeyID: key.id, Key: encodeKey(key.public)})
+	case len(rest) == 1 && r.Method == http.MethodPut:
+		s.putSecret(w, r, org, sc, secretType, strings.ToUpper(rest[0]))
+	case len(rest) >= 1:
+		secret, ok := secrets[strings.ToUpper(rest[0])]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		switch {
+		case len(rest) == 1 && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, newSecretResponse(r, org, secretType, secret))
+		case len(rest) == 1 && r.Method == http.MethodDelete:
+			delete(secrets, secret.Name)
+			w.WriteHeader(http.StatusNoContent)
+		case len(rest) == 2 && rest[1] == "repositories" && org != nil && r.Method == http.MethodGet:
+			writeScopedRepos(w, r, org, secret.Visibility, secret.SelectedRepositoryIDs)
+		default:
+			writeError(w, http.StatusNotFound, "Not Found")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (s *Server) putSecret(w http.ResponseWriter, r *http.Request, org *organization, sc *scope, secretType string, name string) {
+	var body struct {
+		EncryptedValue        string        `json:"encrypted_value"`
+		KeyID                 string        `json:"key_id"`
+		Visibility            string        `json:"visibility"`
+		SelectedRepositoryIDs []json.Number `json:"selected_repository_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	key, err := s.publicKey(sc, secretType)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if body.KeyID != key.id {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Bad request - key_id %s is not the current public key", body.KeyID))
+		return
+	}
+	value, err := key.open(body.EncryptedValue)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	var visibility string
+	var selected []int
+	if org != nil {
+		visibility = body.Visibility
+		selected, err = parseSelected(org, visibility, body.SelectedRepositoryIDs)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+	}
+
+	if sc.secrets[secretType] == nil {
+		sc.secrets[secretType] = map[string]*Secret{}
+	}
+	now := s.now()
+	status := http.StatusNoContent
+	secret, ok := sc.secrets[secretType][name]
+	if !ok {
+		secret = &Secret{Name: name, CreatedAt: now}
+		sc.secrets[secretType][name] = secret
+		status = http.StatusCreated
+	}
+	secret.Value = value
+	secret.KeyID = key.id
+	secret.Visibility = visibility
+	secret.SelectedRepositoryIDs = selected
+	secret.UpdatedAt = now
+	w.WriteHeader(status)
+}
+
+// serveVariables answers the variables endpoints of a scope, where org is
+// only set for organization variables.
+func (s *Server) serveVariables(w http.ResponseWriter, r *http.Request, org *organization, sc *scope, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		names := make([]string, 0, len(sc.variables))
+		for name := range sc.variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		items := make([]variableResponse, 0, len(names))
+		for _, name := range names {
+			items = append(items, newVariableResponse(r, org, sc.variables[name]))
+		}
+		writePage(w, r, "variables", items)
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		s.postVariable(w, r, org, sc)
+	case len(rest) >= 1:
+		variable, ok := sc.variables[strings.ToUpper(rest[0])]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		switch {
+		case len(rest) == 1 && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, newVariableResponse(r, org, variable))
+		case len(rest) == 1 && r.Method == http.MethodPatch:
+			s.patchVariable(w, r, org, sc, variable)
+		case len(rest) == 1 && r.Method == http.MethodDelete:
+			delete(sc.variables, variable.Name)
+			w.WriteHeader(http.StatusNoContent)
+		case len(rest) == 2 && rest[1] == "repositories" && org != nil && r.Method == http.MethodGet:
+			writeScopedRepos(w, r, org, variable.Visibility, variable.SelectedRepositoryIDs)
+		default:
+			writeError(w, http.StatusNotFound, "Not Found")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+type variableRequest struct {
+	Name                  *string       `json:"name"`
+	Value                 *string       `json:"value"`
+	Visibility            *string       `json:"visibility"`
+	SelectedRepositoryIDs []json.Number `json:"selected_repository_ids"`
+}
+
+func (s *Server) postVariable(w http.ResponseWriter, r *http.Request, org *organization, sc *scope) {
+	var body variableRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if body.Name == nil || *body.Name == "" || body.Value == nil {
+		writeError(w, http.StatusUnprocessableEntity, "name and value are required")
+		return
+	}
+	name := strings.ToUpper(*body.Name)
+	if _, ok := sc.variables[name]; ok {
+		writeError(w, http.StatusConflict, "Already exists - Variable already exists")
+		return
+	}
+	variable := &Variable{Name: name, Value: *body.Value}
+	if org != nil {
+		variable.Visibility = deref(body.Visibility)
+		selected, err := parseSelected(org, variable.Visibility, body.SelectedRepositoryIDs)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		variable.SelectedRepositoryIDs = selected
+	}
+	variable.CreatedAt = s.now()
+	variable.UpdatedAt = variable.CreatedAt
+	sc.variables[name] = variable
+	writeJSON(w, http.StatusCreated, map[string]string{})
+}
+
+// patchVariable changes the fields given in the request, renaming the
+// variable when a new name is given.
+func (s *Server) patchVariable(w http.ResponseWriter, r *http.Request, org *organization, sc *scope, variable *Variable) {
+	var body variableRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	updated := *variable
+	if body.Name != nil && *body.Name != "" {
+		updated.Name = strings.ToUpper(*body.Name)
+		if _, ok := sc.variables[updated.Name]; ok && updated.Name != variable.Name {
+			writeError(w, http.StatusConflict, "Already exists - Variable already exists")
+			return
+		}
+	}
+	if body.Value != nil {
+		updated.Value = *body.Value
+	}
+	if org != nil && (body.Visibility != nil || body.SelectedRepositoryIDs != nil) {
+		if body.Visibility != nil {
+			updated.Visibility = *body.Visibility
+		}
+		selected, err := parseSelected(org, updated.Visibility, body.SelectedRepositoryIDs)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		updated.SelectedRepositoryIDs = selected
+	}
+	updated.UpdatedAt = s.now()
+	delete(sc.variables, variable.Name)
+	sc.variables[updated.Name] = &updated
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) serveEnvironments(w http.ResponseWriter, r *http.Request, org *organization, repo *repository, rest []string) {
+	if len(rest) == 0 {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		names := make([]string, 0, len(repo.environments))
+		for name := range repo.environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		items := make([]data.Environment, 0, len(names))
+		for _, name := range names {
+			items = append(items, repo.environments[name].Environment)
+		}
+		writePage(w, r, "environments", items)
+		return
+	}
+
+	if len(rest) == 1 && r.Method == http.MethodPut {
+		s.putEnvironment(w, r, org, repo, rest[0])
+		return
+	}
+	env, ok := repo.environments[rest[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, env.Environment)
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		delete(repo.environments, env.Name)
+		w.WriteHeader(http.StatusNoContent)
+	case len(rest) == 2 && rest[1] == "deployment-branch-policies" && r.Method == http.MethodGet:
+		writePage(w, r, "branch_policies", env.branchPolicies)
+	case len(rest) == 2 && rest[1] == "deployment-branch-policies" && r.Method == http.MethodPost:
+		var policy data.CreateBranchPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+		if env.DeploymentBranchPolicy == nil || !env.DeploymentBranchPolicy.CustomBranchPolicies {
+			writeError(w, http.StatusNotFound, "Custom deployment branch policies are not enabled for this environment")
+			return
+		}
+		if policy.Type == "" {
+			policy.Type = "branch"
+		}
+		created := data.BranchPolicy{ID: s.newID(), Name: policy.Name, Type: policy.Type}
+		env.branchPolicies = append(env.branchPolicies, created)
+		writeJSON(w, http.StatusOK, created)
+	case len(rest) >= 2 && rest[1] == "secrets":
+		s.serveSecrets(w, r, nil, &env.scope, "actions", rest[2:])
+	case len(rest) >= 2 && rest[1] == "variables":
+		s.serveVariables(w, r, nil, &env.scope, rest[2:])
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// putEnvironment creates or updates an environment, turning the settings
+// into the protection rules GitHub lists. Reviewers are given by ID, so
+// they are looked up among the users and the teams of the organization.
+func (s *Server) putEnvironment(w http.ResponseWriter, r *http.Request, org *organization, repo *repository, name string) {
+	var settings data.CreateEnvironment
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+			return
+		}
+	}
+	reviewers := make([]data.EnvironmentReviewer, 0, len(settings.Reviewers))
+	for _, reviewer := range settings.Reviewers {
+		resolved, ok := s.reviewer(org, reviewer)
+		if !ok {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s %d not found", reviewer.Type, reviewer.ID))
+			return
+		}
+		reviewers = append(reviewers, resolved)
+	}
+
+	env := s.addEnvironment(repo, name)
+	var rules []data.ProtectionRule
+	if settings.WaitTimer > 0 {
+		rules = append(rules, data.ProtectionRule{ID: s.newID(), Type: "wait_timer", WaitTimer: settings.WaitTimer})
+	}
+	if len(reviewers) > 0 {
+		rules = append(rules, data.ProtectionRule{
+			ID:                s.newID(),
+			Type:              "required_reviewers",
+			PreventSelfReview: settings.PreventSelfReview,
+			Reviewers:         reviewers,
+		})
+	}
+	if settings.DeploymentBranchPolicy != nil {
+		rules = append(rules, data.ProtectionRule{ID: s.newID(), Type: "branch_policy"})
+	}
+	if rules == nil {
+		rules = []data.ProtectionRule{}
+	}
+	env.ProtectionRules = rules
+	env.DeploymentBranchPolicy = settings.DeploymentBranchPolicy
+	env.CanAdminsBypass = settings.CanAdminsBypass
+	env.UpdatedAt = s.now()
+	writeJSON(w, http.StatusOK, env.Environment)
+}
+
+func (s *Server) reviewer(org *organization, reviewer data.CreateEnvironmentReviewer) (data.EnvironmentReviewer, bool) {
+	if reviewer.Type == "Team" {
+		for slug, id := range org.teams {
+			if id == reviewer.ID {
+				return data.EnvironmentReviewer{Type: "Team", Reviewer: data.ReviewerInfo{ID: id, Slug: slug}}, true
+			}
+		}
+		return data.EnvironmentReviewer{}, false
+	}
+	for login, id := range s.users {
+		if id == reviewer.ID {
+			return data.EnvironmentReviewer{Type: "User", Reviewer: data.ReviewerInfo{ID: id, Login: login}}, true
+		}
+	}
+	return data.EnvironmentReviewer{}, false
+}
+
+// serveContents returns a file of repo, base64 encoded as GitHub does, or
+// lists the files and directories directly in a directory.
+func serveContents(w http.ResponseWriter, repo *repository, filePath string) {
+	if content, ok := repo.files[filePath]; ok {
+		writeJSON(w, http.StatusOK, data.RepoContent{
+			Type:     "file",
+			Name:     path.Base(filePath),
+			Path:     filePath,
+			Content:  base64.StdEncoding.EncodeToString([]byte(content)),
+			Encoding: "base64",
+		})
+		return
+	}
+
+	prefix := ""
+	if filePath != "" {
+		prefix = filePath + "/"
+	}
+	seen := map[string]bool{}
+	entries := []data.RepoContent{}
+	for file := range repo.files {
+		rest, ok := strings.CutPrefix(file, prefix)
+		if !ok {
+			continue
+		}
+		name, _, isDir := strings.Cut(rest, "/")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		entry := data.RepoContent{Type: "file", Name: name, Path: prefix + name}
+		if isDir {
+			entry.Type = "dir"
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// parseSelected checks the visibility of an organization secret or
+// variable and returns the IDs of its selected repositories, which may be
+// sent as numbers or strings.
+func parseSelected(org *organization, visibility string, ids []json.Number) ([]int, error) {
+	switch visibility {
+	case "all", "private":
+		return nil, nil
+	case "selected":
+	default:
+		return nil, fmt.Errorf("visibility must be all, private or selected, not %q", visibility)
+	}
+	selected := make([]int, 0, len(ids))
+	for _, raw := range ids {
+		id, err := strconv.Atoi(raw.String())
+		if err != nil || org.repoByID(id) == nil {
+			return nil, fmt.Errorf("repository %s not found in %s", raw, org.login)
+		}
+		selected = append(selected, id)
+	}
+	return selected, nil
+}
+
+func newSecretResponse(r *http.Request, org *organization, secretType string, secret *Secret) secretResponse {
+	response := secretResponse{Name: secret.Name, CreatedAt: secret.CreatedAt, UpdatedAt: secret.UpdatedAt}
+	if org != nil {
+		response.Visibility = secret.Visibility
+		if secret.Visibility == "selected" {
+			response.SelectedRepositoriesURL = fmt.Sprintf("%s/orgs/%s/%s/secrets/%s/repositories", baseURL(r), org.login, secretType, secret.Name)
+		}
+	}
+	return response
+}
+
+func newVariableResponse(r *http.Request, org *organization, variable *Variable) variableResponse {
+	response := variableResponse{
+		Name:      variable.Name,
+		Value:     variable.Value,
+		CreatedAt: variable.CreatedAt,
+		UpdatedAt: variable.UpdatedAt,
+	}
+	if org != nil {
+		response.Visibility = variable.Visibility
+		if variable.Visibility == "selected" {
+			response.SelectedRepositoriesURL = fmt.Sprintf("%s/orgs/%s/actions/variables/%s/repositories", baseURL(r), org.login, variable.Name)
+		}
+	}
+	return response
+}
+
+// writeScopedRepos lists the repositories an organization secret or
+// variable is selected for, which is refused unless its visibility is
+// selected.
+func writeScopedRepos(w http.ResponseWriter, r *http.Request, org *organization, visibility string, ids []int) {
+	if visibility != "selected" {
+		writeError(w, http.StatusConflict, "The visibility is not set to selected")
+		return
+	}
+	repos := make([]data.ScopedRepository, 0, len(ids))
+	for _, id := range ids {
+		if repo := org.repoByID(id); repo != nil {
+			repos = append(repos, data.ScopedRepository{ID: repo.id, Name: repo.name})
+		}
+	}
+	writePage(w, r, "repositories", repos)
+}
+
+// writePage writes the page of items asked for by the page and per_page
+// parameters, with total_count and a Link header to the next page.
+func writePage[T any](w http.ResponseWriter, r *http.Request, key string, items []T) {
+	query := r.URL.Query()
+	perPage, err := strconv.Atoi(query.Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	perPage = min(perPage, maxPerPage)
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		query.Set("per_page", strconv.Itoa(perPage))
+		query.Set("page", strconv.Itoa(page+1))
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, baseURL(r), r.URL.EscapedPath(), query.Encode()))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_count": len(items),
+		key:           items[start:end],
+	})
+}
+
+// baseURL returns the scheme and host a request was sent to, so links work
+// behind a proxy as well as when the server is reached directly.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,348 @@
+package fakegithub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+func TestOrgSecretSelectedRepos(t *testing.T) {
+	for _, secretType := range []string{"Actions", "Dependabot", "Codespaces"} {
+		t.Run(secretType, func(t *testing.T) {
+			s := New()
+			idA := s.AddRepository("test-org", "repo-a", "private")
+			s.AddRepository("test-org", "repo-b", "private")
+			g := newTestAPIGetter(t, s)
+
+			secrets := []data.ImportedSecret{{
+				Level:           "Organization",
+				Type:            secretType,
+				Name:            "DEPLOY_KEY",
+				Value:           "s3cr3t value",
+				Access:          "selected",
+				RepositoryNames: []string{"repo-a"},
+			}}
+			for _, err := range g.ResolveSecretRepos("test-org", secrets) {
+				if err != nil {
+					t.Fatalf("Failed to resolve repositories: %v", err)
+				}
+			}
+			if err := g.CreateImportedSecret("test-org", secrets[0]); err != nil {
+				t.Fatalf("Failed to create secret: %v", err)
+			}
+
+			secret, ok := s.Secret("test-org", "", "", secretType, "DEPLOY_KEY")
+			if !ok {
+				t.Fatal("Expected the secret to exist")
+			}
+			if secret.Value != "s3cr3t value" || secret.Visibility != "selected" {
+				t.Errorf("Unexpected secret: %+v", secret)
+			}
+			if len(secret.SelectedRepositoryIDs) != 1 || secret.SelectedRepositoryIDs[0] != idA {
+				t.Errorf("Expected the secret to be selected for repo-a (%d), got %v", idA, secret.SelectedRepositoryIDs)
+			}
+
+			state, err := g.ReadOrgSecrets("test-org", 1)
+			if err != nil {
+				t.Fatalf("Failed to read secrets: %v", err)
+			}
+			if len(state) != 1 || strings.Join(state[0].RepositoryNames, ",") != "repo-a" {
+				t.Errorf("Expected DEPLOY_KEY selected for repo-a, got %+v", state)
+			}
+		})
+	}
+}
+
+func TestPutSecretRefused(t *testing.T) {
+	s := New()
+	s.AddRepository("test-org", "repo-a", "private")
+	g := newTestAPIGetter(t, s)
+	publicKey, err := g.SecretPublicKey("test-org", data.ImportedSecret{Level: "Organization", Type: "Actions"})
+	if err != nil {
+		t.Fatalf("Failed to get the public key: %v", err)
+	}
+	encrypted, err := g.EncryptSecret(publicKey.Key, "value")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		body   map[string]interface{}
+		status int
+	}{
+		{
+			name:   "stale key",
+			body:   map[string]interface{}{"encrypted_value": encrypted, "key_id": "1", "visibility": "all"},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "not encrypted",
+			body:   map[string]interface{}{"encrypted_value": "dmFsdWU=", "key_id": publicKey.KeyID, "visibility": "all"},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "unknown visibility",
+			body:   map[string]interface{}{"encrypted_value": encrypted, "key_id": publicKey.KeyID, "visibility": "public"},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "unknown repository",
+			body: map[string]interface{}{"encrypted_value": encrypted, "key_id": publicKey.KeyID, "visibility": "selected",
+				"selected_repository_ids": []int{1}},
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "valid",
+			body:   map[string]interface{}{"encrypted_value": encrypted, "key_id": publicKey.KeyID, "visibility": "private"},
+			status: http.StatusCreated,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			req := httptest.NewRequest(http.MethodPut, "/orgs/test-org/actions/secrets/TOKEN", bytes.NewReader(body))
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			if recorder.Code != tc.status {
+				t.Errorf("Expected status %d, got %d: %s", tc.status, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteSecret(t *testing.T) {
+	s := New()
+	s.AddRepository("test-org", "repo-a", "private")
+	g := newTestAPIGetter(t, s)
+	secret := data.ImportedSecret{Level: "Repository", Type: "Dependabot", Name: "TOKEN", Value: "value", RepositoryNames: []string{"repo-a"}}
+	if err := g.CreateImportedSecret("test-org", secret); err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	if err := g.DeleteImportedSecret("test-org", secret); err != nil {
+		t.Fatalf("Failed to delete secret: %v", err)
+	}
+	if _, ok := s.Secret("test-org", "repo-a", "", "Dependabot", "TOKEN"); ok {
+		t.Error("Expected the secret to be deleted")
+	}
+	if err := g.DeleteImportedSecret("test-org", secret); !utils.IsNotFound(err) {
+		t.Errorf("Expected deleting again to be not found, got %v", err)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	s := New()
+	idA := s.AddRepository("test-org", "repo-a", "private")
+	if err := s.AddEnvironment("test-org", "repo-a", "qa env"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	g := newTestAPIGetter(t, s)
+
+	variables := []data.ImportedVariable{
+		{Level: "Organization", Name: "REGION", Value: "eu", Visibility: "selected", SelectedRepos: []string{"repo-a"}},
+		{Level: "Repository", Name: "REGION", Value: "us", SelectedRepos: []string{"repo-a"}},
+		{Level: "Environment", Name: "REGION", Value: "ap", SelectedRepos: []string{"repo-a"}, EnvironmentName: "qa env"},
+	}
+	for _, err := range g.ResolveVariableRepos("test-org", variables) {
+		if err != nil {
+			t.Fatalf("Failed to resolve repositories: %v", err)
+		}
+	}
+	for _, variable := range variables {
+		if err := g.CreateImportedVariable("test-org", variable); err != nil {
+			t.Fatalf("Failed to create %s variable: %v", variable.Level, err)
+		}
+	}
+
+	org, _ := s.Variable("test-org", "", "", "REGION")
+	if org.Value != "eu" || len(org.SelectedRepositoryIDs) != 1 || org.SelectedRepositoryIDs[0] != idA {
+		t.Errorf("Unexpected organization variable: %+v", org)
+	}
+	if repo, _ := s.Variable("test-org", "repo-a", "", "REGION"); repo.Value != "us" || repo.Visibility != "" {
+		t.Errorf("Unexpected repository variable: %+v", repo)
+	}
+	if env, _ := s.Variable("test-org", "repo-a", "qa env", "REGION"); env.Value != "ap" {
+		t.Errorf("Unexpected environment variable: %+v", env)
+	}
+
+	if err := g.CreateImportedVariable("test-org", variables[1]); !utils.IsConflict(err) {
+		t.Errorf("Expected creating REGION again to conflict, got %v", err)
+	}
+	variables[1].Value = "us-east"
+	if err := g.UpdateImportedVariable("test-org", variables[1]); err != nil {
+		t.Fatalf("Failed to update variable: %v", err)
+	}
+	if repo, _ := s.Variable("test-org", "repo-a", "", "REGION"); repo.Value != "us-east" {
+		t.Errorf("Expected the updated value us-east, got %q", repo.Value)
+	}
+
+	if err := g.DeleteImportedVariable("test-org", variables[2]); err != nil {
+		t.Fatalf("Failed to delete variable: %v", err)
+	}
+	if _, ok := s.Variable("test-org", "repo-a", "qa env", "REGION"); ok {
+		t.Error("Expected the environment variable to be deleted")
+	}
+}
+
+func TestListPages(t *testing.T) {
+	s := New()
+	s.AddRepository("test-org", "repo-a", "private")
+	g := newTestAPIGetter(t, s)
+	// Variables are listed 30 at a time, so these take two pages
+	for i := 0; i < 35; i++ {
+		variable := data.ImportedVariable{Level: "Repository", Name: fmt.Sprintf("VAR_%02d", i), Value: "v", SelectedRepos: []string{"repo-a"}}
+		if err := g.CreateImportedVariable("test-org", variable); err != nil {
+			t.Fatalf("Failed to create variable: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/repos/test-org/repo-a/actions/variables?per_page=30", nil)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	if link := recorder.Header().Get("Link"); !strings.Contains(link, "page=2") || !strings.Contains(link, `rel="next"`) {
+		t.Errorf("Expected a Link to page 2, got %q", link)
+	}
+
+	body, err := g.GetRepoActionVariables("test-org", "repo-a")
+	if err != nil {
+		t.Fatalf("Failed to list variables: %v", err)
+	}
+	var response data.VariableResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to parse variables: %v", err)
+	}
+	if len(response.Variables) != 35 {
+		t.Errorf("Expected all 35 variables across pages, got %d", len(response.Variables))
+	}
+}
+
+func TestEnvironments(t *testing.T) {
+	s := New()
+	s.AddRepository("test-org", "repo-a", "private")
+	userID := s.AddUser("octocat")
+	teamID := s.AddTeam("test-org", "platform")
+	g := newTestAPIGetter(t, s)
+
+	settings := data.CreateEnvironment{
+		WaitTimer:         5,
+		PreventSelfReview: true,
+		Reviewers: []data.CreateEnvironmentReviewer{
+			{Type: "User", ID: userID},
+			{Type: "Team", ID: teamID},
+		},
+		DeploymentBranchPolicy: &data.DeploymentBranchPolicy{CustomBranchPolicies: true},
+	}
+	body, _ := json.Marshal(settings)
+	if err := g.CreateEnvironment("test-org", "repo-a", "prod/eu", bytes.NewReader(body)); err != nil {
+		t.Fatalf("Failed to create environment: %v", err)
+	}
+	policy, _ := json.Marshal(data.CreateBranchPolicy{Name: "release/*", Type: "branch"})
+	if err := g.CreateEnvironmentBranchPolicy("test-org", "repo-a", "prod/eu", bytes.NewReader(policy)); err != nil {
+		t.Fatalf("Failed to create branch policy: %v", err)
+	}
+
+	listed, err := g.GetRepoEnvironments("test-org", "repo-a")
+	if err != nil {
+		t.Fatalf("Failed to list environments: %v", err)
+	}
+	var response data.EnvironmentsResponse
+	if err := json.Unmarshal(listed, &response); err != nil {
+		t.Fatalf("Failed to parse environments: %v", err)
+	}
+	if len(response.Environments) != 1 || response.Environments[0].Name != "prod/eu" {
+		t.Fatalf("Expected environment prod/eu, got %+v", response.Environments)
+	}
+	reviewers := utils.FormatEnvironmentReviewers(response.Environments[0].ProtectionRules)
+	if strings.Join(reviewers, ",") != "User:octocat,Team:platform" {
+		t.Errorf("Unexpected reviewers: %v", reviewers)
+	}
+	if policies := s.BranchPolicies("test-org", "repo-a", "prod/eu"); len(policies) != 1 || policies[0].Name != "release/*" {
+		t.Errorf("Unexpected branch policies: %+v", policies)
+	}
+
+	settings.Reviewers = []data.CreateEnvironmentReviewer{{Type: "User", ID: 1}}
+	body, _ = json.Marshal(settings)
+	if err := g.CreateEnvironment("test-org", "repo-a", "staging", bytes.NewReader(body)); err == nil {
+		t.Error("Expected an unknown reviewer to be refused")
+	}
+	if _, err := g.GetUser("hubot"); !utils.IsNotFound(err) {
+		t.Errorf("Expected an unknown user to be not found, got %v", err)
+	}
+}
+
+func TestContents(t *testing.T) {
+	s := New()
+	s.AddRepository("test-org", "repo-a", "private")
+	for filePath, content := range map[string]string{
+		".github/workflows/ci.yml":         "run: echo ${{ secrets.NPM_TOKEN }}",
+		".github/workflows/release.yml":    "run: echo ${{ vars.REGION }}",
+		".github/actions/setup/action.yml": "run: echo ${{ secrets.DEPLOY_KEY }}",
+		"README.md":                        "readme",
+	} {
+		if err := s.AddFile("test-org", "repo-a", filePath, content); err != nil {
+			t.Fatalf("Failed to add %s: %v", filePath, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/repos/test-org/repo-a/contents", nil)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	var root []data.RepoContent
+	if err := json.Unmarshal(recorder.Body.Bytes(), &root); err != nil {
+		t.Fatalf("Expected the root to be listed: %v", err)
+	}
+	if len(root) != 2 || root[0].Name != ".github" || root[0].Type != "dir" || root[1].Path != "README.md" || root[1].Type != "file" {
+		t.Errorf("Unexpected root listing: %+v", root)
+	}
+
+	files, err := newTestAPIGetter(t, s).ReadWorkflowFiles("test-org", "repo-a")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 workflow and action files, got %+v", files)
+	}
+	if files[0].Path != ".github/workflows/ci.yml" || files[0].Content != "run: echo ${{ secrets.NPM_TOKEN }}" {
+		t.Errorf("Unexpected first file: %+v", files[0])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/repos/test-org/repo-a/contents/.github/missing", nil)
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected a missing path to be not found, got %d", recorder.Code)
+	}
+}
+
+func TestServeHTTPNotFound(t *testing.T) {
+	s := New()
+	s.AddRepository("test-org", "repo-a", "private")
+	for _, path := range []string{
+		"/orgs/other-org/actions/secrets",
+		"/repos/test-org/repo-b/actions/secrets",
+		"/repos/test-org/repo-a/environments/missing/secrets",
+		"/orgs/test-org/packages",
+		"/api/v3/orgs/test-org/actions/secrets/MISSING",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected %s to be not found, got %d", path, recorder.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/orgs/test-org/actions/secrets", nil)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected the /api/v3 prefix to be served, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,180 @@
+package fakegithub
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"gopkg.in/yaml.v3"
+)
+
+// Seed is a YAML, or JSON, file of what the server starts with. Secrets and
+// variables are written as in the files create reads, with plain values.
+type Seed struct {
+	Users         []string           `yaml:"users"`
+	Organizations []SeedOrganization `yaml:"organizations"`
+}
+
+type SeedOrganization struct {
+	Login        string                  `yaml:"login"`
+	Teams        []string                `yaml:"teams"`
+	Repositories []SeedRepository        `yaml:"repositories"`
+	Secrets      []data.ImportedSecret   `yaml:"secrets"`
+	Variables    []data.ImportedVariable `yaml:"variables"`
+}
+
+// SeedRepository lists, in Files, the content of files of the repository by
+// their path, such as .github/workflows/ci.yml.
+type SeedRepository struct {
+	Name         string            `yaml:"name"`
+	Visibility   string            `yaml:"visibility"`
+	Environments []string          `yaml:"environments"`
+	Files        map[string]string `yaml:"files"`
+}
+
+// ReadSeed reads a seed file.
+func ReadSeed(fileName string) (*Seed, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var seed Seed
+	if err := yaml.Unmarshal(content, &seed); err != nil {
+		return nil, fmt.Errorf("failed to read seed file %s: %w", fileName, err)
+	}
+	return &seed, nil
+}
+
+// Seed adds the users, organizations, repositories, environments, files,
+// secrets and variables of seed, stopping at the first that can't be added.
+func (s *Server) Seed(seed *Seed) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, login := range seed.Users {
+		if _, ok := s.users[login]; !ok {
+			s.users[login] = s.newID()
+		}
+	}
+	for _, seedOrg := range seed.Organizations {
+		if seedOrg.Login == "" {
+			return errors.New("an organization in the seed has no login")
+		}
+		org := s.addOrganization(seedOrg.Login)
+		for _, slug := range seedOrg.Teams {
+			if _, ok := org.teams[slug]; !ok {
+				org.teams[slug] = s.newID()
+			}
+		}
+		for _, seedRepo := range seedOrg.Repositories {
+			repo := s.addRepository(org.login, seedRepo.Name, seedRepo.Visibility)
+			for _, name := range seedRepo.Environments {
+				s.addEnvironment(repo, name)
+			}
+			for filePath, content := range seedRepo.Files {
+				repo.files[strings.Trim(filePath, "/")] = content
+			}
+		}
+		for _, secret := range seedOrg.Secrets {
+			if err := s.seedSecret(org, secret); err != nil {
+				return fmt.Errorf("secret %s of %s: %w", secret.Name, org.login, err)
+			}
+		}
+		for _, variable := range seedOrg.Variables {
+			if err := s.seedVariable(org, variable); err != nil {
+				return fmt.Errorf("variable %s of %s: %w", variable.Name, org.login, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) seedSecret(org *organization, imported data.ImportedSecret) error {
+	secretType := strings.ToLower(imported.Type)
+	if imported.Level == "Environment" {
+		secretType = "actions"
+	}
+	if !slices.Contains(secretTypes, secretType) {
+		return fmt.Errorf("unknown type %q", imported.Type)
+	}
+	sc, err := s.seedScope(org, imported.Level, imported.RepositoryNames, imported.EnvironmentName)
+	if err != nil {
+		return err
+	}
+	key, err := s.publicKey(sc, secretType)
+	if err != nil {
+		return err
+	}
+	secret := &Secret{
+		Name:      strings.ToUpper(imported.Name),
+		Value:     imported.Value,
+		KeyID:     key.id,
+		CreatedAt: s.now(),
+	}
+	secret.UpdatedAt = secret.CreatedAt
+	if imported.Level == "Organization" {
+		secret.Visibility = imported.Access
+		if secret.SelectedRepositoryIDs, err = seedSelected(org, imported.Access, imported.RepositoryNames); err != nil {
+			return err
+		}
+	}
+	if sc.secrets[secretType] == nil {
+		sc.secrets[secretType] = map[string]*Secret{}
+	}
+	sc.secrets[secretType][secret.Name] = secret
+	return nil
+}
+
+func (s *Server) seedVariable(org *organization, imported data.ImportedVariable) error {
+	sc, err := s.seedScope(org, imported.Level, imported.SelectedRepos, imported.EnvironmentName)
+	if err != nil {
+		return err
+	}
+	variable := &Variable{
+		Name:      strings.ToUpper(imported.Name),
+		Value:     imported.Value,
+		CreatedAt: s.now(),
+	}
+	variable.UpdatedAt = variable.CreatedAt
+	if imported.Level == "Organization" {
+		variable.Visibility = imported.Visibility
+		if variable.SelectedRepositoryIDs, err = seedSelected(org, imported.Visibility, imported.SelectedRepos); err != nil {
+			return err
+		}
+	}
+	sc.variables[variable.Name] = variable
+	return nil
+}
+
+// seedScope returns the scope of a seeded secret or variable, which for the
+// Repository and Environment levels is in its first repository.
+func (s *Server) seedScope(org *organization, level string, repos []string, environment string) (*scope, error) {
+	switch level {
+	case "Organization":
+		return &org.scope, nil
+	case "Repository", "Environment":
+	default:
+		return nil, fmt.Errorf("unknown level %q", level)
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("a repository is required at the %s level", level)
+	}
+	if level == "Repository" {
+		environment = ""
+	} else if environment == "" {
+		return nil, errors.New("an environment_name is required at the Environment level")
+	}
+	return s.scope(org.login, repos[0], environment)
+}
+
+func seedSelected(org *organization, visibility string, repos []string) ([]int, error) {
+	switch visibility {
+	case "all", "private":
+		return nil, nil
+	case "selected":
+		return org.repoIDs(repos)
+	}
+	return nil, fmt.Errorf("visibility must be all, private or selected, not %q", visibility)
+}
@@ -0,0 +1,141 @@
+package fakegithub
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSeed = `users: [octocat]
+organizations:
+  - login: test-org
+    teams: [platform]
+    repositories:
+      - name: repo-a
+        environments: [production]
+        files:
+          .github/workflows/ci.yml: "run: echo ${{ secrets.DEPLOY_KEY }}"
+      - name: repo-b
+        visibility: public
+    secrets:
+      - level: Organization
+        type: Dependabot
+        name: NPM_TOKEN
+        value: npm-value
+        visibility: selected
+        selected_repositories: [repo-b]
+      - level: Environment
+        type: Actions
+        name: DEPLOY_KEY
+        value: deploy-value
+        selected_repositories: [repo-a]
+        environment_name: production
+    variables:
+      - level: Repository
+        name: REGION
+        value: eu
+        selected_repositories: [repo-a]
+`
+
+func TestReadSeed(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "seed.yaml")
+	if err := os.WriteFile(fileName, []byte(testSeed), 0600); err != nil {
+		t.Fatalf("Failed to write seed: %v", err)
+	}
+	seed, err := ReadSeed(fileName)
+	if err != nil {
+		t.Fatalf("Failed to read seed: %v", err)
+	}
+
+	s := New()
+	if err := s.Seed(seed); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	repoB, _ := s.repository("test-org", "repo-b")
+	secret, ok := s.Secret("test-org", "", "", "Dependabot", "NPM_TOKEN")
+	if !ok || secret.Value != "npm-value" || len(secret.SelectedRepositoryIDs) != 1 || secret.SelectedRepositoryIDs[0] != repoB.id {
+		t.Errorf("Unexpected organization secret: %+v", secret)
+	}
+	if secret, _ := s.Secret("test-org", "repo-a", "production", "Actions", "DEPLOY_KEY"); secret.Value != "deploy-value" {
+		t.Errorf("Unexpected environment secret: %+v", secret)
+	}
+	if variable, _ := s.Variable("test-org", "repo-a", "", "REGION"); variable.Value != "eu" {
+		t.Errorf("Unexpected repository variable: %+v", variable)
+	}
+	files, err := newTestAPIGetter(t, s).ReadWorkflowFiles("test-org", "repo-a")
+	if err != nil || len(files) != 1 || files[0].Content != "run: echo ${{ secrets.DEPLOY_KEY }}" {
+		t.Errorf("Expected the seeded workflow, got %+v and %v", files, err)
+	}
+	if s.AddUser("octocat") == 0 || s.AddTeam("test-org", "platform") == 0 {
+		t.Error("Expected the user and team to be seeded")
+	}
+
+	// Seeded secrets are listed like written ones
+	state, err := newTestAPIGetter(t, s).ReadOrgSecrets("test-org", 2)
+	if err != nil {
+		t.Fatalf("Failed to read secrets: %v", err)
+	}
+	if len(state) != 2 {
+		t.Errorf("Expected 2 secrets, got %+v", state)
+	}
+}
+
+func TestReadSeedErrors(t *testing.T) {
+	if _, err := ReadSeed(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected an error reading a missing file")
+	}
+	fileName := filepath.Join(t.TempDir(), "seed.yaml")
+	if err := os.WriteFile(fileName, []byte("organizations: {"), 0600); err != nil {
+		t.Fatalf("Failed to write seed: %v", err)
+	}
+	if _, err := ReadSeed(fileName); err == nil {
+		t.Error("Expected an error reading invalid YAML")
+	}
+}
+
+func TestSeedErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		seed string
+		want string
+	}{
+		{"no login", "organizations: [{teams: [a]}]", "has no login"},
+		{
+			"unknown repository",
+			"organizations: [{login: o, secrets: [{level: Repository, type: Actions, name: A, selected_repositories: [r]}]}]",
+			"repository o/r not found",
+		},
+		{
+			"unknown type",
+			"organizations: [{login: o, secrets: [{level: Organization, type: Packages, name: A, visibility: all}]}]",
+			"unknown type",
+		},
+		{
+			"no environment",
+			"organizations: [{login: o, repositories: [{name: r}], variables: [{level: Environment, name: A, selected_repositories: [r]}]}]",
+			"environment_name is required",
+		},
+		{
+			"unknown visibility",
+			"organizations: [{login: o, variables: [{level: Organization, name: A, visibility: public}]}]",
+			"visibility must be",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "seed.yaml")
+			if err := os.WriteFile(fileName, []byte(tc.seed), 0600); err != nil {
+				t.Fatalf("Failed to write seed: %v", err)
+			}
+			seed, err := ReadSeed(fileName)
+			if err != nil {
+				t.Fatalf("Failed to read seed: %v", err)
+			}
+			err = New().Seed(seed)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Expected an error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
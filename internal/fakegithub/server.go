@@ -0,0 +1,437 @@
+package fakegithub
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// Hostname is the host to pass as --hostname so requests go over plain HTTP
+// to api.github.localhost, which a proxy such as the dev server can answer.
+const Hostname = "github.localhost"
+
+// Types of secret, as the REST API names them in paths
+var secretTypes = []string{"actions", "codespaces", "dependabot"}
+
+// Server is an in-memory GitHub holding the organizations, repositories,
+// environments, secrets and variables the commands read and write. It
+// answers the REST and GraphQL requests of utils.GitHubGetter, decrypting
+// each secret written with the private key of its scope, so tests can
+// check the values GitHub would have stored.
+type Server struct {
+	mu     sync.Mutex
+	orgs   map[string]*organization
+	users  map[string]int
+	nextID int
+	now    func() time.Time
+}
+
+type organization struct {
+	login string
+	scope
+	repos map[string]*repository
+	teams map[string]int
+}
+
+type repository struct {
+	id         int
+	name       string
+	visibility string
+	updatedAt  time.Time
+	scope
+	environments map[string]*environment
+	files        map[string]string
+}
+
+type environment struct {
+	data.Environment
+	scope
+	branchPolicies []data.BranchPolicy
+}
+
+// scope holds the secrets, variables and public keys of an organization,
+// repository or environment. Names are kept in upper case, as GitHub does.
+type scope struct {
+	secrets   map[string]map[string]*Secret
+	variables map[string]*Variable
+	keys      map[string]*keyPair
+}
+
+// Secret is a secret as the server stores it, with its value decrypted.
+// Visibility and SelectedRepositoryIDs are only set for organization
+// secrets.
+type Secret struct {
+	Name                  string
+	Value                 string
+	KeyID                 string
+	Visibility            string
+	SelectedRepositoryIDs []int
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+// Variable is a variable as the server stores it. Visibility and
+// SelectedRepositoryIDs are only set for organization variables.
+type Variable struct {
+	Name                  string
+	Value                 string
+	Visibility            string
+	SelectedRepositoryIDs []int
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+type keyPair struct {
+	id      string
+	public  *[32]byte
+	private *[32]byte
+}
+
+func New() *Server {
+	return &Server{
+		orgs:   map[string]*organization{},
+		users:  map[string]int{},
+		nextID: 1000,
+		now:    func() time.Time { return time.Now().UTC().Truncate(time.Second) },
+	}
+}
+
+func newScope() scope {
+	return scope{
+		secrets:   map[string]map[string]*Secret{},
+		variables: map[string]*Variable{},
+		keys:      map[string]*keyPair{},
+	}
+}
+
+func (s *Server) newID() int {
+	s.nextID++
+	return s.nextID
+}
+
+// AddOrganization adds an empty organization, doing nothing when it exists.
+func (s *Server) AddOrganization(login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addOrganization(login)
+}
+
+func (s *Server) addOrganization(login string) *organization {
+	if org, ok := s.orgs[login]; ok {
+		return org
+	}
+	org := &organization{
+		login: login,
+		scope: newScope(),
+		repos: map[string]*repository{},
+		teams: map[string]int{},
+	}
+	s.orgs[login] = org
+	return org
+}
+
+// AddRepository adds a repository to owner, adding the organization when
+// needed, and returns its ID. Visibility defaults to private.
+func (s *Server) AddRepository(owner string, name string, visibility string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addRepository(owner, name, visibility).id
+}
+
+func (s *Server) addRepository(owner string, name string, visibility string) *repository {
+	org := s.addOrganization(owner)
+	if repo, ok := org.repos[name]; ok {
+		return repo
+	}
+	if visibility == "" {
+		visibility = "private"
+	}
+	repo := &repository{
+		id:           s.newID(),
+		name:         name,
+		visibility:   strings.ToLower(visibility),
+		updatedAt:    s.now(),
+		scope:        newScope(),
+		environments: map[string]*environment{},
+		files:        map[string]string{},
+	}
+	org.repos[name] = repo
+	return repo
+}
+
+// AddEnvironment adds an environment without protection rules to a
+// repository of owner.
+func (s *Server) AddEnvironment(owner string, repo string, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repository(owner, repo)
+	if err != nil {
+		return err
+	}
+	s.addEnvironment(r, name)
+	return nil
+}
+
+func (s *Server) addEnvironment(repo *repository, name string) *environment {
+	if env, ok := repo.environments[name]; ok {
+		return env
+	}
+	now := s.now()
+	env := &environment{
+		Environment: data.Environment{
+			ID:              s.newID(),
+			Name:            name,
+			ProtectionRules: []data.ProtectionRule{},
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		},
+		scope: newScope(),
+	}
+	repo.environments[name] = env
+	return env
+}
+
+// AddFile adds a file to the default branch of a repository of owner, at a
+// path relative to its root, such as .github/workflows/ci.yml.
+func (s *Server) AddFile(owner string, repo string, filePath string, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repository(owner, repo)
+	if err != nil {
+		return err
+	}
+	r.files[strings.Trim(filePath, "/")] = content
+	return nil
+}
+
+// AddUser adds a user that can review deployments and returns its ID.
+func (s *Server) AddUser(login string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.users[login]; ok {
+		return id
+	}
+	s.users[login] = s.newID()
+	return s.users[login]
+}
+
+// AddTeam adds a team to owner and returns its ID.
+func (s *Server) AddTeam(owner string, slug string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	org := s.addOrganization(owner)
+	if id, ok := org.teams[slug]; ok {
+		return id
+	}
+	org.teams[slug] = s.newID()
+	return org.teams[slug]
+}
+
+// Secret returns a secret of secretType, such as Actions, in owner, in a
+// repository of owner when repo is set, or in an environment of the
+// repository when environment is also set.
+func (s *Server) Secret(owner string, repo string, environment string, secretType string, name string) (Secret, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, err := s.scope(owner, repo, environment)
+	if err != nil {
+		return Secret{}, false
+	}
+	secret, ok := sc.secrets[strings.ToLower(secretType)][strings.ToUpper(name)]
+	if !ok {
+		return Secret{}, false
+	}
+	return *secret, true
+}
+
+// Variable returns a variable of owner, a repository or an environment, as
+// Secret finds secrets.
+func (s *Server) Variable(owner string, repo string, environment string, name string) (Variable, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, err := s.scope(owner, repo, environment)
+	if err != nil {
+		return Variable{}, false
+	}
+	variable, ok := sc.variables[strings.ToUpper(name)]
+	if !ok {
+		return Variable{}, false
+	}
+	return *variable, true
+}
+
+// Environment returns an environment of a repository of owner, with its
+// protection rules as the REST API lists them.
+func (s *Server) Environment(owner string, repo string, name string) (data.Environment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repository(owner, repo)
+	if err != nil {
+		return data.Environment{}, false
+	}
+	env, ok := r.environments[name]
+	if !ok {
+		return data.Environment{}, false
+	}
+	return env.Environment, true
+}
+
+// BranchPolicies returns the deployment branch policies of an environment.
+func (s *Server) BranchPolicies(owner string, repo string, environment string) []data.BranchPolicy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repository(owner, repo)
+	if err != nil {
+		return nil
+	}
+	if env, ok := r.environments[environment]; ok {
+		return append([]data.BranchPolicy(nil), env.branchPolicies...)
+	}
+	return nil
+}
+
+// RotatePublicKey replaces the public key of secretType in a scope, found as
+// Secret finds it, so secrets encrypted with the previous key are refused.
+func (s *Server) RotatePublicKey(owner string, repo string, environment string, secretType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, err := s.scope(owner, repo, environment)
+	if err != nil {
+		return err
+	}
+	key, err := s.generateKey()
+	if err != nil {
+		return err
+	}
+	sc.keys[strings.ToLower(secretType)] = key
+	return nil
+}
+
+func (s *Server) repository(owner string, repo string) (*repository, error) {
+	org, ok := s.orgs[owner]
+	if !ok {
+		return nil, fmt.Errorf("organization %s not found", owner)
+	}
+	r, ok := org.repos[repo]
+	if !ok {
+		return nil, fmt.Errorf("repository %s/%s not found", owner, repo)
+	}
+	return r, nil
+}
+
+func (s *Server) scope(owner string, repo string, environment string) (*scope, error) {
+	if repo == "" {
+		org, ok := s.orgs[owner]
+		if !ok {
+			return nil, fmt.Errorf("organization %s not found", owner)
+		}
+		return &org.scope, nil
+	}
+	r, err := s.repository(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	if environment == "" {
+		return &r.scope, nil
+	}
+	env, ok := r.environments[environment]
+	if !ok {
+		return nil, fmt.Errorf("environment %s not found in %s/%s", environment, owner, repo)
+	}
+	return &env.scope, nil
+}
+
+// publicKey returns the key secrets of secretType are encrypted with in sc,
+// generating it the first time it is asked for.
+func (s *Server) publicKey(sc *scope, secretType string) (*keyPair, error) {
+	if key, ok := sc.keys[secretType]; ok {
+		return key, nil
+	}
+	key, err := s.generateKey()
+	if err != nil {
+		return nil, err
+	}
+	sc.keys[secretType] = key
+	return key, nil
+}
+
+func (s *Server) generateKey() (*keyPair, error) {
+	public, private, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate a public key: %w", err)
+	}
+	return &keyPair{id: strconv.Itoa(s.newID()), public: public, private: private}, nil
+}
+
+// open decrypts a value sealed with the public key, as GitHub does when a
+// secret is written.
+func (k *keyPair) open(encryptedValue string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encryptedValue)
+	if err != nil {
+		return "", errors.New("encrypted_value is not base64 encoded")
+	}
+	value, ok := box.OpenAnonymous(nil, sealed, k.public, k.private)
+	if !ok {
+		return "", errors.New("encrypted_value could not be decrypted with the public key")
+	}
+	return string(value), nil
+}
+
+func encodeKey(key *[32]byte) string {
+	return base64.StdEncoding.EncodeToString(key[:])
+}
+
+// repoIDs returns the IDs of the named repositories of org, failing on the
+// first that does not exist.
+func (org *organization) repoIDs(names []string) ([]int, error) {
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		repo, ok := org.repos[name]
+		if !ok {
+			return nil, fmt.Errorf("repository %s/%s not found", org.login, name)
+		}
+		ids = append(ids, repo.id)
+	}
+	return ids, nil
+}
+
+// sortedRepos returns the repositories of org in order of name, as the
+// GraphQL API lists them.
+func (org *organization) sortedRepos() []*repository {
+	repos := make([]*repository, 0, len(org.repos))
+	for _, repo := range org.repos {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].name < repos[j].name
+	})
+	return repos
+}
+
+func (org *organization) repoByID(id int) *repository {
+	for _, repo := range org.repos {
+		if repo.id == id {
+			return repo
+		}
+	}
+	return nil
+}
+
+// writeError writes an error in the form of the REST API, which go-gh
+// reads the message of.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{
+		"message":           message,
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
@@ -0,0 +1,199 @@
+package fakegithub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/utils"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestAPIGetter returns a getter whose requests are answered by s,
+// without a listener.
+func newTestAPIGetter(t *testing.T, s *Server) *utils.APIGetter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return utils.NewAPIGetter(utils.NewGitHubGetter(gqlClient, restClient))
+}
+
+func TestAddRepository(t *testing.T) {
+	s := New()
+	id := s.AddRepository("test-org", "repo-a", "")
+	if again := s.AddRepository("test-org", "repo-a", "public"); again != id {
+		t.Errorf("Expected adding repo-a again to return ID %d, got %d", id, again)
+	}
+	if other := s.AddRepository("test-org", "repo-b", "Public"); other == id {
+		t.Errorf("Expected repo-b to get a new ID, got %d", other)
+	}
+
+	repo, err := s.repository("test-org", "repo-a")
+	if err != nil {
+		t.Fatalf("Expected repo-a to exist: %v", err)
+	}
+	if repo.visibility != "private" {
+		t.Errorf("Expected the default visibility to be private, got %s", repo.visibility)
+	}
+	repo, _ = s.repository("test-org", "repo-b")
+	if repo.visibility != "public" {
+		t.Errorf("Expected the visibility to be lower case, got %s", repo.visibility)
+	}
+}
+
+func TestAddEnvironment(t *testing.T) {
+	s := New()
+	if err := s.AddEnvironment("test-org", "repo-a", "production"); err == nil {
+		t.Error("Expected an error adding an environment to a missing repository")
+	}
+	s.AddRepository("test-org", "repo-a", "private")
+	if err := s.AddEnvironment("test-org", "repo-a", "production"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	env, ok := s.Environment("test-org", "repo-a", "production")
+	if !ok {
+		t.Fatal("Expected the environment to exist")
+	}
+	if env.Name != "production" || len(env.ProtectionRules) != 0 {
+		t.Errorf("Unexpected environment: %+v", env)
+	}
+	if _, ok := s.Environment("test-org", "repo-a", "staging"); ok {
+		t.Error("Expected staging not to exist")
+	}
+}
+
+func TestSecretLookup(t *testing.T) {
+	s := New()
+	s.AddRepository("test-org", "repo-a", "private")
+	if err := s.AddEnvironment("test-org", "repo-a", "production"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	g := newTestAPIGetter(t, s)
+
+	secrets := []data.ImportedSecret{
+		{Level: "Organization", Type: "Dependabot", Name: "shared", Value: "org-value", Access: "all"},
+		{Level: "Repository", Type: "Codespaces", Name: "SHARED", Value: "repo-value", RepositoryNames: []string{"repo-a"}},
+		{Level: "Environment", Type: "Actions", Name: "SHARED", Value: "env-value", RepositoryNames: []string{"repo-a"}, EnvironmentName: "production"},
+	}
+	for _, secret := range secrets {
+		if err := g.CreateImportedSecret("test-org", secret); err != nil {
+			t.Fatalf("Failed to create %s secret: %v", secret.Level, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		repo        string
+		environment string
+		secretType  string
+		want        string
+	}{
+		{"organization", "", "", "Dependabot", "org-value"},
+		{"repository", "repo-a", "", "Codespaces", "repo-value"},
+		{"environment", "repo-a", "production", "Actions", "env-value"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			secret, ok := s.Secret("test-org", tc.repo, tc.environment, tc.secretType, "shared")
+			if !ok {
+				t.Fatal("Expected the secret to exist")
+			}
+			if secret.Value != tc.want {
+				t.Errorf("Expected value %q, got %q", tc.want, secret.Value)
+			}
+			if secret.Name != "SHARED" {
+				t.Errorf("Expected the name in upper case, got %s", secret.Name)
+			}
+		})
+	}
+
+	if _, ok := s.Secret("test-org", "repo-a", "", "Actions", "SHARED"); ok {
+		t.Error("Expected no Actions secret in repo-a")
+	}
+	if _, ok := s.Secret("other-org", "", "", "Actions", "SHARED"); ok {
+		t.Error("Expected no secret in a missing organization")
+	}
+}
+
+func TestRotatePublicKey(t *testing.T) {
+	s := New()
+	s.AddRepository("test-org", "repo-a", "private")
+	g := newTestAPIGetter(t, s)
+	secret := data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "TOKEN", Value: "first", RepositoryNames: []string{"repo-a"}}
+	if err := g.CreateImportedSecret("test-org", secret); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	before, _ := s.Secret("test-org", "repo-a", "", "Actions", "TOKEN")
+
+	if err := s.RotatePublicKey("test-org", "repo-a", "", "Actions"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The getter still holds the old key, so the write is refused once and
+	// retried with the new key
+	secret.Value = "second"
+	if err := g.CreateImportedSecret("test-org", secret); err != nil {
+		t.Fatalf("Expected the write to be retried with the rotated key: %v", err)
+	}
+	after, _ := s.Secret("test-org", "repo-a", "", "Actions", "TOKEN")
+	if after.Value != "second" {
+		t.Errorf("Expected value second, got %q", after.Value)
+	}
+	if after.KeyID == before.KeyID {
+		t.Errorf("Expected the secret to be written with a new key, still %s", after.KeyID)
+	}
+
+	if err := s.RotatePublicKey("test-org", "repo-b", "", "Actions"); err == nil {
+		t.Error("Expected an error rotating the key of a missing repository")
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	s := New()
+	if err := s.AddFile("test-org", "repo-a", "action.yml", "name: setup"); err == nil {
+		t.Error("Expected an error adding a file to a missing repository")
+	}
+	s.AddRepository("test-org", "repo-a", "private")
+	if err := s.AddFile("test-org", "repo-a", "/action.yml", "name: setup"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repo, _ := s.repository("test-org", "repo-a")
+	if repo.files["action.yml"] != "name: setup" {
+		t.Errorf("Expected the file at action.yml, got %v", repo.files)
+	}
+}
+
+func TestAddUserAndTeam(t *testing.T) {
+	s := New()
+	user := s.AddUser("octocat")
+	if again := s.AddUser("octocat"); again != user {
+		t.Errorf("Expected the same ID for octocat, got %d and %d", user, again)
+	}
+	team := s.AddTeam("test-org", "platform")
+	if again := s.AddTeam("test-org", "platform"); again != team {
+		t.Errorf("Expected the same ID for platform, got %d and %d", team, again)
+	}
+	if user == team {
+		t.Error("Expected users and teams to have different IDs")
+	}
+}
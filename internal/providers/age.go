@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// ageProvider reads secrets from a local age encrypted YAML, or JSON, file
+// mapping names to values or to maps of fields. The file is decrypted the
+// first time a secret is looked up.
+type ageProvider struct {
+	path          string
+	identityFile  string
+	identityEnv   string
+	passphraseEnv string
+
+	once    sync.Once
+	secrets map[string]interface{}
+	err     error
+}
+
+func newAgeProvider(config ProviderConfig) (*ageProvider, error) {
+	if config.Path == "" {
+		return nil, errors.New("a path is required")
+	}
+	set := 0
+	for _, value := range []string{config.IdentityFile, config.IdentityEnv, config.PassphraseEnv} {
+		if value != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, errors.New("exactly one of identity_file, identity_env or passphrase_env is required")
+	}
+	return &ageProvider{
+		path:          config.Path,
+		identityFile:  config.IdentityFile,
+		identityEnv:   config.IdentityEnv,
+		passphraseEnv: config.PassphraseEnv,
+	}, nil
+}
+
+func (p *ageProvider) Lookup(key string, field string) (string, error) {
+	p.once.Do(p.load)
+	if p.err != nil {
+		return "", p.err
+	}
+	value, ok := p.secrets[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found in %s", key, p.path)
+	}
+	return selectField(key, value, field)
+}
+
+func (p *ageProvider) load() {
+	identities, err := p.identities()
+	if err != nil {
+		p.err = err
+		return
+	}
+	f, err := os.Open(p.path)
+	if err != nil {
+		p.err = err
+		return
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			zap.S().Errorf("Error closing file: %v", err)
+		}
+	}()
+
+	var src io.Reader = bufio.NewReader(f)
+	if start, _ := src.(*bufio.Reader).Peek(len(armor.Header)); string(start) == armor.Header {
+		src = armor.NewReader(src)
+	}
+	plaintext, err := age.Decrypt(src, identities...)
+	if err == nil {
+		var content []byte
+		if content, err = io.ReadAll(plaintext); err == nil {
+			err = yaml.Unmarshal(content, &p.secrets)
+		}
+	}
+	if err != nil {
+		p.err = fmt.Errorf("failed to decrypt %s: %w", p.path, err)
+	}
+}
+
+func (p *ageProvider) identities() ([]age.Identity, error) {
+	switch {
+	case p.passphraseEnv != "":
+		passphrase, ok := os.LookupEnv(p.passphraseEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s with the age passphrase is not set", p.passphraseEnv)
+		}
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Identity{identity}, nil
+	case p.identityEnv != "":
+		keys, ok := os.LookupEnv(p.identityEnv)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s with the age identity is not set", p.identityEnv)
+		}
+		identities, err := age.ParseIdentities(strings.NewReader(keys))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the age identity in %s: %w", p.identityEnv, err)
+		}
+		return identities, nil
+	}
+	keys, err := os.ReadFile(expandHome(p.identityFile))
+	if err != nil {
+		return nil, err
+	}
+	identities, err := age.ParseIdentities(bytes.NewReader(keys))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the age identity file %s: %w", p.identityFile, err)
+	}
+	return identities, nil
+}
+
+// expandHome replaces a leading ~ with the home directory of the user.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+const testAgeSecrets = `npm_token: npm-value
+database:
+  username: app
+  password: pa55
+`
+
+// writeAgeFile encrypts content to recipient, armored when armored is set.
+func writeAgeFile(t *testing.T, recipient age.Recipient, content string, armored bool) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var dst io.Writer = &buf
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(&buf)
+		dst = armorWriter
+	}
+	w, err := age.Encrypt(dst, recipient)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	_, _ = io.WriteString(w, content)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if armorWriter != nil {
+		_ = armorWriter.Close()
+	}
+	fileName := filepath.Join(t.TempDir(), "secrets.age")
+	if err := os.WriteFile(fileName, buf.Bytes(), 0600); err != nil {
+		t.Fatalf("Failed to write age file: %v", err)
+	}
+	return fileName
+}
+
+func TestAgeProvider(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Failed to generate identity: %v", err)
+	}
+	identityFile := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(identityFile, []byte("# created: today\n"+identity.String()+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write identity: %v", err)
+	}
+	t.Setenv("SEVA_TEST_AGE_IDENTITY", identity.String())
+
+	testCases := []struct {
+		name    string
+		armored bool
+		config  ProviderConfig
+	}{
+		{"identity file", false, ProviderConfig{IdentityFile: identityFile}},
+		{"armored with identity env", true, ProviderConfig{IdentityEnv: "SEVA_TEST_AGE_IDENTITY"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config.Path = writeAgeFile(t, identity.Recipient(), testAgeSecrets, tc.armored)
+			provider, err := newAgeProvider(tc.config)
+			if err != nil {
+				t.Fatalf("newAgeProvider() error = %v", err)
+			}
+			lookups := []struct{ key, field, want string }{
+				{"npm_token", "", "npm-value"},
+				{"database", "password", "pa55"},
+			}
+			for _, lookup := range lookups {
+				got, err := provider.Lookup(lookup.key, lookup.field)
+				if err != nil || got != lookup.want {
+					t.Errorf("Lookup(%s, %s): expected %q, got %q, %v", lookup.key, lookup.field, lookup.want, got, err)
+				}
+			}
+			if _, err := provider.Lookup("missing", ""); err == nil || !strings.Contains(err.Error(), "secret missing not found in") {
+				t.Errorf("Expected a not found error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAgeProviderPassphrase(t *testing.T) {
+	recipient, err := age.NewScryptRecipient("correct horse")
+	if err != nil {
+		t.Fatalf("Failed to create recipient: %v", err)
+	}
+	recipient.SetWorkFactor(10)
+	path := writeAgeFile(t, recipient, testAgeSecrets, false)
+
+	t.Setenv("SEVA_TEST_AGE_PASSPHRASE", "correct horse")
+	provider, _ := newAgeProvider(ProviderConfig{Path: path, PassphraseEnv: "SEVA_TEST_AGE_PASSPHRASE"})
+	if got, err := provider.Lookup("npm_token", ""); err != nil || got != "npm-value" {
+		t.Errorf("Expected npm-value, got %q, %v", got, err)
+	}
+
+	t.Setenv("SEVA_TEST_AGE_PASSPHRASE", "wrong")
+	provider, _ = newAgeProvider(ProviderConfig{Path: path, PassphraseEnv: "SEVA_TEST_AGE_PASSPHRASE"})
+	if _, err := provider.Lookup("npm_token", ""); err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+		t.Errorf("Expected a decryption error, got %v", err)
+	}
+
+	provider, _ = newAgeProvider(ProviderConfig{Path: path, PassphraseEnv: "SEVA_TEST_UNSET_PASSPHRASE"})
+	if _, err := provider.Lookup("npm_token", ""); err == nil || err.Error() != "environment variable SEVA_TEST_UNSET_PASSPHRASE with the age passphrase is not set" {
+		t.Errorf("Expected an error without a passphrase, got %v", err)
+	}
+}
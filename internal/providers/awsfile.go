@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// awsSecret is a secret in the form aws secretsmanager get-secret-value
+// prints it.
+type awsSecret struct {
+	ARN          string `json:"ARN"`
+	Name         string `json:"Name"`
+	SecretString string `json:"SecretString"`
+}
+
+// awsSecretsFileProvider reads secrets from a JSON file of AWS Secrets
+// Manager secrets, which is read the first time a secret is looked up.
+type awsSecretsFileProvider struct {
+	path string
+
+	once    sync.Once
+	secrets map[string]awsSecret
+	err     error
+}
+
+func newAWSSecretsFileProvider(config ProviderConfig) (*awsSecretsFileProvider, error) {
+	if config.Path == "" {
+		return nil, errors.New("a path is required")
+	}
+	return &awsSecretsFileProvider{path: config.Path}, nil
+}
+
+// Lookup finds a secret by name or ARN. Without a field its SecretString is
+// returned as is, otherwise the SecretString is read as a JSON object.
+func (p *awsSecretsFileProvider) Lookup(key string, field string) (string, error) {
+	p.once.Do(p.load)
+	if p.err != nil {
+		return "", p.err
+	}
+	secret, ok := p.secrets[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found in %s", key, p.path)
+	}
+	if field == "" {
+		return secret.SecretString, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, remove #%s", key, field)
+	}
+	return selectField(key, fields, field)
+}
+
+// load reads the file, which holds a single secret, a list of secrets, or
+// the output of aws secretsmanager batch-get-secret-value.
+func (p *awsSecretsFileProvider) load() {
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		p.err = err
+		return
+	}
+	var secrets []awsSecret
+	trimmed := strings.TrimSpace(string(content))
+	if strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(content, &secrets)
+	} else {
+		var document struct {
+			awsSecret
+			SecretValues []awsSecret `json:"SecretValues"`
+		}
+		err = json.Unmarshal(content, &document)
+		secrets = document.SecretValues
+		if document.Name != "" {
+			secrets = append(secrets, document.awsSecret)
+		}
+	}
+	if err != nil {
+		p.err = fmt.Errorf("failed to read AWS secrets file %s: %w", p.path, err)
+		return
+	}
+
+	p.secrets = map[string]awsSecret{}
+	for _, secret := range secrets {
+		p.secrets[secret.Name] = secret
+		if secret.ARN != "" {
+			p.secrets[secret.ARN] = secret
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fileName, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return fileName
+}
+
+func TestAWSSecretsFileProvider(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"list", `[{"ARN":"arn:aws:secretsmanager:eu-west-1:123:secret:prod/db-AbCd","Name":"prod/db","SecretString":"{\"username\":\"app\",\"password\":\"pa55\"}"},{"Name":"prod/npm","SecretString":"npm-value"}]`},
+		{"batch", `{"SecretValues":[{"ARN":"arn:aws:secretsmanager:eu-west-1:123:secret:prod/db-AbCd","Name":"prod/db","SecretString":"{\"username\":\"app\",\"password\":\"pa55\"}"},{"Name":"prod/npm","SecretString":"npm-value"}],"Errors":[]}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, err := newAWSSecretsFileProvider(ProviderConfig{Path: writeTestFile(t, "secrets.json", tc.content)})
+			if err != nil {
+				t.Fatalf("newAWSSecretsFileProvider() error = %v", err)
+			}
+			lookups := []struct{ key, field, want string }{
+				{"prod/db", "password", "pa55"},
+				{"arn:aws:secretsmanager:eu-west-1:123:secret:prod/db-AbCd", "username", "app"},
+				{"prod/npm", "", "npm-value"},
+			}
+			for _, lookup := range lookups {
+				got, err := provider.Lookup(lookup.key, lookup.field)
+				if err != nil || got != lookup.want {
+					t.Errorf("Lookup(%s, %s): expected %q, got %q, %v", lookup.key, lookup.field, lookup.want, got, err)
+				}
+			}
+			if _, err := provider.Lookup("prod/npm", "token"); err == nil || err.Error() != "secret prod/npm is not a JSON object, remove #token" {
+				t.Errorf("Expected an error for a field of a plain secret, got %v", err)
+			}
+			if _, err := provider.Lookup("prod/missing", ""); err == nil {
+				t.Error("Expected an error for a missing secret")
+			}
+		})
+	}
+}
+
+func TestAWSSecretsFileProviderSingle(t *testing.T) {
+	provider, _ := newAWSSecretsFileProvider(ProviderConfig{Path: writeTestFile(t, "db.json", `{"Name":"prod/db","SecretString":"pa55","VersionId":"1"}`)})
+	got, err := provider.Lookup("prod/db", "")
+	if err != nil || got != "pa55" {
+		t.Errorf("Expected pa55, got %q, %v", got, err)
+	}
+
+	provider, _ = newAWSSecretsFileProvider(ProviderConfig{Path: writeTestFile(t, "bad.json", `{"Name":`)})
+	if _, err := provider.Lookup("prod/db", ""); err == nil {
+		t.Error("Expected an error for an invalid file")
+	}
+	if _, err := newAWSSecretsFileProvider(ProviderConfig{}); err == nil {
+		t.Error("Expected an error without a path")
+	}
+}
@@ -0,0 +1,197 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Types of secret store a provider can read from
+const (
+	TypeVault          = "vault"
+	TypeAWSSecretsFile = "aws-secrets-file"
+	TypeAge            = "age"
+)
+
+// Provider looks up secret values kept in an external secret store.
+type Provider interface {
+	// Lookup returns the value of the secret at key or, when field is not
+	// empty, of that field of the secret. Errors never include values.
+	Lookup(key string, field string) (string, error)
+}
+
+// Config is a file configuring the secret stores values are read from.
+type Config struct {
+	Providers []ProviderConfig `yaml:"providers"`
+}
+
+// ProviderConfig names a secret store and says how to reach it. Only the
+// fields of its type are used.
+type ProviderConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+
+	// Vault KV
+	Address   string `yaml:"address"`
+	Mount     string `yaml:"mount"`
+	KVVersion int    `yaml:"kv_version"`
+	Namespace string `yaml:"namespace"`
+	TokenEnv  string `yaml:"token_env"`
+
+	// AWS Secrets Manager JSON file and age encrypted file
+	Path string `yaml:"path"`
+
+	// age encrypted file
+	IdentityFile  string `yaml:"identity_file"`
+	IdentityEnv   string `yaml:"identity_env"`
+	PassphraseEnv string `yaml:"passphrase_env"`
+}
+
+// Registry resolves references to the providers of a config by name.
+type Registry struct {
+	providers map[string]Provider
+}
+
+// LoadRegistry reads a provider config file. Providers only reach their
+// store on the first lookup, so loading a config never needs credentials.
+func LoadRegistry(fileName string) (*Registry, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to read provider config %s: %w", fileName, err)
+	}
+	registry, err := NewRegistry(config.Providers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provider config %s: %w", fileName, err)
+	}
+	return registry, nil
+}
+
+// NewRegistry returns a registry of the configured providers, reporting
+// every provider that is not configured correctly.
+func NewRegistry(configs []ProviderConfig) (*Registry, error) {
+	registry := &Registry{providers: map[string]Provider{}}
+	var errs []error
+	for i, config := range configs {
+		if config.Name == "" {
+			errs = append(errs, fmt.Errorf("provider %d: a name is required", i+1))
+			continue
+		}
+		if _, ok := registry.providers[config.Name]; ok {
+			errs = append(errs, fmt.Errorf("provider %s: the name is used more than once", config.Name))
+			continue
+		}
+		provider, err := newProvider(config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("provider %s: %w", config.Name, err))
+			continue
+		}
+		registry.providers[config.Name] = provider
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return registry, nil
+}
+
+func newProvider(config ProviderConfig) (Provider, error) {
+	switch config.Type {
+	case TypeVault:
+		return newVaultProvider(config)
+	case TypeAWSSecretsFile:
+		return newAWSSecretsFileProvider(config)
+	case TypeAge:
+		return newAgeProvider(config)
+	}
+	return nil, fmt.Errorf("unknown type %q, expected %s, %s or %s", config.Type, TypeVault, TypeAWSSecretsFile, TypeAge)
+}
+
+// Register adds a provider, such as one for a store without a built in
+// type, replacing any provider of the same name.
+func (r *Registry) Register(name string, provider Provider) {
+	r.providers[name] = provider
+}
+
+// Names returns the names of the providers in alphabetical order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the value a reference of the form provider:key#field
+// refers to. A nil registry has no providers.
+func (r *Registry) Lookup(ref string) (string, error) {
+	name, key, field, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	if r == nil || len(r.providers) == 0 {
+		return "", fmt.Errorf("no secret store providers are configured to look up %s, use --providers-config", name)
+	}
+	provider, ok := r.providers[name]
+	if !ok {
+		return "", fmt.Errorf("unknown secret store provider %q, expected one of %s", name, strings.Join(r.Names(), ", "))
+	}
+	return provider.Lookup(key, field)
+}
+
+// ParseReference splits a reference of the form provider:key#field, where
+// the field is optional.
+func ParseReference(ref string) (string, string, string, error) {
+	name, rest, ok := strings.Cut(ref, ":")
+	if !ok || name == "" {
+		return "", "", "", fmt.Errorf("secret store reference %q must be of the form provider:key#field", ref)
+	}
+	key, field, _ := strings.Cut(rest, "#")
+	if key == "" {
+		return "", "", "", fmt.Errorf("secret store reference %q is missing the key of the secret", ref)
+	}
+	return name, key, field, nil
+}
+
+// selectField returns a secret value that is either a string, when no field
+// is named, or a map of fields. A map with a single field doesn't need the
+// field to be named.
+func selectField(key string, value interface{}, field string) (string, error) {
+	switch value := value.(type) {
+	case string:
+		if field != "" {
+			return "", fmt.Errorf("secret %s has no fields, remove #%s", key, field)
+		}
+		return value, nil
+	case map[string]interface{}:
+		if field == "" {
+			if len(value) != 1 {
+				names := make([]string, 0, len(value))
+				for name := range value {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				return "", fmt.Errorf("secret %s has the fields %s, name one with #field", key, strings.Join(names, ", "))
+			}
+			for name := range value {
+				field = name
+			}
+		}
+		fieldValue, ok := value[field]
+		if !ok {
+			return "", fmt.Errorf("secret %s has no field %s", key, field)
+		}
+		if text, ok := fieldValue.(string); ok {
+			return text, nil
+		}
+		return "", fmt.Errorf("field %s of secret %s is not a string", field, key)
+	}
+	return "", fmt.Errorf("secret %s is neither a string nor a map of fields", key)
+}
@@ -0,0 +1,157 @@
+package providers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type staticProvider map[string]string
+
+func (p staticProvider) Lookup(key string, field string) (string, error) {
+	value, ok := p[key+"#"+field]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+func TestParseReference(t *testing.T) {
+	testCases := []struct {
+		ref       string
+		wantName  string
+		wantKey   string
+		wantField string
+		wantErr   string
+	}{
+		{"vault:ci/npm#token", "vault", "ci/npm", "token", ""},
+		{"aws:prod/db", "aws", "prod/db", "", ""},
+		{"aws:arn:aws:secretsmanager:eu-west-1:123:secret:db#password", "aws", "arn:aws:secretsmanager:eu-west-1:123:secret:db", "password", ""},
+		{"vault", "", "", "", "must be of the form provider:key#field"},
+		{":ci/npm", "", "", "", "must be of the form provider:key#field"},
+		{"vault:#token", "", "", "", "is missing the key of the secret"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.ref, func(t *testing.T) {
+			name, key, field, err := ParseReference(tc.ref)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil || name != tc.wantName || key != tc.wantKey || field != tc.wantField {
+				t.Errorf("Expected %s, %s, %s, got %s, %s, %s, %v", tc.wantName, tc.wantKey, tc.wantField, name, key, field, err)
+			}
+		})
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "providers.yaml")
+	config := `providers:
+  - name: vault
+    type: vault
+    address: http://127.0.0.1:8200
+  - name: aws
+    type: aws-secrets-file
+    path: secrets.json
+  - name: local
+    type: age
+    path: secrets.age
+    identity_env: SEVA_AGE_IDENTITY
+`
+	if err := os.WriteFile(fileName, []byte(config), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	registry, err := LoadRegistry(fileName)
+	if err != nil {
+		t.Fatalf("LoadRegistry() error = %v", err)
+	}
+	if strings.Join(registry.Names(), ",") != "aws,local,vault" {
+		t.Errorf("Expected the three providers, got %v", registry.Names())
+	}
+}
+
+func TestNewRegistryErrors(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	_, err := NewRegistry([]ProviderConfig{
+		{Type: TypeVault},
+		{Name: "vault", Type: TypeVault},
+		{Name: "aws", Type: TypeAWSSecretsFile, Path: "a.json"},
+		{Name: "aws", Type: TypeAWSSecretsFile, Path: "b.json"},
+		{Name: "gcp", Type: "gcp"},
+		{Name: "local", Type: TypeAge, Path: "secrets.age"},
+	})
+	if err == nil {
+		t.Fatal("Expected an error for the invalid providers")
+	}
+	for _, want := range []string{
+		"provider 1: a name is required",
+		"provider vault: an address, or the VAULT_ADDR environment variable, is required",
+		"provider aws: the name is used more than once",
+		`provider gcp: unknown type "gcp", expected vault, aws-secrets-file or age`,
+		"provider local: exactly one of identity_file, identity_env or passphrase_env is required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	registry, err := NewRegistry(nil)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	registry.Register("static", staticProvider{"ci/npm#token": "npm-value"})
+
+	value, err := registry.Lookup("static:ci/npm#token")
+	if err != nil || value != "npm-value" {
+		t.Errorf("Expected npm-value, got %q, %v", value, err)
+	}
+	if _, err := registry.Lookup("vault:ci/npm"); err == nil || err.Error() != `unknown secret store provider "vault", expected one of static` {
+		t.Errorf("Expected an unknown provider error, got %v", err)
+	}
+	var empty *Registry
+	if _, err := empty.Lookup("vault:ci/npm"); err == nil || !strings.Contains(err.Error(), "no secret store providers are configured to look up vault, use --providers-config") {
+		t.Errorf("Expected an error without providers, got %v", err)
+	}
+}
+
+func TestSelectField(t *testing.T) {
+	fields := map[string]interface{}{"user": "ci", "token": "t0k3n", "port": 5432}
+	testCases := []struct {
+		name    string
+		value   interface{}
+		field   string
+		want    string
+		wantErr string
+	}{
+		{"string", "plain", "", "plain", ""},
+		{"string with field", "plain", "token", "", "secret ci/npm has no fields, remove #token"},
+		{"field", fields, "token", "t0k3n", ""},
+		{"single field", map[string]interface{}{"token": "t0k3n"}, "", "t0k3n", ""},
+		{"several fields", fields, "", "", "secret ci/npm has the fields port, token, user, name one with #field"},
+		{"missing field", fields, "password", "", "secret ci/npm has no field password"},
+		{"not a string", fields, "port", "", "field port of secret ci/npm is not a string"},
+		{"list", []interface{}{"a"}, "", "", "secret ci/npm is neither a string nor a map of fields"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := selectField("ci/npm", tc.value, tc.field)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("Expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Errorf("Expected %q, got %q, %v", tc.want, got, err)
+			}
+		})
+	}
+}
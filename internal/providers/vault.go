@@ -0,0 +1,163 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// vaultTimeout bounds each request to Vault, so an unreachable server fails
+// the secret rather than hanging the command.
+const vaultTimeout = 30 * time.Second
+
+// vaultProvider reads secrets from a HashiCorp Vault KV secrets engine,
+// keeping each secret it reads so the fields of a secret are read once.
+// The lock only guards secrets, so different paths are read concurrently.
+type vaultProvider struct {
+	address   string
+	mount     string
+	kvVersion int
+	namespace string
+	tokenEnv  string
+	client    *http.Client
+
+	mu      sync.Mutex
+	secrets map[string]*vaultSecret
+}
+
+// vaultSecret is a secret being, or already, read. ready is closed once
+// data and err are set, so lookups of the same path share one request.
+type vaultSecret struct {
+	ready chan struct{}
+	data  map[string]interface{}
+	err   error
+}
+
+func newVaultProvider(config ProviderConfig) (*vaultProvider, error) {
+	provider := &vaultProvider{
+		address:   config.Address,
+		mount:     strings.Trim(config.Mount, "/"),
+		kvVersion: config.KVVersion,
+		namespace: config.Namespace,
+		tokenEnv:  config.TokenEnv,
+		client:    &http.Client{Timeout: vaultTimeout},
+		secrets:   map[string]*vaultSecret{},
+	}
+	if provider.address == "" {
+		provider.address = os.Getenv("VAULT_ADDR")
+	}
+	if provider.address == "" {
+		return nil, errors.New("an address, or the VAULT_ADDR environment variable, is required")
+	}
+	provider.address = strings.TrimSuffix(provider.address, "/")
+	if provider.mount == "" {
+		provider.mount = "secret"
+	}
+	if provider.kvVersion == 0 {
+		provider.kvVersion = 2
+	}
+	if provider.kvVersion != 1 && provider.kvVersion != 2 {
+		return nil, fmt.Errorf("unknown kv_version %d, expected 1 or 2", provider.kvVersion)
+	}
+	if provider.tokenEnv == "" {
+		provider.tokenEnv = "VAULT_TOKEN"
+	}
+	return provider, nil
+}
+
+func (p *vaultProvider) Lookup(key string, field string) (string, error) {
+	secret, err := p.read(strings.Trim(key, "/"))
+	if err != nil {
+		return "", err
+	}
+	return selectField(key, secret, field)
+}
+
+// read returns the secret at path, reading it from Vault when it has not
+// been already. A failed read is not kept, so the next lookup tries again.
+func (p *vaultProvider) read(path string) (map[string]interface{}, error) {
+	p.mu.Lock()
+	if cached, ok := p.secrets[path]; ok {
+		p.mu.Unlock()
+		<-cached.ready
+		return cached.data, cached.err
+	}
+	cached := &vaultSecret{ready: make(chan struct{})}
+	p.secrets[path] = cached
+	p.mu.Unlock()
+
+	cached.data, cached.err = p.fetch(path)
+	if cached.err != nil {
+		p.mu.Lock()
+		delete(p.secrets, path)
+		p.mu.Unlock()
+	}
+	close(cached.ready)
+	return cached.data, cached.err
+}
+
+func (p *vaultProvider) fetch(path string) (map[string]interface{}, error) {
+	token, ok := os.LookupEnv(p.tokenEnv)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %s with the Vault token is not set", p.tokenEnv)
+	}
+	url := fmt.Sprintf("%s/v1/%s/%s", p.address, p.mount, path)
+	if p.kvVersion == 2 {
+		url = fmt.Sprintf("%s/v1/%s/data/%s", p.address, p.mount, path)
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from Vault: %w", path, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("secret %s not found in Vault mount %s", path, p.mount)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to read %s from Vault: %s", path, resp.Status)
+	}
+
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode %s from Vault: %w", path, err)
+	}
+	var secret map[string]interface{}
+	if p.kvVersion == 2 {
+		var versioned struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		err = json.Unmarshal(response.Data, &versioned)
+		secret = versioned.Data
+	} else {
+		err = json.Unmarshal(response.Data, &secret)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s from Vault: %w", path, err)
+	}
+	if secret == nil {
+		// KV version 2 returns no data for a deleted version
+		return nil, fmt.Errorf("secret %s in Vault mount %s has no data", path, p.mount)
+	}
+	return secret, nil
+}
@@ -0,0 +1,170 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newVaultDevServer stands in for a Vault dev server, serving the KV
+// secrets in secrets by the path of their API request to the token root.
+func newVaultDevServer(t *testing.T, secrets map[string]string, requests *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			*requests++
+		}
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		body, ok := secrets[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultProviderKV2(t *testing.T) {
+	var requests int
+	server := newVaultDevServer(t, map[string]string{
+		"/v1/secret/data/ci/npm": `{"data":{"data":{"token":"npm-value","user":"ci"},"metadata":{"version":3}}}`,
+		"/v1/secret/data/ci/old": `{"data":{"data":null,"metadata":{"deletion_time":"2024-01-01T00:00:00Z"}}}`,
+	}, &requests)
+	t.Setenv("VAULT_TOKEN", "root")
+	provider, err := newVaultProvider(ProviderConfig{Address: server.URL + "/"})
+	if err != nil {
+		t.Fatalf("newVaultProvider() error = %v", err)
+	}
+
+	for _, field := range []string{"token", "user"} {
+		if _, err := provider.Lookup("ci/npm", field); err != nil {
+			t.Fatalf("Lookup() error = %v", err)
+		}
+	}
+	value, _ := provider.Lookup("/ci/npm", "token")
+	if value != "npm-value" {
+		t.Errorf("Expected npm-value, got %q", value)
+	}
+	if requests != 1 {
+		t.Errorf("Expected the secret to be read once, got %d requests", requests)
+	}
+
+	if _, err := provider.Lookup("ci/missing", ""); err == nil || err.Error() != "secret ci/missing not found in Vault mount secret" {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	if _, err := provider.Lookup("ci/old", ""); err == nil || err.Error() != "secret ci/old in Vault mount secret has no data" {
+		t.Errorf("Expected an error for a deleted secret, got %v", err)
+	}
+}
+
+func TestVaultProviderConcurrentReads(t *testing.T) {
+	release := make(chan struct{})
+	var slowRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/secret/data/ci/slow" {
+			slowRequests.Add(1)
+			<-release
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"token":"value"}}}`))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("VAULT_TOKEN", "root")
+	provider, err := newVaultProvider(ProviderConfig{Address: server.URL})
+	if err != nil {
+		t.Fatalf("newVaultProvider() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := provider.Lookup("ci/slow", "token"); err != nil {
+				t.Errorf("Lookup() error = %v", err)
+			}
+		}()
+	}
+
+	// Another path is read while the first is still in flight
+	for slowRequests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := provider.Lookup("ci/fast", "token")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Lookup() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected ci/fast to be read while ci/slow is in flight")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := slowRequests.Load(); n != 1 {
+		t.Errorf("Expected concurrent lookups of a path to share one request, got %d", n)
+	}
+}
+
+func TestVaultProviderKV1(t *testing.T) {
+	var namespace string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace = r.Header.Get("X-Vault-Namespace")
+		if r.URL.Path != "/v1/kv/ci/npm" || r.Header.Get("X-Vault-Token") != "team-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"token":"npm-value"}}`))
+	}))
+	defer server.Close()
+	t.Setenv("TEAM_VAULT_TOKEN", "team-token")
+	provider, err := newVaultProvider(ProviderConfig{Address: server.URL, Mount: "/kv/", KVVersion: 1, Namespace: "team-a", TokenEnv: "TEAM_VAULT_TOKEN"})
+	if err != nil {
+		t.Fatalf("newVaultProvider() error = %v", err)
+	}
+
+	value, err := provider.Lookup("ci/npm", "")
+	if err != nil || value != "npm-value" {
+		t.Errorf("Expected npm-value, got %q, %v", value, err)
+	}
+	if namespace != "team-a" {
+		t.Errorf("Expected the namespace header team-a, got %q", namespace)
+	}
+}
+
+func TestVaultProviderErrors(t *testing.T) {
+	server := newVaultDevServer(t, nil, nil)
+
+	provider, _ := newVaultProvider(ProviderConfig{Address: server.URL, TokenEnv: "SEVA_TEST_UNSET_TOKEN"})
+	if _, err := provider.Lookup("ci/npm", ""); err == nil || err.Error() != "environment variable SEVA_TEST_UNSET_TOKEN with the Vault token is not set" {
+		t.Errorf("Expected an error without a token, got %v", err)
+	}
+
+	t.Setenv("VAULT_TOKEN", "wrong")
+	provider, _ = newVaultProvider(ProviderConfig{Address: server.URL})
+	if _, err := provider.Lookup("ci/npm", ""); err == nil || !strings.Contains(err.Error(), "failed to read ci/npm from Vault: 403 Forbidden") {
+		t.Errorf("Expected a permission error, got %v", err)
+	}
+
+	t.Setenv("VAULT_ADDR", server.URL)
+	if _, err := newVaultProvider(ProviderConfig{KVVersion: 3}); err == nil || err.Error() != "unknown kv_version 3, expected 1 or 2" {
+		t.Errorf("Expected a kv_version error, got %v", err)
+	}
+}
@@ -0,0 +1,379 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// ApplyChange is a step in reconciling an organization with a manifest,
+// along with the secret or variable it writes or deletes.
+type ApplyChange struct {
+	Item     data.PlanItem
+	Secret   *data.ImportedSecret
+	Variable *data.ImportedVariable
+}
+
+// ReadManifest reads a YAML, or JSON, manifest of the secrets and variables
+// of an organization, validating its entries in the same way as the rows of
+// a secrets or variables file.
+func ReadManifest(fileName string) (*data.Manifest, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is read as YAML, of which it is a subset
+	var manifest data.Manifest
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", fileName, err)
+	}
+
+	var errs []error
+	secretsTable, err := newDocumentTable(secretRecords(manifest.Secrets), secretSchema, secretFields)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Secrets, err = secretsTable.secrets(validateSecretSettings); err != nil {
+		errs = append(errs, fmt.Errorf("secrets:\n%w", err))
+	}
+	variablesTable, err := newDocumentTable(variableRecords(manifest.Variables), variableSchema, variableFields)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Variables, err = variablesTable.variables(validateVariableSettings); err != nil {
+		errs = append(errs, fmt.Errorf("variables:\n%w", err))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid manifest %s:\n%w", fileName, errors.Join(errs...))
+	}
+	return &manifest, nil
+}
+
+// applyScope is an organization, repository or environment whose secrets
+// and variables the manifest manages.
+type applyScope struct {
+	level       string
+	repo        string
+	environment string
+}
+
+func (s applyScope) secretTypes() []string {
+	if s.level == "Environment" {
+		return []string{"Actions"}
+	}
+	return []string{"Actions", "Codespaces", "Dependabot"}
+}
+
+// PlanApply compares the organization with the manifest, returning a change
+// for each secret and variable in it. With prune, these are followed by a
+// delete for each secret and variable that is not in the manifest, at the
+// organization level and in the repositories and environments the manifest
+// has entries for. Secret values cannot be read back, so an existing secret
+// is only updated when its visibility or selected repositories differ.
+func (g *APIGetter) PlanApply(owner string, manifest *data.Manifest, prune bool) []ApplyChange {
+	p := newPlanner(g, owner)
+	p.onConflict = ConflictUpdate
+	var names []string
+	for _, secret := range manifest.Secrets {
+		names = append(names, secret.RepositoryNames...)
+	}
+	for _, variable := range manifest.Variables {
+		names = append(names, variable.SelectedRepos...)
+	}
+	p.prefetchRepos(names)
+
+	changes := make([]ApplyChange, 0, len(manifest.Secrets)+len(manifest.Variables))
+	for _, secret := range manifest.Secrets {
+		secret := secret
+		changes = append(changes, ApplyChange{Item: p.planApplySecret(&secret), Secret: &secret})
+	}
+	for _, variable := range manifest.Variables {
+		variable := variable
+		item := p.planVariable(variable)
+		if item.Action != data.PlanError && variable.Level == "Organization" && variable.Visibility == "selected" {
+			ids, err := p.selectedRepoIDs(variable.SelectedRepos)
+			if err != nil {
+				item = planError(item, err)
+			}
+			variable.SelectedReposIDs = ids
+		}
+		changes = append(changes, ApplyChange{Item: item, Variable: &variable})
+	}
+	if prune {
+		changes = append(changes, p.planPrune(manifest)...)
+	}
+	return changes
+}
+
+func (p *planner) planApplySecret(secret *data.ImportedSecret) data.PlanItem {
+	repo := firstName(secret.RepositoryNames)
+	item := data.PlanItem{
+		Level:  secret.Level,
+		Type:   secret.Type,
+		Name:   secret.Name,
+		Target: p.target(secret.Level, repo, secret.EnvironmentName),
+	}
+	if err := validateSecretSettings(*secret); err != nil {
+		return planError(item, err)
+	}
+	if secret.Level == "Organization" {
+		if secret.Access == "selected" {
+			ids, err := p.selectedRepoIDs(secret.RepositoryNames)
+			if err != nil {
+				return planError(item, err)
+			}
+			secret.RepositoryIDs = ids
+		}
+	} else if _, err := p.resolveRepo(repo); err != nil {
+		return planError(item, err)
+	}
+
+	existing, err := p.listSecrets(secret.Level, secret.Type, repo, secret.EnvironmentName)
+	if err != nil {
+		return planError(item, err)
+	}
+	current, found := findSecret(existing, secret.Name)
+	switch {
+	case !found:
+		item.Action = data.PlanCreate
+		if secret.Level == "Organization" {
+			item.Details = append(item.Details, describeVisibility(secret.Access, secret.RepositoryNames))
+		}
+	case secret.Level == "Organization":
+		var currentRepos []string
+		if current.Visibility == "selected" {
+			currentRepos, err = p.scopedSecretRepos(secret.Type, current.Name)
+			if err != nil {
+				return planError(item, err)
+			}
+		}
+		item.Details = visibilityChanges(current.Visibility, secret.Access, currentRepos, secret.RepositoryNames)
+		item.Action = data.PlanUpdate
+		if len(item.Details) == 0 {
+			item.Action = data.PlanUnchanged
+		}
+	default:
+		item.Action = data.PlanUnchanged
+	}
+	if item.Action != data.PlanUnchanged && secret.Value == "" {
+		return planError(item, fmt.Errorf("a secret value is required to %s it", item.Action))
+	}
+	return item
+}
+
+// selectedRepoIDs returns the IDs of the named repositories in the
+// organization, as selected organization items are scoped by ID.
+func (p *planner) selectedRepoIDs(names []string) ([]string, error) {
+	var ids []string
+	for _, name := range nonEmpty(names) {
+		id, err := p.resolveRepo(name)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, strconv.Itoa(id))
+	}
+	return ids, nil
+}
+
+// findSecret looks a secret up by name, regardless of case, as GitHub
+// returns secret names upper cased.
+func findSecret(secrets map[string]data.Secret, name string) (data.Secret, bool) {
+	if secret, ok := secrets[name]; ok {
+		return secret, true
+	}
+	for existing, secret := range secrets {
+		if strings.EqualFold(existing, name) {
+			return secret, true
+		}
+	}
+	return data.Secret{}, false
+}
+
+// planPrune returns a delete for each secret and variable in the scopes of
+// the manifest that is not in it, ordered by scope and then name.
+func (p *planner) planPrune(manifest *data.Manifest) []ApplyChange {
+	scopes := []applyScope{{level: "Organization"}}
+	seen := map[applyScope]bool{scopes[0]: true}
+	addScope := func(level string, repos []string, environment string) {
+		scope := applyScope{level: level}
+		if level != "Organization" {
+			scope.repo = firstName(repos)
+			scope.environment = environment
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	wanted := map[string]bool{}
+	for _, secret := range manifest.Secrets {
+		addScope(secret.Level, secret.RepositoryNames, secret.EnvironmentName)
+		wanted[applyKey(secret.Level, secret.Type, firstName(secret.RepositoryNames), secret.EnvironmentName, secret.Name)] = true
+	}
+	for _, variable := range manifest.Variables {
+		addScope(variable.Level, variable.SelectedRepos, variable.EnvironmentName)
+		wanted[applyKey(variable.Level, "Variable", firstName(variable.SelectedRepos), variable.EnvironmentName, variable.Name)] = true
+	}
+
+	var changes []ApplyChange
+	for _, scope := range scopes {
+		target := p.target(scope.level, scope.repo, scope.environment)
+		if scope.level != "Organization" {
+			if _, err := p.resolveRepo(scope.repo); err != nil {
+				// The manifest entries of the repository already report
+				// that it could not be found
+				continue
+			}
+		}
+		for _, secretType := range scope.secretTypes() {
+			zap.S().Debugf("Listing %s secrets to prune in %s", secretType, target)
+			existing, err := p.listSecrets(scope.level, secretType, scope.repo, scope.environment)
+			if err != nil {
+				changes = append(changes, ApplyChange{Item: planError(data.PlanItem{Level: scope.level, Type: secretType, Target: target}, err)})
+				continue
+			}
+			for _, name := range sortedKeys(existing) {
+				if wanted[applyKey(scope.level, secretType, scope.repo, scope.environment, name)] {
+					continue
+				}
+				changes = append(changes, ApplyChange{
+					Item: pruneItem(scope.level, secretType, name, target),
+					Secret: &data.ImportedSecret{
+						Level:           scope.level,
+						Type:            secretType,
+						Name:            name,
+						RepositoryNames: []string{scope.repo},
+						EnvironmentName: scope.environment,
+					},
+				})
+			}
+		}
+
+		zap.S().Debugf("Listing variables to prune in %s", target)
+		existing, err := p.listVariables(scope.level, scope.repo, scope.environment)
+		if err != nil {
+			changes = append(changes, ApplyChange{Item: planError(data.PlanItem{Level: scope.level, Type: "Actions", Target: target}, err)})
+			continue
+		}
+		var names []string
+		for _, variable := range existing {
+			names = append(names, variable.Name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if wanted[applyKey(scope.level, "Variable", scope.repo, scope.environment, name)] {
+				continue
+			}
+			changes = append(changes, ApplyChange{
+				Item: pruneItem(scope.level, "Actions", name, target),
+				Variable: &data.ImportedVariable{
+					Level:           scope.level,
+					Name:            name,
+					SelectedRepos:   []string{scope.repo},
+					EnvironmentName: scope.environment,
+				},
+			})
+		}
+	}
+	return changes
+}
+
+func applyKey(level string, kind string, repo string, environment string, name string) string {
+	if level == "Organization" {
+		repo, environment = "", ""
+	}
+	return strings.Join([]string{level, kind, repo, environment, strings.ToUpper(name)}, "/")
+}
+
+func pruneItem(level string, itemType string, name string, target string) data.PlanItem {
+	return data.PlanItem{
+		Action:  data.PlanDelete,
+		Level:   level,
+		Type:    itemType,
+		Name:    name,
+		Target:  target,
+		Details: []string{"not in manifest"},
+	}
+}
+
+func sortedKeys(secrets map[string]data.Secret) []string {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyPlanItems returns the plan items of the changes, for printing.
+func ApplyPlanItems(changes []ApplyChange) []data.PlanItem {
+	items := make([]data.PlanItem, 0, len(changes))
+	for _, change := range changes {
+		items = append(items, change.Item)
+	}
+	return items
+}
+
+// Apply makes the creates and updates of a plan, then its deletes, and
+// returns a result for each, numbered by the change's place in the plan.
+// Unchanged items and errors are left out, so a plan with errors should not
+// be applied. Deleting an item that is already gone succeeds.
+func (g *APIGetter) Apply(owner string, changes []ApplyChange, concurrency int) []data.RowResult {
+	var writes, deletes []int
+	for i, change := range changes {
+		switch change.Item.Action {
+		case data.PlanCreate, data.PlanUpdate:
+			writes = append(writes, i)
+		case data.PlanDelete:
+			deletes = append(deletes, i)
+		}
+	}
+
+	results := make([]data.RowResult, 0, len(writes)+len(deletes))
+	for _, indexes := range [][]int{writes, deletes} {
+		stepResults := make([]data.RowResult, len(indexes))
+		RunConcurrently(concurrency, len(indexes), func(index int) {
+			change := changes[indexes[index]]
+			err := g.applyChange(owner, change)
+			if err != nil {
+				zap.S().Errorf("Error arose applying %s of %s: %v", change.Item.Action, change.Item.Name, err)
+			}
+			item := change.Item
+			stepResults[index] = NewRowResult(indexes[index]+1, item.Level, item.Type, item.Name, item.Target, err)
+		})
+		results = append(results, stepResults...)
+	}
+	return results
+}
+
+func (g *APIGetter) applyChange(owner string, change ApplyChange) error {
+	switch {
+	case change.Item.Action == data.PlanDelete && change.Secret != nil:
+		zap.S().Debugf("Deleting %s level %s secret %s", change.Secret.Level, change.Secret.Type, change.Secret.Name)
+		if err := g.DeleteImportedSecret(owner, *change.Secret); err != nil && !IsNotFound(err) {
+			return err
+		}
+	case change.Item.Action == data.PlanDelete && change.Variable != nil:
+		zap.S().Debugf("Deleting %s level variable %s", change.Variable.Level, change.Variable.Name)
+		if err := g.DeleteImportedVariable(owner, *change.Variable); err != nil && !IsNotFound(err) {
+			return err
+		}
+	case change.Secret != nil:
+		zap.S().Debugf("Writing %s level %s secret %s", change.Secret.Level, change.Secret.Type, change.Secret.Name)
+		return g.CreateImportedSecret(owner, *change.Secret)
+	case change.Item.Action == data.PlanCreate:
+		zap.S().Debugf("Creating %s level variable %s", change.Variable.Level, change.Variable.Name)
+		return g.CreateImportedVariable(owner, *change.Variable)
+	default:
+		zap.S().Debugf("Updating %s level variable %s", change.Variable.Level, change.Variable.Name)
+		return g.UpdateImportedVariable(owner, *change.Variable)
+	}
+	return nil
+}
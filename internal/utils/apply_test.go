@@ -0,0 +1,272 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func writeManifest(t *testing.T, name string, content string) string {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fileName, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	return fileName
+}
+
+func TestReadManifest(t *testing.T) {
+	fileName := writeManifest(t, "org.yaml", `organization: test-org
+secrets:
+  - level: organization
+    type: actions
+    name: NPM_TOKEN
+    value: npm
+    visibility: selected
+    selected_repositories: [api]
+variables:
+  - level: Repository
+    name: THEME
+    value: dark
+    selected_repositories: [web]
+`)
+	manifest, err := ReadManifest(fileName)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if manifest.Organization != "test-org" || len(manifest.Secrets) != 1 || len(manifest.Variables) != 1 {
+		t.Fatalf("Unexpected manifest %+v", manifest)
+	}
+	if manifest.Secrets[0].Level != "Organization" || manifest.Secrets[0].Type != "Actions" {
+		t.Errorf("Expected levels and types to be normalized, got %+v", manifest.Secrets[0])
+	}
+
+	fileName = writeManifest(t, "org.json", `{"variables":[{"level":"Organization","name":"REGION","value":"eu","visibility":"all"}]}`)
+	manifest, err = ReadManifest(fileName)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if len(manifest.Secrets) != 0 || manifest.Variables[0].Name != "REGION" {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+}
+
+func TestReadManifestInvalid(t *testing.T) {
+	fileName := writeManifest(t, "org.yaml", `secrets:
+  - level: Repository
+    type: Actions
+    name: API_KEY
+variables:
+  - level: Organization
+    name: REGION
+    value: eu
+    visibility: internal
+`)
+	_, err := ReadManifest(fileName)
+	if err == nil {
+		t.Fatal("Expected validation errors, got nil")
+	}
+	for _, want := range []string{
+		"secrets:\nrow 1, column selected_repositories: a repository name is required",
+		"variables:\nrow 1, column visibility: unknown visibility",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	responses := map[string]string{
+		"orgs/test-org/actions/secrets":                        `{"total_count":3,"secrets":[{"name":"NPM_TOKEN","visibility":"selected"},{"name":"DEPLOY_KEY","visibility":"all"},{"name":"OLD_TOKEN","visibility":"all"}]}`,
+		"orgs/test-org/actions/secrets/NPM_TOKEN/repositories": `{"total_count":1,"repositories":[{"id":2,"name":"web"}]}`,
+		"orgs/test-org/codespaces/secrets":                     `{"total_count":0,"secrets":[]}`,
+		"orgs/test-org/dependabot/secrets":                     `{"total_count":0,"secrets":[]}`,
+		"orgs/test-org/actions/variables":                      `{"total_count":2,"variables":[{"name":"REGION","value":"us","visibility":"all"},{"name":"STALE","value":"x","visibility":"all"}]}`,
+		"repos/test-org/api/actions/secrets":                   `{"total_count":1,"secrets":[{"name":"API_KEY"}]}`,
+		"repos/test-org/api/codespaces/secrets":                `{"total_count":0,"secrets":[]}`,
+		"repos/test-org/api/dependabot/secrets":                `{"total_count":1,"secrets":[{"name":"REGISTRY"}]}`,
+		"repos/test-org/api/actions/variables":                 `{"total_count":1,"variables":[{"name":"THEME","value":"dark"}]}`,
+		"repos/test-org/api/environments/production/secrets":   `{"total_count":0,"secrets":[]}`,
+		"repos/test-org/api/environments/production/variables": `{"total_count":0,"variables":[]}`,
+		"repos/test-org/docs/actions/secrets":                  `{"total_count":1,"secrets":[{"name":"UNMANAGED"}]}`,
+	}
+	g := newFakeAPIGetter(t, planTestHandler(t, responses, map[string]int{"api": 1, "web": 2}))
+
+	manifest := &data.Manifest{
+		Secrets: []data.ImportedSecret{
+			{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Value: "npm", Access: "selected", RepositoryNames: []string{"api"}},
+			{Level: "Organization", Type: "Actions", Name: "deploy_key", Access: "all"},
+			{Level: "Repository", Type: "Actions", Name: "API_KEY", RepositoryNames: []string{"api"}},
+			{Level: "Environment", Type: "Actions", Name: "DB_PASSWORD", Value: "s3cr3t", RepositoryNames: []string{"api"}, EnvironmentName: "production"},
+		},
+		Variables: []data.ImportedVariable{
+			{Level: "Organization", Name: "REGION", Value: "eu", Visibility: "all"},
+			{Level: "Repository", Name: "THEME", Value: "dark", SelectedRepos: []string{"api"}},
+		},
+	}
+
+	changes := g.PlanApply("test-org", manifest, true)
+	var got []string
+	for _, change := range changes {
+		item := change.Item
+		got = append(got, item.Action+" "+item.Level+" "+item.Type+" "+item.Name+" "+strings.Join(item.Details, "; "))
+	}
+	expected := []string{
+		"update Organization Actions NPM_TOKEN repositories: +api, -web",
+		"unchanged Organization Actions deploy_key ",
+		"unchanged Repository Actions API_KEY ",
+		"create Environment Actions DB_PASSWORD ",
+		"update Organization Actions REGION value changed",
+		"unchanged Repository Actions THEME ",
+		"delete Organization Actions OLD_TOKEN not in manifest",
+		"delete Organization Actions STALE not in manifest",
+		"delete Repository Dependabot REGISTRY not in manifest",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected plan:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if strings.Join(changes[0].Secret.RepositoryIDs, ";") != "1" {
+		t.Errorf("Expected the selected repositories to be resolved, got %v", changes[0].Secret.RepositoryIDs)
+	}
+	if changes[8].Secret == nil || changes[8].Secret.RepositoryNames[0] != "api" {
+		t.Errorf("Expected the pruned secret to name its repository, got %+v", changes[8].Secret)
+	}
+
+	withoutPrune := g.PlanApply("test-org", manifest, false)
+	if len(withoutPrune) != 6 {
+		t.Errorf("Expected no deletes without prune, got %d changes", len(withoutPrune))
+	}
+}
+
+func TestPlanApplySecretWithoutValue(t *testing.T) {
+	responses := map[string]string{
+		"orgs/test-org/actions/secrets": `{"total_count":1,"secrets":[{"name":"DEPLOY_KEY","visibility":"all"}]}`,
+	}
+	g := newFakeAPIGetter(t, planTestHandler(t, responses, nil))
+	manifest := &data.Manifest{Secrets: []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "DEPLOY_KEY", Access: "private"},
+		{Level: "Organization", Type: "Actions", Name: "NEW_KEY", Access: "all"},
+	}}
+
+	changes := g.PlanApply("test-org", manifest, false)
+	for i, want := range []string{"a secret value is required to update it", "a secret value is required to create it"} {
+		if changes[i].Item.Action != data.PlanError || changes[i].Item.Details[0] != want {
+			t.Errorf("Change %d: expected error %q, got %+v", i, want, changes[i].Item)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyResponse := `{"key_id":"key-1","key":"` + base64.StdEncoding.EncodeToString(publicKey[:]) + `"}`
+
+	var mu sync.Mutex
+	var requests []string
+	bodies := map[string]map[string]interface{}{}
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/public-key") {
+			_, _ = w.Write([]byte(keyResponse))
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Body != nil {
+			var body map[string]interface{}
+			raw, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(raw, &body)
+			bodies[r.Method+" "+r.URL.Path] = body
+		}
+		switch {
+		case r.URL.Path == "/repos/test-org/api/actions/secrets/GONE":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	})
+
+	changes := []ApplyChange{
+		{
+			Item:   data.PlanItem{Action: data.PlanDelete, Level: "Organization", Type: "Actions", Name: "OLD_TOKEN", Target: "test-org"},
+			Secret: &data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "OLD_TOKEN", RepositoryNames: []string{""}},
+		},
+		{
+			Item:   data.PlanItem{Action: data.PlanUpdate, Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Target: "test-org"},
+			Secret: &data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Value: "npm", Access: "selected", RepositoryNames: []string{"api"}, RepositoryIDs: []string{"1"}},
+		},
+		{
+			Item:     data.PlanItem{Action: data.PlanUnchanged, Level: "Repository", Type: "Actions", Name: "THEME", Target: "test-org/api"},
+			Variable: &data.ImportedVariable{Level: "Repository", Name: "THEME", Value: "dark", SelectedRepos: []string{"api"}},
+		},
+		{
+			Item:     data.PlanItem{Action: data.PlanUpdate, Level: "Organization", Type: "Actions", Name: "REGION", Target: "test-org"},
+			Variable: &data.ImportedVariable{Level: "Organization", Name: "REGION", Value: "eu", Visibility: "all"},
+		},
+		{
+			Item:     data.PlanItem{Action: data.PlanCreate, Level: "Environment", Type: "Actions", Name: "LEVEL", Target: "test-org/api (production)"},
+			Variable: &data.ImportedVariable{Level: "Environment", Name: "LEVEL", Value: "debug", SelectedRepos: []string{"api"}, EnvironmentName: "production"},
+		},
+		{
+			Item:   data.PlanItem{Action: data.PlanDelete, Level: "Repository", Type: "Actions", Name: "GONE", Target: "test-org/api"},
+			Secret: &data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "GONE", RepositoryNames: []string{"api"}},
+		},
+	}
+
+	results := g.Apply("test-org", changes, 2)
+	if len(results) != 5 {
+		t.Fatalf("Expected a result for each change but the unchanged one, got %+v", results)
+	}
+	var rows []int
+	for _, result := range results {
+		rows = append(rows, result.Row)
+		if result.Result != data.ResultSucceeded {
+			t.Errorf("Row %d: expected success, got %+v", result.Row, result)
+		}
+	}
+	if !sort.IntsAreSorted(rows[:3]) || rows[3] != 1 || rows[4] != 6 {
+		t.Errorf("Expected writes before deletes, numbered by plan row, got %v", rows)
+	}
+
+	expected := []string{
+		"PUT /orgs/test-org/actions/secrets/NPM_TOKEN",
+		"PATCH /orgs/test-org/actions/variables/REGION",
+		"POST /repos/test-org/api/environments/production/variables",
+		"DELETE /orgs/test-org/actions/secrets/OLD_TOKEN",
+		"DELETE /repos/test-org/api/actions/secrets/GONE",
+	}
+	sorted := append([]string(nil), requests...)
+	sort.Strings(sorted)
+	sort.Strings(expected)
+	if strings.Join(sorted, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected requests:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(requests, "\n"))
+	}
+	secretBody := bodies["PUT /orgs/test-org/actions/secrets/NPM_TOKEN"]
+	if secretBody["visibility"] != "selected" || secretBody["key_id"] != "key-1" || secretBody["encrypted_value"] == "" {
+		t.Errorf("Unexpected secret payload %v", secretBody)
+	}
+	if ids, _ := secretBody["selected_repository_ids"].([]interface{}); len(ids) != 1 || ids[0] != float64(1) {
+		t.Errorf("Expected the secret to be scoped to repository 1, got %v", secretBody["selected_repository_ids"])
+	}
+	if body := bodies["PATCH /orgs/test-org/actions/variables/REGION"]; body["value"] != "eu" {
+		t.Errorf("Unexpected variable payload %v", body)
+	}
+}
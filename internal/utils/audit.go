@@ -0,0 +1,298 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+// Kinds of item an audit reports
+const (
+	KindSecret   = "secret"
+	KindVariable = "variable"
+)
+
+// Patterns of references to the secrets and vars contexts. Expressions are
+// case insensitive, and a context can be indexed by a quoted name or by an
+// expression, which can't be resolved, as can the whole context be passed
+// to toJSON.
+var (
+	secretRefPattern       = contextRefPattern("secrets")
+	variableRefPattern     = contextRefPattern("vars")
+	secretDynamicPattern   = contextDynamicPattern("secrets")
+	variableDynamicPattern = contextDynamicPattern("vars")
+)
+
+func contextRefPattern(context string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|[^\w.])` + context +
+		`\s*(?:\.\s*([A-Za-z_][A-Za-z0-9_]*)|\[\s*'([^']+)'\s*\]|\[\s*"([^"]+)"\s*\])`)
+}
+
+func contextDynamicPattern(context string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|[^\w.])` + context + `\s*\[\s*[^'"\s]|tojson\(\s*` + context + `\s*\)`)
+}
+
+// WorkflowReferences are the secrets and variables the workflows of a
+// repository refer to by name, in upper case, and whether they refer to
+// either by expression.
+type WorkflowReferences struct {
+	Secrets          map[string]bool
+	Variables        map[string]bool
+	DynamicSecrets   bool
+	DynamicVariables bool
+}
+
+func NewWorkflowReferences() *WorkflowReferences {
+	return &WorkflowReferences{Secrets: map[string]bool{}, Variables: map[string]bool{}}
+}
+
+// Scan adds the references of a workflow or action file. Comments are
+// scanned too, so a reference that was commented out still counts.
+func (r *WorkflowReferences) Scan(content string) {
+	addReferences(r.Secrets, secretRefPattern, content)
+	addReferences(r.Variables, variableRefPattern, content)
+	r.DynamicSecrets = r.DynamicSecrets || secretDynamicPattern.MatchString(content)
+	r.DynamicVariables = r.DynamicVariables || variableDynamicPattern.MatchString(content)
+}
+
+func addReferences(names map[string]bool, pattern *regexp.Regexp, content string) {
+	for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+		for _, name := range match[1:] {
+			if name != "" {
+				names[strings.ToUpper(name)] = true
+			}
+		}
+	}
+}
+
+// AuditUnused reads the Actions secrets and the variables of owner, and the
+// workflows and composite actions of each of its repositories, reading up
+// to concurrency repositories at once, and reports those no repository that
+// can use them refers to.
+func (g *APIGetter) AuditUnused(owner string, concurrency int) (*data.UnusedReport, error) {
+	zap.S().Debugf("Gathering secrets and variables of %s", owner)
+	state, err := g.ReadOrgState(owner, concurrency, true, true)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := g.ListRepos(owner, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]*WorkflowReferences, len(repos))
+	fileCounts := make([]int, len(repos))
+	errs := make([]error, len(repos))
+	RunConcurrently(concurrency, len(repos), func(index int) {
+		zap.S().Debugf("Scanning the workflows of %s", repos[index].Name)
+		files, err := g.ReadWorkflowFiles(owner, repos[index].Name)
+		if err != nil {
+			errs[index] = err
+			return
+		}
+		refs[index] = NewWorkflowReferences()
+		for _, file := range files {
+			refs[index].Scan(file.Content)
+		}
+		fileCounts[index] = len(files)
+	})
+
+	report := &data.UnusedReport{Repositories: len(repos), Dynamic: []string{}}
+	repoRefs := map[string]*WorkflowReferences{}
+	for index, repo := range repos {
+		if errs[index] != nil {
+			return nil, errs[index]
+		}
+		repoRefs[repo.Name] = refs[index]
+		report.WorkflowFiles += fileCounts[index]
+		if refs[index].DynamicSecrets || refs[index].DynamicVariables {
+			report.Dynamic = append(report.Dynamic, repo.Name)
+		}
+	}
+	sort.Strings(report.Dynamic)
+	report.Unused = FindUnused(state, repos, repoRefs)
+	return report, nil
+}
+
+// auditItem is a secret or variable of the state being audited, with the
+// repositories that can use it.
+type auditItem struct {
+	data.UnusedItem
+	repos []string
+}
+
+// FindUnused returns the Actions secrets and the variables of state that no
+// repository able to use them refers to, ordered by scope. Organization
+// items can be used by the repositories their visibility allows, and items
+// of a repository or its environments by the repository. A repository that
+// sets a secret or variable of the same name overrides the organization
+// one, so its references don't count for it.
+func FindUnused(state *OrgState, repos []data.RepoInfo, refs map[string]*WorkflowReferences) []data.UnusedItem {
+	var items []auditItem
+	overrides := map[string]bool{}
+	for _, secret := range state.Secrets {
+		if secret.Type != "Actions" {
+			continue
+		}
+		items = append(items, newAuditItem(KindSecret, secret.Level, secret.Name, secret.Access,
+			secret.RepositoryNames, secret.EnvironmentName, repos))
+	}
+	for _, variable := range state.Variables {
+		items = append(items, newAuditItem(KindVariable, variable.Level, variable.Name, variable.Visibility,
+			variable.SelectedRepos, variable.EnvironmentName, repos))
+	}
+	for _, item := range items {
+		if item.Level == "Repository" {
+			overrides[overrideKey(item.Kind, item.Repository, item.Name)] = true
+		}
+	}
+
+	unused := []data.UnusedItem{}
+	for _, item := range items {
+		var overridden []string
+		used := false
+		for _, repo := range item.repos {
+			repoRefs := refs[repo]
+			if repoRefs == nil {
+				continue
+			}
+			names, dynamic := repoRefs.Secrets, repoRefs.DynamicSecrets
+			if item.Kind == KindVariable {
+				names, dynamic = repoRefs.Variables, repoRefs.DynamicVariables
+			}
+			if !dynamic && !names[strings.ToUpper(item.Name)] {
+				continue
+			}
+			if item.Level == "Organization" && overrides[overrideKey(item.Kind, repo, item.Name)] {
+				overridden = append(overridden, repo)
+				continue
+			}
+			used = true
+			break
+		}
+		if used {
+			continue
+		}
+		switch {
+		case len(overridden) > 0:
+			item.Details = append(item.Details, "only referred to by repositories that override it: "+strings.Join(overridden, ", "))
+		case len(item.repos) == 0:
+			item.Details = append(item.Details, "no repository can use it")
+		}
+		unused = append(unused, item.UnusedItem)
+	}
+
+	levels := map[string]int{"Organization": 0, "Repository": 1, "Environment": 2}
+	sort.SliceStable(unused, func(i, j int) bool {
+		a, b := unused[i], unused[j]
+		if levels[a.Level] != levels[b.Level] {
+			return levels[a.Level] < levels[b.Level]
+		}
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		if a.Environment != b.Environment {
+			return a.Environment < b.Environment
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return unused
+}
+
+func newAuditItem(kind string, level string, name string, visibility string, repoNames []string, environment string, repos []data.RepoInfo) auditItem {
+	item := auditItem{UnusedItem: data.UnusedItem{Kind: kind, Level: level, Name: name}}
+	if level != "Organization" {
+		item.Repository = firstName(repoNames)
+		item.Environment = environment
+		item.repos = nonEmpty([]string{item.Repository})
+		return item
+	}
+	item.Visibility = visibility
+	switch visibility {
+	case "selected":
+		item.repos = repoNames
+	case "private":
+		// Private organization items are available to private and internal
+		// repositories
+		for _, repo := range repos {
+			if !strings.EqualFold(repo.Visibility, "public") {
+				item.repos = append(item.repos, repo.Name)
+			}
+		}
+	default:
+		for _, repo := range repos {
+			item.repos = append(item.repos, repo.Name)
+		}
+	}
+	return item
+}
+
+func overrideKey(kind string, repo string, name string) string {
+	return kind + "/" + repo + "/" + strings.ToUpper(name)
+}
+
+// PrintUnused writes the unused items of a report as a table, followed by
+// the repositories whose references can't be resolved and a summary line.
+func PrintUnused(w io.Writer, report *data.UnusedReport) error {
+	if len(report.Unused) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		if _, err := fmt.Fprintln(tw, "KIND\tLEVEL\tNAME\tREPOSITORY\tVISIBILITY\tDETAILS"); err != nil {
+			return err
+		}
+		for _, item := range report.Unused {
+			_, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				item.Kind, item.Level, item.Name,
+				orDash(auditScope(item)), orDash(item.Visibility), strings.Join(item.Details, "; "))
+			if err != nil {
+				return err
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Dynamic) > 0 {
+		_, err := fmt.Fprintf(w, "Workflows of %s refer to secrets or variables by expression, so all those they can use were treated as used.\n",
+			strings.Join(report.Dynamic, ", "))
+		if err != nil {
+			return err
+		}
+	}
+	secrets := 0
+	for _, item := range report.Unused {
+		if item.Kind == KindSecret {
+			secrets++
+		}
+	}
+	_, err := fmt.Fprintf(w, "Unused: %d secrets and %d variables, from %d workflow and action files in %d repositories.\n",
+		secrets, len(report.Unused)-secrets, report.WorkflowFiles, report.Repositories)
+	return err
+}
+
+// WriteUnusedJSON writes a report as indented JSON.
+func WriteUnusedJSON(w io.Writer, report *data.UnusedReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func auditScope(item data.UnusedItem) string {
+	if item.Environment != "" {
+		return fmt.Sprintf("%s (%s)", item.Repository, item.Environment)
+	}
+	return item.Repository
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+func TestWorkflowReferencesScan(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		secrets          []string
+		variables        []string
+		dynamicSecrets   bool
+		dynamicVariables bool
+	}{
+		{"property", "token: ${{ secrets.npm_token }}\nregion: ${{ vars.REGION }}", []string{"NPM_TOKEN"}, []string{"REGION"}, false, false},
+		{"quoted index", `a: ${{ secrets['DEPLOY_KEY'] }} b: ${{ vars["LOG_LEVEL"] }}`, []string{"DEPLOY_KEY"}, []string{"LOG_LEVEL"}, false, false},
+		{"case insensitive", "${{ Secrets.Token }} ${{ VARS.region }}", []string{"TOKEN"}, []string{"REGION"}, false, false},
+		{"inherit", "uses: ./.github/workflows/deploy.yml\nsecrets: inherit", nil, nil, false, false},
+		{"other property", "${{ github.secrets.TOKEN }} ${{ mysecrets.TOKEN }}", nil, nil, false, false},
+		{"dynamic index", "${{ secrets[format('{0}_TOKEN', matrix.env)] }}", nil, nil, true, false},
+		{"toJSON", "run: echo '${{ toJSON(vars) }}'", nil, nil, false, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			refs := NewWorkflowReferences()
+			refs.Scan(tc.content)
+			if len(refs.Secrets) != len(tc.secrets) || len(refs.Variables) != len(tc.variables) {
+				t.Errorf("Expected secrets %v and variables %v, got %v and %v", tc.secrets, tc.variables, refs.Secrets, refs.Variables)
+			}
+			for _, name := range tc.secrets {
+				if !refs.Secrets[name] {
+					t.Errorf("Expected secret %s to be referenced", name)
+				}
+			}
+			for _, name := range tc.variables {
+				if !refs.Variables[name] {
+					t.Errorf("Expected variable %s to be referenced", name)
+				}
+			}
+			if refs.DynamicSecrets != tc.dynamicSecrets || refs.DynamicVariables != tc.dynamicVariables {
+				t.Errorf("Expected dynamic secrets %t and variables %t, got %t and %t",
+					tc.dynamicSecrets, tc.dynamicVariables, refs.DynamicSecrets, refs.DynamicVariables)
+			}
+		})
+	}
+}
+
+func TestFindUnused(t *testing.T) {
+	state := &OrgState{
+		Secrets: []SecretState{
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Access: "all"}},
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "DOCS_TOKEN", Access: "private"}},
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "DEPLOY_KEY", Access: "selected", RepositoryNames: []string{"api"}}},
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "ORPHAN", Access: "selected"}},
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Dependabot", Name: "REGISTRY", Access: "all"}},
+			{ImportedSecret: data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "DEPLOY_KEY", Access: "RepoOnly", RepositoryNames: []string{"api"}}},
+			{ImportedSecret: data.ImportedSecret{Level: "Environment", Type: "Actions", Name: "DB_PASSWORD", Access: "EnvironmentOnly", RepositoryNames: []string{"api"}, EnvironmentName: "production"}},
+			{ImportedSecret: data.ImportedSecret{Level: "Environment", Type: "Actions", Name: "DB_PASSWORD", Access: "EnvironmentOnly", RepositoryNames: []string{"web"}, EnvironmentName: "production"}},
+		},
+		Variables: []VariableState{
+			{ImportedVariable: data.ImportedVariable{Level: "Organization", Name: "REGION", Visibility: "all"}},
+			{ImportedVariable: data.ImportedVariable{Level: "Organization", Name: "LOG_LEVEL", Visibility: "all"}},
+			{ImportedVariable: data.ImportedVariable{Level: "Repository", Name: "IMAGE", SelectedRepos: []string{"web"}}},
+		},
+	}
+	repos := []data.RepoInfo{{Name: "api", Visibility: "PRIVATE"}, {Name: "web", Visibility: "INTERNAL"}, {Name: "docs", Visibility: "PUBLIC"}}
+	refs := map[string]*WorkflowReferences{}
+	for name, content := range map[string]string{
+		"api":  "${{ secrets.NPM_TOKEN }} ${{ secrets.DEPLOY_KEY }} ${{ secrets.DB_PASSWORD }}",
+		"web":  "${{ toJSON(vars) }}",
+		"docs": "${{ secrets.DOCS_TOKEN }}",
+	} {
+		refs[name] = NewWorkflowReferences()
+		refs[name].Scan(content)
+	}
+
+	unused := FindUnused(state, repos, refs)
+	var got []string
+	for _, item := range unused {
+		got = append(got, item.Kind+" "+item.Level+" "+item.Name+" "+item.Repository+" "+strings.Join(item.Details, "; "))
+	}
+	want := []string{
+		"secret Organization DEPLOY_KEY  only referred to by repositories that override it: api",
+		"secret Organization DOCS_TOKEN  ",
+		"secret Organization ORPHAN  no repository can use it",
+		"secret Environment DB_PASSWORD web ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected unused:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if unused[0].Visibility != "selected" {
+		t.Errorf("Expected the visibility of organization items, got %q", unused[0].Visibility)
+	}
+}
+
+func TestPrintUnused(t *testing.T) {
+	report := &data.UnusedReport{
+		Unused: []data.UnusedItem{
+			{Kind: KindSecret, Level: "Organization", Name: "ORPHAN", Visibility: "selected", Details: []string{"no repository can use it"}},
+			{Kind: KindVariable, Level: "Environment", Name: "IMAGE", Repository: "api", Environment: "production"},
+		},
+		Repositories:  3,
+		WorkflowFiles: 5,
+		Dynamic:       []string{"web"},
+	}
+	var buf bytes.Buffer
+	if err := PrintUnused(&buf, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{
+		"KIND",
+		"ORPHAN",
+		"no repository can use it",
+		"api (production)",
+		"Workflows of web refer to secrets or variables by expression",
+		"Unused: 1 secrets and 1 variables, from 5 workflow and action files in 3 repositories.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	buf.Reset()
+	if err := PrintUnused(&buf, &data.UnusedReport{Repositories: 1}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "KIND") {
+		t.Errorf("Expected no table without unused items, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteUnusedJSON(t *testing.T) {
+	report := &data.UnusedReport{
+		Unused:       []data.UnusedItem{{Kind: KindSecret, Level: "Repository", Name: "TOKEN", Repository: "api"}},
+		Repositories: 1,
+		Dynamic:      []string{},
+	}
+	var buf bytes.Buffer
+	if err := WriteUnusedJSON(&buf, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded data.UnusedReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if len(decoded.Unused) != 1 || decoded.Unused[0].Repository != "api" || decoded.Repositories != 1 {
+		t.Errorf("Unexpected report: %+v", decoded)
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import "sync"
+
+// RunConcurrently calls work once for every index in [0, count) using at
+// most concurrency goroutines, and returns once every call has finished.
+// Callers write results into a slice by index to keep output ordering
+// independent of the order in which the work completes.
+func RunConcurrently(concurrency int, count int, work func(index int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > count {
+		concurrency = count
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				work(index)
+			}
+		}()
+	}
+
+	for index := 0; index < count; index++ {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunConcurrentlyVisitsEveryIndex(t *testing.T) {
+	results := make([]int, 50)
+
+	RunConcurrently(8, len(results), func(index int) {
+		results[index] = index * 2
+	})
+
+	for i, result := range results {
+		if result != i*2 {
+			t.Errorf("Expected result %d at index %d, got %d", i*2, i, result)
+		}
+	}
+}
+
+func TestRunConcurrentlyBoundsWorkers(t *testing.T) {
+	var running int32
+	var maxRunning int32
+	var mu sync.Mutex
+
+	RunConcurrently(3, 20, func(index int) {
+		current := atomic.AddInt32(&running, 1)
+		mu.Lock()
+		if current > maxRunning {
+			maxRunning = current
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	})
+
+	if maxRunning > 3 {
+		t.Errorf("Expected at most 3 concurrent workers, got %d", maxRunning)
+	}
+	if maxRunning < 1 {
+		t.Error("Expected work to run")
+	}
+}
+
+func TestRunConcurrentlyDefaultsToSequential(t *testing.T) {
+	var order []int
+
+	RunConcurrently(0, 5, func(index int) {
+		order = append(order, index)
+	})
+
+	for i, index := range order {
+		if index != i {
+			t.Fatalf("Expected sequential order, got %v", order)
+		}
+	}
+	if len(order) != 5 {
+		t.Errorf("Expected 5 calls, got %d", len(order))
+	}
+}
+
+func TestRunConcurrentlyNoWork(t *testing.T) {
+	called := false
+	RunConcurrently(4, 0, func(index int) {
+		called = true
+	})
+	if called {
+		t.Error("Expected no calls for an empty work list")
+	}
+}
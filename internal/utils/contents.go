@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+// maxActionDepth is how many directories below .github/actions composite
+// actions are looked for.
+const maxActionDepth = 3
+
+// WorkflowFile is a workflow or composite action read from a repository.
+type WorkflowFile struct {
+	Path    string
+	Content string
+}
+
+// GetRepoContent reads a file, or lists a directory, of the default branch
+// of a repository. The root is listed when path is empty.
+func (g *GitHubGetter) GetRepoContent(owner string, repo string, filePath string) ([]byte, error) {
+	url := fmt.Sprintf("repos/%s/%s/contents", owner, repo)
+	if filePath != "" {
+		url += "/" + escapeContentPath(filePath)
+	}
+
+	resp, err := doRequest(&g.restClient, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s in %s/%s: %w", filePath, owner, repo, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	return io.ReadAll(resp.Body)
+}
+
+func escapeContentPath(filePath string) string {
+	segments := strings.Split(filePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// ReadWorkflowFiles reads the workflows of a repository and its composite
+// actions, which are the action.yml files of the root and of the
+// directories below .github/actions. A repository that is empty, or has
+// neither, has no files.
+func (g *APIGetter) ReadWorkflowFiles(owner string, repo string) ([]WorkflowFile, error) {
+	var paths []string
+	for _, dir := range []struct {
+		path  string
+		depth int
+		match func(string) bool
+	}{
+		{".github/workflows", 0, isWorkflowFile},
+		{".github/actions", maxActionDepth, isActionFile},
+		{"", 0, isActionFile},
+	} {
+		found, err := g.listContentFiles(owner, repo, dir.path, dir.depth, dir.match)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, found...)
+	}
+
+	files := make([]WorkflowFile, 0, len(paths))
+	for _, filePath := range paths {
+		zap.S().Debugf("Reading %s in %s/%s", filePath, owner, repo)
+		content, err := g.readContentFile(owner, repo, filePath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, WorkflowFile{Path: filePath, Content: content})
+	}
+	return files, nil
+}
+
+// listContentFiles returns the paths of the files in dir that match, and
+// of those in the directories up to depth below it.
+func (g *APIGetter) listContentFiles(owner string, repo string, dir string, depth int, match func(string) bool) ([]string, error) {
+	response, err := g.GetRepoContent(owner, repo, dir)
+	if IsNotFound(err) || IsConflict(err) {
+		// The contents API returns 409 for an empty repository
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []data.RepoContent
+	if err := json.Unmarshal(response, &entries); err != nil {
+		// dir is a file rather than a directory
+		zap.S().Debugf("%s in %s/%s is not a directory", dir, owner, repo)
+		return nil, nil
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		switch {
+		case entry.Type == "file" && match(entry.Name):
+			paths = append(paths, entry.Path)
+		case entry.Type == "dir" && depth > 0:
+			found, err := g.listContentFiles(owner, repo, entry.Path, depth-1, match)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, found...)
+		}
+	}
+	return paths, nil
+}
+
+func (g *APIGetter) readContentFile(owner string, repo string, filePath string) (string, error) {
+	response, err := g.GetRepoContent(owner, repo, filePath)
+	var content data.RepoContent
+	if err == nil {
+		err = json.Unmarshal(response, &content)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s in %s/%s: %w", filePath, owner, repo, err)
+	}
+	if content.Encoding != "base64" {
+		return content.Content, nil
+	}
+	// The content is wrapped at 60 characters
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(content.Content, "\n", ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s in %s/%s: %w", filePath, owner, repo, err)
+	}
+	return string(decoded), nil
+}
+
+func isWorkflowFile(name string) bool {
+	ext := path.Ext(name)
+	return ext == ".yml" || ext == ".yaml"
+}
+
+func isActionFile(name string) bool {
+	return name == "action.yml" || name == "action.yaml"
+}
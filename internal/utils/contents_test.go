@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+// contentFile returns a contents API response for a file, wrapped the way
+// GitHub wraps base64 content.
+func contentFile(t *testing.T, filePath string, content string) []byte {
+	t.Helper()
+	encoded := base64.StdEncoding.EncodeToString([]byte(content))
+	wrapped := ""
+	for len(encoded) > 60 {
+		wrapped += encoded[:60] + "\n"
+		encoded = encoded[60:]
+	}
+	response, err := json.Marshal(data.RepoContent{Type: "file", Path: filePath, Content: wrapped + encoded, Encoding: "base64"})
+	if err != nil {
+		t.Fatalf("Failed to marshal %s: %v", filePath, err)
+	}
+	return response
+}
+
+// contentDir returns a contents API response listing entries, given as
+// name and type pairs, of dir.
+func contentDir(t *testing.T, dir string, entries ...string) []byte {
+	t.Helper()
+	var contents []data.RepoContent
+	for i := 0; i < len(entries); i += 2 {
+		entryPath := entries[i]
+		if dir != "" {
+			entryPath = dir + "/" + entries[i]
+		}
+		contents = append(contents, data.RepoContent{Name: entries[i], Type: entries[i+1], Path: entryPath})
+	}
+	response, err := json.Marshal(contents)
+	if err != nil {
+		t.Fatalf("Failed to marshal %s: %v", dir, err)
+	}
+	return response
+}
+
+func TestReadWorkflowFiles(t *testing.T) {
+	ci := "steps:\n  - run: echo ${{ secrets.NPM_TOKEN }} and a long enough line to be wrapped in base64\n"
+	mock := &MockAPIGetter{RepoContents: map[string][]byte{
+		"api/.github/workflows":                     contentDir(t, ".github/workflows", "ci.yml", "file", "release.yaml", "file", "README.md", "file"),
+		"api/.github/workflows/ci.yml":              contentFile(t, ".github/workflows/ci.yml", ci),
+		"api/.github/workflows/release.yaml":        contentFile(t, ".github/workflows/release.yaml", "release"),
+		"api/.github/actions":                       contentDir(t, ".github/actions", "setup", "dir"),
+		"api/.github/actions/setup":                 contentDir(t, ".github/actions/setup", "action.yml", "file", "helper.yml", "file"),
+		"api/.github/actions/setup/action.yml":      contentFile(t, ".github/actions/setup/action.yml", "setup"),
+		"api/":                                      contentDir(t, "", "action.yaml", "file", "main.go", "file", ".github", "dir"),
+		"api/action.yaml":                           contentFile(t, "action.yaml", "root"),
+		"web/.github/workflows":                     contentFile(t, ".github/workflows", "not a directory"),
+		"web/.github/actions/setup/action.yml":      contentFile(t, ".github/actions/setup/action.yml", "unlisted"),
+		"docs/.github/workflows/ci.yml":             contentFile(t, ".github/workflows/ci.yml", "unlisted"),
+		"docs/.github/actions/setup/action.yml.bak": contentFile(t, "unlisted", "unlisted"),
+	}}
+	g := NewAPIGetter(mock)
+
+	files, err := g.ReadWorkflowFiles("test-org", "api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	want := []string{".github/workflows/ci.yml", ".github/workflows/release.yaml", ".github/actions/setup/action.yml", "action.yaml"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("Expected files %v, got %v", want, paths)
+	}
+	if files[0].Content != ci {
+		t.Errorf("Expected the decoded workflow, got %q", files[0].Content)
+	}
+
+	for _, repo := range []string{"web", "docs"} {
+		files, err := g.ReadWorkflowFiles("test-org", repo)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", repo, err)
+		}
+		if len(files) != 0 {
+			t.Errorf("Expected no files in %s, got %d", repo, len(files))
+		}
+	}
+}
+
+func TestReadWorkflowFilesEmptyRepository(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		recorder.Header().Set("Content-Type", "application/json")
+		recorder.WriteHeader(http.StatusConflict)
+		_, _ = recorder.Write([]byte(`{"message":"This repository is empty."}`))
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	g := NewAPIGetter(NewGitHubGetter(gqlClient, restClient))
+	files, err := g.ReadWorkflowFiles("test-org", "empty")
+	if err != nil {
+		t.Fatalf("Expected an empty repository to have no files, got %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("Expected no files, got %d", len(files))
+	}
+}
+
+func TestEscapeContentPath(t *testing.T) {
+	if got := escapeContentPath(".github/actions/my action#1/action.yml"); got != ".github/actions/my%20action%231/action.yml" {
+		t.Errorf("Unexpected escaped path %s", got)
+	}
+}
@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// DeleteFlags are the flags shared by the secrets and variables delete
+// commands.
+type DeleteFlags struct {
+	FileName    string
+	Name        string
+	Level       string
+	Repo        string
+	Environment string
+	Yes         bool
+}
+
+// AddDeleteFlags registers the shared delete flags on cmd, describing what
+// is deleted as kind, such as "secret".
+func AddDeleteFlags(cmd *cobra.Command, flags *DeleteFlags, kind string) {
+	cmd.Flags().StringVarP(&flags.FileName, "from-file", "f", "", fmt.Sprintf("Path and Name of CSV, JSON or YAML file, in the same format as create, listing %ss to delete", kind))
+	cmd.Flags().StringVarP(&flags.Name, "name", "n", "", fmt.Sprintf("Name of a single %s to delete", kind))
+	cmd.Flags().StringVarP(&flags.Level, "level", "l", "organization", fmt.Sprintf("Level of the %s to delete: {organization|repository|environment}", kind))
+	cmd.Flags().StringVarP(&flags.Repo, "repo", "r", "", fmt.Sprintf("Repository of a repository or environment level %s", kind))
+	cmd.Flags().StringVarP(&flags.Environment, "environment", "e", "", fmt.Sprintf("Environment of an environment level %s", kind))
+	cmd.Flags().BoolVarP(&flags.Yes, "yes", "y", false, fmt.Sprintf("Delete the %ss without asking for confirmation", kind))
+}
+
+// Validate checks that exactly one of a file or a name was given.
+func (f *DeleteFlags) Validate(kind string) error {
+	if len(f.FileName) == 0 && len(f.Name) == 0 {
+		return fmt.Errorf("a file or %s name must be specified for the %ss to delete", kind, kind)
+	} else if len(f.FileName) > 0 && len(f.Name) > 0 {
+		return errors.New("specify only one of `--name` or `--from-file`")
+	}
+	return nil
+}
+
+// deletion is a secret or variable to delete, as listed before asking for
+// confirmation.
+type deletion struct {
+	description string
+	name        string
+	target      string
+	delete      func() error
+}
+
+func (g *GitHubGetter) deleteResource(url string) error {
+	zap.S().Debugf("Deleting %s", url)
+	resp, err := doRequest(&g.restClient, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	return nil
+}
+
+// DeleteImportedSecret deletes a secret read from a file, or built from
+// flags, at its level and type.
+func (g *APIGetter) DeleteImportedSecret(owner string, secret data.ImportedSecret) error {
+	if err := ValidateSecretScope(secret); err != nil {
+		return err
+	}
+	repo := firstName(secret.RepositoryNames)
+
+	var err error
+	switch secret.Level + "/" + secret.Type {
+	case "Organization/Actions":
+		err = g.DeleteOrgActionSecret(owner, secret.Name)
+	case "Organization/Codespaces":
+		err = g.DeleteOrgCodespacesSecret(owner, secret.Name)
+	case "Organization/Dependabot":
+		err = g.DeleteOrgDependabotSecret(owner, secret.Name)
+	case "Repository/Actions":
+		err = g.DeleteRepoActionSecret(owner, repo, secret.Name)
+	case "Repository/Codespaces":
+		err = g.DeleteRepoCodespacesSecret(owner, repo, secret.Name)
+	case "Repository/Dependabot":
+		err = g.DeleteRepoDependabotSecret(owner, repo, secret.Name)
+	case "Environment/Actions":
+		err = g.DeleteEnvironmentActionSecret(owner, repo, secret.EnvironmentName, secret.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete secret %s: %w", secret.Name, err)
+	}
+	return nil
+}
+
+// DeleteImportedVariable deletes a variable read from a file, or built from
+// flags, at its level.
+func (g *APIGetter) DeleteImportedVariable(owner string, variable data.ImportedVariable) error {
+	if err := ValidateVariableScope(variable); err != nil {
+		return err
+	}
+	repo := firstName(variable.SelectedRepos)
+
+	var err error
+	switch variable.Level {
+	case "Organization":
+		err = g.DeleteOrganizationVariable(owner, variable.Name)
+	case "Repository":
+		err = g.DeleteRepoVariable(owner, repo, variable.Name)
+	case "Environment":
+		err = g.DeleteEnvironmentVariable(owner, repo, variable.EnvironmentName, variable.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete variable %s: %w", variable.Name, err)
+	}
+	return nil
+}
+
+// DeleteSecrets lists the secrets, asks for confirmation unless yes is set,
+// and deletes them. Secrets that no longer exist are skipped, and an error
+// counting the secrets that could not be deleted is returned.
+func (g *APIGetter) DeleteSecrets(owner string, secrets []data.ImportedSecret, yes bool, in io.Reader, out io.Writer) error {
+	deletions := make([]deletion, len(secrets))
+	for i := range secrets {
+		secret := secrets[i]
+		normalizeSecret(&secret)
+		if err := ValidateSecretScope(secret); err != nil {
+			return fmt.Errorf("invalid secret %s: %w", secret.Name, err)
+		}
+		deletions[i] = deletion{
+			description: fmt.Sprintf("%s %s secret %s", secret.Level, secret.Type, secret.Name),
+			name:        secret.Name,
+			target:      Target(owner, secret.Level, firstName(secret.RepositoryNames), secret.EnvironmentName),
+			delete:      func() error { return g.DeleteImportedSecret(owner, secret) },
+		}
+	}
+	return runDeletions(owner, "secret", deletions, yes, in, out)
+}
+
+// DeleteVariables lists, confirms and deletes variables in the same way as
+// DeleteSecrets.
+func (g *APIGetter) DeleteVariables(owner string, variables []data.ImportedVariable, yes bool, in io.Reader, out io.Writer) error {
+	deletions := make([]deletion, len(variables))
+	for i := range variables {
+		variable := variables[i]
+		normalizeVariable(&variable)
+		if err := ValidateVariableScope(variable); err != nil {
+			return fmt.Errorf("invalid variable %s: %w", variable.Name, err)
+		}
+		deletions[i] = deletion{
+			description: fmt.Sprintf("%s variable %s", variable.Level, variable.Name),
+			name:        variable.Name,
+			target:      Target(owner, variable.Level, firstName(variable.SelectedRepos), variable.EnvironmentName),
+			delete:      func() error { return g.DeleteImportedVariable(owner, variable) },
+		}
+	}
+	return runDeletions(owner, "variable", deletions, yes, in, out)
+}
+
+func runDeletions(owner string, kind string, deletions []deletion, yes bool, in io.Reader, out io.Writer) error {
+	if len(deletions) == 0 {
+		return fmt.Errorf("no %ss found to delete", kind)
+	}
+
+	if _, err := fmt.Fprintf(out, "The following %d %ss will be deleted:\n", len(deletions), kind); err != nil {
+		return err
+	}
+	for _, item := range deletions {
+		if _, err := fmt.Fprintf(out, "  %s in %s\n", item.description, item.target); err != nil {
+			return err
+		}
+	}
+	if !yes {
+		confirmed, err := Confirm(in, out, fmt.Sprintf("Delete these %ss? [y/N]: ", kind))
+		if err != nil {
+			return fmt.Errorf("%w, use --yes to delete without confirmation", err)
+		}
+		if !confirmed {
+			_, err := fmt.Fprintf(out, "No %ss were deleted.\n", kind)
+			return err
+		}
+	}
+
+	deleted := 0
+	failed := 0
+	for _, item := range deletions {
+		zap.S().Debugf("Deleting %s", item.description)
+		err := item.delete()
+		switch {
+		case err == nil:
+			deleted++
+		case IsNotFound(err):
+			zap.S().Warnf("%s %s was not found in %s, skipping", strings.ToUpper(kind[:1])+kind[1:], item.name, item.target)
+		default:
+			failed++
+			zap.S().Errorf("Error arose deleting %s %s: %v", kind, item.name, err)
+		}
+	}
+
+	if _, err := fmt.Fprintf(out, "Successfully deleted %d %ss for: %s.\n", deleted, kind, owner); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d %ss", failed, len(deletions), kind)
+	}
+	return nil
+}
+
+// Confirm asks a yes or no question, returning true only for an answer
+// starting with y. An error is returned when no answer can be read, such as
+// when running without a terminal.
+func Confirm(in io.Reader, out io.Writer, prompt string) (bool, error) {
+	if _, err := fmt.Fprint(out, prompt); err != nil {
+		return false, err
+	}
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("no answer to confirm: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return strings.HasPrefix(answer, "y"), nil
+}
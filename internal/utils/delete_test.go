@@ -0,0 +1,314 @@
+package utils
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+func TestDeleteImportedSecretPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret data.ImportedSecret
+		path   string
+	}{
+		{
+			name:   "organization actions",
+			secret: data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "TOKEN"},
+			path:   "/orgs/test-org/actions/secrets/TOKEN",
+		},
+		{
+			name:   "organization codespaces",
+			secret: data.ImportedSecret{Level: "Organization", Type: "Codespaces", Name: "TOKEN"},
+			path:   "/orgs/test-org/codespaces/secrets/TOKEN",
+		},
+		{
+			name:   "organization dependabot",
+			secret: data.ImportedSecret{Level: "Organization", Type: "Dependabot", Name: "TOKEN"},
+			path:   "/orgs/test-org/dependabot/secrets/TOKEN",
+		},
+		{
+			name:   "repository actions",
+			secret: data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "TOKEN", RepositoryNames: []string{"repo1"}},
+			path:   "/repos/test-org/repo1/actions/secrets/TOKEN",
+		},
+		{
+			name:   "repository codespaces",
+			secret: data.ImportedSecret{Level: "Repository", Type: "Codespaces", Name: "TOKEN", RepositoryNames: []string{"repo1"}},
+			path:   "/repos/test-org/repo1/codespaces/secrets/TOKEN",
+		},
+		{
+			name:   "repository dependabot",
+			secret: data.ImportedSecret{Level: "Repository", Type: "Dependabot", Name: "TOKEN", RepositoryNames: []string{"repo1"}},
+			path:   "/repos/test-org/repo1/dependabot/secrets/TOKEN",
+		},
+		{
+			name:   "environment actions",
+			secret: data.ImportedSecret{Level: "Environment", Type: "Actions", Name: "TOKEN", RepositoryNames: []string{"repo1"}, EnvironmentName: "prod"},
+			path:   "/repos/test-org/repo1/environments/prod/secrets/TOKEN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, path string
+			g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+				method, path = r.Method, r.URL.Path
+				w.WriteHeader(http.StatusNoContent)
+			})
+			if err := g.DeleteImportedSecret("test-org", tt.secret); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if method != http.MethodDelete {
+				t.Errorf("Expected DELETE, got %s", method)
+			}
+			if path != tt.path {
+				t.Errorf("Expected path %s, got %s", tt.path, path)
+			}
+		})
+	}
+}
+
+func TestDeleteImportedVariablePaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		variable data.ImportedVariable
+		path     string
+	}{
+		{
+			name:     "organization",
+			variable: data.ImportedVariable{Level: "Organization", Name: "VAR"},
+			path:     "/orgs/test-org/actions/variables/VAR",
+		},
+		{
+			name:     "repository",
+			variable: data.ImportedVariable{Level: "Repository", Name: "VAR", SelectedRepos: []string{"repo1"}},
+			path:     "/repos/test-org/repo1/actions/variables/VAR",
+		},
+		{
+			name:     "environment",
+			variable: data.ImportedVariable{Level: "Environment", Name: "VAR", SelectedRepos: []string{"repo1"}, EnvironmentName: "prod"},
+			path:     "/repos/test-org/repo1/environments/prod/variables/VAR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, path string
+			g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+				method, path = r.Method, r.URL.Path
+				w.WriteHeader(http.StatusNoContent)
+			})
+			if err := g.DeleteImportedVariable("test-org", tt.variable); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if method != http.MethodDelete {
+				t.Errorf("Expected DELETE, got %s", method)
+			}
+			if path != tt.path {
+				t.Errorf("Expected path %s, got %s", tt.path, path)
+			}
+		})
+	}
+}
+
+func TestDeleteImportedErrors(t *testing.T) {
+	requests := 0
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	})
+
+	err := g.DeleteImportedSecret("test-org", data.ImportedSecret{Level: "Organization", Type: "Packages", Name: "TOKEN"})
+	if err == nil || !strings.Contains(err.Error(), "unknown secret type") {
+		t.Errorf("Expected unknown secret type error, got %v", err)
+	}
+	err = g.DeleteImportedVariable("test-org", data.ImportedVariable{Level: "Repository", Name: "VAR"})
+	if err == nil || !strings.Contains(err.Error(), "repository name is required") {
+		t.Errorf("Expected missing repository error, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected invalid rows to make no requests, got %d", requests)
+	}
+
+	err = g.DeleteImportedSecret("test-org", data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "TOKEN"})
+	if !IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	err = g.DeleteImportedVariable("test-org", data.ImportedVariable{Level: "Organization", Name: "VAR"})
+	if !IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestDeleteFlagsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   DeleteFlags
+		wantErr string
+	}{
+		{name: "no source", flags: DeleteFlags{}, wantErr: "a file or secret name must be specified for the secrets to delete"},
+		{name: "both sources", flags: DeleteFlags{FileName: "secrets.csv", Name: "TOKEN"}, wantErr: "specify only one"},
+		{name: "name only", flags: DeleteFlags{Name: "TOKEN"}},
+		{name: "file only", flags: DeleteFlags{FileName: "secrets.csv"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.flags.Validate("secret")
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDeleteSecrets(t *testing.T) {
+	var deleted []string
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("Unexpected %s %s", r.Method, r.URL.Path)
+		}
+		if strings.HasSuffix(r.URL.Path, "/MISSING_SECRET") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		deleted = append(deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	secrets := []data.ImportedSecret{
+		{Level: "organization", Type: "ACTIONS", Name: "ORG_SECRET"},
+		{Level: "Environment", Type: "Actions", Name: "ENV_SECRET", RepositoryNames: []string{"repo1"}, EnvironmentName: "prod"},
+		{Level: "Repository", Type: "Codespaces", Name: "MISSING_SECRET", RepositoryNames: []string{"repo1"}},
+	}
+	var out bytes.Buffer
+	if err := g.DeleteSecrets("test-org", secrets, true, strings.NewReader(""), &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"/orgs/test-org/actions/secrets/ORG_SECRET",
+		"/repos/test-org/repo1/environments/prod/secrets/ENV_SECRET",
+	}
+	if strings.Join(deleted, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected deletes %v, got %v", expected, deleted)
+	}
+	for _, want := range []string{
+		"The following 3 secrets will be deleted:",
+		"Organization Actions secret ORG_SECRET in test-org",
+		"Environment Actions secret ENV_SECRET in test-org/repo1 (prod)",
+		"Successfully deleted 2 secrets for: test-org.",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDeleteVariablesConfirmation(t *testing.T) {
+	variables := []data.ImportedVariable{{Level: "Environment", Name: "VAR", SelectedRepos: []string{"repo1"}, EnvironmentName: "prod"}}
+
+	tests := []struct {
+		name        string
+		input       string
+		wantDeleted bool
+		wantErr     bool
+	}{
+		{name: "confirmed", input: "yes\n", wantDeleted: true},
+		{name: "declined", input: "\n"},
+		{name: "no answer", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted string
+			g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+				deleted = r.URL.Path
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			var out bytes.Buffer
+			err := g.DeleteVariables("test-org", variables, false, strings.NewReader(tt.input), &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeleteVariables() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "--yes") {
+				t.Errorf("Expected the error to suggest --yes, got %v", err)
+			}
+			if tt.wantDeleted && deleted != "/repos/test-org/repo1/environments/prod/variables/VAR" {
+				t.Errorf("Expected the environment variable to be deleted, got %q", deleted)
+			}
+			if !tt.wantDeleted && deleted != "" {
+				t.Errorf("Expected nothing to be deleted, got %q", deleted)
+			}
+			if tt.name == "declined" && !strings.Contains(out.String(), "No variables were deleted.") {
+				t.Errorf("Expected nothing deleted to be reported, got:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestDeleteFailures(t *testing.T) {
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Must have admin rights to Repository."}`))
+	})
+
+	var out bytes.Buffer
+	err := g.DeleteVariables("test-org", []data.ImportedVariable{{Level: "Organization", Name: "VAR"}}, true, strings.NewReader(""), &out)
+	if err == nil || !strings.Contains(err.Error(), "failed to delete 1 of 1 variables") {
+		t.Errorf("Expected a failure count error, got %v", err)
+	}
+
+	err = g.DeleteSecrets("test-org", []data.ImportedSecret{{Level: "Environment", Type: "Actions", Name: "TOKEN", RepositoryNames: []string{"repo1"}}}, true, strings.NewReader(""), &out)
+	if err == nil || !strings.Contains(err.Error(), "invalid secret TOKEN: an environment name is required") {
+		t.Errorf("Expected a missing environment error, got %v", err)
+	}
+
+	err = g.DeleteSecrets("test-org", nil, true, strings.NewReader(""), &out)
+	if err == nil || err.Error() != "no secrets found to delete" {
+		t.Errorf("Expected an error for nothing to delete, got %v", err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    bool
+		wantErr bool
+	}{
+		{name: "yes", input: "yes\n", want: true},
+		{name: "short yes", input: "Y\n", want: true},
+		{name: "no", input: "n\n", want: false},
+		{name: "empty answer", input: "\n", want: false},
+		{name: "answer without newline", input: "y", want: true},
+		{name: "no input", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := Confirm(strings.NewReader(tt.input), &out, "Continue? ")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Confirm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Confirm() = %v, want %v", got, tt.want)
+			}
+			if out.String() != "Continue? " {
+				t.Errorf("Expected prompt to be written, got %q", out.String())
+			}
+		})
+	}
+}
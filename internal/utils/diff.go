@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+// maxDiffValue is the length variable values are cut to in the details of a
+// diff, so a multi-line value doesn't take over the table.
+const maxDiffValue = 40
+
+// NewFileState returns the state described by the secrets and variables of
+// an export file, which have no update times.
+func NewFileState(secrets []data.ImportedSecret, variables []data.ImportedVariable) *OrgState {
+	state := &OrgState{}
+	for _, secret := range secrets {
+		state.Secrets = append(state.Secrets, SecretState{ImportedSecret: secret})
+	}
+	for _, variable := range variables {
+		state.Variables = append(state.Variables, VariableState{ImportedVariable: variable})
+	}
+	return state
+}
+
+// DiffOrgStates compares the secrets and variables of target with those of
+// source, matching them by level, type, name and, below the organization, by
+// repository and environment name. Items are returned in the order of
+// source, followed by the items only target has. Details describe how
+// target would change to match source. Secrets are compared by scope, and
+// are changed when source was updated after target, as their values can't
+// be read; variables are also compared by value.
+func DiffOrgStates(source *OrgState, target *OrgState) []data.DiffItem {
+	var items []data.DiffItem
+
+	targetSecrets := map[string]SecretState{}
+	for _, secret := range target.Secrets {
+		targetSecrets[secretStateKey(secret)] = secret
+	}
+	seen := map[string]bool{}
+	for _, secret := range source.Secrets {
+		key := secretStateKey(secret)
+		seen[key] = true
+		item := secretDiffItem(secret)
+		existing, ok := targetSecrets[key]
+		if !ok {
+			item.Status = data.DiffMissing
+		} else {
+			item.Details = secretChanges(secret, existing)
+			item.Status = diffStatus(item.Details)
+		}
+		items = append(items, item)
+	}
+	for _, secret := range target.Secrets {
+		if key := secretStateKey(secret); !seen[key] {
+			seen[key] = true
+			item := secretDiffItem(secret)
+			item.Status = data.DiffExtra
+			items = append(items, item)
+		}
+	}
+
+	targetVariables := map[string]VariableState{}
+	for _, variable := range target.Variables {
+		targetVariables[variableStateKey(variable)] = variable
+	}
+	seen = map[string]bool{}
+	for _, variable := range source.Variables {
+		key := variableStateKey(variable)
+		seen[key] = true
+		item := variableDiffItem(variable)
+		existing, ok := targetVariables[key]
+		if !ok {
+			item.Status = data.DiffMissing
+		} else {
+			item.Details = variableChanges(variable, existing)
+			item.Status = diffStatus(item.Details)
+		}
+		items = append(items, item)
+	}
+	for _, variable := range target.Variables {
+		if key := variableStateKey(variable); !seen[key] {
+			seen[key] = true
+			item := variableDiffItem(variable)
+			item.Status = data.DiffExtra
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func secretStateKey(secret SecretState) string {
+	return applyKey(secret.Level, secret.Type, firstName(secret.RepositoryNames), secret.EnvironmentName, secret.Name)
+}
+
+func variableStateKey(variable VariableState) string {
+	return applyKey(variable.Level, "Variable", firstName(variable.SelectedRepos), variable.EnvironmentName, variable.Name)
+}
+
+func secretDiffItem(secret SecretState) data.DiffItem {
+	item := data.DiffItem{Kind: "secret", Level: secret.Level, Type: secret.Type, Name: secret.Name}
+	if secret.Level != "Organization" {
+		item.Repository = firstName(secret.RepositoryNames)
+		item.Environment = secret.EnvironmentName
+	}
+	return item
+}
+
+func variableDiffItem(variable VariableState) data.DiffItem {
+	item := data.DiffItem{Kind: "variable", Level: variable.Level, Name: variable.Name}
+	if variable.Level != "Organization" {
+		item.Repository = firstName(variable.SelectedRepos)
+		item.Environment = variable.EnvironmentName
+	}
+	return item
+}
+
+func diffStatus(details []string) string {
+	if len(details) > 0 {
+		return data.DiffChanged
+	}
+	return data.DiffIdentical
+}
+
+func secretChanges(source SecretState, target SecretState) []string {
+	var changes []string
+	if source.Level == "Organization" {
+		changes = visibilityChanges(target.Access, source.Access, target.RepositoryNames, source.RepositoryNames)
+	}
+	if !source.UpdatedAt.IsZero() && !target.UpdatedAt.IsZero() && source.UpdatedAt.After(target.UpdatedAt) {
+		changes = append(changes, fmt.Sprintf("updated in source at %s, after target at %s",
+			source.UpdatedAt.UTC().Format(time.RFC3339), target.UpdatedAt.UTC().Format(time.RFC3339)))
+	}
+	return changes
+}
+
+func variableChanges(source VariableState, target VariableState) []string {
+	var changes []string
+	if source.Level == "Organization" {
+		changes = visibilityChanges(target.Visibility, source.Visibility, target.SelectedRepos, source.SelectedRepos)
+	}
+	if source.Value != target.Value {
+		changes = append(changes, fmt.Sprintf("value: %s -> %s", quoteDiffValue(target.Value), quoteDiffValue(source.Value)))
+	}
+	return changes
+}
+
+func quoteDiffValue(value string) string {
+	if runes := []rune(value); len(runes) > maxDiffValue {
+		return fmt.Sprintf("%q...", string(runes[:maxDiffValue]))
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// CountDiffStatuses returns the number of diff items with each status.
+func CountDiffStatuses(items []data.DiffItem) map[string]int {
+	counts := map[string]int{}
+	for _, item := range items {
+		counts[item.Status]++
+	}
+	return counts
+}
+
+// Differences returns the items of a diff that are not identical.
+func Differences(items []data.DiffItem) []data.DiffItem {
+	differences := []data.DiffItem{}
+	for _, item := range items {
+		if item.Status != data.DiffIdentical {
+			differences = append(differences, item)
+		}
+	}
+	return differences
+}
+
+// PrintDiff writes the items that differ as a table followed by a summary
+// line, which also counts the identical items.
+func PrintDiff(w io.Writer, items []data.DiffItem) error {
+	differences := Differences(items)
+	if len(differences) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		if _, err := fmt.Fprintln(tw, "STATUS\tKIND\tLEVEL\tTYPE\tNAME\tREPOSITORY\tDETAILS"); err != nil {
+			return err
+		}
+		for _, item := range differences {
+			_, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				item.Status, item.Kind, item.Level, orDash(item.Type), item.Name,
+				orDash(diffScope(item)), strings.Join(item.Details, "; "))
+			if err != nil {
+				return err
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+
+	counts := CountDiffStatuses(items)
+	_, err := fmt.Fprintf(w, "Diff: %d missing, %d extra, %d changed, %d identical.\n",
+		counts[data.DiffMissing], counts[data.DiffExtra], counts[data.DiffChanged], counts[data.DiffIdentical])
+	return err
+}
+
+// WriteDiffJSON writes the items that differ as an indented JSON array.
+func WriteDiffJSON(w io.Writer, items []data.DiffItem) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Differences(items))
+}
+
+func diffScope(item data.DiffItem) string {
+	if item.Environment != "" {
+		return fmt.Sprintf("%s (%s)", item.Repository, item.Environment)
+	}
+	return item.Repository
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+func TestDiffOrgStates(t *testing.T) {
+	older := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	source := &OrgState{
+		Secrets: []SecretState{
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Access: "private"}, UpdatedAt: older},
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "DEPLOY_KEY", Access: "selected", RepositoryNames: []string{"api", "web"}}},
+			{ImportedSecret: data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "api_key", Access: "RepoOnly", RepositoryNames: []string{"api"}}, UpdatedAt: newer},
+			{ImportedSecret: data.ImportedSecret{Level: "Environment", Type: "Actions", Name: "DB_PASSWORD", Access: "EnvironmentOnly", RepositoryNames: []string{"api"}, EnvironmentName: "production"}},
+		},
+		Variables: []VariableState{
+			{ImportedVariable: data.ImportedVariable{Level: "Organization", Name: "REGION", Value: "eu", Visibility: "all"}},
+			{ImportedVariable: data.ImportedVariable{Level: "Repository", Name: "LOG_LEVEL", Value: "info", SelectedRepos: []string{"api"}}},
+		},
+	}
+	target := &OrgState{
+		Secrets: []SecretState{
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Access: "private"}, UpdatedAt: newer},
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "DEPLOY_KEY", Access: "selected", RepositoryNames: []string{"api", "docs"}}},
+			{ImportedSecret: data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "API_KEY", Access: "RepoOnly", RepositoryNames: []string{"api"}}, UpdatedAt: older},
+			{ImportedSecret: data.ImportedSecret{Level: "Organization", Type: "Dependabot", Name: "NPM_TOKEN", Access: "all"}},
+		},
+		Variables: []VariableState{
+			{ImportedVariable: data.ImportedVariable{Level: "Organization", Name: "REGION", Value: "us", Visibility: "private"}},
+			{ImportedVariable: data.ImportedVariable{Level: "Repository", Name: "LOG_LEVEL", Value: "info", SelectedRepos: []string{"api"}}},
+			{ImportedVariable: data.ImportedVariable{Level: "Repository", Name: "LOG_LEVEL", Value: "info", SelectedRepos: []string{"web"}}},
+		},
+	}
+
+	items := DiffOrgStates(source, target)
+	expected := []struct {
+		status  string
+		kind    string
+		name    string
+		details string
+	}{
+		{data.DiffIdentical, "secret", "NPM_TOKEN", ""},
+		{data.DiffChanged, "secret", "DEPLOY_KEY", "repositories: +web, -docs"},
+		{data.DiffChanged, "secret", "api_key", "updated in source at 2024-05-01T00:00:00Z, after target at 2024-04-01T00:00:00Z"},
+		{data.DiffMissing, "secret", "DB_PASSWORD", ""},
+		{data.DiffExtra, "secret", "NPM_TOKEN", ""},
+		{data.DiffChanged, "variable", "REGION", `visibility: private -> all; value: "us" -> "eu"`},
+		{data.DiffIdentical, "variable", "LOG_LEVEL", ""},
+		{data.DiffExtra, "variable", "LOG_LEVEL", ""},
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %d items, got %+v", len(expected), items)
+	}
+	for i, want := range expected {
+		got := items[i]
+		if got.Status != want.status || got.Kind != want.kind || got.Name != want.name || strings.Join(got.Details, "; ") != want.details {
+			t.Errorf("Item %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+	if items[3].Repository != "api" || items[3].Environment != "production" {
+		t.Errorf("Expected the environment secret to keep its scope, got %+v", items[3])
+	}
+	if items[4].Type != "Dependabot" || items[7].Repository != "web" {
+		t.Errorf("Expected the extra items of the target, got %+v and %+v", items[4], items[7])
+	}
+}
+
+func TestDiffOrgStatesLongValue(t *testing.T) {
+	source := &OrgState{Variables: []VariableState{{ImportedVariable: data.ImportedVariable{Level: "Organization", Name: "CERT", Value: strings.Repeat("a", 50), Visibility: "all"}}}}
+	target := &OrgState{Variables: []VariableState{{ImportedVariable: data.ImportedVariable{Level: "Organization", Name: "CERT", Value: "b", Visibility: "all"}}}}
+
+	items := DiffOrgStates(source, target)
+	want := `value: "b" -> "` + strings.Repeat("a", maxDiffValue) + `"...`
+	if len(items) != 1 || strings.Join(items[0].Details, "; ") != want {
+		t.Errorf("Expected details %q, got %+v", want, items)
+	}
+}
+
+func TestNewFileState(t *testing.T) {
+	state := NewFileState(
+		[]data.ImportedSecret{{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Access: "all"}},
+		[]data.ImportedVariable{{Level: "Organization", Name: "REGION", Value: "eu", Visibility: "all"}},
+	)
+	if len(state.Secrets) != 1 || state.Secrets[0].Name != "NPM_TOKEN" || !state.Secrets[0].UpdatedAt.IsZero() {
+		t.Errorf("Expected the file secret without an update time, got %+v", state.Secrets)
+	}
+	if len(state.Variables) != 1 || state.Variables[0].Value != "eu" {
+		t.Errorf("Expected the file variable, got %+v", state.Variables)
+	}
+}
+
+var testDiffItems = []data.DiffItem{
+	{Status: data.DiffMissing, Kind: "secret", Level: "Environment", Type: "Actions", Name: "DB_PASSWORD", Repository: "api", Environment: "production"},
+	{Status: data.DiffChanged, Kind: "variable", Level: "Organization", Name: "REGION", Details: []string{`value: "us" -> "eu"`}},
+	{Status: data.DiffIdentical, Kind: "variable", Level: "Organization", Name: "SAME"},
+}
+
+func TestPrintDiff(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PrintDiff(&buf, testDiffItems); err != nil {
+		t.Fatalf("PrintDiff() error = %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"STATUS", "api (production)", `value: "us" -> "eu"`, "Diff: 1 missing, 0 extra, 1 changed, 1 identical."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output containing %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "SAME") {
+		t.Errorf("Expected identical items to be left out of the table, got:\n%s", output)
+	}
+
+	buf.Reset()
+	if err := PrintDiff(&buf, testDiffItems[2:]); err != nil {
+		t.Fatalf("PrintDiff() error = %v", err)
+	}
+	if buf.String() != "Diff: 0 missing, 0 extra, 0 changed, 1 identical.\n" {
+		t.Errorf("Expected only the summary without differences, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteDiffJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDiffJSON(&buf, testDiffItems); err != nil {
+		t.Fatalf("WriteDiffJSON() error = %v", err)
+	}
+	var items []data.DiffItem
+	if err := json.Unmarshal(buf.Bytes(), &items); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if len(items) != 2 || items[0].Environment != "production" || items[1].Status != data.DiffChanged {
+		t.Errorf("Expected the two differences, got %+v", items)
+	}
+
+	buf.Reset()
+	if err := WriteDiffJSON(&buf, nil); err != nil {
+		t.Fatalf("WriteDiffJSON() error = %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("Expected an empty array, got %s", buf.String())
+	}
+}
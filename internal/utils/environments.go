@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+// Environment names may contain spaces and other characters that
+// are not valid in a URL path segment
+func escapeEnvironment(environment string) string {
+	return url.PathEscape(environment)
+}
+
+func (g *GitHubGetter) GetRepoEnvironments(owner string, repo string) ([]byte, error) {
+	url := fmt.Sprintf("repos/%s/%s/environments", owner, repo)
+
+	return getAllPages(&g.restClient, url, "environments", maxPerPage)
+}
+
+func (g *GitHubGetter) GetEnvironmentBranchPolicies(owner string, repo string, environment string) ([]byte, error) {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/deployment-branch-policies", owner, repo, escapeEnvironment(environment))
+
+	return getAllPages(&g.restClient, url, "branch_policies", maxPerPage)
+}
+
+func (g *GitHubGetter) GetUser(login string) ([]byte, error) {
+	url := fmt.Sprintf("users/%s", login)
+
+	resp, err := doRequest(&g.restClient, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %s: %w", login, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	return io.ReadAll(resp.Body)
+}
+
+func (g *GitHubGetter) GetOrgTeam(owner string, slug string) ([]byte, error) {
+	url := fmt.Sprintf("orgs/%s/teams/%s", owner, slug)
+
+	resp, err := doRequest(&g.restClient, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team %s in %s: %w", slug, owner, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	return io.ReadAll(resp.Body)
+}
+
+func (g *GitHubGetter) CreateEnvironment(owner string, repo string, environment string, data io.Reader) error {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s", owner, repo, escapeEnvironment(environment))
+
+	resp, err := doRequest(&g.restClient, "PUT", url, data)
+	if err != nil {
+		return fmt.Errorf("failed to create environment %s in %s: %w", environment, repo, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (g *GitHubGetter) CreateEnvironmentBranchPolicy(owner string, repo string, environment string, data io.Reader) error {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/deployment-branch-policies", owner, repo, escapeEnvironment(environment))
+
+	resp, err := doRequest(&g.restClient, "POST", url, data)
+	if err != nil {
+		return fmt.Errorf("failed to create deployment branch policy for environment %s: %w", environment, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Reviewers are exported as Type:identifier, where the identifier is
+// the login of a User or the slug of a Team
+func FormatEnvironmentReviewers(rules []data.ProtectionRule) []string {
+	var reviewers []string
+	for _, rule := range rules {
+		for _, reviewer := range rule.Reviewers {
+			switch reviewer.Type {
+			case "Team":
+				reviewers = append(reviewers, "Team:"+reviewer.Reviewer.Slug)
+			default:
+				reviewers = append(reviewers, "User:"+reviewer.Reviewer.Login)
+			}
+		}
+	}
+	return reviewers
+}
+
+func ParseEnvironmentReviewer(reviewer string) (string, string) {
+	reviewerType, identifier, found := strings.Cut(reviewer, ":")
+	if !found {
+		return "User", reviewer
+	}
+	return reviewerType, identifier
+}
+
+// Deployment branch policies are exported as type:pattern, a pattern
+// without a type is treated as a branch
+func ParseEnvironmentBranchPolicy(policy string) *data.CreateBranchPolicy {
+	policyType, name, found := strings.Cut(policy, ":")
+	if !found {
+		return &data.CreateBranchPolicy{Name: policy, Type: "branch"}
+	}
+	return &data.CreateBranchPolicy{Name: name, Type: policyType}
+}
+
+func CreateEnvironmentData(environment data.ImportedEnvironment, reviewers []data.CreateEnvironmentReviewer) *data.CreateEnvironment {
+	e := data.CreateEnvironment{
+		WaitTimer:         environment.WaitTimer,
+		PreventSelfReview: environment.PreventSelfReview,
+		Reviewers:         reviewers,
+		CanAdminsBypass:   environment.CanAdminsBypass,
+	}
+	switch environment.DeploymentBranchPolicy {
+	case "protected":
+		e.DeploymentBranchPolicy = &data.DeploymentBranchPolicy{ProtectedBranches: true}
+	case "custom":
+		e.DeploymentBranchPolicy = &data.DeploymentBranchPolicy{CustomBranchPolicies: true}
+	}
+	return &e
+}
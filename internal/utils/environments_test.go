@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+func TestGetRepoEnvironmentsMock(t *testing.T) {
+	mockGetter := NewMockAPIGetter()
+	expectedResponse := []byte(`{"total_count":1,"environments":[{"id":1,"name":"production"}]}`)
+	mockGetter.RepoEnvironmentsData = expectedResponse
+
+	response, err := mockGetter.GetRepoEnvironments("test-org", "test-repo")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	var environments data.EnvironmentsResponse
+	if err := json.Unmarshal(response, &environments); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(environments.Environments) != 1 || environments.Environments[0].Name != "production" {
+		t.Errorf("Expected environment 'production', got %+v", environments.Environments)
+	}
+}
+
+func TestGetRepoEnvironmentsPath(t *testing.T) {
+	var capturedPath string
+	mockClient := &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			capturedPath = path
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"total_count":0,"environments":[]}`))),
+			}, nil
+		},
+	}
+
+	getter := newAPIGetterWithMockREST(mockClient)
+
+	if _, err := getter.GetRepoEnvironments("test-org", "test-repo"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expectedPath := "repos/test-org/test-repo/environments"
+	if capturedPath != expectedPath {
+		t.Errorf("Expected path %s, got %s", expectedPath, capturedPath)
+	}
+}
+
+func TestEnvironmentActionSecretPaths(t *testing.T) {
+	var captured []string
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		captured = append(captured, r.Method+" "+r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"total_count":0,"secrets":[],"variables":[]}`))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	})
+
+	for _, environment := range []string{"staging west", "qa/eu"} {
+		if _, err := g.GetEnvironmentActionSecrets("test-org", "test-repo", environment); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if err := g.CreateEnvironmentActionSecret("test-org", "test-repo", environment, "ENV_SECRET", bytes.NewReader([]byte("{}"))); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if err := g.DeleteEnvironmentActionSecret("test-org", "test-repo", environment, "ENV_SECRET"); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if _, err := g.GetEnvironmentActionVariables("test-org", "test-repo", environment); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if err := g.CreateEnvironmentVariable("test-org", "test-repo", environment, bytes.NewReader([]byte("{}"))); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if err := g.UpdateEnvironmentVariable("test-org", "test-repo", environment, "ENV_VAR", bytes.NewReader([]byte("{}"))); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if err := g.DeleteEnvironmentVariable("test-org", "test-repo", environment, "ENV_VAR"); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	var expected []string
+	for _, escaped := range []string{"staging%20west", "qa%2Feu"} {
+		base := "/repos/test-org/test-repo/environments/" + escaped
+		expected = append(expected,
+			"GET "+base+"/secrets",
+			"PUT "+base+"/secrets/ENV_SECRET",
+			"DELETE "+base+"/secrets/ENV_SECRET",
+			"GET "+base+"/variables",
+			"POST "+base+"/variables",
+			"PATCH "+base+"/variables/ENV_VAR",
+			"DELETE "+base+"/variables/ENV_VAR",
+		)
+	}
+	if len(captured) != len(expected) {
+		t.Fatalf("Expected requests %v, got %v", expected, captured)
+	}
+	for i, want := range expected {
+		if captured[i] != want {
+			t.Errorf("Expected request %s, got %s", want, captured[i])
+		}
+	}
+}
+
+func TestGetEnvironmentActionSecretsError(t *testing.T) {
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	})
+
+	if _, err := g.GetEnvironmentActionSecrets("test-org", "test-repo", "production"); err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
+
+func TestFormatEnvironmentReviewers(t *testing.T) {
+	rules := []data.ProtectionRule{
+		{Type: "wait_timer", WaitTimer: 10},
+		{
+			Type: "required_reviewers",
+			Reviewers: []data.EnvironmentReviewer{
+				{Type: "User", Reviewer: data.ReviewerInfo{ID: 1, Login: "octocat"}},
+				{Type: "Team", Reviewer: data.ReviewerInfo{ID: 2, Slug: "platform"}},
+			},
+		},
+	}
+
+	reviewers := FormatEnvironmentReviewers(rules)
+
+	expected := []string{"User:octocat", "Team:platform"}
+	if len(reviewers) != len(expected) {
+		t.Fatalf("Expected %d reviewers, got %d", len(expected), len(reviewers))
+	}
+	for i := range expected {
+		if reviewers[i] != expected[i] {
+			t.Errorf("Expected reviewer %s, got %s", expected[i], reviewers[i])
+		}
+	}
+}
+
+func TestParseEnvironmentReviewer(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedType       string
+		expectedIdentifier string
+	}{
+		{"User:octocat", "User", "octocat"},
+		{"Team:platform", "Team", "platform"},
+		{"octocat", "User", "octocat"},
+	}
+
+	for _, tc := range tests {
+		reviewerType, identifier := ParseEnvironmentReviewer(tc.input)
+		if reviewerType != tc.expectedType || identifier != tc.expectedIdentifier {
+			t.Errorf("ParseEnvironmentReviewer(%s) = %s, %s; expected %s, %s", tc.input, reviewerType, identifier, tc.expectedType, tc.expectedIdentifier)
+		}
+	}
+}
+
+func TestParseEnvironmentBranchPolicy(t *testing.T) {
+	policy := ParseEnvironmentBranchPolicy("tag:v*")
+	if policy.Type != "tag" || policy.Name != "v*" {
+		t.Errorf("Expected tag policy v*, got %s policy %s", policy.Type, policy.Name)
+	}
+
+	policy = ParseEnvironmentBranchPolicy("release/*")
+	if policy.Type != "branch" || policy.Name != "release/*" {
+		t.Errorf("Expected branch policy release/*, got %s policy %s", policy.Type, policy.Name)
+	}
+}
+
+func TestCreateEnvironmentData(t *testing.T) {
+	reviewers := []data.CreateEnvironmentReviewer{{Type: "User", ID: 1}}
+
+	tests := []struct {
+		policy            string
+		expectNil         bool
+		expectProtected   bool
+		expectCustomRules bool
+	}{
+		{"all", true, false, false},
+		{"protected", false, true, false},
+		{"custom", false, false, true},
+	}
+
+	for _, tc := range tests {
+		environment := data.ImportedEnvironment{
+			RepositoryName:         "repo1",
+			Name:                   "production",
+			WaitTimer:              5,
+			CanAdminsBypass:        true,
+			DeploymentBranchPolicy: tc.policy,
+		}
+
+		result := CreateEnvironmentData(environment, reviewers)
+
+		if result.WaitTimer != 5 || !result.CanAdminsBypass || len(result.Reviewers) != 1 {
+			t.Errorf("Unexpected environment data for %s: %+v", tc.policy, result)
+		}
+		if tc.expectNil {
+			if result.DeploymentBranchPolicy != nil {
+				t.Errorf("Expected no deployment branch policy for %s", tc.policy)
+			}
+			continue
+		}
+		if result.DeploymentBranchPolicy == nil {
+			t.Fatalf("Expected deployment branch policy for %s", tc.policy)
+		}
+		if result.DeploymentBranchPolicy.ProtectedBranches != tc.expectProtected ||
+			result.DeploymentBranchPolicy.CustomBranchPolicies != tc.expectCustomRules {
+			t.Errorf("Unexpected deployment branch policy for %s: %+v", tc.policy, result.DeploymentBranchPolicy)
+		}
+	}
+}
+
+func TestCreateEnvironmentMock(t *testing.T) {
+	mockGetter := NewMockAPIGetter()
+
+	if err := mockGetter.CreateEnvironment("test-org", "repo1", "production", nil); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if len(mockGetter.CreatedEnvironments) != 1 || mockGetter.CreatedEnvironments[0] != "repo1/production" {
+		t.Errorf("Expected environment repo1/production to be recorded, got %v", mockGetter.CreatedEnvironments)
+	}
+
+	mockGetter.ShouldReturnError = true
+	if err := mockGetter.CreateEnvironment("test-org", "repo1", "staging", nil); err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
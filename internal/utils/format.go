@@ -0,0 +1,321 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of export and import files
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Fields of a JSON or YAML document, matching the tags of
+// data.ImportedSecret and data.ImportedVariable, in the order of the CSV
+// columns.
+var (
+	secretFields   = []string{"level", "type", "name", "value", "visibility", "selected_repositories", "selected_repository_ids", "environment_name"}
+	variableFields = []string{"level", "name", "value", "visibility", "selected_repositories", "selected_repository_ids", "environment_name"}
+)
+
+// ValidateFormat checks format is one of csv, json or yaml.
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatCSV, FormatJSON, FormatYAML:
+		return nil
+	}
+	return fmt.Errorf("invalid format %q, expected csv, json or yaml", format)
+}
+
+// FormatFileName replaces the extension of a file name with that of format.
+func FormatFileName(fileName string, format string) string {
+	extension := "." + format
+	if format == FormatYAML {
+		extension = ".yaml"
+	}
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName)) + extension
+}
+
+// DetectFormat determines the format of an import file from its extension,
+// or else from its content, falling back to CSV.
+func DetectFormat(fileName string, content []byte) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".csv":
+		return FormatCSV
+	}
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")), bytes.HasPrefix(trimmed, []byte("{")):
+		return FormatJSON
+	case bytes.HasPrefix(trimmed, []byte("---")), bytes.HasPrefix(trimmed, []byte("- ")):
+		return FormatYAML
+	}
+	return FormatCSV
+}
+
+// RecordWriter writes the rows of an export. CSV rows are written as they
+// come, while JSON and YAML documents are written on Flush, once every row is
+// known.
+type RecordWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// NewSecretsWriter returns a RecordWriter for rows in the CSV format of a
+// secrets export, header first, writing them to w in format.
+func NewSecretsWriter(w io.Writer, format string) (RecordWriter, error) {
+	return newRecordWriter(w, format, secretSchema, func(table *csvTable) interface{} {
+		secrets := make([]data.ImportedSecret, 0, len(table.rows))
+		for _, row := range table.rows {
+			secret := table.secret(row)
+			secret.RepositoryNames = fieldList(secret.RepositoryNames)
+			secret.RepositoryIDs = fieldList(secret.RepositoryIDs)
+			secrets = append(secrets, secret)
+		}
+		return secrets
+	})
+}
+
+// NewVariablesWriter returns a RecordWriter for rows in the CSV format of a
+// variables export, in the same way as NewSecretsWriter.
+func NewVariablesWriter(w io.Writer, format string) (RecordWriter, error) {
+	return newRecordWriter(w, format, variableSchema, func(table *csvTable) interface{} {
+		variables := make([]data.ImportedVariable, 0, len(table.rows))
+		for _, row := range table.rows {
+			variable := table.variable(row)
+			variable.SelectedRepos = fieldList(variable.SelectedRepos)
+			variable.SelectedReposIDs = fieldList(variable.SelectedReposIDs)
+			variables = append(variables, variable)
+		}
+		return variables
+	})
+}
+
+func newRecordWriter(w io.Writer, format string, schema []schemaColumn, convert func(*csvTable) interface{}) (RecordWriter, error) {
+	if err := ValidateFormat(format); err != nil {
+		return nil, err
+	}
+	if format == FormatCSV {
+		return csv.NewWriter(w), nil
+	}
+	return &documentWriter{w: w, format: format, schema: schema, convert: convert}, nil
+}
+
+// documentWriter collects the rows of an export to write them as a single
+// JSON or YAML document.
+type documentWriter struct {
+	w       io.Writer
+	format  string
+	schema  []schemaColumn
+	convert func(*csvTable) interface{}
+	records [][]string
+	err     error
+}
+
+func (d *documentWriter) Write(record []string) error {
+	d.records = append(d.records, append([]string(nil), record...))
+	return nil
+}
+
+func (d *documentWriter) Flush() {
+	if d.err != nil || len(d.records) == 0 {
+		return
+	}
+	table, err := newCSVTable(d.records, d.schema)
+	if err != nil {
+		d.err = err
+		return
+	}
+	document := d.convert(table)
+	if d.format == FormatJSON {
+		encoder := json.NewEncoder(d.w)
+		encoder.SetIndent("", "  ")
+		d.err = encoder.Encode(document)
+	} else {
+		encoder := yaml.NewEncoder(d.w)
+		encoder.SetIndent(2)
+		if d.err = encoder.Encode(document); d.err == nil {
+			d.err = encoder.Close()
+		}
+	}
+	d.records = nil
+}
+
+func (d *documentWriter) Error() error {
+	return d.err
+}
+
+// fieldList returns the values of a list column, with an empty list rather
+// than a single empty value when the column is empty.
+func fieldList(values []string) []string {
+	values = nonEmpty(values)
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// ReadSecretsFile reads secrets from a CSV, JSON or YAML file, detecting its
+// format, and validates them in the same way as ParseSecretRecords. JSON and
+// YAML files hold a list of secrets, numbered as rows from 1.
+func ReadSecretsFile(fileName string) ([]data.ImportedSecret, error) {
+	return readSecretsFile(fileName, validateSecretSettings)
+}
+
+// ReadSecretScopesFile reads secrets in the same way as ReadSecretsFile,
+// but only checks the columns naming each secret and where it is, so the
+// access and value columns of a file listing secrets to delete may be
+// left empty.
+func ReadSecretScopesFile(fileName string) ([]data.ImportedSecret, error) {
+	return readSecretsFile(fileName, ValidateSecretScope)
+}
+
+func readSecretsFile(fileName string, validate func(data.ImportedSecret) error) ([]data.ImportedSecret, error) {
+	table, err := readImportFile(fileName, secretSchema, secretFields, func(format string, content []byte) ([][]string, error) {
+		var secrets []data.ImportedSecret
+		if err := unmarshalDocument(format, content, &secrets); err != nil {
+			return nil, err
+		}
+		return secretRecords(secrets), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := table.secrets(validate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s:\n%w", fileName, err)
+	}
+	return secrets, nil
+}
+
+// ReadVariablesFile reads variables from a CSV, JSON or YAML file in the same
+// way as ReadSecretsFile.
+func ReadVariablesFile(fileName string) ([]data.ImportedVariable, error) {
+	return readVariablesFile(fileName, validateVariableSettings)
+}
+
+// ReadVariableScopesFile reads variables in the same way as
+// ReadSecretScopesFile.
+func ReadVariableScopesFile(fileName string) ([]data.ImportedVariable, error) {
+	return readVariablesFile(fileName, ValidateVariableScope)
+}
+
+func readVariablesFile(fileName string, validate func(data.ImportedVariable) error) ([]data.ImportedVariable, error) {
+	table, err := readImportFile(fileName, variableSchema, variableFields, func(format string, content []byte) ([][]string, error) {
+		var variables []data.ImportedVariable
+		if err := unmarshalDocument(format, content, &variables); err != nil {
+			return nil, err
+		}
+		return variableRecords(variables), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	variables, err := table.variables(validate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid variables file %s:\n%w", fileName, err)
+	}
+	return variables, nil
+}
+
+// secretRecords converts the secrets of a JSON or YAML document to rows
+// under a header of secretFields.
+func secretRecords(secrets []data.ImportedSecret) [][]string {
+	records := make([][]string, 0, len(secrets))
+	for _, secret := range secrets {
+		records = append(records, []string{
+			secret.Level,
+			secret.Type,
+			secret.Name,
+			secret.Value,
+			secret.Access,
+			strings.Join(secret.RepositoryNames, ";"),
+			strings.Join(secret.RepositoryIDs, ";"),
+			secret.EnvironmentName,
+		})
+	}
+	return records
+}
+
+// variableRecords converts the variables of a JSON or YAML document to rows
+// under a header of variableFields.
+func variableRecords(variables []data.ImportedVariable) [][]string {
+	records := make([][]string, 0, len(variables))
+	for _, variable := range variables {
+		records = append(records, []string{
+			variable.Level,
+			variable.Name,
+			variable.Value,
+			variable.Visibility,
+			strings.Join(variable.SelectedRepos, ";"),
+			strings.Join(variable.SelectedReposIDs, ";"),
+			variable.EnvironmentName,
+		})
+	}
+	return records
+}
+
+// readImportFile reads an import file into a table. CSV files are read as
+// they are, while the entries of a JSON or YAML document are converted to
+// rows by toRecords, under a header of the document's field names.
+func readImportFile(fileName string, schema []schemaColumn, fields []string, toRecords func(string, []byte) ([][]string, error)) (*csvTable, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	format := DetectFormat(fileName, content)
+	zap.S().Debugf("Reading %s as %s", fileName, format)
+	if format == FormatCSV {
+		csvReader := csv.NewReader(bytes.NewReader(content))
+		csvReader.FieldsPerRecord = -1
+		records, err := csvReader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		table, err := newCSVTable(records, schema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file %s: %w", fileName, err)
+		}
+		return table, nil
+	}
+	records, err := toRecords(format, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s as %s: %w", fileName, format, err)
+	}
+	return newDocumentTable(records, schema, fields)
+}
+
+// newDocumentTable builds a table from the entries of a JSON or YAML
+// document, so they are validated in the same way as rows of a CSV file.
+func newDocumentTable(records [][]string, schema []schemaColumn, fields []string) (*csvTable, error) {
+	table, err := newCSVTable(append([][]string{fields}, records...), schema)
+	if err != nil {
+		return nil, err
+	}
+	table.document = true
+	return table, nil
+}
+
+func unmarshalDocument(format string, content []byte, v interface{}) error {
+	if format == FormatJSON {
+		return json.Unmarshal(content, v)
+	}
+	return yaml.Unmarshal(content, v)
+}
@@ -5,12 +5,18 @@ import (
 
 	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/katiem0/gh-seva/internal/data"
+	"github.com/katiem0/gh-seva/internal/providers"
 	"github.com/shurcooL/graphql"
 )
 
+// Getter is the GitHub API a command reads and writes secrets, variables and
+// environments through. GitHubGetter implements it with the REST and GraphQL
+// APIs, and another backend, such as recorded fixtures or a fake
+// organization, can be used in its place with NewAPIGetter.
 type Getter interface {
-	GetReposList(owner string, endCursor *string) ([]data.ReposQuery, error)
-	GetRepo(owner string, name string) ([]data.RepoSingleQuery, error)
+	GetReposList(owner string, endCursor *string) (*data.ReposQuery, error)
+	GetRepo(owner string, name string) (*data.RepoSingleQuery, error)
+	GetRepoIDs(owner string, names []string) (map[string]int, error)
 	GetOrgActionSecrets(owner string) ([]byte, error)
 	GetRepoActionSecrets(owner string, repo string) ([]byte, error)
 	GetScopedOrgActionSecrets(owner string, secret string) ([]byte, error)
@@ -20,66 +26,106 @@ type Getter interface {
 	GetOrgCodespacesSecrets(owner string) ([]byte, error)
 	GetRepoCodespacesSecrets(owner string, repo string) ([]byte, error)
 	GetScopedOrgCodespacesSecrets(owner string, secret string) ([]byte, error)
-	CreateSecretsList(data [][]string) []data.ImportedSecret
+	GetRepoEnvironments(owner string, repo string) ([]byte, error)
+	GetEnvironmentActionSecrets(owner string, repo string, environment string) ([]byte, error)
 	GetOrgActionPublicKey(owner string) ([]byte, error)
 	GetRepoActionPublicKey(owner string, repo string) ([]byte, error)
 	GetOrgCodespacesPublicKey(owner string) ([]byte, error)
 	GetRepoCodespacesPublicKey(owner string, repo string) ([]byte, error)
 	GetOrgDependabotPublicKey(owner string) ([]byte, error)
 	GetRepoDependabotPublicKey(owner string, repo string) ([]byte, error)
-	EncryptSecret(publickey string, secret string) (string, error)
+	GetEnvironmentActionPublicKey(owner string, repo string, environment string) ([]byte, error)
 	CreateOrgActionSecret(owner string, secret string, data io.Reader) error
 	CreateRepoActionSecret(owner string, repo string, secret string, data io.Reader) error
 	CreateOrgCodespacesSecret(owner string, secret string, data io.Reader) error
 	CreateRepoCodespacesSecret(owner string, repo string, secret string, data io.Reader) error
 	CreateOrgDependabotSecret(owner string, secret string, data io.Reader) error
 	CreateRepoDependabotSecret(owner string, repo string, secret string, data io.Reader) error
+	CreateEnvironmentActionSecret(owner string, repo string, environment string, secret string, data io.Reader) error
 	GetOrgActionVariables(owner string) ([]byte, error)
 	GetRepoActionVariables(owner string, repo string) ([]byte, error)
 	GetScopedOrgActionVariables(owner string, secret string) ([]byte, error)
+	GetEnvironmentActionVariables(owner string, repo string, environment string) ([]byte, error)
+	CreateOrganizationVariable(owner string, data io.Reader) error
+	CreateRepoVariable(owner string, repo string, data io.Reader) error
+	CreateEnvironmentVariable(owner string, repo string, environment string, data io.Reader) error
+	UpdateOrganizationVariable(owner string, variable string, data io.Reader) error
+	UpdateRepoVariable(owner string, repo string, variable string, data io.Reader) error
+	UpdateEnvironmentVariable(owner string, repo string, environment string, variable string, data io.Reader) error
+	GetEnvironmentBranchPolicies(owner string, repo string, environment string) ([]byte, error)
+	GetUser(login string) ([]byte, error)
+	GetOrgTeam(owner string, slug string) ([]byte, error)
+	GetRepoContent(owner string, repo string, filePath string) ([]byte, error)
+	CreateEnvironment(owner string, repo string, environment string, data io.Reader) error
+	CreateEnvironmentBranchPolicy(owner string, repo string, environment string, data io.Reader) error
+	DeleteOrgActionSecret(owner string, secret string) error
+	DeleteRepoActionSecret(owner string, repo string, secret string) error
+	DeleteEnvironmentActionSecret(owner string, repo string, environment string, secret string) error
+	DeleteOrgCodespacesSecret(owner string, secret string) error
+	DeleteRepoCodespacesSecret(owner string, repo string, secret string) error
+	DeleteOrgDependabotSecret(owner string, secret string) error
+	DeleteRepoDependabotSecret(owner string, repo string, secret string) error
+	DeleteOrganizationVariable(owner string, variable string) error
+	DeleteRepoVariable(owner string, repo string, variable string) error
+	DeleteEnvironmentVariable(owner string, repo string, environment string, variable string) error
 }
 
-type APIGetter struct {
+// GitHubGetter is the Getter backed by the GitHub REST and GraphQL APIs of
+// GitHub.com or a GitHub Enterprise Server.
+type GitHubGetter struct {
 	gqlClient  api.GraphQLClient
 	restClient api.RESTClient
 }
 
-func NewAPIGetter(gqlClient *api.GraphQLClient, restClient *api.RESTClient) *APIGetter {
-	return &APIGetter{
+var _ Getter = (*GitHubGetter)(nil)
+
+func NewGitHubGetter(gqlClient *api.GraphQLClient, restClient *api.RESTClient) *GitHubGetter {
+	return &GitHubGetter{
 		gqlClient:  *gqlClient,
 		restClient: *restClient,
 	}
 }
 
-type sourceAPIGetter struct {
-	restClient api.RESTClient
+// APIGetter builds the commands, such as creating a secret from a file,
+// on the API calls of a Getter.
+type APIGetter struct {
+	Getter
+	stores *providers.Registry
+	keys   *publicKeyCache
 }
 
-func NewSourceAPIGetter(restClient api.RESTClient) *sourceAPIGetter {
-	return &sourceAPIGetter{
-		restClient: restClient,
+func NewAPIGetter(backend Getter) *APIGetter {
+	return &APIGetter{
+		Getter: backend,
+		keys:   newPublicKeyCache(),
 	}
 }
 
-func (g *APIGetter) GetReposList(owner string, endCursor *string) (*data.ReposQuery, error) {
+// SetSecretStores sets the providers that secret value references are
+// resolved with when each secret is written.
+func (g *APIGetter) SetSecretStores(stores *providers.Registry) {
+	g.stores = stores
+}
+
+func (g *GitHubGetter) GetReposList(owner string, endCursor *string) (*data.ReposQuery, error) {
 	query := new(data.ReposQuery)
 	variables := map[string]interface{}{
 		"endCursor": (*graphql.String)(endCursor),
 		"owner":     graphql.String(owner),
 	}
 
-	err := g.gqlClient.Query("getRepos", &query, variables)
+	err := doQuery(&g.gqlClient, "getRepos", &query, variables)
 
 	return query, err
 }
 
-func (g *APIGetter) GetRepo(owner string, name string) (*data.RepoSingleQuery, error) {
+func (g *GitHubGetter) GetRepo(owner string, name string) (*data.RepoSingleQuery, error) {
 	query := new(data.RepoSingleQuery)
 	variables := map[string]interface{}{
 		"owner": graphql.String(owner),
 		"name":  graphql.String(name),
 	}
 
-	err := g.gqlClient.Query("getRepo", &query, variables)
+	err := doQuery(&g.gqlClient, "getRepo", &query, variables)
 	return query, err
 }
@@ -11,15 +11,15 @@ import (
 	"github.com/katiem0/gh-seva/internal/data"
 )
 
-func TestNewAPIGetter(t *testing.T) {
+func TestNewGitHubGetter(t *testing.T) {
 	// Create mock clients
 	gqlClient := &api.GraphQLClient{}
 	restClient := &api.RESTClient{}
 
-	getter := NewAPIGetter(gqlClient, restClient)
+	getter := NewGitHubGetter(gqlClient, restClient)
 
 	if getter == nil {
-		t.Error("NewAPIGetter() returned nil")
+		t.Error("NewGitHubGetter() returned nil")
 		return
 	}
 
@@ -32,19 +32,21 @@ func TestNewAPIGetter(t *testing.T) {
 	}
 }
 
-func TestNewSourceAPIGetter(t *testing.T) {
-	// Create mock client
-	restClient := api.RESTClient{}
+func TestNewAPIGetter(t *testing.T) {
+	backend := NewMockAPIGetter()
+	backend.OrgActionSecretsData = []byte(`{"total_count":0,"secrets":[]}`)
 
-	getter := NewSourceAPIGetter(restClient)
+	getter := NewAPIGetter(backend)
 
-	if getter == nil {
-		t.Error("NewAPIGetter() returned nil")
-		return
+	if getter.Getter != backend {
+		t.Error("Backend not properly set")
 	}
-
-	if getter.restClient != restClient {
-		t.Error("REST client not properly set")
+	if getter.keys == nil {
+		t.Error("Public key cache not created")
+	}
+	response, err := getter.GetOrgActionSecrets("test-org")
+	if err != nil || string(response) != string(backend.OrgActionSecretsData) {
+		t.Errorf("Expected the backend to serve the request, got %s, %v", response, err)
 	}
 }
 
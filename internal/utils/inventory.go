@@ -0,0 +1,304 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+// SecretState is a secret as it is set in an organization, in the form read
+// from a file. Secret values can't be read, so the value is always empty.
+type SecretState struct {
+	data.ImportedSecret
+	UpdatedAt time.Time
+}
+
+// VariableState is a variable as it is set in an organization, in the form
+// read from a file.
+type VariableState struct {
+	data.ImportedVariable
+	UpdatedAt time.Time
+}
+
+// OrgState is every organization, repository and environment level secret
+// and variable of an organization.
+type OrgState struct {
+	Secrets   []SecretState
+	Variables []VariableState
+}
+
+// secretSource reads the secrets of one application from an organization
+// and its repositories.
+type secretSource struct {
+	secretType  string
+	orgSecrets  func(owner string) ([]byte, error)
+	scopedRepos func(owner string, secret string) ([]byte, error)
+	repoSecrets func(owner string, repo string) ([]byte, error)
+}
+
+func (g *APIGetter) secretSources() []secretSource {
+	return []secretSource{
+		{"Actions", g.GetOrgActionSecrets, g.GetScopedOrgActionSecrets, g.GetRepoActionSecrets},
+		{"Dependabot", g.GetOrgDependabotSecrets, g.GetScopedOrgDependabotSecrets, g.GetRepoDependabotSecrets},
+		{"Codespaces", g.GetOrgCodespacesSecrets, g.GetScopedOrgCodespacesSecrets, g.GetRepoCodespacesSecrets},
+	}
+}
+
+// ReadOrgSecrets reads the names and scopes of the organization, repository
+// and environment secrets of owner, reading up to concurrency repositories
+// at once.
+func (g *APIGetter) ReadOrgSecrets(owner string, concurrency int) ([]SecretState, error) {
+	state, err := g.ReadOrgState(owner, concurrency, true, false)
+	if err != nil {
+		return nil, err
+	}
+	return state.Secrets, nil
+}
+
+// ReadOrgState reads the secrets and, or, the variables of owner, its
+// repositories and their environments. An application whose secrets are
+// not found, such as Codespaces on GitHub Enterprise Server, has none.
+func (g *APIGetter) ReadOrgState(owner string, concurrency int, secrets bool, variables bool) (*OrgState, error) {
+	state := &OrgState{}
+	if secrets {
+		for _, app := range g.secretSources() {
+			zap.S().Debugf("Gathering Organization %s secrets for %s", app.secretType, owner)
+			orgSecrets, err := app.readOrgSecrets(owner)
+			if err != nil {
+				return nil, err
+			}
+			state.Secrets = append(state.Secrets, orgSecrets...)
+		}
+	}
+	if variables {
+		zap.S().Debugf("Gathering Organization variables for %s", owner)
+		orgVariables, err := g.readOrgVariables(owner)
+		if err != nil {
+			return nil, err
+		}
+		state.Variables = append(state.Variables, orgVariables...)
+	}
+
+	allRepos, err := g.ListRepos(owner, nil)
+	if err != nil {
+		return nil, err
+	}
+	repoStates := make([]OrgState, len(allRepos))
+	repoErrors := make([]error, len(allRepos))
+	RunConcurrently(concurrency, len(allRepos), func(index int) {
+		repo := allRepos[index]
+		zap.S().Debugf("Gathering repo level secrets and variables for %s", repo.Name)
+		repoStates[index], repoErrors[index] = g.readRepoState(owner, repo, secrets, variables)
+	})
+	for index := range allRepos {
+		if repoErrors[index] != nil {
+			return nil, repoErrors[index]
+		}
+		state.Secrets = append(state.Secrets, repoStates[index].Secrets...)
+		state.Variables = append(state.Variables, repoStates[index].Variables...)
+	}
+	return state, nil
+}
+
+func (app secretSource) readOrgSecrets(owner string) ([]SecretState, error) {
+	secrets, err := readSecrets(app.orgSecrets(owner))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s secrets of %s: %w", app.secretType, owner, err)
+	}
+
+	var states []SecretState
+	for _, secret := range secrets {
+		state := SecretState{
+			ImportedSecret: data.ImportedSecret{
+				Level:  "Organization",
+				Type:   app.secretType,
+				Name:   secret.Name,
+				Access: secret.Visibility,
+			},
+			UpdatedAt: secret.UpdatedAt,
+		}
+		if secret.Visibility == "selected" {
+			scopedRepos, err := app.scopedRepos(owner, secret.Name)
+			var scopedResponse data.ScopedResponse
+			if err == nil {
+				err = json.Unmarshal(scopedRepos, &scopedResponse)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read repositories of %s secret %s: %w", app.secretType, secret.Name, err)
+			}
+			for _, repo := range scopedResponse.Repositories {
+				state.RepositoryNames = append(state.RepositoryNames, repo.Name)
+				state.RepositoryIDs = append(state.RepositoryIDs, strconv.Itoa(repo.ID))
+			}
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (g *APIGetter) readOrgVariables(owner string) ([]VariableState, error) {
+	variables, err := readVariables(g.GetOrgActionVariables(owner))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variables of %s: %w", owner, err)
+	}
+
+	var states []VariableState
+	for _, variable := range variables {
+		state := VariableState{
+			ImportedVariable: data.ImportedVariable{
+				Level:      "Organization",
+				Name:       variable.Name,
+				Value:      variable.Value,
+				Visibility: variable.Visibility,
+			},
+			UpdatedAt: variable.UpdatedAt,
+		}
+		if variable.Visibility == "selected" {
+			response, err := g.GetScopedOrgActionVariables(owner, variable.Name)
+			var scopedResponse data.ScopedResponse
+			if err == nil {
+				err = json.Unmarshal(response, &scopedResponse)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read repositories of variable %s: %w", variable.Name, err)
+			}
+			for _, repo := range scopedResponse.Repositories {
+				state.SelectedRepos = append(state.SelectedRepos, repo.Name)
+				state.SelectedReposIDs = append(state.SelectedReposIDs, strconv.Itoa(repo.ID))
+			}
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// readRepoState reads the secrets and, or, the variables of a single
+// repository and of its environments.
+func (g *APIGetter) readRepoState(owner string, repo data.RepoInfo, secrets bool, variables bool) (OrgState, error) {
+	repoID := strconv.Itoa(repo.DatabaseId)
+	var state OrgState
+	if secrets {
+		for _, app := range g.secretSources() {
+			repoSecrets, err := readSecrets(app.repoSecrets(owner, repo.Name))
+			if err != nil {
+				return state, fmt.Errorf("failed to read %s secrets of %s/%s: %w", app.secretType, owner, repo.Name, err)
+			}
+			for _, secret := range repoSecrets {
+				state.Secrets = append(state.Secrets, SecretState{
+					ImportedSecret: data.ImportedSecret{
+						Level:           "Repository",
+						Type:            app.secretType,
+						Name:            secret.Name,
+						Access:          "RepoOnly",
+						RepositoryNames: []string{repo.Name},
+						RepositoryIDs:   []string{repoID},
+					},
+					UpdatedAt: secret.UpdatedAt,
+				})
+			}
+		}
+	}
+	if variables {
+		repoVariables, err := readVariables(g.GetRepoActionVariables(owner, repo.Name))
+		if err != nil {
+			return state, fmt.Errorf("failed to read variables of %s/%s: %w", owner, repo.Name, err)
+		}
+		for _, variable := range repoVariables {
+			state.Variables = append(state.Variables, VariableState{
+				ImportedVariable: data.ImportedVariable{
+					Level:            "Repository",
+					Name:             variable.Name,
+					Value:            variable.Value,
+					Visibility:       "RepoOnly",
+					SelectedRepos:    []string{repo.Name},
+					SelectedReposIDs: []string{repoID},
+				},
+				UpdatedAt: variable.UpdatedAt,
+			})
+		}
+	}
+
+	response, err := g.GetRepoEnvironments(owner, repo.Name)
+	var environmentsResponse data.EnvironmentsResponse
+	if err == nil {
+		err = json.Unmarshal(response, &environmentsResponse)
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read environments of %s/%s: %w", owner, repo.Name, err)
+	}
+	for _, environment := range environmentsResponse.Environments {
+		if secrets {
+			envSecrets, err := readSecrets(g.GetEnvironmentActionSecrets(owner, repo.Name, environment.Name))
+			if err != nil {
+				return state, fmt.Errorf("failed to read secrets of environment %s in %s/%s: %w", environment.Name, owner, repo.Name, err)
+			}
+			for _, secret := range envSecrets {
+				state.Secrets = append(state.Secrets, SecretState{
+					ImportedSecret: data.ImportedSecret{
+						Level:           "Environment",
+						Type:            "Actions",
+						Name:            secret.Name,
+						Access:          "EnvironmentOnly",
+						RepositoryNames: []string{repo.Name},
+						RepositoryIDs:   []string{repoID},
+						EnvironmentName: environment.Name,
+					},
+					UpdatedAt: secret.UpdatedAt,
+				})
+			}
+		}
+		if variables {
+			envVariables, err := readVariables(g.GetEnvironmentActionVariables(owner, repo.Name, environment.Name))
+			if err != nil {
+				return state, fmt.Errorf("failed to read variables of environment %s in %s/%s: %w", environment.Name, owner, repo.Name, err)
+			}
+			for _, variable := range envVariables {
+				state.Variables = append(state.Variables, VariableState{
+					ImportedVariable: data.ImportedVariable{
+						Level:            "Environment",
+						Name:             variable.Name,
+						Value:            variable.Value,
+						Visibility:       "EnvironmentOnly",
+						SelectedRepos:    []string{repo.Name},
+						SelectedReposIDs: []string{repoID},
+						EnvironmentName:  environment.Name,
+					},
+					UpdatedAt: variable.UpdatedAt,
+				})
+			}
+		}
+	}
+	return state, nil
+}
+
+// readSecrets decodes a list of secrets, treating a list that is not found
+// as empty.
+func readSecrets(response []byte, err error) ([]data.Secret, error) {
+	if IsNotFound(err) {
+		zap.S().Debugf("Secrets not found, treating them as empty: %v", err)
+		return nil, nil
+	}
+	var secretsResponse data.SecretsResponse
+	if err == nil {
+		err = json.Unmarshal(response, &secretsResponse)
+	}
+	return secretsResponse.Secrets, err
+}
+
+// readVariables decodes a list of variables, treating a list that is not
+// found as empty.
+func readVariables(response []byte, err error) ([]data.Variable, error) {
+	if IsNotFound(err) {
+		zap.S().Debugf("Variables not found, treating them as empty: %v", err)
+		return nil, nil
+	}
+	var variablesResponse data.VariableResponse
+	if err == nil {
+		err = json.Unmarshal(response, &variablesResponse)
+	}
+	return variablesResponse.Variables, err
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// inventoryTestHandler lists the repositories api and web over GraphQL and
+// serves the given REST responses, every other list being not found.
+func inventoryTestHandler(t *testing.T, responses map[string]string, requested *[]string) http.HandlerFunc {
+	var mu sync.Mutex
+	rest := planTestHandler(t, responses, nil)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/graphql" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"totalCount":2,"nodes":[{"databaseId":1,"name":"api"},{"databaseId":2,"name":"web"}],"pageInfo":{"hasNextPage":false}}}}}`))
+			return
+		}
+		if requested != nil {
+			mu.Lock()
+			*requested = append(*requested, r.URL.Path)
+			mu.Unlock()
+		}
+		rest(w, r)
+	}
+}
+
+var inventoryResponses = map[string]string{
+	"orgs/test-org/actions/secrets":                         `{"total_count":1,"secrets":[{"name":"DEPLOY_KEY","visibility":"selected","updated_at":"2024-05-01T10:00:00Z"}]}`,
+	"orgs/test-org/actions/secrets/DEPLOY_KEY/repositories": `{"total_count":1,"repositories":[{"id":2,"name":"web"}]}`,
+	"orgs/test-org/dependabot/secrets":                      `{"total_count":1,"secrets":[{"name":"REGISTRY","visibility":"all"}]}`,
+	"orgs/test-org/actions/variables":                       `{"total_count":1,"variables":[{"name":"REGION","value":"eu","visibility":"selected"}]}`,
+	"orgs/test-org/actions/variables/REGION/repositories":   `{"total_count":1,"repositories":[{"id":1,"name":"api"}]}`,
+	"repos/test-org/api/actions/secrets":                    `{"total_count":1,"secrets":[{"name":"API_KEY"}]}`,
+	"repos/test-org/api/actions/variables":                  `{"total_count":1,"variables":[{"name":"LOG_LEVEL","value":"debug"}]}`,
+	"repos/test-org/api/environments":                       `{"total_count":1,"environments":[{"name":"production"}]}`,
+	"repos/test-org/api/environments/production/secrets":    `{"total_count":1,"secrets":[{"name":"DB_PASSWORD"}]}`,
+	"repos/test-org/api/environments/production/variables":  `{"total_count":1,"variables":[{"name":"DB_HOST","value":"db.internal"}]}`,
+	"repos/test-org/web/environments":                       `{"total_count":0,"environments":[]}`,
+}
+
+func TestReadOrgState(t *testing.T) {
+	g := newFakeAPIGetter(t, inventoryTestHandler(t, inventoryResponses, nil))
+
+	state, err := g.ReadOrgState("test-org", 2, true, true)
+	if err != nil {
+		t.Fatalf("ReadOrgState() error = %v", err)
+	}
+
+	var secrets []string
+	for _, secret := range state.Secrets {
+		secrets = append(secrets, strings.Join([]string{secret.Level, secret.Type, secret.Name, secret.Access, strings.Join(secret.RepositoryNames, ";"), secret.EnvironmentName}, "/"))
+	}
+	expectedSecrets := []string{
+		"Organization/Actions/DEPLOY_KEY/selected/web/",
+		"Organization/Dependabot/REGISTRY/all//",
+		"Repository/Actions/API_KEY/RepoOnly/api/",
+		"Environment/Actions/DB_PASSWORD/EnvironmentOnly/api/production",
+	}
+	if strings.Join(secrets, ",") != strings.Join(expectedSecrets, ",") {
+		t.Errorf("Expected secrets %v, got %v", expectedSecrets, secrets)
+	}
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if !state.Secrets[0].UpdatedAt.Equal(want) {
+		t.Errorf("Expected updated_at %v, got %v", want, state.Secrets[0].UpdatedAt)
+	}
+	if strings.Join(state.Secrets[0].RepositoryIDs, ";") != "2" {
+		t.Errorf("Expected the scoped repository IDs to be kept, got %v", state.Secrets[0].RepositoryIDs)
+	}
+
+	var variables []string
+	for _, variable := range state.Variables {
+		variables = append(variables, strings.Join([]string{variable.Level, variable.Name, variable.Value, variable.Visibility, strings.Join(variable.SelectedRepos, ";"), variable.EnvironmentName}, "/"))
+	}
+	expectedVariables := []string{
+		"Organization/REGION/eu/selected/api/",
+		"Repository/LOG_LEVEL/debug/RepoOnly/api/",
+		"Environment/DB_HOST/db.internal/EnvironmentOnly/api/production",
+	}
+	if strings.Join(variables, ",") != strings.Join(expectedVariables, ",") {
+		t.Errorf("Expected variables %v, got %v", expectedVariables, variables)
+	}
+}
+
+func TestReadOrgSecrets(t *testing.T) {
+	var requested []string
+	g := newFakeAPIGetter(t, inventoryTestHandler(t, inventoryResponses, &requested))
+
+	secrets, err := g.ReadOrgSecrets("test-org", 1)
+	if err != nil {
+		t.Fatalf("ReadOrgSecrets() error = %v", err)
+	}
+	if len(secrets) != 4 {
+		t.Errorf("Expected 4 secrets, got %+v", secrets)
+	}
+	for _, path := range requested {
+		if strings.Contains(path, "/variables") {
+			t.Errorf("Expected no variables to be read, got a request for %s", path)
+		}
+	}
+}
+
+func TestReadOrgStateError(t *testing.T) {
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+	})
+
+	_, err := g.ReadOrgState("test-org", 1, false, true)
+	if err == nil || !strings.Contains(err.Error(), "failed to read variables of test-org") {
+		t.Errorf("Expected a read error for the organization variables, got %v", err)
+	}
+}
@@ -5,8 +5,10 @@ package utils
 import (
 	"fmt"
 	"io"
-	"strings"
+	"net/http"
+	"sync"
 
+	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/katiem0/gh-seva/internal/data"
 )
 
@@ -23,9 +25,22 @@ type MockAPIGetter struct {
 	OrgCodespacesSecretsData       []byte
 	RepoCodespacesSecretsData      []byte
 	ScopedOrgCodespacesSecretsData []byte
+	RepoEnvironmentsData           []byte
+	EnvironmentActionSecretsData   []byte
 	OrgActionVariablesData         []byte
 	RepoActionVariablesData        []byte
 	ScopedOrgActionVariablesData   []byte
+	EnvironmentActionVariablesData []byte
+	BranchPoliciesData             []byte
+	UserData                       []byte
+	TeamData                       []byte
+	RepoContents                   map[string][]byte
+	RepoIDs                        map[string]int
+	CreatedEnvironments            []string
+	CreatedBranchPolicies          []string
+	Created                        []string
+	Updated                        []string
+	Deleted                        []string
 	PublicKeyData                  []byte
 	EncryptedSecret                string
 	ImportedSecrets                []data.ImportedSecret
@@ -33,87 +48,115 @@ type MockAPIGetter struct {
 	GetReposListError              bool
 	GetOrgActionSecretsError       bool
 	ShouldReturnError              bool
+
+	mu sync.Mutex
 }
 
+var _ Getter = (*MockAPIGetter)(nil)
+
 func NewMockAPIGetter() *MockAPIGetter {
 	return &MockAPIGetter{}
 }
 
 // GetReposList mocks the retrieval of repositories list
 func (m *MockAPIGetter) GetReposList(owner string, endCursor *string) (*data.ReposQuery, error) {
+	if m.GetReposListError {
+		return nil, fmt.Errorf("mock error for GetReposList")
+	}
+	if m.ReposResponse == nil {
+		return &data.ReposQuery{}, nil
+	}
 	return m.ReposResponse, nil
 }
 
 // GetRepo mocks the retrieval of a single repository
 func (m *MockAPIGetter) GetRepo(owner string, name string) (*data.RepoSingleQuery, error) {
+	if m.GetRepoError {
+		return nil, fmt.Errorf("mock error for GetRepo")
+	}
+	if m.RepoResponse == nil {
+		return &data.RepoSingleQuery{}, nil
+	}
 	return m.RepoResponse, nil
 }
 
 // GetOrgActionSecrets mocks retrieving organization action secrets
 func (m *MockAPIGetter) GetOrgActionSecrets(owner string) ([]byte, error) {
-	return m.OrgActionSecretsData, nil
+	if m.GetOrgActionSecretsError {
+		return nil, fmt.Errorf("mock error for GetOrgActionSecrets")
+	}
+	return orEmpty(m.OrgActionSecretsData), nil
 }
 
 // GetRepoActionSecrets mocks retrieving repository action secrets
 func (m *MockAPIGetter) GetRepoActionSecrets(owner string, repo string) ([]byte, error) {
-	return m.RepoActionSecretsData, nil
+	return orEmpty(m.RepoActionSecretsData), nil
 }
 
 // GetScopedOrgActionSecrets mocks retrieving scoped organization action secrets
 func (m *MockAPIGetter) GetScopedOrgActionSecrets(owner string, secret string) ([]byte, error) {
-	return m.ScopedOrgActionSecretsData, nil
+	return orEmpty(m.ScopedOrgActionSecretsData), nil
 }
 
 // GetOrgDependabotSecrets mocks retrieving organization dependabot secrets
 func (m *MockAPIGetter) GetOrgDependabotSecrets(owner string) ([]byte, error) {
-	return m.OrgDependabotSecretsData, nil
+	return orEmpty(m.OrgDependabotSecretsData), nil
 }
 
 // GetRepoDependabotSecrets mocks retrieving repository dependabot secrets
 func (m *MockAPIGetter) GetRepoDependabotSecrets(owner string, repo string) ([]byte, error) {
-	return m.RepoDependabotSecretsData, nil
+	return orEmpty(m.RepoDependabotSecretsData), nil
 }
 
 // GetScopedOrgDependabotSecrets mocks retrieving scoped organization dependabot secrets
 func (m *MockAPIGetter) GetScopedOrgDependabotSecrets(owner string, secret string) ([]byte, error) {
-	return m.ScopedOrgDependabotSecretsData, nil
+	return orEmpty(m.ScopedOrgDependabotSecretsData), nil
 }
 
 // GetOrgCodespacesSecrets mocks retrieving organization codespaces secrets
 func (m *MockAPIGetter) GetOrgCodespacesSecrets(owner string) ([]byte, error) {
-	return m.OrgCodespacesSecretsData, nil
+	return orEmpty(m.OrgCodespacesSecretsData), nil
 }
 
 // GetRepoCodespacesSecrets mocks retrieving repository codespaces secrets
 func (m *MockAPIGetter) GetRepoCodespacesSecrets(owner string, repo string) ([]byte, error) {
-	return m.RepoCodespacesSecretsData, nil
+	return orEmpty(m.RepoCodespacesSecretsData), nil
 }
 
 // GetScopedOrgCodespacesSecrets mocks retrieving scoped organization codespaces secrets
 func (m *MockAPIGetter) GetScopedOrgCodespacesSecrets(owner string, secret string) ([]byte, error) {
-	return m.ScopedOrgCodespacesSecretsData, nil
+	return orEmpty(m.ScopedOrgCodespacesSecretsData), nil
+}
+
+// GetRepoEnvironments mocks retrieving the environments of a repository
+func (m *MockAPIGetter) GetRepoEnvironments(owner string, repo string) ([]byte, error) {
+	return orEmpty(m.RepoEnvironmentsData), nil
+}
+
+// GetEnvironmentActionSecrets mocks retrieving environment action secrets
+func (m *MockAPIGetter) GetEnvironmentActionSecrets(owner string, repo string, environment string) ([]byte, error) {
+	return orEmpty(m.EnvironmentActionSecretsData), nil
 }
 
-// CreateSecretsList mocks creating a list of secrets from CSV data
+// CreateSecretsList returns ImportedSecrets when set, otherwise it parses
+// the CSV data as APIGetter does
 func (m *MockAPIGetter) CreateSecretsList(filedata [][]string) []data.ImportedSecret {
 	if m.ImportedSecrets != nil {
 		return m.ImportedSecrets
 	}
+	return (&APIGetter{}).CreateSecretsList(filedata)
+}
 
-	// Provide a default implementation if no mock data is set
-	var importSecretList []data.ImportedSecret
-	var secret data.ImportedSecret
-	for _, each := range filedata[1:] {
-		secret.Level = each[0]
-		secret.Type = each[1]
-		secret.Name = each[2]
-		secret.Value = each[3]
-		secret.Access = each[4]
-		secret.RepositoryNames = strings.Split(each[5], ";")
-		secret.RepositoryIDs = strings.Split(each[6], ";")
-		importSecretList = append(importSecretList, secret)
+// GetRepoIDs mocks looking up repositories, returning the IDs in RepoIDs of
+// the named repositories
+func (m *MockAPIGetter) GetRepoIDs(owner string, names []string) (map[string]int, error) {
+	ids := map[string]int{}
+	for _, name := range names {
+		if id, ok := m.RepoIDs[name]; ok {
+			ids[name] = id
+		}
 	}
-	return importSecretList
+	return ids, nil
 }
 
 // GetOrgActionPublicKey mocks retrieving organization action public key
@@ -146,6 +189,11 @@ func (m *MockAPIGetter) GetRepoDependabotPublicKey(owner string, repo string) ([
 	return m.PublicKeyData, nil
 }
 
+// GetEnvironmentActionPublicKey mocks retrieving environment action public key
+func (m *MockAPIGetter) GetEnvironmentActionPublicKey(owner string, repo string, environment string) ([]byte, error) {
+	return m.PublicKeyData, nil
+}
+
 // EncryptSecret mocks encrypting a secret
 func (m *MockAPIGetter) EncryptSecret(publickey string, secret string) (string, error) {
 	return m.EncryptedSecret, nil
@@ -153,75 +201,194 @@ func (m *MockAPIGetter) EncryptSecret(publickey string, secret string) (string,
 
 // CreateOrgActionSecret mocks creating an organization action secret
 func (m *MockAPIGetter) CreateOrgActionSecret(owner string, secret string, data io.Reader) error {
-	return nil
+	return m.record(&m.Created, "CreateOrgActionSecret", fmt.Sprintf("orgs/%s/actions/secrets/%s", owner, secret))
 }
 
 // CreateRepoActionSecret mocks creating a repository action secret
 func (m *MockAPIGetter) CreateRepoActionSecret(owner string, repo string, secret string, data io.Reader) error {
-	return nil
+	return m.record(&m.Created, "CreateRepoActionSecret", fmt.Sprintf("repos/%s/%s/actions/secrets/%s", owner, repo, secret))
 }
 
 // CreateOrgCodespacesSecret mocks creating an organization codespaces secret
 func (m *MockAPIGetter) CreateOrgCodespacesSecret(owner string, secret string, data io.Reader) error {
-	return nil
+	return m.record(&m.Created, "CreateOrgCodespacesSecret", fmt.Sprintf("orgs/%s/codespaces/secrets/%s", owner, secret))
 }
 
 // CreateRepoCodespacesSecret mocks creating a repository codespaces secret
 func (m *MockAPIGetter) CreateRepoCodespacesSecret(owner string, repo string, secret string, data io.Reader) error {
-	return nil
+	return m.record(&m.Created, "CreateRepoCodespacesSecret", fmt.Sprintf("repos/%s/%s/codespaces/secrets/%s", owner, repo, secret))
 }
 
 // CreateOrgDependabotSecret mocks creating an organization dependabot secret
 func (m *MockAPIGetter) CreateOrgDependabotSecret(owner string, secret string, data io.Reader) error {
-	return nil
+	return m.record(&m.Created, "CreateOrgDependabotSecret", fmt.Sprintf("orgs/%s/dependabot/secrets/%s", owner, secret))
 }
 
 // CreateRepoDependabotSecret mocks creating a repository dependabot secret
 func (m *MockAPIGetter) CreateRepoDependabotSecret(owner string, repo string, secret string, data io.Reader) error {
-	return nil
+	return m.record(&m.Created, "CreateRepoDependabotSecret", fmt.Sprintf("repos/%s/%s/dependabot/secrets/%s", owner, repo, secret))
+}
+
+// CreateEnvironmentActionSecret mocks creating an environment action secret
+func (m *MockAPIGetter) CreateEnvironmentActionSecret(owner string, repo string, environment string, secret string, data io.Reader) error {
+	return m.record(&m.Created, "CreateEnvironmentActionSecret", fmt.Sprintf("repos/%s/%s/environments/%s/secrets/%s", owner, repo, environment, secret))
 }
 
 // GetOrgActionVariables mocks retrieving organization action variables
 func (m *MockAPIGetter) GetOrgActionVariables(owner string) ([]byte, error) {
-	return m.OrgActionVariablesData, nil
+	return orEmpty(m.OrgActionVariablesData), nil
 }
 
 // GetRepoActionVariables mocks retrieving repository action variables
 func (m *MockAPIGetter) GetRepoActionVariables(owner string, repo string) ([]byte, error) {
-	return m.RepoActionVariablesData, nil
+	return orEmpty(m.RepoActionVariablesData), nil
 }
 
 // GetScopedOrgActionVariables mocks retrieving scoped organization action variables
 func (m *MockAPIGetter) GetScopedOrgActionVariables(owner string, variable string) ([]byte, error) {
-	return m.ScopedOrgActionVariablesData, nil
+	return orEmpty(m.ScopedOrgActionVariablesData), nil
+}
+
+// GetEnvironmentActionVariables mocks retrieving environment action variables
+func (m *MockAPIGetter) GetEnvironmentActionVariables(owner string, repo string, environment string) ([]byte, error) {
+	return orEmpty(m.EnvironmentActionVariablesData), nil
 }
 
-// CreateVariableList mocks creating a list of variables from CSV data
+// CreateVariableList parses the CSV data as APIGetter does
 func (m *MockAPIGetter) CreateVariableList(filedata [][]string) []data.ImportedVariable {
-	var variableList []data.ImportedVariable
-	var vars data.ImportedVariable
-	for _, each := range filedata[1:] {
-		vars.Level = each[0]
-		vars.Name = each[1]
-		vars.Value = each[2]
-		vars.Visibility = each[3]
-		vars.SelectedRepos = strings.Split(each[4], ";")
-		vars.SelectedReposIDs = strings.Split(each[5], ";")
-		variableList = append(variableList, vars)
-	}
-	return variableList
+	return (&APIGetter{}).CreateVariableList(filedata)
 }
 
+// CreateOrganizationVariable mocks creating a variable, recording the path it is created at
 func (m *MockAPIGetter) CreateOrganizationVariable(owner string, data io.Reader) error {
+	return m.record(&m.Created, "CreateOrganizationVariable", fmt.Sprintf("orgs/%s/actions/variables", owner))
+}
+
+// CreateRepoVariable mocks creating a variable, recording the path it is created at
+func (m *MockAPIGetter) CreateRepoVariable(owner string, repo string, data io.Reader) error {
+	return m.record(&m.Created, "CreateRepoVariable", fmt.Sprintf("repos/%s/%s/actions/variables", owner, repo))
+}
+
+// CreateEnvironmentVariable mocks creating a variable, recording the path it is created at
+func (m *MockAPIGetter) CreateEnvironmentVariable(owner string, repo string, environment string, data io.Reader) error {
+	return m.record(&m.Created, "CreateEnvironmentVariable", fmt.Sprintf("repos/%s/%s/environments/%s/variables", owner, repo, environment))
+}
+
+// Update methods record the path of each updated variable
+func (m *MockAPIGetter) UpdateOrganizationVariable(owner string, variable string, data io.Reader) error {
+	return m.record(&m.Updated, "UpdateOrganizationVariable", fmt.Sprintf("orgs/%s/actions/variables/%s", owner, variable))
+}
+
+func (m *MockAPIGetter) UpdateRepoVariable(owner string, repo string, variable string, data io.Reader) error {
+	return m.record(&m.Updated, "UpdateRepoVariable", fmt.Sprintf("repos/%s/%s/actions/variables/%s", owner, repo, variable))
+}
+
+func (m *MockAPIGetter) UpdateEnvironmentVariable(owner string, repo string, environment string, variable string, data io.Reader) error {
+	return m.record(&m.Updated, "UpdateEnvironmentVariable", fmt.Sprintf("repos/%s/%s/environments/%s/variables/%s", owner, repo, environment, variable))
+}
+
+// GetEnvironmentBranchPolicies mocks retrieving environment deployment branch policies
+func (m *MockAPIGetter) GetEnvironmentBranchPolicies(owner string, repo string, environment string) ([]byte, error) {
+	return orEmpty(m.BranchPoliciesData), nil
+}
+
+// GetUser mocks retrieving a user account
+func (m *MockAPIGetter) GetUser(login string) ([]byte, error) {
+	return orEmpty(m.UserData), nil
+}
+
+// GetRepoContent mocks reading a file or directory, keyed by repo/path in
+// RepoContents, which is not found when missing
+func (m *MockAPIGetter) GetRepoContent(owner string, repo string, filePath string) ([]byte, error) {
+	content, ok := m.RepoContents[repo+"/"+filePath]
+	if !ok {
+		return nil, &api.HTTPError{StatusCode: http.StatusNotFound, Message: "Not Found"}
+	}
+	return content, nil
+}
+
+// GetOrgTeam mocks retrieving an organization team
+func (m *MockAPIGetter) GetOrgTeam(owner string, slug string) ([]byte, error) {
+	return orEmpty(m.TeamData), nil
+}
+
+// CreateEnvironment mocks creating a repository environment
+func (m *MockAPIGetter) CreateEnvironment(owner string, repo string, environment string, data io.Reader) error {
 	if m.ShouldReturnError {
-		return fmt.Errorf("mock error for CreateOrganizationVariable")
+		return fmt.Errorf("mock error for CreateEnvironment")
 	}
+	m.CreatedEnvironments = append(m.CreatedEnvironments, repo+"/"+environment)
 	return nil
 }
 
-func (m *MockAPIGetter) CreateRepoVariable(owner string, repo string, data io.Reader) error {
+// CreateEnvironmentBranchPolicy mocks creating an environment deployment branch policy
+func (m *MockAPIGetter) CreateEnvironmentBranchPolicy(owner string, repo string, environment string, data io.Reader) error {
+	if m.ShouldReturnError {
+		return fmt.Errorf("mock error for CreateEnvironmentBranchPolicy")
+	}
+	m.CreatedBranchPolicies = append(m.CreatedBranchPolicies, repo+"/"+environment)
+	return nil
+}
+
+// Delete methods record the path of each deleted secret or variable
+func (m *MockAPIGetter) DeleteOrgActionSecret(owner string, secret string) error {
+	return m.record(&m.Deleted, "DeleteOrgActionSecret", fmt.Sprintf("orgs/%s/actions/secrets/%s", owner, secret))
+}
+
+func (m *MockAPIGetter) DeleteRepoActionSecret(owner string, repo string, secret string) error {
+	return m.record(&m.Deleted, "DeleteRepoActionSecret", fmt.Sprintf("repos/%s/%s/actions/secrets/%s", owner, repo, secret))
+}
+
+func (m *MockAPIGetter) DeleteEnvironmentActionSecret(owner string, repo string, environment string, secret string) error {
+	return m.record(&m.Deleted, "DeleteEnvironmentActionSecret", fmt.Sprintf("repos/%s/%s/environments/%s/secrets/%s", owner, repo, environment, secret))
+}
+
+func (m *MockAPIGetter) DeleteOrgCodespacesSecret(owner string, secret string) error {
+	return m.record(&m.Deleted, "DeleteOrgCodespacesSecret", fmt.Sprintf("orgs/%s/codespaces/secrets/%s", owner, secret))
+}
+
+func (m *MockAPIGetter) DeleteRepoCodespacesSecret(owner string, repo string, secret string) error {
+	return m.record(&m.Deleted, "DeleteRepoCodespacesSecret", fmt.Sprintf("repos/%s/%s/codespaces/secrets/%s", owner, repo, secret))
+}
+
+func (m *MockAPIGetter) DeleteOrgDependabotSecret(owner string, secret string) error {
+	return m.record(&m.Deleted, "DeleteOrgDependabotSecret", fmt.Sprintf("orgs/%s/dependabot/secrets/%s", owner, secret))
+}
+
+func (m *MockAPIGetter) DeleteRepoDependabotSecret(owner string, repo string, secret string) error {
+	return m.record(&m.Deleted, "DeleteRepoDependabotSecret", fmt.Sprintf("repos/%s/%s/dependabot/secrets/%s", owner, repo, secret))
+}
+
+func (m *MockAPIGetter) DeleteOrganizationVariable(owner string, variable string) error {
+	return m.record(&m.Deleted, "DeleteOrganizationVariable", fmt.Sprintf("orgs/%s/actions/variables/%s", owner, variable))
+}
+
+func (m *MockAPIGetter) DeleteRepoVariable(owner string, repo string, variable string) error {
+	return m.record(&m.Deleted, "DeleteRepoVariable", fmt.Sprintf("repos/%s/%s/actions/variables/%s", owner, repo, variable))
+}
+
+func (m *MockAPIGetter) DeleteEnvironmentVariable(owner string, repo string, environment string, variable string) error {
+	return m.record(&m.Deleted, "DeleteEnvironmentVariable", fmt.Sprintf("repos/%s/%s/environments/%s/variables/%s", owner, repo, environment, variable))
+}
+
+// orEmpty returns an empty JSON object for a response that was not set, as
+// an API returns an empty list rather than no content.
+func orEmpty(response []byte) []byte {
+	if response == nil {
+		return []byte("{}")
+	}
+	return response
+}
+
+// record appends path to paths, or fails as method when ShouldReturnError is
+// set. Commands call the mock from several goroutines, so paths are appended
+// under a lock.
+func (m *MockAPIGetter) record(paths *[]string, method string, path string) error {
 	if m.ShouldReturnError {
-		return fmt.Errorf("mock error for CreateRepoVariable")
+		return fmt.Errorf("mock error for %s", method)
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	*paths = append(*paths, path)
 	return nil
 }
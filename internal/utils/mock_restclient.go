@@ -84,7 +84,7 @@ func (t *testAPIGetterWrapper) CreateOrgActionSecret(owner string, secret string
 	url := fmt.Sprintf("orgs/%s/actions/secrets/%s", owner, secret)
 	resp, err := t.mockClient.Request("PUT", url, data)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -98,7 +98,40 @@ func (t *testAPIGetterWrapper) CreateRepoActionSecret(owner string, repo string,
 	url := fmt.Sprintf("repos/%s/%s/actions/secrets/%s", owner, repo, secret)
 	resp, err := t.mockClient.Request("PUT", url, data)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("Error closing response body: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// Environment methods
+func (t *testAPIGetterWrapper) GetRepoEnvironments(owner string, repo string) ([]byte, error) {
+	url := fmt.Sprintf("repos/%s/%s/environments", owner, repo)
+	resp, err := t.mockClient.Request("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (t *testAPIGetterWrapper) GetEnvironmentActionSecrets(owner string, repo string, environment string) ([]byte, error) {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/secrets", owner, repo, escapeEnvironment(environment))
+	resp, err := t.mockClient.Request("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (t *testAPIGetterWrapper) CreateEnvironmentActionSecret(owner string, repo string, environment string, secret string, data io.Reader) error {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/secrets/%s", owner, repo, escapeEnvironment(environment), secret)
+	resp, err := t.mockClient.Request("PUT", url, data)
+	if err != nil {
+		return err
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -165,7 +198,7 @@ func (t *testAPIGetterWrapper) CreateOrgCodespacesSecret(owner string, secret st
 	url := fmt.Sprintf("orgs/%s/codespaces/secrets/%s", owner, secret)
 	resp, err := t.mockClient.Request("PUT", url, data)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -179,7 +212,7 @@ func (t *testAPIGetterWrapper) CreateRepoCodespacesSecret(owner string, repo str
 	url := fmt.Sprintf("repos/%s/%s/codespaces/secrets/%s", owner, repo, secret)
 	resp, err := t.mockClient.Request("PUT", url, data)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -246,7 +279,7 @@ func (t *testAPIGetterWrapper) CreateOrgDependabotSecret(owner string, secret st
 	url := fmt.Sprintf("orgs/%s/dependabot/secrets/%s", owner, secret)
 	resp, err := t.mockClient.Request("PUT", url, data)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -260,7 +293,7 @@ func (t *testAPIGetterWrapper) CreateRepoDependabotSecret(owner string, repo str
 	url := fmt.Sprintf("repos/%s/%s/dependabot/secrets/%s", owner, repo, secret)
 	resp, err := t.mockClient.Request("PUT", url, data)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -310,6 +343,29 @@ func (t *testAPIGetterWrapper) GetScopedOrgActionVariables(owner string, variabl
 	return responseData, err
 }
 
+func (t *testAPIGetterWrapper) GetEnvironmentActionVariables(owner string, repo string, environment string) ([]byte, error) {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/variables", owner, repo, escapeEnvironment(environment))
+	resp, err := t.mockClient.Request("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (t *testAPIGetterWrapper) CreateEnvironmentVariable(owner string, repo string, environment string, data io.Reader) error {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/variables", owner, repo, escapeEnvironment(environment))
+	resp, err := t.mockClient.Request("POST", url, data)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("Error closing response body: %v\n", err)
+		}
+	}()
+	return nil
+}
+
 // Add other error mock types here
 type errorMockAPIGetter struct {
 	MockAPIGetter
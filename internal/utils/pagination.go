@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Secrets, repositories and environments can be listed 100 at a time,
+// while the variables endpoints only allow 30 per page
+const (
+	maxPerPage          = 100
+	maxVariablesPerPage = 30
+)
+
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+type restRequester interface {
+	Request(method string, path string, body io.Reader) (*http.Response, error)
+}
+
+// getAllPages requests every page of a list endpoint and returns a single
+// response in the same shape as one page, with the items found under key
+// from all pages merged together. The next page is taken from the Link
+// header, falling back to total_count when the header is not returned.
+func getAllPages(client restRequester, path string, key string, perPage int) ([]byte, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	nextPath := fmt.Sprintf("%s%sper_page=%d", path, separator, perPage)
+
+	var items []json.RawMessage
+	totalCount := 0
+	page := 1
+
+	for nextPath != "" {
+		zap.S().Debugf("Requesting page %d of %s", page, path)
+		resp, err := doRequest(client, "GET", nextPath, nil)
+		if err != nil {
+			return nil, err
+		}
+		responseData, err := io.ReadAll(resp.Body)
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			zap.S().Errorf("Error closing response body: %v", closeErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var pageObject map[string]json.RawMessage
+		if err := json.Unmarshal(responseData, &pageObject); err != nil {
+			return nil, fmt.Errorf("failed to parse page %d of %s: %w", page, path, err)
+		}
+		var pageItems []json.RawMessage
+		if raw, ok := pageObject[key]; ok {
+			if err := json.Unmarshal(raw, &pageItems); err != nil {
+				return nil, fmt.Errorf("failed to parse %s on page %d of %s: %w", key, page, path, err)
+			}
+		}
+		if raw, ok := pageObject["total_count"]; ok {
+			if err := json.Unmarshal(raw, &totalCount); err != nil {
+				return nil, fmt.Errorf("failed to parse total_count on page %d of %s: %w", page, path, err)
+			}
+		}
+		items = append(items, pageItems...)
+
+		nextPath = ""
+		if match := linkNextPattern.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+			nextPath = match[1]
+		} else if resp.Header.Get("Link") == "" && len(pageItems) > 0 && len(items) < totalCount {
+			nextPath = fmt.Sprintf("%s%sper_page=%d&page=%d", path, separator, perPage, page+1)
+		}
+		page++
+	}
+
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+	return json.Marshal(map[string]interface{}{
+		"total_count": len(items),
+		key:           items,
+	})
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+func pagedResponse(body string, link string) *http.Response {
+	header := http.Header{}
+	if link != "" {
+		header.Set("Link", link)
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+	}
+}
+
+func TestGetAllPagesFollowsLinkHeader(t *testing.T) {
+	var requestedPaths []string
+	mockClient := &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			requestedPaths = append(requestedPaths, path)
+			switch len(requestedPaths) {
+			case 1:
+				return pagedResponse(
+					`{"total_count":3,"secrets":[{"name":"SECRET1"},{"name":"SECRET2"}]}`,
+					`<https://api.github.com/orgs/test-org/actions/secrets?per_page=2&page=2>; rel="next", <https://api.github.com/orgs/test-org/actions/secrets?per_page=2&page=2>; rel="last"`,
+				), nil
+			default:
+				return pagedResponse(
+					`{"total_count":3,"secrets":[{"name":"SECRET3"}]}`,
+					`<https://api.github.com/orgs/test-org/actions/secrets?per_page=2&page=1>; rel="prev", <https://api.github.com/orgs/test-org/actions/secrets?per_page=2&page=1>; rel="first"`,
+				), nil
+			}
+		},
+	}
+
+	result, err := getAllPages(mockClient, "orgs/test-org/actions/secrets", "secrets", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedPaths := []string{
+		"orgs/test-org/actions/secrets?per_page=2",
+		"https://api.github.com/orgs/test-org/actions/secrets?per_page=2&page=2",
+	}
+	if len(requestedPaths) != len(expectedPaths) {
+		t.Fatalf("Expected %d requests, got %d: %v", len(expectedPaths), len(requestedPaths), requestedPaths)
+	}
+	for i := range expectedPaths {
+		if requestedPaths[i] != expectedPaths[i] {
+			t.Errorf("Expected request %s, got %s", expectedPaths[i], requestedPaths[i])
+		}
+	}
+
+	var response data.SecretsResponse
+	if err := json.Unmarshal(result, &response); err != nil {
+		t.Fatalf("Failed to unmarshal merged response: %v", err)
+	}
+	if response.TotalCount != 3 || len(response.Secrets) != 3 {
+		t.Fatalf("Expected 3 merged secrets, got total_count %d and %d secrets", response.TotalCount, len(response.Secrets))
+	}
+	if response.Secrets[2].Name != "SECRET3" {
+		t.Errorf("Expected last secret to be SECRET3, got %s", response.Secrets[2].Name)
+	}
+}
+
+func TestGetAllPagesFallsBackToTotalCount(t *testing.T) {
+	var requestedPaths []string
+	mockClient := &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			requestedPaths = append(requestedPaths, path)
+			page := len(requestedPaths)
+			var repos []string
+			for i := 0; i < 2 && (page-1)*2+i < 5; i++ {
+				id := (page-1)*2 + i + 1
+				repos = append(repos, fmt.Sprintf(`{"id":%d,"name":"repo%d"}`, id, id))
+			}
+			return pagedResponse(fmt.Sprintf(`{"total_count":5,"repositories":[%s]}`, strings.Join(repos, ",")), ""), nil
+		},
+	}
+
+	result, err := getAllPages(mockClient, "orgs/test-org/actions/secrets/SCOPED/repositories", "repositories", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(requestedPaths) != 3 {
+		t.Fatalf("Expected 3 requests, got %d: %v", len(requestedPaths), requestedPaths)
+	}
+	if requestedPaths[2] != "orgs/test-org/actions/secrets/SCOPED/repositories?per_page=2&page=3" {
+		t.Errorf("Unexpected path for last page: %s", requestedPaths[2])
+	}
+
+	var response data.ScopedResponse
+	if err := json.Unmarshal(result, &response); err != nil {
+		t.Fatalf("Failed to unmarshal merged response: %v", err)
+	}
+	if len(response.Repositories) != 5 || response.Repositories[4].Name != "repo5" {
+		t.Errorf("Expected 5 merged repositories ending in repo5, got %+v", response.Repositories)
+	}
+}
+
+func TestGetAllPagesSinglePage(t *testing.T) {
+	requests := 0
+	mockClient := &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			requests++
+			return pagedResponse(`{"total_count":1,"variables":[{"name":"VAR1","value":"value1"}]}`, ""), nil
+		},
+	}
+
+	result, err := getAllPages(mockClient, "orgs/test-org/actions/variables", "variables", maxVariablesPerPage)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+
+	var response data.VariableResponse
+	if err := json.Unmarshal(result, &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Variables) != 1 || response.Variables[0].Value != "value1" {
+		t.Errorf("Unexpected variables: %+v", response.Variables)
+	}
+}
+
+func TestGetAllPagesEmptyList(t *testing.T) {
+	mockClient := &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			return pagedResponse(`{"total_count":0,"secrets":[]}`, ""), nil
+		},
+	}
+
+	result, err := getAllPages(mockClient, "repos/test-org/test-repo/actions/secrets", "secrets", maxPerPage)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(result) != `{"secrets":[],"total_count":0}` {
+		t.Errorf("Unexpected empty response: %s", string(result))
+	}
+}
+
+func TestGetAllPagesErrors(t *testing.T) {
+	t.Run("request error", func(t *testing.T) {
+		stubSleep(t)
+		mockClient := &mockRESTClient{
+			RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+				return nil, fmt.Errorf("network error")
+			},
+		}
+		if _, err := getAllPages(mockClient, "orgs/test-org/actions/secrets", "secrets", maxPerPage); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+
+	t.Run("malformed page", func(t *testing.T) {
+		mockClient := &mockRESTClient{
+			RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+				return pagedResponse(`{"total_count":1,"secrets":`, ""), nil
+			},
+		}
+		if _, err := getAllPages(mockClient, "orgs/test-org/actions/secrets", "secrets", maxPerPage); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
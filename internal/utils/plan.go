@@ -0,0 +1,499 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+func firstName(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// planner looks up the current state of the target organization for a dry
+// run, caching each list so rows sharing a scope only fetch it once.
+type planner struct {
+	g          *APIGetter
+	owner      string
+	onConflict string
+	repos      map[string]int
+	repoErrs   map[string]error
+	secrets    map[string]map[string]data.Secret
+	variables  map[string]map[string]data.Variable
+	listErrs   map[string]error
+}
+
+func newPlanner(g *APIGetter, owner string) *planner {
+	return &planner{
+		g:         g,
+		owner:     owner,
+		repos:     map[string]int{},
+		repoErrs:  map[string]error{},
+		secrets:   map[string]map[string]data.Secret{},
+		variables: map[string]map[string]data.Variable{},
+		listErrs:  map[string]error{},
+	}
+}
+
+// PlanSecrets reports what creating the secrets would change in the
+// organization without writing anything. Secret values cannot be read back,
+// so an existing secret is always reported as an update.
+func (g *APIGetter) PlanSecrets(owner string, secrets []data.ImportedSecret) []data.PlanItem {
+	p := newPlanner(g, owner)
+	var names []string
+	for _, secret := range secrets {
+		if secret.Level == "Organization" && secret.Access != "selected" {
+			continue
+		}
+		names = append(names, secret.RepositoryNames...)
+	}
+	p.prefetchRepos(names)
+	items := make([]data.PlanItem, 0, len(secrets))
+	for _, secret := range secrets {
+		items = append(items, p.planSecret(secret))
+	}
+	return items
+}
+
+// PlanVariables reports what creating the variables would change in the
+// organization without writing anything, following the onConflict policy for
+// variables that already exist.
+func (g *APIGetter) PlanVariables(owner string, variables []data.ImportedVariable, onConflict string) []data.PlanItem {
+	p := newPlanner(g, owner)
+	p.onConflict = onConflict
+	var names []string
+	for _, variable := range variables {
+		if variable.Level == "Organization" && variable.Visibility != "selected" {
+			continue
+		}
+		names = append(names, variable.SelectedRepos...)
+	}
+	p.prefetchRepos(names)
+	items := make([]data.PlanItem, 0, len(variables))
+	for _, variable := range variables {
+		items = append(items, p.planVariable(variable))
+	}
+	return items
+}
+
+func (p *planner) planSecret(secret data.ImportedSecret) data.PlanItem {
+	repo := firstName(secret.RepositoryNames)
+	item := data.PlanItem{
+		Level:  secret.Level,
+		Type:   secret.Type,
+		Name:   secret.Name,
+		Target: p.target(secret.Level, repo, secret.EnvironmentName),
+	}
+	if err := ValidateImportedSecret(secret); err != nil {
+		return planError(item, err)
+	}
+
+	if secret.Level == "Organization" {
+		if err := p.checkSelectedRepos(secret.Access, secret.RepositoryNames); err != nil {
+			return planError(item, err)
+		}
+	} else if _, err := p.resolveRepo(repo); err != nil {
+		return planError(item, err)
+	}
+
+	existing, err := p.listSecrets(secret.Level, secret.Type, repo, secret.EnvironmentName)
+	if err != nil {
+		return planError(item, err)
+	}
+	current, found := existing[strings.ToUpper(secret.Name)]
+	if !found {
+		item.Action = data.PlanCreate
+		if secret.Level == "Organization" {
+			item.Details = append(item.Details, describeVisibility(secret.Access, secret.RepositoryNames))
+		}
+		return item
+	}
+
+	item.Action = data.PlanUpdate
+	item.Details = append(item.Details, "value will be replaced")
+	if secret.Level == "Organization" {
+		var currentRepos []string
+		if current.Visibility == "selected" {
+			currentRepos, err = p.scopedSecretRepos(secret.Type, current.Name)
+			if err != nil {
+				return planError(item, err)
+			}
+		}
+		item.Details = append(item.Details, visibilityChanges(current.Visibility, secret.Access, currentRepos, secret.RepositoryNames)...)
+	}
+	return item
+}
+
+func (p *planner) planVariable(variable data.ImportedVariable) data.PlanItem {
+	repo := firstName(variable.SelectedRepos)
+	item := data.PlanItem{
+		Level:  variable.Level,
+		Type:   "Actions",
+		Name:   variable.Name,
+		Target: p.target(variable.Level, repo, variable.EnvironmentName),
+	}
+	if err := ValidateImportedVariable(variable); err != nil {
+		return planError(item, err)
+	}
+
+	if variable.Level == "Organization" {
+		if err := p.checkSelectedRepos(variable.Visibility, variable.SelectedRepos); err != nil {
+			return planError(item, err)
+		}
+	} else if _, err := p.resolveRepo(repo); err != nil {
+		return planError(item, err)
+	}
+
+	existing, err := p.listVariables(variable.Level, repo, variable.EnvironmentName)
+	if err != nil {
+		return planError(item, err)
+	}
+	current, found := existing[strings.ToUpper(variable.Name)]
+	if !found {
+		item.Action = data.PlanCreate
+		if variable.Level == "Organization" {
+			item.Details = append(item.Details, describeVisibility(variable.Visibility, variable.SelectedRepos))
+		}
+		return item
+	}
+
+	switch p.onConflict {
+	case ConflictSkip:
+		item.Action = data.PlanUnchanged
+		item.Details = append(item.Details, "already exists, skipped")
+		return item
+	case ConflictFail:
+		return planError(item, fmt.Errorf("variable %s already exists", variable.Name))
+	}
+
+	if current.Value != variable.Value {
+		item.Details = append(item.Details, "value changed")
+	}
+	if variable.Level == "Organization" {
+		var currentRepos []string
+		if current.Visibility == "selected" {
+			scoped, err := p.g.GetScopedOrgActionVariables(p.owner, variable.Name)
+			if err != nil {
+				return planError(item, err)
+			}
+			if currentRepos, err = scopedRepoNames(scoped); err != nil {
+				return planError(item, err)
+			}
+		}
+		item.Details = append(item.Details, visibilityChanges(current.Visibility, variable.Visibility, currentRepos, variable.SelectedRepos)...)
+	}
+	item.Action = data.PlanUpdate
+	if len(item.Details) == 0 {
+		item.Action = data.PlanUnchanged
+	}
+	return item
+}
+
+func (p *planner) target(level string, repo string, environment string) string {
+	return Target(p.owner, level, repo, environment)
+}
+
+// Target describes where a secret or variable at level lives, for output.
+func Target(owner string, level string, repo string, environment string) string {
+	switch level {
+	case "Repository":
+		return fmt.Sprintf("%s/%s", owner, repo)
+	case "Environment":
+		return fmt.Sprintf("%s/%s (%s)", owner, repo, environment)
+	}
+	return owner
+}
+
+// prefetchRepos resolves every repository named in the file in as few
+// queries as possible. Should the batch fail, resolveRepo looks each one up
+// on its own instead.
+func (p *planner) prefetchRepos(names []string) {
+	if len(nonEmpty(names)) == 0 {
+		return
+	}
+	ids, err := p.g.GetRepoIDs(p.owner, names)
+	if err != nil {
+		zap.S().Debugf("Error resolving repositories in %s: %v", p.owner, err)
+		return
+	}
+	for _, name := range uniqueNames(names) {
+		if id, ok := ids[name]; ok {
+			p.repos[name] = id
+		} else {
+			p.repoErrs[name] = fmt.Errorf("repository %s could not be found in %s", name, p.owner)
+		}
+	}
+}
+
+func (p *planner) resolveRepo(name string) (int, error) {
+	if id, ok := p.repos[name]; ok {
+		return id, nil
+	}
+	if err, ok := p.repoErrs[name]; ok {
+		return 0, err
+	}
+	zap.S().Debugf("Resolving repository %s in %s", name, p.owner)
+	repo, err := p.g.GetRepo(p.owner, name)
+	if err == nil && repo.Repository.Name == "" {
+		err = errors.New("not found")
+	}
+	if err != nil {
+		err = fmt.Errorf("repository %s could not be found in %s: %w", name, p.owner, err)
+		p.repoErrs[name] = err
+		return 0, err
+	}
+	p.repos[name] = repo.Repository.DatabaseId
+	return repo.Repository.DatabaseId, nil
+}
+
+// checkSelectedRepos resolves the repositories an organization level item is
+// scoped to, as they are matched by name in the target.
+func (p *planner) checkSelectedRepos(visibility string, names []string) error {
+	if visibility != "selected" {
+		return nil
+	}
+	for _, name := range nonEmpty(names) {
+		if _, err := p.resolveRepo(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listSecrets returns the existing secrets keyed by upper case name, as
+// GitHub returns secret names upper cased.
+func (p *planner) listSecrets(level string, secretType string, repo string, environment string) (map[string]data.Secret, error) {
+	key := strings.Join([]string{level, secretType, repo, environment}, "/")
+	if secrets, ok := p.secrets[key]; ok {
+		return secrets, nil
+	}
+	if err, ok := p.listErrs[key]; ok {
+		return nil, err
+	}
+
+	var response []byte
+	var err error
+	switch level + "/" + secretType {
+	case "Organization/Actions":
+		response, err = p.g.GetOrgActionSecrets(p.owner)
+	case "Organization/Codespaces":
+		response, err = p.g.GetOrgCodespacesSecrets(p.owner)
+	case "Organization/Dependabot":
+		response, err = p.g.GetOrgDependabotSecrets(p.owner)
+	case "Repository/Actions":
+		response, err = p.g.GetRepoActionSecrets(p.owner, repo)
+	case "Repository/Codespaces":
+		response, err = p.g.GetRepoCodespacesSecrets(p.owner, repo)
+	case "Repository/Dependabot":
+		response, err = p.g.GetRepoDependabotSecrets(p.owner, repo)
+	case "Environment/Actions":
+		response, err = p.g.GetEnvironmentActionSecrets(p.owner, repo, environment)
+	}
+	var secretsResponse data.SecretsResponse
+	if err == nil {
+		err = json.Unmarshal(response, &secretsResponse)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to list existing secrets for %s: %w", p.target(level, repo, environment), err)
+		p.listErrs[key] = err
+		return nil, err
+	}
+
+	secrets := make(map[string]data.Secret, len(secretsResponse.Secrets))
+	for _, secret := range secretsResponse.Secrets {
+		secrets[strings.ToUpper(secret.Name)] = secret
+	}
+	p.secrets[key] = secrets
+	return secrets, nil
+}
+
+// listVariables returns the existing variables keyed by upper case name, as
+// GitHub returns variable names upper cased.
+func (p *planner) listVariables(level string, repo string, environment string) (map[string]data.Variable, error) {
+	key := strings.Join([]string{level, repo, environment}, "/")
+	if variables, ok := p.variables[key]; ok {
+		return variables, nil
+	}
+	if err, ok := p.listErrs[key]; ok {
+		return nil, err
+	}
+
+	var response []byte
+	var err error
+	switch level {
+	case "Organization":
+		response, err = p.g.GetOrgActionVariables(p.owner)
+	case "Repository":
+		response, err = p.g.GetRepoActionVariables(p.owner, repo)
+	case "Environment":
+		response, err = p.g.GetEnvironmentActionVariables(p.owner, repo, environment)
+	}
+	var variablesResponse data.VariableResponse
+	if err == nil {
+		err = json.Unmarshal(response, &variablesResponse)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to list existing variables for %s: %w", p.target(level, repo, environment), err)
+		p.listErrs[key] = err
+		return nil, err
+	}
+
+	variables := make(map[string]data.Variable, len(variablesResponse.Variables))
+	for _, variable := range variablesResponse.Variables {
+		variables[strings.ToUpper(variable.Name)] = variable
+	}
+	p.variables[key] = variables
+	return variables, nil
+}
+
+func (p *planner) scopedSecretRepos(secretType string, secret string) ([]string, error) {
+	var response []byte
+	var err error
+	switch secretType {
+	case "Actions":
+		response, err = p.g.GetScopedOrgActionSecrets(p.owner, secret)
+	case "Codespaces":
+		response, err = p.g.GetScopedOrgCodespacesSecrets(p.owner, secret)
+	case "Dependabot":
+		response, err = p.g.GetScopedOrgDependabotSecrets(p.owner, secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scopedRepoNames(response)
+}
+
+func scopedRepoNames(response []byte) ([]string, error) {
+	var scoped data.ScopedResponse
+	if err := json.Unmarshal(response, &scoped); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(scoped.Repositories))
+	for _, repo := range scoped.Repositories {
+		names = append(names, repo.Name)
+	}
+	return names, nil
+}
+
+func describeVisibility(visibility string, repos []string) string {
+	if visibility == "selected" {
+		return fmt.Sprintf("visibility: selected (%s)", strings.Join(nonEmpty(repos), ", "))
+	}
+	return "visibility: " + visibility
+}
+
+// visibilityChanges describes how the visibility and selected repositories of
+// an organization level item differ from what is currently set.
+func visibilityChanges(currentVisibility string, visibility string, currentRepos []string, repos []string) []string {
+	var changes []string
+	if currentVisibility != visibility {
+		changes = append(changes, fmt.Sprintf("visibility: %s -> %s", currentVisibility, visibility))
+	}
+	if visibility != "selected" {
+		return changes
+	}
+
+	current := map[string]bool{}
+	for _, repo := range currentRepos {
+		current[repo] = true
+	}
+	desired := map[string]bool{}
+	var repoChanges []string
+	for _, repo := range nonEmpty(repos) {
+		desired[repo] = true
+		if !current[repo] {
+			repoChanges = append(repoChanges, "+"+repo)
+		}
+	}
+	for _, repo := range currentRepos {
+		if !desired[repo] {
+			repoChanges = append(repoChanges, "-"+repo)
+		}
+	}
+	if len(repoChanges) > 0 {
+		sort.Strings(repoChanges)
+		changes = append(changes, "repositories: "+strings.Join(repoChanges, ", "))
+	}
+	return changes
+}
+
+func planError(item data.PlanItem, err error) data.PlanItem {
+	item.Action = data.PlanError
+	item.Details = []string{err.Error()}
+	return item
+}
+
+// MarkPlanErrors replaces the plan item of each row with an error found
+// before planning, such as a repository without a mapping. Rows that will
+// be skipped are left unchanged.
+func MarkPlanErrors(items []data.PlanItem, errs []error) {
+	for i, err := range errs {
+		if err == nil || i >= len(items) {
+			continue
+		}
+		var skipErr *SkipError
+		if errors.As(err, &skipErr) {
+			items[i].Action = data.PlanUnchanged
+			items[i].Details = []string{"skipped, " + skipErr.Reason}
+			continue
+		}
+		items[i] = planError(items[i], err)
+	}
+}
+
+// CountPlanActions returns the number of plan items for each action.
+func CountPlanActions(items []data.PlanItem) map[string]int {
+	counts := map[string]int{}
+	for _, item := range items {
+		counts[item.Action]++
+	}
+	return counts
+}
+
+// PrintPlan writes the plan as a table followed by a summary line, which
+// only counts deletes when there are any.
+func PrintPlan(w io.Writer, items []data.PlanItem) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "ACTION\tLEVEL\tTYPE\tNAME\tTARGET\tDETAILS"); err != nil {
+		return err
+	}
+	for _, item := range items {
+		_, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			item.Action, item.Level, item.Type, item.Name, item.Target, strings.Join(item.Details, "; "))
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	counts := CountPlanActions(items)
+	deletes := ""
+	if counts[data.PlanDelete] > 0 {
+		deletes = fmt.Sprintf(" %d to delete,", counts[data.PlanDelete])
+	}
+	_, err := fmt.Fprintf(w, "\nPlan: %d to create, %d to update,%s %d unchanged, %d with errors.\n",
+		counts[data.PlanCreate], counts[data.PlanUpdate], deletes, counts[data.PlanUnchanged], counts[data.PlanError])
+	return err
+}
@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+// newFakeAPIGetter returns an APIGetter whose REST and GraphQL clients are
+// served by handler, with request paths relative to the API root.
+func newFakeAPIGetter(t *testing.T, handler http.HandlerFunc) *APIGetter {
+	t.Helper()
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		resp := recorder.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	options := api.ClientOptions{Host: "github.com", AuthToken: "test-token", Transport: transport}
+	restClient, err := api.NewRESTClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	gqlClient, err := api.NewGraphQLClient(options)
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL client: %v", err)
+	}
+	return NewAPIGetter(NewGitHubGetter(gqlClient, restClient))
+}
+
+// planTestHandler serves the given REST responses and resolves the
+// repositories in repoIDs over GraphQL. Any request that is not a read fails
+// the test.
+func planTestHandler(t *testing.T, responses map[string]string, repoIDs map[string]int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/graphql" {
+			var query struct {
+				Variables map[string]string `json:"variables"`
+			}
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &query)
+			if name, ok := query.Variables["name"]; ok {
+				if id, ok := repoIDs[name]; ok {
+					_, _ = w.Write([]byte(`{"data":{"repository":{"databaseId":` + jsonInt(id) + `,"name":"` + name + `"}}}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"data":{"repository":null},"errors":[{"type":"NOT_FOUND","message":"Could not resolve to a Repository"}]}`))
+				return
+			}
+			_, _ = w.Write(repoBatchResponse(query.Variables, repoIDs))
+			return
+		}
+		if r.Method != "GET" {
+			t.Errorf("Unexpected %s %s during a dry run", r.Method, r.URL.Path)
+		}
+		if body, ok := responses[strings.TrimPrefix(r.URL.Path, "/")]; ok {
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	}
+}
+
+// repoBatchResponse answers a getRepos query, where each repository name
+// is passed as the variable n<i> and returned under the alias r<i>.
+func repoBatchResponse(variables map[string]string, repoIDs map[string]int) []byte {
+	result := map[string]interface{}{}
+	var errs []map[string]interface{}
+	for key, name := range variables {
+		if !strings.HasPrefix(key, "n") {
+			continue
+		}
+		alias := "r" + strings.TrimPrefix(key, "n")
+		if id, ok := repoIDs[name]; ok {
+			result[alias] = map[string]interface{}{"databaseId": id, "name": name}
+			continue
+		}
+		result[alias] = nil
+		errs = append(errs, map[string]interface{}{"type": "NOT_FOUND", "path": []string{alias}, "message": "Could not resolve to a Repository"})
+	}
+	response := map[string]interface{}{"data": result}
+	if len(errs) > 0 {
+		response["errors"] = errs
+	}
+	body, _ := json.Marshal(response)
+	return body
+}
+
+func jsonInt(i int) string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+func TestPlanSecrets(t *testing.T) {
+	stubSleep(t)
+	responses := map[string]string{
+		"orgs/test-org/actions/secrets":                        `{"total_count":1,"secrets":[{"name":"ORG_TOKEN","visibility":"selected"}]}`,
+		"orgs/test-org/actions/secrets/ORG_TOKEN/repositories": `{"total_count":2,"repositories":[{"id":1,"name":"repo-a"},{"id":3,"name":"repo-c"}]}`,
+		"orgs/test-org/dependabot/secrets":                     `{"total_count":0,"secrets":[]}`,
+		"repos/test-org/repo-a/actions/secrets":                `{"total_count":1,"secrets":[{"name":"REPO_TOKEN"}]}`,
+		"repos/test-org/repo-a/environments/prod/secrets":      `{"total_count":0,"secrets":[]}`,
+	}
+	getter := newFakeAPIGetter(t, planTestHandler(t, responses, map[string]int{"repo-a": 1, "repo-b": 2}))
+
+	secrets := []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "ORG_TOKEN", Value: "v", Access: "selected", RepositoryNames: []string{"repo-a", "repo-b"}, RepositoryIDs: []string{"1", "2"}},
+		{Level: "Organization", Type: "Dependabot", Name: "NEW_TOKEN", Value: "v", Access: "all", RepositoryNames: []string{""}, RepositoryIDs: []string{""}},
+		{Level: "Repository", Type: "Actions", Name: "REPO_TOKEN", Value: "v", Access: "RepoOnly", RepositoryNames: []string{"repo-a"}},
+		{Level: "Environment", Type: "Actions", Name: "ENV_TOKEN", Value: "v", Access: "EnvironmentOnly", RepositoryNames: []string{"repo-a"}, EnvironmentName: "prod"},
+		{Level: "Repository", Type: "Actions", Name: "MISSING_REPO", Value: "v", Access: "RepoOnly", RepositoryNames: []string{"repo-z"}},
+		{Level: "Organization", Type: "Actions", Name: "STALE_ID", Value: "v", Access: "selected", RepositoryNames: []string{"repo-b"}, RepositoryIDs: []string{"99"}},
+		{Level: "Repository", Type: "Actions", Name: "", Value: "v", RepositoryNames: []string{"repo-a"}},
+	}
+
+	plan := getter.PlanSecrets("test-org", secrets)
+	if len(plan) != len(secrets) {
+		t.Fatalf("Expected %d plan items, got %d", len(secrets), len(plan))
+	}
+
+	expected := []struct {
+		action  string
+		target  string
+		details string
+	}{
+		{data.PlanUpdate, "test-org", "value will be replaced; repositories: +repo-b, -repo-c"},
+		{data.PlanCreate, "test-org", "visibility: all"},
+		{data.PlanUpdate, "test-org/repo-a", "value will be replaced"},
+		{data.PlanCreate, "test-org/repo-a (prod)", ""},
+		{data.PlanError, "test-org/repo-z", "repository repo-z could not be found in test-org"},
+		{data.PlanCreate, "test-org", "visibility: selected (repo-b)"},
+		{data.PlanError, "test-org/repo-a", "a secret name is required"},
+	}
+	for i, want := range expected {
+		got := plan[i]
+		details := strings.Join(got.Details, "; ")
+		if got.Action != want.action || got.Target != want.target || !strings.HasPrefix(details, want.details) {
+			t.Errorf("Item %d: expected %s %s %q, got %s %s %q", i, want.action, want.target, want.details, got.Action, got.Target, details)
+		}
+	}
+}
+
+func TestPlanSecretsLowerCaseName(t *testing.T) {
+	responses := map[string]string{
+		"orgs/test-org/actions/secrets":                        `{"total_count":1,"secrets":[{"name":"ORG_TOKEN","visibility":"selected"}]}`,
+		"orgs/test-org/actions/secrets/ORG_TOKEN/repositories": `{"total_count":1,"repositories":[{"id":1,"name":"repo-a"}]}`,
+		"repos/test-org/repo-a/actions/secrets":                `{"total_count":1,"secrets":[{"name":"REPO_TOKEN"}]}`,
+	}
+	getter := newFakeAPIGetter(t, planTestHandler(t, responses, map[string]int{"repo-a": 1}))
+
+	plan := getter.PlanSecrets("test-org", []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "org_token", Value: "v", Access: "selected", RepositoryNames: []string{"repo-a"}},
+		{Level: "Repository", Type: "Actions", Name: "repo_token", Value: "v", RepositoryNames: []string{"repo-a"}},
+	})
+	for _, item := range plan {
+		if item.Action != data.PlanUpdate {
+			t.Errorf("Expected %s to be planned as an update of the existing secret, got %s %v", item.Name, item.Action, item.Details)
+		}
+	}
+	if details := strings.Join(plan[0].Details, "; "); details != "value will be replaced" {
+		t.Errorf("Expected the repositories of the existing secret to be compared, got %q", details)
+	}
+}
+
+func TestPlanVariables(t *testing.T) {
+	stubSleep(t)
+	responses := map[string]string{
+		"orgs/test-org/actions/variables":                     `{"total_count":2,"variables":[{"name":"REGION","value":"us","visibility":"all"},{"name":"SCOPED","value":"x","visibility":"private"}]}`,
+		"repos/test-org/repo-a/actions/variables":             `{"total_count":1,"variables":[{"name":"LEVEL","value":"debug"}]}`,
+		"repos/test-org/repo-a/environments/prod/variables":   `{"total_count":0,"variables":[]}`,
+		"orgs/test-org/actions/variables/SCOPED/repositories": `{"total_count":0,"repositories":[]}`,
+	}
+	getter := newFakeAPIGetter(t, planTestHandler(t, responses, map[string]int{"repo-a": 1}))
+
+	variables := []data.ImportedVariable{
+		{Level: "Organization", Name: "region", Value: "us", Visibility: "all"},
+		{Level: "Organization", Name: "SCOPED", Value: "y", Visibility: "selected", SelectedRepos: []string{"repo-a"}, SelectedReposIDs: []string{"1"}},
+		{Level: "Repository", Name: "LEVEL", Value: "info", SelectedRepos: []string{"repo-a"}},
+		{Level: "Environment", Name: "STAGE", Value: "prod", SelectedRepos: []string{"repo-a"}, EnvironmentName: "prod"},
+		{Level: "Environment", Name: "STAGE", Value: "prod", SelectedRepos: []string{"repo-a"}, EnvironmentName: "missing"},
+	}
+
+	plan := getter.PlanVariables("test-org", variables, ConflictUpdate)
+
+	expected := []struct {
+		action  string
+		details string
+	}{
+		{data.PlanUnchanged, ""},
+		{data.PlanUpdate, "value changed; visibility: private -> selected; repositories: +repo-a"},
+		{data.PlanUpdate, "value changed"},
+		{data.PlanCreate, ""},
+		{data.PlanError, "failed to list existing variables for test-org/repo-a (missing)"},
+	}
+	for i, want := range expected {
+		got := plan[i]
+		details := strings.Join(got.Details, "; ")
+		if got.Action != want.action || !strings.HasPrefix(details, want.details) {
+			t.Errorf("Item %d: expected %s %q, got %s %q", i, want.action, want.details, got.Action, details)
+		}
+	}
+}
+
+func TestPlanCachesLists(t *testing.T) {
+	requests := 0
+	getter := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"total_count":0,"secrets":[]}`))
+	})
+
+	secrets := []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "ONE", Value: "v", Access: "all"},
+		{Level: "Organization", Type: "Actions", Name: "TWO", Value: "v", Access: "all"},
+	}
+	getter.PlanSecrets("test-org", secrets)
+
+	if requests != 1 {
+		t.Errorf("Expected the organization secrets to be listed once, got %d requests", requests)
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	plan := []data.PlanItem{
+		{Action: data.PlanCreate, Level: "Organization", Type: "Actions", Name: "ONE", Target: "test-org", Details: []string{"visibility: all"}},
+		{Action: data.PlanUpdate, Level: "Repository", Type: "Actions", Name: "TWO", Target: "test-org/repo", Details: []string{"value changed"}},
+		{Action: data.PlanError, Level: "Environment", Type: "Actions", Name: "THREE", Target: "test-org/repo (prod)", Details: []string{"not found"}},
+	}
+
+	var buf bytes.Buffer
+	if err := PrintPlan(&buf, plan); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"ACTION", "test-org/repo (prod)", "visibility: all", "Plan: 1 to create, 1 to update, 0 unchanged, 1 with errors."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestPlanVariablesOnConflict(t *testing.T) {
+	responses := map[string]string{
+		"orgs/test-org/actions/variables": `{"total_count":1,"variables":[{"name":"REGION","value":"us","visibility":"all"}]}`,
+	}
+	getter := newFakeAPIGetter(t, planTestHandler(t, responses, nil))
+	variables := []data.ImportedVariable{
+		{Level: "Organization", Name: "REGION", Value: "eu", Visibility: "all"},
+		{Level: "Organization", Name: "NEW", Value: "eu", Visibility: "all"},
+	}
+
+	testCases := []struct {
+		onConflict string
+		expected   string
+	}{
+		{ConflictUpdate, data.PlanUpdate},
+		{ConflictSkip, data.PlanUnchanged},
+		{ConflictFail, data.PlanError},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.onConflict, func(t *testing.T) {
+			plan := getter.PlanVariables("test-org", variables, tc.onConflict)
+			if plan[0].Action != tc.expected {
+				t.Errorf("Expected existing variable to be %s, got %s", tc.expected, plan[0].Action)
+			}
+			if plan[1].Action != data.PlanCreate {
+				t.Errorf("Expected new variable to be created, got %s", plan[1].Action)
+			}
+		})
+	}
+}
+
+func TestMarkPlanErrors(t *testing.T) {
+	items := []data.PlanItem{
+		{Action: data.PlanCreate, Name: "A", Details: []string{"visibility: all"}},
+		{Action: data.PlanUpdate, Name: "B", Details: []string{"value changed"}},
+	}
+	items = append(items, data.PlanItem{Action: data.PlanCreate, Name: "C"})
+	MarkPlanErrors(items, []error{nil, errors.New("no repository mapping for: web"), &SkipError{Reason: "repository web does not exist in test-org"}})
+	if items[0].Action != data.PlanCreate {
+		t.Errorf("Expected the first item to be unchanged, got %+v", items[0])
+	}
+	if items[1].Action != data.PlanError || strings.Join(items[1].Details, "; ") != "no repository mapping for: web" {
+		t.Errorf("Expected the second item to be an error, got %+v", items[1])
+	}
+	if items[2].Action != data.PlanUnchanged || items[2].Details[0] != "skipped, repository web does not exist in test-org" {
+		t.Errorf("Expected the third item to be skipped, got %+v", items[2])
+	}
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+// publicKeyCache keeps the public keys secrets are encrypted with, so a run
+// writing many secrets reads each key once. A getter talks to a single host,
+// so keys are cached per getter by owner, repository, environment and app.
+// A nil cache reads every key.
+type publicKeyCache struct {
+	mu   sync.Mutex
+	keys map[string]*cachedPublicKey
+}
+
+// cachedPublicKey is a key being, or already, read. ready is closed once
+// key and err are set, so concurrent secrets of a scope share one request.
+type cachedPublicKey struct {
+	ready chan struct{}
+	key   data.PublicKey
+	err   error
+}
+
+func newPublicKeyCache() *publicKeyCache {
+	return &publicKeyCache{keys: map[string]*cachedPublicKey{}}
+}
+
+// get returns the key cached for scope, reading it with fetch when it is
+// not. A failed read is not kept, so the next secret of the scope tries
+// again.
+func (c *publicKeyCache) get(scope string, fetch func() (data.PublicKey, error)) (data.PublicKey, error) {
+	if c == nil {
+		return fetch()
+	}
+	c.mu.Lock()
+	if cached, ok := c.keys[scope]; ok {
+		c.mu.Unlock()
+		<-cached.ready
+		return cached.key, cached.err
+	}
+	cached := &cachedPublicKey{ready: make(chan struct{})}
+	c.keys[scope] = cached
+	c.mu.Unlock()
+
+	cached.key, cached.err = fetch()
+	if cached.err != nil {
+		c.mu.Lock()
+		delete(c.keys, scope)
+		c.mu.Unlock()
+	}
+	close(cached.ready)
+	return cached.key, cached.err
+}
+
+// invalidate drops the key cached for scope if it is still keyID, so the
+// next secret of the scope reads the current key.
+func (c *publicKeyCache) invalidate(scope string, keyID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.keys[scope]
+	if !ok {
+		return
+	}
+	select {
+	case <-cached.ready:
+		if cached.key.KeyID == keyID {
+			delete(c.keys, scope)
+		}
+	default:
+		// Being read again already
+	}
+}
+
+// publicKeyScope identifies the public key of the level and type of secret
+// in owner.
+func publicKeyScope(owner string, secret data.ImportedSecret) string {
+	scope := owner + "/" + secret.Type
+	switch secret.Level {
+	case "Repository":
+		scope = owner + "/" + firstName(secret.RepositoryNames) + "/" + secret.Type
+	case "Environment":
+		scope = owner + "/" + firstName(secret.RepositoryNames) + "/environments/" + secret.EnvironmentName + "/" + secret.Type
+	}
+	return scope
+}
+
+// isRejectedKey reports whether writing a secret failed because GitHub
+// refused its encrypted value, as happens when it was encrypted with a key
+// that has since been rotated.
+func isRejectedKey(err error) bool {
+	var httpErr *api.HTTPError
+	return errors.As(err, &httpErr) &&
+		(httpErr.StatusCode == http.StatusBadRequest || httpErr.StatusCode == http.StatusUnprocessableEntity)
+}
@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestPublicKeyCacheGet(t *testing.T) {
+	cache := newPublicKeyCache()
+	var mu sync.Mutex
+	reads := 0
+	fetch := func() (data.PublicKey, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reads++
+		return data.PublicKey{KeyID: "key-1", Key: "abc"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := cache.get("test-org/Actions", fetch)
+			if err != nil || key.KeyID != "key-1" {
+				t.Errorf("get() = %+v, %v", key, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if reads != 1 {
+		t.Errorf("Expected the key to be read once, got %d reads", reads)
+	}
+
+	if _, err := cache.get("test-org/api/Actions", fetch); err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if reads != 2 {
+		t.Errorf("Expected a key to be read for each scope, got %d reads", reads)
+	}
+}
+
+func TestPublicKeyCacheErrorsNotKept(t *testing.T) {
+	cache := newPublicKeyCache()
+	reads := 0
+	fetch := func() (data.PublicKey, error) {
+		reads++
+		if reads == 1 {
+			return data.PublicKey{}, errors.New("unavailable")
+		}
+		return data.PublicKey{KeyID: "key-1"}, nil
+	}
+
+	if _, err := cache.get("test-org/Actions", fetch); err == nil {
+		t.Fatal("Expected the first read to fail")
+	}
+	key, err := cache.get("test-org/Actions", fetch)
+	if err != nil || key.KeyID != "key-1" {
+		t.Errorf("Expected the key to be read again, got %+v, %v", key, err)
+	}
+}
+
+func TestPublicKeyCacheInvalidate(t *testing.T) {
+	cache := newPublicKeyCache()
+	keyID := "key-1"
+	reads := 0
+	fetch := func() (data.PublicKey, error) {
+		reads++
+		return data.PublicKey{KeyID: keyID}, nil
+	}
+	if _, err := cache.get("test-org/Actions", fetch); err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+
+	keyID = "key-2"
+	cache.invalidate("test-org/Actions", "key-0")
+	if key, _ := cache.get("test-org/Actions", fetch); key.KeyID != "key-1" {
+		t.Errorf("Expected a key other than the one refused to be kept, got %s", key.KeyID)
+	}
+	cache.invalidate("test-org/Actions", "key-1")
+	if key, _ := cache.get("test-org/Actions", fetch); key.KeyID != "key-2" {
+		t.Errorf("Expected the current key after invalidating, got %s", key.KeyID)
+	}
+	if reads != 2 {
+		t.Errorf("Expected 2 reads, got %d", reads)
+	}
+
+	var nilCache *publicKeyCache
+	nilCache.invalidate("test-org/Actions", "key-2")
+	if _, err := nilCache.get("test-org/Actions", fetch); err != nil || reads != 3 {
+		t.Errorf("Expected a nil cache to read every key, got %d reads, %v", reads, err)
+	}
+}
+
+func TestPublicKeyScope(t *testing.T) {
+	tests := []struct {
+		secret data.ImportedSecret
+		want   string
+	}{
+		{data.ImportedSecret{Level: "Organization", Type: "Dependabot"}, "test-org/Dependabot"},
+		{data.ImportedSecret{Level: "Repository", Type: "Actions", RepositoryNames: []string{"api"}}, "test-org/api/Actions"},
+		{data.ImportedSecret{Level: "Environment", Type: "Actions", RepositoryNames: []string{"api"}, EnvironmentName: "prod"}, "test-org/api/environments/prod/Actions"},
+	}
+	for _, tc := range tests {
+		if got := publicKeyScope("test-org", tc.secret); got != tc.want {
+			t.Errorf("publicKeyScope(%+v) = %s, want %s", tc.secret, got, tc.want)
+		}
+	}
+}
+
+func TestCreateImportedSecretRotatedKey(t *testing.T) {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(publicKey[:])
+
+	tests := []struct {
+		name      string
+		rotateTo  string
+		refuseAll bool
+		wantErr   bool
+		wantPuts  []string
+	}{
+		{
+			name:     "rotated key is retried",
+			rotateTo: "key-2",
+			wantPuts: []string{"key-1", "key-2"},
+		},
+		{
+			name:      "refused with the current key",
+			rotateTo:  "key-1",
+			refuseAll: true,
+			wantErr:   true,
+			wantPuts:  []string{"key-1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			keyID := "key-1"
+			var puts []string
+			var lastValue string
+			g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "/public-key") {
+					_, _ = w.Write([]byte(`{"key_id":"` + keyID + `","key":"` + encodedKey + `"}`))
+					return
+				}
+				var body map[string]string
+				raw, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(raw, &body)
+				puts = append(puts, body["key_id"])
+				lastValue = body["encrypted_value"]
+				if tc.refuseAll || body["key_id"] != keyID {
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					_, _ = w.Write([]byte(`{"message":"Bad key_id"}`))
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			})
+			secret := data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "TOKEN", Value: "value", RepositoryNames: []string{"api"}}
+
+			// Cache key-1, then rotate the key before the secret is written
+			if _, err := g.SecretPublicKey("test-org", secret); err != nil {
+				t.Fatalf("SecretPublicKey() error = %v", err)
+			}
+			keyID = tc.rotateTo
+			err := g.CreateImportedSecret("test-org", secret)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CreateImportedSecret() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if strings.Join(puts, ",") != strings.Join(tc.wantPuts, ",") {
+				t.Errorf("Expected writes with keys %v, got %v", tc.wantPuts, puts)
+			}
+			if !tc.wantErr {
+				encrypted, _ := base64.StdEncoding.DecodeString(lastValue)
+				if decrypted, ok := box.OpenAnonymous(nil, encrypted, publicKey, privateKey); !ok || string(decrypted) != "value" {
+					t.Errorf("Expected the retried value to decrypt, got %q", decrypted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+// Column names accepted for the source and target repositories of a
+// mapping file, including those of the GitHub Enterprise Importer
+// (github_source_repo and github_target_repo).
+var (
+	repoMapSourceColumns = map[string]bool{"source": true, "github_source_repo": true}
+	repoMapTargetColumns = map[string]bool{"target": true, "github_target_repo": true}
+)
+
+// LoadRepoMap reads a CSV file mapping source repository names to their
+// names in the target organization.
+func LoadRepoMap(fileName string) (map[string]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			zap.S().Errorf("Error closing file: %v", err)
+		}
+	}()
+
+	csvReader := csv.NewReader(f)
+	csvReader.FieldsPerRecord = -1
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return ParseRepoMap(records)
+}
+
+// ParseRepoMap builds a repository mapping from CSV records, whose header
+// must name a source and target column. Repositories may be given as a name,
+// owner/name or a URL, and are matched without regard to case.
+func ParseRepoMap(records [][]string) (map[string]string, error) {
+	if len(records) == 0 {
+		return nil, errors.New("the repository mapping file is empty")
+	}
+	sourceCol, targetCol := -1, -1
+	for i, column := range records[0] {
+		column = normalizeColumn(column)
+		if repoMapSourceColumns[column] {
+			sourceCol = i
+		} else if repoMapTargetColumns[column] {
+			targetCol = i
+		}
+	}
+	if sourceCol < 0 || targetCol < 0 {
+		return nil, errors.New("the repository mapping file needs github_source_repo and github_target_repo, or source and target, columns")
+	}
+
+	repoMap := map[string]string{}
+	for i, row := range records[1:] {
+		if len(row) <= sourceCol || len(row) <= targetCol {
+			return nil, fmt.Errorf("row %d of the repository mapping file needs a source and target repository", i+1)
+		}
+		source, target := repoName(row[sourceCol]), repoName(row[targetCol])
+		if source == "" && target == "" {
+			continue
+		}
+		if source == "" || target == "" {
+			return nil, fmt.Errorf("row %d of the repository mapping file needs a source and target repository", i+1)
+		}
+		key := strings.ToLower(source)
+		if existing, ok := repoMap[key]; ok && existing != target {
+			return nil, fmt.Errorf("repository %s is mapped to both %s and %s", source, existing, target)
+		}
+		repoMap[key] = target
+	}
+	return repoMap, nil
+}
+
+func normalizeColumn(column string) string {
+	column = strings.ToLower(strings.TrimSpace(column))
+	column = strings.TrimPrefix(column, "--")
+	return strings.NewReplacer("-", "_", " ", "_").Replace(column)
+}
+
+// repoName returns the name of a repository given as a name, owner/name or
+// URL.
+func repoName(value string) string {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "/")
+	value = strings.TrimSuffix(value, ".git")
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		value = value[i+1:]
+	}
+	return value
+}
+
+// MapSecretRepos renames the repositories of each secret to their names in
+// the target organization. The returned errors line up with secrets, naming
+// the repositories of a secret that have no mapping.
+func MapSecretRepos(secrets []data.ImportedSecret, repoMap map[string]string) []error {
+	errs := make([]error, len(secrets))
+	for i, secret := range secrets {
+		if secret.Level == "Organization" && secret.Access != "selected" {
+			continue
+		}
+		secrets[i].RepositoryNames, errs[i] = mapRepoNames(secret.RepositoryNames, repoMap)
+	}
+	return errs
+}
+
+// MapVariableRepos renames the repositories of each variable in the same
+// way as MapSecretRepos.
+func MapVariableRepos(variables []data.ImportedVariable, repoMap map[string]string) []error {
+	errs := make([]error, len(variables))
+	for i, variable := range variables {
+		if variable.Level == "Organization" && variable.Visibility != "selected" {
+			continue
+		}
+		variables[i].SelectedRepos, errs[i] = mapRepoNames(variable.SelectedRepos, repoMap)
+	}
+	return errs
+}
+
+// mapRepoNames returns names renamed through repoMap. Should any name have
+// no mapping, names are returned unchanged along with an error.
+func mapRepoNames(names []string, repoMap map[string]string) ([]string, error) {
+	mapped := make([]string, len(names))
+	var unmapped []string
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		target, ok := repoMap[strings.ToLower(name)]
+		if !ok {
+			unmapped = append(unmapped, name)
+			continue
+		}
+		mapped[i] = target
+	}
+	if len(unmapped) > 0 {
+		sort.Strings(unmapped)
+		return names, fmt.Errorf("no repository mapping for: %s", strings.Join(unmapped, ", "))
+	}
+	return mapped, nil
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+func TestParseRepoMap(t *testing.T) {
+	testCases := []struct {
+		name    string
+		records [][]string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "importer columns",
+			records: [][]string{
+				{"github_source_org", "github_source_repo", "github_target_org", "github_target_repo"},
+				{"source-org", "API", "target-org", "platform-api"},
+				{"source-org", "web", "target-org", "platform-web"},
+			},
+			want: map[string]string{"api": "platform-api", "web": "platform-web"},
+		},
+		{
+			name: "flag style columns with urls",
+			records: [][]string{
+				{"--github-target-repo", "--github-source-repo"},
+				{"https://github.com/target-org/platform-api", "https://github.com/source-org/api.git"},
+			},
+			want: map[string]string{"api": "platform-api"},
+		},
+		{
+			name: "source and target columns",
+			records: [][]string{
+				{"Source", "Target"},
+				{"source-org/api", "target-org/platform-api"},
+				{"", ""},
+			},
+			want: map[string]string{"api": "platform-api"},
+		},
+		{
+			name:    "no header",
+			records: [][]string{{"api", "platform-api"}, {"web", "platform-web"}},
+			wantErr: "needs github_source_repo and github_target_repo, or source and target, columns",
+		},
+		{
+			name:    "unrecognized header",
+			records: [][]string{{"source_repository", "target_repository"}, {"api", "platform-api"}},
+			wantErr: "needs github_source_repo and github_target_repo, or source and target, columns",
+		},
+		{
+			name:    "source only",
+			records: [][]string{{"source", "new_name"}, {"api", "platform-api"}},
+			wantErr: "needs github_source_repo and github_target_repo, or source and target, columns",
+		},
+		{
+			name:    "empty",
+			records: nil,
+			wantErr: "empty",
+		},
+		{
+			name:    "missing target",
+			records: [][]string{{"source", "target"}, {"api", ""}},
+			wantErr: "row 1 of the repository mapping file needs a source and target repository",
+		},
+		{
+			name:    "conflicting mapping",
+			records: [][]string{{"source", "target"}, {"api", "platform-api"}, {"API", "legacy-api"}},
+			wantErr: "repository API is mapped to both platform-api and legacy-api",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseRepoMap(tc.records)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("Expected %v, got %v", tc.want, got)
+			}
+			for source, target := range tc.want {
+				if got[source] != target {
+					t.Errorf("Expected %s to map to %s, got %s", source, target, got[source])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRepoMap(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "mapping.csv")
+	content := "github_source_org,github_source_repo,github_target_org,github_target_repo,notes\n" +
+		"source-org,api,target-org,platform-api\n"
+	if err := os.WriteFile(fileName, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write mapping: %v", err)
+	}
+	repoMap, err := LoadRepoMap(fileName)
+	if err != nil {
+		t.Fatalf("LoadRepoMap() error = %v", err)
+	}
+	if repoMap["api"] != "platform-api" {
+		t.Errorf("Expected api to map to platform-api, got %v", repoMap)
+	}
+
+	if _, err := LoadRepoMap(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestMapSecretRepos(t *testing.T) {
+	repoMap := map[string]string{"api": "platform-api", "web": "platform-web"}
+	secrets := []data.ImportedSecret{
+		{Level: "Organization", Access: "selected", Name: "SCOPED", RepositoryNames: []string{"API", "web"}, RepositoryIDs: []string{"1", "2"}},
+		{Level: "Organization", Access: "all", Name: "ALL", RepositoryNames: []string{""}},
+		{Level: "Repository", Name: "REPO", RepositoryNames: []string{"web"}},
+		{Level: "Environment", Name: "ENV", RepositoryNames: []string{"docs"}, EnvironmentName: "prod"},
+		{Level: "Organization", Access: "selected", Name: "PARTIAL", RepositoryNames: []string{"api", "tools", "docs"}},
+	}
+	errs := MapSecretRepos(secrets, repoMap)
+
+	expectedNames := [][]string{{"platform-api", "platform-web"}, {""}, {"platform-web"}, {"docs"}, {"api", "tools", "docs"}}
+	for i, want := range expectedNames {
+		if strings.Join(secrets[i].RepositoryNames, ",") != strings.Join(want, ",") {
+			t.Errorf("Secret %s: expected %v, got %v", secrets[i].Name, want, secrets[i].RepositoryNames)
+		}
+	}
+	expectedErrs := []string{"", "", "", "no repository mapping for: docs", "no repository mapping for: docs, tools"}
+	for i, want := range expectedErrs {
+		if want == "" && errs[i] != nil {
+			t.Errorf("Secret %s: unexpected error %v", secrets[i].Name, errs[i])
+		}
+		if want != "" && (errs[i] == nil || errs[i].Error() != want) {
+			t.Errorf("Secret %s: expected error %q, got %v", secrets[i].Name, want, errs[i])
+		}
+	}
+}
+
+func TestMapVariableRepos(t *testing.T) {
+	repoMap := map[string]string{"api": "platform-api"}
+	variables := []data.ImportedVariable{
+		{Level: "Organization", Visibility: "selected", Name: "SCOPED", SelectedRepos: []string{"api"}},
+		{Level: "Organization", Visibility: "private", Name: "PRIVATE"},
+		{Level: "Repository", Name: "REPO", SelectedRepos: []string{"web"}},
+	}
+	errs := MapVariableRepos(variables, repoMap)
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+	if variables[0].SelectedRepos[0] != "platform-api" {
+		t.Errorf("Expected api to be renamed, got %v", variables[0].SelectedRepos)
+	}
+	if errs[2] == nil || variables[2].SelectedRepos[0] != "web" {
+		t.Errorf("Expected an unmapped repository error for web, got %v", errs[2])
+	}
+}
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+// maxReposPerQuery is the number of repositories looked up in a single
+// GraphQL query, keeping each query well inside the node limit.
+const maxReposPerQuery = 50
+
+// GetRepoIDs looks up the database IDs of the named repositories in owner,
+// batching the lookups into as few queries as possible. Repositories that do
+// not exist are left out of the result.
+func (g *GitHubGetter) GetRepoIDs(owner string, names []string) (map[string]int, error) {
+	unique := uniqueNames(names)
+	ids := make(map[string]int, len(unique))
+	for start := 0; start < len(unique); start += maxReposPerQuery {
+		end := min(start+maxReposPerQuery, len(unique))
+		if err := g.getRepoIDBatch(owner, unique[start:end], ids); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func (g *GitHubGetter) getRepoIDBatch(owner string, names []string, ids map[string]int) error {
+	var params, fields strings.Builder
+	variables := map[string]interface{}{"owner": owner}
+	params.WriteString("$owner: String!")
+	for i, name := range names {
+		fmt.Fprintf(&params, ", $n%d: String!", i)
+		fmt.Fprintf(&fields, " r%d: repository(owner: $owner, name: $n%d) { databaseId name }", i, i)
+		variables[fmt.Sprintf("n%d", i)] = name
+	}
+	query := fmt.Sprintf("query getRepos(%s) {%s }", params.String(), fields.String())
+
+	zap.S().Debugf("Resolving %d repositories in %s", len(names), owner)
+	response := map[string]*data.RepoInfo{}
+	err := retryQuery("getRepos", func() error {
+		return g.gqlClient.Do(query, variables, &response)
+	})
+	if err != nil && !isNotFoundOnly(err) {
+		return err
+	}
+	for i, name := range names {
+		if repo := response[fmt.Sprintf("r%d", i)]; repo != nil && repo.Name != "" {
+			ids[name] = repo.DatabaseId
+		}
+	}
+	return nil
+}
+
+// isNotFoundOnly reports whether every error of a GraphQL response is for a
+// repository that could not be found.
+func isNotFoundOnly(err error) bool {
+	var gqlErr *api.GraphQLError
+	if !errors.As(err, &gqlErr) {
+		return false
+	}
+	for _, item := range gqlErr.Errors {
+		if item.Type != "NOT_FOUND" {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueNames(names []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, name := range nonEmpty(names) {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// ResolveSecretRepos replaces the repository IDs of selected organization
+// secrets with the IDs of the named repositories in owner, as IDs differ
+// between organizations. The IDs from the file are only kept when a secret
+// names no repositories. The returned errors line up with secrets, naming
+// the repositories of a secret that do not exist in owner.
+func (g *APIGetter) ResolveSecretRepos(owner string, secrets []data.ImportedSecret) []error {
+	scopes := make([][]string, len(secrets))
+	for i, secret := range secrets {
+		if secret.Level == "Organization" && secret.Access == "selected" {
+			scopes[i] = secret.RepositoryNames
+		}
+	}
+	ids, errs := g.resolveScopedRepos(owner, scopes)
+	for i := range secrets {
+		if ids[i] != nil {
+			secrets[i].RepositoryIDs = ids[i]
+		}
+	}
+	return errs
+}
+
+// ResolveVariableRepos replaces the repository IDs of selected organization
+// variables in the same way as ResolveSecretRepos.
+func (g *APIGetter) ResolveVariableRepos(owner string, variables []data.ImportedVariable) []error {
+	scopes := make([][]string, len(variables))
+	for i, variable := range variables {
+		if variable.Level == "Organization" && variable.Visibility == "selected" {
+			scopes[i] = variable.SelectedRepos
+		}
+	}
+	ids, errs := g.resolveScopedRepos(owner, scopes)
+	for i := range variables {
+		if ids[i] != nil {
+			variables[i].SelectedReposIDs = ids[i]
+		}
+	}
+	return errs
+}
+
+// resolveScopedRepos looks up every repository named in scopes at once and
+// returns the IDs for each scope, or nil when a scope names no repositories.
+func (g *APIGetter) resolveScopedRepos(owner string, scopes [][]string) ([][]string, []error) {
+	ids := make([][]string, len(scopes))
+	errs := make([]error, len(scopes))
+
+	var names []string
+	for _, scope := range scopes {
+		names = append(names, scope...)
+	}
+	if len(nonEmpty(names)) == 0 {
+		return ids, errs
+	}
+	repoIDs, err := g.GetRepoIDs(owner, names)
+	if err != nil {
+		err = fmt.Errorf("failed to resolve repositories in %s: %w", owner, err)
+	}
+
+	for i, scope := range scopes {
+		scope = nonEmpty(scope)
+		if len(scope) == 0 {
+			continue
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		var unknown []string
+		for _, name := range scope {
+			id, ok := repoIDs[name]
+			if !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			ids[i] = append(ids[i], strconv.Itoa(id))
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			ids[i] = nil
+			errs[i] = fmt.Errorf("unknown repositories in %s: %s", owner, strings.Join(unknown, ", "))
+		}
+	}
+	return ids, errs
+}
+
+// ListRepos returns the named repositories of owner, or every repository of
+// owner when no names are given.
+func (g *APIGetter) ListRepos(owner string, names []string) ([]data.RepoInfo, error) {
+	var allRepos []data.RepoInfo
+	if len(names) > 0 {
+		zap.S().Infof("Processing repos: %s", names)
+		for _, name := range names {
+			zap.S().Debugf("Processing %s/%s", owner, name)
+			repoQuery, err := g.GetRepo(owner, name)
+			if err != nil {
+				zap.S().Error("Error raised in getting repos", zap.Error(err))
+				return nil, err
+			}
+			allRepos = append(allRepos, repoQuery.Repository)
+		}
+		return allRepos, nil
+	}
+
+	var reposCursor *string
+	for {
+		zap.S().Debugf("Processing list of repositories for %s", owner)
+		reposQuery, err := g.GetReposList(owner, reposCursor)
+		if err != nil {
+			zap.S().Error("Error raised in processing list of repos", zap.Error(err))
+			return nil, err
+		}
+		allRepos = append(allRepos, reposQuery.Organization.Repositories.Nodes...)
+		reposCursor = &reposQuery.Organization.Repositories.PageInfo.EndCursor
+		if !reposQuery.Organization.Repositories.PageInfo.HasNextPage {
+			break
+		}
+	}
+	return allRepos, nil
+}
+
+// SkipMissingSecretRepos marks each repository and environment secret whose
+// repository does not exist in owner as skipped, as a source organization
+// often has repositories that were not migrated. Rows that already have an
+// error are left alone.
+func (g *APIGetter) SkipMissingSecretRepos(owner string, secrets []data.ImportedSecret, rowErrors []error) error {
+	repos := make([]string, len(secrets))
+	names := make([]string, len(secrets))
+	for i, secret := range secrets {
+		if secret.Level != "Organization" {
+			repos[i] = firstName(secret.RepositoryNames)
+		}
+		names[i] = secret.Name
+	}
+	return g.skipMissingRepos(owner, "secret", repos, names, rowErrors)
+}
+
+// SkipMissingVariableRepos marks each repository and environment variable
+// whose repository does not exist in owner as skipped, in the same way as
+// SkipMissingSecretRepos.
+func (g *APIGetter) SkipMissingVariableRepos(owner string, variables []data.ImportedVariable, rowErrors []error) error {
+	repos := make([]string, len(variables))
+	names := make([]string, len(variables))
+	for i, variable := range variables {
+		if variable.Level != "Organization" {
+			repos[i] = firstName(variable.SelectedRepos)
+		}
+		names[i] = variable.Name
+	}
+	return g.skipMissingRepos(owner, "variable", repos, names, rowErrors)
+}
+
+// skipMissingRepos sets a SkipError for each row whose repository in repos
+// does not exist in owner. Rows with no repository are left alone.
+func (g *APIGetter) skipMissingRepos(owner string, kind string, repos []string, names []string, rowErrors []error) error {
+	var lookup []string
+	for i, repo := range repos {
+		if repo != "" && rowErrors[i] == nil {
+			lookup = append(lookup, repo)
+		}
+	}
+	if len(lookup) == 0 {
+		return nil
+	}
+	repoIDs, err := g.GetRepoIDs(owner, lookup)
+	if err != nil {
+		zap.S().Errorf("Error arose resolving repositories in %s", owner)
+		return err
+	}
+	for i, repo := range repos {
+		if repo == "" || rowErrors[i] != nil {
+			continue
+		}
+		if _, ok := repoIDs[repo]; !ok {
+			zap.S().Warnf("Repository %s does not exist in %s, skipping %s %s", repo, owner, kind, names[i])
+			rowErrors[i] = &SkipError{Reason: fmt.Sprintf("repository %s does not exist in %s", repo, owner)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+// repoQueryHandler resolves batched repository lookups from repoIDs,
+// counting the queries made.
+func repoQueryHandler(t *testing.T, repoIDs map[string]int, queries *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			return
+		}
+		*queries++
+		var query struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &query)
+		if !strings.HasPrefix(query.Query, "query getRepos(") {
+			t.Errorf("Unexpected query %s", query.Query)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(repoBatchResponse(query.Variables, repoIDs))
+	}
+}
+
+func TestGetRepoIDs(t *testing.T) {
+	repoIDs := map[string]int{}
+	var names []string
+	for i := 0; i < 120; i++ {
+		name := fmt.Sprintf("repo-%d", i)
+		repoIDs[name] = 1000 + i
+		names = append(names, name)
+	}
+	names = append(names, "repo-1", "", "missing-repo")
+
+	queries := 0
+	g := newFakeAPIGetter(t, repoQueryHandler(t, repoIDs, &queries))
+	ids, err := g.GetRepoIDs("test-org", names)
+	if err != nil {
+		t.Fatalf("GetRepoIDs() error = %v", err)
+	}
+	if queries != 3 {
+		t.Errorf("Expected 121 unique names to take 3 queries, got %d", queries)
+	}
+	if len(ids) != 120 {
+		t.Errorf("Expected 120 repositories to be found, got %d", len(ids))
+	}
+	if ids["repo-119"] != 1119 {
+		t.Errorf("Expected repo-119 to have id 1119, got %d", ids["repo-119"])
+	}
+	if _, ok := ids["missing-repo"]; ok {
+		t.Error("Expected missing-repo to be left out")
+	}
+
+	queries = 0
+	ids, err = g.GetRepoIDs("test-org", []string{""})
+	if err != nil || len(ids) != 0 || queries != 0 {
+		t.Errorf("Expected no query for no names, got %v, %v after %d queries", ids, err, queries)
+	}
+}
+
+func TestGetRepoIDsError(t *testing.T) {
+	stubSleep(t)
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":null,"errors":[{"type":"FORBIDDEN","message":"Resource not accessible by integration"}]}`))
+	})
+	if _, err := g.GetRepoIDs("test-org", []string{"repo-a"}); err == nil || !strings.Contains(err.Error(), "Resource not accessible") {
+		t.Errorf("Expected the GraphQL error to be returned, got %v", err)
+	}
+}
+
+func TestResolveSecretRepos(t *testing.T) {
+	queries := 0
+	g := newFakeAPIGetter(t, repoQueryHandler(t, map[string]int{"repo-a": 11, "repo-b": 22}, &queries))
+
+	secrets := []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "SCOPED", Access: "selected", RepositoryNames: []string{"repo-a", "repo-b"}, RepositoryIDs: []string{"1", "2"}},
+		{Level: "Organization", Type: "Dependabot", Name: "BY_ID", Access: "selected", RepositoryNames: []string{""}, RepositoryIDs: []string{"7"}},
+		{Level: "Organization", Type: "Actions", Name: "UNKNOWN", Access: "selected", RepositoryNames: []string{"repo-z", "repo-a", "repo-y"}, RepositoryIDs: []string{"1", "2", "3"}},
+		{Level: "Organization", Type: "Actions", Name: "ALL", Access: "all", RepositoryNames: []string{"repo-x"}, RepositoryIDs: []string{"9"}},
+		{Level: "Repository", Type: "Actions", Name: "REPO", Access: "RepoOnly", RepositoryNames: []string{"repo-w"}, RepositoryIDs: []string{"8"}},
+	}
+	errs := g.ResolveSecretRepos("test-org", secrets)
+
+	if queries != 1 {
+		t.Errorf("Expected a single query, got %d", queries)
+	}
+	expectedIDs := [][]string{{"11", "22"}, {"7"}, {"1", "2", "3"}, {"9"}, {"8"}}
+	for i, want := range expectedIDs {
+		if strings.Join(secrets[i].RepositoryIDs, ",") != strings.Join(want, ",") {
+			t.Errorf("Secret %s: expected ids %v, got %v", secrets[i].Name, want, secrets[i].RepositoryIDs)
+		}
+	}
+	for i, err := range errs {
+		if i == 2 {
+			if err == nil || err.Error() != "unknown repositories in test-org: repo-y, repo-z" {
+				t.Errorf("Expected unknown repositories error, got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Secret %s: unexpected error %v", secrets[i].Name, err)
+		}
+	}
+}
+
+func TestResolveVariableRepos(t *testing.T) {
+	stubSleep(t)
+	queries := 0
+	g := newFakeAPIGetter(t, repoQueryHandler(t, map[string]int{"repo-a": 11}, &queries))
+
+	variables := []data.ImportedVariable{
+		{Level: "Organization", Name: "SCOPED", Visibility: "selected", SelectedRepos: []string{"repo-a"}, SelectedReposIDs: []string{"1"}},
+		{Level: "Organization", Name: "PRIVATE", Visibility: "private"},
+	}
+	errs := g.ResolveVariableRepos("test-org", variables)
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+	if strings.Join(variables[0].SelectedReposIDs, ",") != "11" {
+		t.Errorf("Expected the id of repo-a in the target, got %v", variables[0].SelectedReposIDs)
+	}
+
+	failing := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	variables = []data.ImportedVariable{
+		{Level: "Organization", Name: "SCOPED", Visibility: "selected", SelectedRepos: []string{"repo-a"}, SelectedReposIDs: []string{"1"}},
+		{Level: "Organization", Name: "ALL", Visibility: "all"},
+	}
+	errs = failing.ResolveVariableRepos("test-org", variables)
+	if errs[0] == nil || !strings.Contains(errs[0].Error(), "failed to resolve repositories in test-org") {
+		t.Errorf("Expected a lookup error for the scoped variable, got %v", errs[0])
+	}
+	if errs[1] != nil {
+		t.Errorf("Expected no error for a variable without repositories, got %v", errs[1])
+	}
+	if strings.Join(variables[0].SelectedReposIDs, ",") != "1" {
+		t.Errorf("Expected ids to be left alone when the lookup fails, got %v", variables[0].SelectedReposIDs)
+	}
+}
+
+func TestListRepos(t *testing.T) {
+	var pages []string
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		var query struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &query)
+		w.Header().Set("Content-Type", "application/json")
+		if name, ok := query.Variables["name"]; ok {
+			_, _ = fmt.Fprintf(w, `{"data":{"repository":{"databaseId":9,"name":%q}}}`, name)
+			return
+		}
+		cursor, _ := query.Variables["endCursor"].(string)
+		pages = append(pages, cursor)
+		if cursor == "" {
+			_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"nodes":[{"databaseId":1,"name":"api"}],"pageInfo":{"endCursor":"page-2","hasNextPage":true}}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"organization":{"repositories":{"nodes":[{"databaseId":2,"name":"web"}],"pageInfo":{"hasNextPage":false}}}}}`))
+	})
+
+	repos, err := g.ListRepos("test-org", nil)
+	if err != nil {
+		t.Fatalf("ListRepos() error = %v", err)
+	}
+	if len(repos) != 2 || repos[0].Name != "api" || repos[1].Name != "web" {
+		t.Errorf("Expected api and web from both pages, got %v", repos)
+	}
+	if strings.Join(pages, ",") != ",page-2" {
+		t.Errorf("Expected the second page to be read from the end cursor, got %v", pages)
+	}
+
+	repos, err = g.ListRepos("test-org", []string{"docs"})
+	if err != nil {
+		t.Fatalf("ListRepos() error = %v", err)
+	}
+	if len(repos) != 1 || repos[0].Name != "docs" || repos[0].DatabaseId != 9 {
+		t.Errorf("Expected only the named repository, got %v", repos)
+	}
+}
+
+func TestListReposError(t *testing.T) {
+	stubSleep(t)
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	if _, err := g.ListRepos("test-org", nil); err == nil {
+		t.Error("Expected an error listing repositories")
+	}
+	if _, err := g.ListRepos("test-org", []string{"api"}); err == nil {
+		t.Error("Expected an error reading a named repository")
+	}
+}
+
+func TestSkipMissingSecretRepos(t *testing.T) {
+	queries := 0
+	g := newFakeAPIGetter(t, repoQueryHandler(t, map[string]int{"api": 7}, &queries))
+
+	secrets := []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "NPM_TOKEN", Access: "all"},
+		{Level: "Repository", Type: "Actions", Name: "API_KEY", RepositoryNames: []string{"api"}},
+		{Level: "Environment", Type: "Actions", Name: "DB_PASSWORD", RepositoryNames: []string{"docs"}, EnvironmentName: "production"},
+	}
+	rowErrors := make([]error, len(secrets))
+	if err := g.SkipMissingSecretRepos("test-org", secrets, rowErrors); err != nil {
+		t.Fatalf("SkipMissingSecretRepos() error = %v", err)
+	}
+	if rowErrors[0] != nil || rowErrors[1] != nil {
+		t.Errorf("Expected existing repositories to be kept, got %v", rowErrors)
+	}
+	var skipErr *SkipError
+	if !errors.As(rowErrors[2], &skipErr) || skipErr.Reason != "repository docs does not exist in test-org" {
+		t.Errorf("Expected docs to be skipped, got %v", rowErrors[2])
+	}
+}
+
+func TestSkipMissingVariableRepos(t *testing.T) {
+	queries := 0
+	g := newFakeAPIGetter(t, repoQueryHandler(t, map[string]int{"platform-api": 7}, &queries))
+
+	variables := []data.ImportedVariable{
+		{Level: "Organization", Name: "REGION", Visibility: "all"},
+		{Level: "Repository", Name: "LEVEL", SelectedRepos: []string{"platform-api"}},
+		{Level: "Environment", Name: "THEME", SelectedRepos: []string{"docs"}, EnvironmentName: "production"},
+		{Level: "Repository", Name: "UNMAPPED", SelectedRepos: []string{"tools"}},
+	}
+	mappingErr := errors.New("no repository mapping for: tools")
+	rowErrors := []error{nil, nil, nil, mappingErr}
+	if err := g.SkipMissingVariableRepos("test-org", variables, rowErrors); err != nil {
+		t.Fatalf("SkipMissingVariableRepos() error = %v", err)
+	}
+	if rowErrors[0] != nil || rowErrors[1] != nil {
+		t.Errorf("Expected existing repositories to be kept, got %v", rowErrors)
+	}
+	var skipErr *SkipError
+	if !errors.As(rowErrors[2], &skipErr) || skipErr.Reason != "repository docs does not exist in test-org" {
+		t.Errorf("Expected docs to be skipped, got %v", rowErrors[2])
+	}
+	if rowErrors[3] != mappingErr {
+		t.Errorf("Expected the mapping error to be kept, got %v", rowErrors[3])
+	}
+
+	queries = 0
+	if err := g.SkipMissingVariableRepos("test-org", variables[:1], make([]error, 1)); err != nil || queries != 0 {
+		t.Errorf("Expected no query without repository rows, got %v after %d queries", err, queries)
+	}
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"go.uber.org/zap"
+)
+
+// Failed requests are retried up to maxRetries times. Server errors back off
+// exponentially from retryBaseDelay, capped at retryMaxDelay, and secondary
+// rate limits without a Retry-After header wait secondaryRateLimitDelay as
+// recommended by GitHub.
+const (
+	maxRetries              = 5
+	retryBaseDelay          = time.Second
+	retryMaxDelay           = time.Minute
+	secondaryRateLimitDelay = time.Minute
+)
+
+// sleep, now and jitter are replaced in tests so retries can be checked
+// without waiting on the clock
+var (
+	sleep  = time.Sleep
+	now    = time.Now
+	jitter = func(delay time.Duration) time.Duration {
+		return time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	}
+)
+
+// doRequest sends a REST request, waiting out primary and secondary rate
+// limits and retrying server and network errors for idempotent methods.
+// The last error is returned once the retries are used up.
+func doRequest(client restRequester, method string, path string, body io.Reader) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body for %s %s: %w", method, path, err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(payload)
+		}
+		resp, err := client.Request(method, path, reader)
+		if err == nil {
+			waitForRateLimitReset(resp.Header)
+			return resp, nil
+		}
+
+		delay, retry := retryDelay(method, err, attempt)
+		if !retry || attempt >= maxRetries {
+			return nil, err
+		}
+		zap.S().Warnf("Request %s %s failed: %v, retrying in %s (%d of %d)", method, path, err, delay, attempt+1, maxRetries)
+		sleep(delay)
+	}
+}
+
+// IsConflict reports whether a request failed because the resource it
+// creates already exists.
+func IsConflict(err error) bool {
+	var httpErr *api.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusConflict
+}
+
+// IsNotFound reports whether a request failed because the resource does
+// not exist.
+func IsNotFound(err error) bool {
+	var httpErr *api.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+// retryDelay reports whether a failed request should be retried and how
+// long to wait before doing so.
+func retryDelay(method string, err error, attempt int) (time.Duration, bool) {
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) {
+		return backoffDelay(attempt), isIdempotent(method)
+	}
+	if isRateLimited(httpErr) {
+		return rateLimitDelay(httpErr.Headers), true
+	}
+	if httpErr.StatusCode >= 500 {
+		return backoffDelay(attempt), isIdempotent(method)
+	}
+	return 0, false
+}
+
+// isIdempotent reports whether a request can be safely repeated after a
+// server error, as a failed POST may still have created the resource.
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+func isRateLimited(httpErr *api.HTTPError) bool {
+	if httpErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if httpErr.StatusCode != http.StatusForbidden {
+		return false
+	}
+	return httpErr.Headers.Get("Retry-After") != "" ||
+		httpErr.Headers.Get("X-RateLimit-Remaining") == "0" ||
+		strings.Contains(strings.ToLower(httpErr.Message), "rate limit")
+}
+
+func rateLimitDelay(headers http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(headers.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if headers.Get("X-RateLimit-Remaining") == "0" {
+		if delay, ok := untilRateLimitReset(headers); ok {
+			return delay
+		}
+	}
+	return secondaryRateLimitDelay + jitter(secondaryRateLimitDelay)
+}
+
+func backoffDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay + jitter(delay)
+}
+
+// untilRateLimitReset returns the time left until the primary rate limit
+// resets, with a second added to allow for clock drift.
+func untilRateLimitReset(headers http.Header) (time.Duration, bool) {
+	reset, err := strconv.ParseInt(headers.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	delay := time.Unix(reset, 0).Sub(now()) + time.Second
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
+
+// waitForRateLimitReset pauses when a successful response used up the last
+// request of the primary rate limit, so the next request is not rejected.
+func waitForRateLimitReset(headers http.Header) {
+	if headers.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	if delay, ok := untilRateLimitReset(headers); ok && delay > 0 {
+		zap.S().Infof("Rate limit reached, waiting %s for it to reset", delay)
+		sleep(delay)
+	}
+}
+
+type graphQLQuerier interface {
+	Query(name string, q interface{}, variables map[string]interface{}) error
+}
+
+// doQuery runs a GraphQL query with the same retries as doRequest. Queries
+// are always safe to repeat, so only errors reported by GraphQL itself,
+// other than rate limiting, are returned straight away.
+func doQuery(client graphQLQuerier, name string, q interface{}, variables map[string]interface{}) error {
+	return retryQuery(name, func() error {
+		return client.Query(name, q, variables)
+	})
+}
+
+// retryQuery runs query, retrying it as doQuery describes.
+func retryQuery(name string, query func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := query()
+		if err == nil {
+			return nil
+		}
+
+		delay, retry := retryDelay(http.MethodGet, err, attempt)
+		var gqlErr *api.GraphQLError
+		if errors.As(err, &gqlErr) {
+			retry = gqlErr.Match("RATE_LIMITED", "")
+			delay = secondaryRateLimitDelay + jitter(secondaryRateLimitDelay)
+		}
+		if !retry || attempt >= maxRetries {
+			return err
+		}
+		zap.S().Warnf("Query %s failed: %v, retrying in %s (%d of %d)", name, err, delay, attempt+1, maxRetries)
+		sleep(delay)
+	}
+}
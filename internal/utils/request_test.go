@@ -0,0 +1,331 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+var testNow = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// stubSleep replaces the clock used by doRequest and returns the delays it
+// would have slept for.
+func stubSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	origSleep, origNow, origJitter := sleep, now, jitter
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+	now = func() time.Time { return testNow }
+	jitter = func(time.Duration) time.Duration { return 0 }
+	t.Cleanup(func() {
+		sleep, now, jitter = origSleep, origNow, origJitter
+	})
+	return &delays
+}
+
+func httpError(status int, message string, headers map[string]string) error {
+	header := http.Header{}
+	for k, v := range headers {
+		header.Set(k, v)
+	}
+	return &api.HTTPError{StatusCode: status, Message: message, Headers: header}
+}
+
+func okResponse(headers map[string]string) *http.Response {
+	header := http.Header{}
+	for k, v := range headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader([]byte("{}"))),
+	}
+}
+
+// sequenceClient returns the given errors in order, then succeeds
+func sequenceClient(calls *int, errs ...error) *mockRESTClient {
+	return &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			*calls++
+			if *calls <= len(errs) {
+				return nil, errs[*calls-1]
+			}
+			return okResponse(nil), nil
+		},
+	}
+}
+
+func TestDoRequestRetriesServerErrors(t *testing.T) {
+	delays := stubSleep(t)
+	calls := 0
+	client := sequenceClient(&calls, httpError(502, "Bad Gateway", nil), httpError(503, "Service Unavailable", nil))
+
+	resp, err := doRequest(client, "GET", "orgs/test-org/actions/secrets", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	expected := []time.Duration{time.Second, 2 * time.Second}
+	if fmt.Sprint(*delays) != fmt.Sprint(expected) {
+		t.Errorf("Expected delays %v, got %v", expected, *delays)
+	}
+}
+
+func TestDoRequestRetriesNetworkErrors(t *testing.T) {
+	stubSleep(t)
+	calls := 0
+	client := sequenceClient(&calls, fmt.Errorf("connection reset by peer"))
+
+	if _, err := doRequest(client, "PUT", "orgs/test-org/actions/secrets/TEST", bytes.NewReader([]byte("{}"))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestDoRequestDoesNotRetryPostServerErrors(t *testing.T) {
+	delays := stubSleep(t)
+	calls := 0
+	client := sequenceClient(&calls, httpError(502, "Bad Gateway", nil))
+
+	_, err := doRequest(client, "POST", "orgs/test-org/actions/variables", bytes.NewReader([]byte("{}")))
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+	if len(*delays) != 0 {
+		t.Errorf("Expected no delays, got %v", *delays)
+	}
+}
+
+func TestDoRequestDoesNotRetryClientErrors(t *testing.T) {
+	stubSleep(t)
+	for _, status := range []int{400, 401, 403, 404, 409, 422} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			calls := 0
+			client := sequenceClient(&calls, httpError(status, "error", nil))
+
+			_, err := doRequest(client, "GET", "repos/test-org/test-repo/actions/secrets", nil)
+			var httpErr *api.HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != status {
+				t.Errorf("Expected HTTP %d error, got %v", status, err)
+			}
+			if calls != 1 {
+				t.Errorf("Expected 1 call, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestDoRequestRateLimits(t *testing.T) {
+	reset := strconv.FormatInt(testNow.Add(30*time.Second).Unix(), 10)
+	testCases := []struct {
+		name     string
+		method   string
+		err      error
+		expected time.Duration
+	}{
+		{
+			name:     "too many requests with retry-after",
+			method:   "POST",
+			err:      httpError(429, "Too Many Requests", map[string]string{"Retry-After": "7"}),
+			expected: 7 * time.Second,
+		},
+		{
+			name:     "primary rate limit waits for reset",
+			method:   "GET",
+			err:      httpError(403, "API rate limit exceeded", map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}),
+			expected: 31 * time.Second,
+		},
+		{
+			name:     "secondary rate limit without headers",
+			method:   "POST",
+			err:      httpError(403, "You have exceeded a secondary rate limit", nil),
+			expected: secondaryRateLimitDelay,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			delays := stubSleep(t)
+			calls := 0
+			client := sequenceClient(&calls, tc.err)
+
+			if _, err := doRequest(client, tc.method, "orgs/test-org/actions/variables", nil); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if calls != 2 {
+				t.Errorf("Expected 2 calls, got %d", calls)
+			}
+			if len(*delays) != 1 || (*delays)[0] != tc.expected {
+				t.Errorf("Expected delay %v, got %v", tc.expected, *delays)
+			}
+		})
+	}
+}
+
+func TestDoRequestGivesUp(t *testing.T) {
+	delays := stubSleep(t)
+	calls := 0
+	client := &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			calls++
+			return nil, httpError(502, "Bad Gateway", nil)
+		},
+	}
+
+	_, err := doRequest(client, "GET", "orgs/test-org/actions/secrets", nil)
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 502 {
+		t.Errorf("Expected HTTP 502 error, got %v", err)
+	}
+	if calls != maxRetries+1 {
+		t.Errorf("Expected %d calls, got %d", maxRetries+1, calls)
+	}
+	if len(*delays) != maxRetries {
+		t.Errorf("Expected %d delays, got %d", maxRetries, len(*delays))
+	}
+	for _, delay := range *delays {
+		if delay > retryMaxDelay {
+			t.Errorf("Delay %v exceeds maximum %v", delay, retryMaxDelay)
+		}
+	}
+}
+
+func TestDoRequestReplaysBody(t *testing.T) {
+	stubSleep(t)
+	var bodies []string
+	client := &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			payload, _ := io.ReadAll(body)
+			bodies = append(bodies, string(payload))
+			if len(bodies) == 1 {
+				return nil, httpError(500, "Internal Server Error", nil)
+			}
+			return okResponse(nil), nil
+		},
+	}
+
+	if _, err := doRequest(client, "PUT", "orgs/test-org/actions/secrets/TEST", bytes.NewReader([]byte(`{"key_id":"1"}`))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != `{"key_id":"1"}` || bodies[1] != bodies[0] {
+		t.Errorf("Expected the body to be sent twice, got %v", bodies)
+	}
+}
+
+func TestDoRequestWaitsWhenRateLimitExhausted(t *testing.T) {
+	delays := stubSleep(t)
+	reset := strconv.FormatInt(testNow.Add(10*time.Second).Unix(), 10)
+	client := &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			return okResponse(map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}), nil
+		},
+	}
+
+	if _, err := doRequest(client, "GET", "orgs/test-org/actions/secrets", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(*delays) != 1 || (*delays)[0] != 11*time.Second {
+		t.Errorf("Expected to wait 11s for the reset, got %v", *delays)
+	}
+}
+
+type mockQuerier struct {
+	errs  []error
+	calls int
+}
+
+func (m *mockQuerier) Query(name string, q interface{}, variables map[string]interface{}) error {
+	m.calls++
+	if m.calls <= len(m.errs) {
+		return m.errs[m.calls-1]
+	}
+	return nil
+}
+
+func TestDoQuery(t *testing.T) {
+	testCases := []struct {
+		name      string
+		errs      []error
+		wantErr   bool
+		wantCalls int
+	}{
+		{"success", nil, false, 1},
+		{"network error retried", []error{fmt.Errorf("non-200 OK status code: 502 Bad Gateway")}, false, 2},
+		{"rate limited retried", []error{&api.GraphQLError{Errors: []api.GraphQLErrorItem{{Type: "RATE_LIMITED"}}}}, false, 2},
+		{"not found returned", []error{&api.GraphQLError{Errors: []api.GraphQLErrorItem{{Type: "NOT_FOUND"}}}}, true, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stubSleep(t)
+			querier := &mockQuerier{errs: tc.errs}
+
+			err := doQuery(querier, "getRepos", nil, nil)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if querier.calls != tc.wantCalls {
+				t.Errorf("Expected %d calls, got %d", tc.wantCalls, querier.calls)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestAPIGetterRetriesRequests(t *testing.T) {
+	stubSleep(t)
+	calls := 0
+	restClient, err := api.NewRESTClient(api.ClientOptions{
+		Host:      "github.com",
+		AuthToken: "test-token",
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			status := http.StatusCreated
+			if calls == 1 {
+				status = http.StatusBadGateway
+			}
+			return &http.Response{
+				StatusCode: status,
+				Status:     http.StatusText(status),
+				Header:     http.Header{},
+				Body:       io.NopCloser(bytes.NewReader(nil)),
+				Request:    req,
+			}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create REST client: %v", err)
+	}
+	getter := &GitHubGetter{restClient: *restClient}
+
+	err = getter.CreateRepoActionSecret("test-org", "test-repo", "TEST", bytes.NewReader([]byte("{}")))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+// SkipError marks a row that was deliberately not written, which is reported
+// as skipped rather than failed.
+type SkipError struct {
+	Reason string
+}
+
+func (e *SkipError) Error() string {
+	return e.Reason
+}
+
+// ResultRow returns the row of the file an item was read from, or else its
+// place in the list being written, counting from 1.
+func ResultRow(row int, index int) int {
+	if row > 0 {
+		return row
+	}
+	return index + 1
+}
+
+// NewRowResult records the outcome of writing the secret or variable in row
+// of a file, keeping the HTTP status of a failed request.
+func NewRowResult(row int, level string, secretType string, name string, target string, err error) data.RowResult {
+	result := data.RowResult{
+		Row:    row,
+		Level:  level,
+		Type:   secretType,
+		Name:   name,
+		Target: target,
+		Result: data.ResultSucceeded,
+	}
+	var skipErr *SkipError
+	if errors.As(err, &skipErr) {
+		result.Result = data.ResultSkipped
+		result.Error = skipErr.Reason
+	} else if err != nil {
+		result.Result = data.ResultFailed
+		result.HTTPStatus = HTTPStatus(err)
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// HTTPStatus returns the status code of a failed request, or 0 when err did
+// not come from a response.
+func HTTPStatus(err error) int {
+	var httpErr *api.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode
+	}
+	return 0
+}
+
+// CountFailedResults returns the number of rows that could not be written.
+func CountFailedResults(results []data.RowResult) int {
+	return countResults(results, data.ResultFailed)
+}
+
+func countResults(results []data.RowResult, outcome string) int {
+	count := 0
+	for _, result := range results {
+		if result.Result == outcome {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteResults writes results to fileName as JSON when it ends in .json,
+// and as CSV otherwise.
+func WriteResults(fileName string, results []data.RowResult) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			zap.S().Errorf("Error closing file: %v", err)
+		}
+	}()
+
+	if strings.EqualFold(filepath.Ext(fileName), ".json") {
+		return writeResultsJSON(f, results)
+	}
+	return writeResultsCSV(f, results)
+}
+
+func writeResultsJSON(w io.Writer, results []data.RowResult) error {
+	if results == nil {
+		results = []data.RowResult{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+func writeResultsCSV(w io.Writer, results []data.RowResult) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{
+		"Row",
+		"Level",
+		"Type",
+		"Name",
+		"Target",
+		"Result",
+		"HTTPStatus",
+		"Error",
+	})
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		status := ""
+		if result.HTTPStatus != 0 {
+			status = strconv.Itoa(result.HTTPStatus)
+		}
+		err = csvWriter.Write([]string{
+			strconv.Itoa(result.Row),
+			result.Level,
+			result.Type,
+			result.Name,
+			result.Target,
+			result.Result,
+			status,
+			result.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// PrintResultSummary writes the number of rows that succeeded, were skipped
+// and failed, naming each row that was not written so it can be found
+// without the results file.
+func PrintResultSummary(w io.Writer, noun string, results []data.RowResult) error {
+	for _, result := range results {
+		if result.Result == data.ResultSucceeded {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "Row %d: %s %s %s: %s\n", result.Row, result.Result, result.Target, result.Name, result.Error); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "Summary: %d %s succeeded, %d skipped, %d failed.\n",
+		countResults(results, data.ResultSucceeded), noun, countResults(results, data.ResultSkipped), countResults(results, data.ResultFailed))
+	return err
+}
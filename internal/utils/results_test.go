@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+func TestNewRowResult(t *testing.T) {
+	result := NewRowResult(1, "Organization", "Actions", "TOKEN", "test-org", nil)
+	if result.Result != data.ResultSucceeded || result.HTTPStatus != 0 || result.Error != "" {
+		t.Errorf("Expected a successful result, got %+v", result)
+	}
+
+	httpErr := &api.HTTPError{StatusCode: 422, Message: "Validation Failed"}
+	result = NewRowResult(2, "Repository", "Actions", "TOKEN", "test-org/repo1", fmt.Errorf("failed to create secret TOKEN: %w", httpErr))
+	if result.Result != data.ResultFailed {
+		t.Errorf("Expected a failed result, got %s", result.Result)
+	}
+	if result.HTTPStatus != 422 {
+		t.Errorf("Expected the wrapped HTTP status 422, got %d", result.HTTPStatus)
+	}
+	if !strings.Contains(result.Error, "failed to create secret TOKEN") {
+		t.Errorf("Expected the error message to be kept, got %q", result.Error)
+	}
+
+	result = NewRowResult(4, "Repository", "Actions", "TOKEN", "test-org/archived", fmt.Errorf("copying: %w", &SkipError{Reason: "repository archived does not exist in test-org"}))
+	if result.Result != data.ResultSkipped || result.Error != "repository archived does not exist in test-org" {
+		t.Errorf("Expected a skipped result with its reason, got %+v", result)
+	}
+
+	result = NewRowResult(3, "Environment", "Actions", "TOKEN", "test-org/repo1 ()", errors.New("a repository and environment name are required"))
+	if result.Result != data.ResultFailed || result.HTTPStatus != 0 {
+		t.Errorf("Expected a failed result without a status, got %+v", result)
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	results := []data.RowResult{
+		{Row: 1, Level: "Organization", Type: "Actions", Name: "ORG_SECRET", Target: "test-org", Result: data.ResultSucceeded},
+		{Row: 2, Level: "Repository", Type: "Actions", Name: "REPO_SECRET", Target: "test-org/repo1", Result: data.ResultFailed, HTTPStatus: 403, Error: "forbidden, check access"},
+	}
+	dir := t.TempDir()
+
+	csvFile := filepath.Join(dir, "results.csv")
+	if err := WriteResults(csvFile, results); err != nil {
+		t.Fatalf("WriteResults() error = %v", err)
+	}
+	content, err := os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatalf("Failed to read results: %v", err)
+	}
+	expected := "Row,Level,Type,Name,Target,Result,HTTPStatus,Error\n" +
+		"1,Organization,Actions,ORG_SECRET,test-org,succeeded,,\n" +
+		"2,Repository,Actions,REPO_SECRET,test-org/repo1,failed,403,\"forbidden, check access\"\n"
+	if string(content) != expected {
+		t.Errorf("Expected CSV:\n%s\ngot:\n%s", expected, content)
+	}
+
+	jsonFile := filepath.Join(dir, "results.JSON")
+	if err := WriteResults(jsonFile, results); err != nil {
+		t.Fatalf("WriteResults() error = %v", err)
+	}
+	content, err = os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("Failed to read results: %v", err)
+	}
+	var decoded []data.RowResult
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		t.Fatalf("Expected JSON results, got %v:\n%s", err, content)
+	}
+	if len(decoded) != 2 || decoded[1] != results[1] {
+		t.Errorf("Expected results to round trip, got %+v", decoded)
+	}
+
+	emptyFile := filepath.Join(dir, "empty.json")
+	if err := WriteResults(emptyFile, nil); err != nil {
+		t.Fatalf("WriteResults() error = %v", err)
+	}
+	content, _ = os.ReadFile(emptyFile)
+	if strings.TrimSpace(string(content)) != "[]" {
+		t.Errorf("Expected an empty JSON array, got %s", content)
+	}
+
+	if err := WriteResults(filepath.Join(dir, "missing", "results.csv"), results); err == nil {
+		t.Error("Expected an error writing to a missing directory")
+	}
+}
+
+func TestPrintResultSummary(t *testing.T) {
+	results := []data.RowResult{
+		{Row: 1, Name: "ORG_VAR", Target: "test-org", Result: data.ResultSucceeded},
+		{Row: 2, Name: "REPO_VAR", Target: "test-org/repo1", Result: data.ResultFailed, Error: "forbidden"},
+		{Row: 3, Name: "ENV_VAR", Target: "test-org/repo1 (prod)", Result: data.ResultSucceeded},
+		{Row: 4, Name: "OLD_VAR", Target: "test-org/archived", Result: data.ResultSkipped, Error: "repository archived does not exist in test-org"},
+	}
+	if failed := CountFailedResults(results); failed != 1 {
+		t.Errorf("Expected 1 failed result, got %d", failed)
+	}
+
+	var out bytes.Buffer
+	if err := PrintResultSummary(&out, "variables", results); err != nil {
+		t.Fatalf("PrintResultSummary() error = %v", err)
+	}
+	expected := "Row 2: failed test-org/repo1 REPO_VAR: forbidden\n" +
+		"Row 4: skipped test-org/archived OLD_VAR: repository archived does not exist in test-org\n" +
+		"Summary: 2 variables succeeded, 1 skipped, 1 failed.\n"
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestResultRow(t *testing.T) {
+	if row := ResultRow(7, 2); row != 7 {
+		t.Errorf("Expected the row of the file, got %d", row)
+	}
+	if row := ResultRow(0, 2); row != 3 {
+		t.Errorf("Expected the place in the list, got %d", row)
+	}
+}
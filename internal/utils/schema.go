@@ -0,0 +1,447 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+// schemaColumn is a column of an input file, identified by the header
+// written by export along with the other names it is accepted under.
+type schemaColumn struct {
+	header   string
+	names    []string
+	required bool
+}
+
+// Columns of a secrets file, in the order they are written by export.
+const (
+	secretLevelCol = iota
+	secretTypeCol
+	secretNameCol
+	secretValueCol
+	secretAccessCol
+	secretRepoNamesCol
+	secretRepoIDsCol
+	secretEnvironmentCol
+)
+
+var secretSchema = []schemaColumn{
+	{header: "SecretLevel", names: []string{"level"}, required: true},
+	{header: "SecretType", names: []string{"type", "app"}, required: true},
+	{header: "SecretName", names: []string{"name"}, required: true},
+	{header: "SecretValue", names: []string{"value"}},
+	{header: "SecretAccess", names: []string{"access", "visibility"}},
+	{header: "RepositoryNames", names: []string{"repositories", "repos", "selectedrepositories"}},
+	{header: "RepositoryIDs", names: []string{"repoids", "selectedrepositoryids"}},
+	{header: "EnvironmentName", names: []string{"environment"}},
+}
+
+// Columns of a variables file, in the order they are written by export.
+const (
+	variableLevelCol = iota
+	variableNameCol
+	variableValueCol
+	variableAccessCol
+	variableRepoNamesCol
+	variableRepoIDsCol
+	variableEnvironmentCol
+)
+
+var variableSchema = []schemaColumn{
+	{header: "VariableLevel", names: []string{"level"}, required: true},
+	{header: "VariableName", names: []string{"name"}, required: true},
+	{header: "VariableValue", names: []string{"value"}},
+	{header: "VariableAccess", names: []string{"access", "visibility"}},
+	{header: "RepositoryNames", names: []string{"repositories", "repos", "selectedrepositories"}},
+	{header: "RepositoryIDs", names: []string{"repoids", "selectedrepositoryids"}},
+	{header: "EnvironmentName", names: []string{"environment"}},
+}
+
+// Columns of an environments file, in the order they are written by export.
+const (
+	environmentRepoCol = iota
+	environmentNameCol
+	environmentWaitTimerCol
+	environmentPreventSelfReviewCol
+	environmentReviewersCol
+	environmentBranchPolicyCol
+	environmentBranchPoliciesCol
+	environmentAdminsBypassCol
+)
+
+var environmentSchema = []schemaColumn{
+	{header: "RepositoryName", names: []string{"repository", "repo"}, required: true},
+	{header: "EnvironmentName", names: []string{"environment", "name"}, required: true},
+	{header: "WaitTimer", names: []string{"wait"}},
+	{header: "PreventSelfReview"},
+	{header: "Reviewers"},
+	{header: "DeploymentBranchPolicy", names: []string{"branchpolicy"}},
+	{header: "BranchPolicies", names: []string{"policies"}},
+	{header: "CanAdminsBypass", names: []string{"adminsbypass"}},
+}
+
+// maxWaitTimer is the longest wait timer GitHub accepts, in minutes.
+const maxWaitTimer = 43200
+
+var (
+	branchPolicyTypes = []string{"all", "protected", "custom"}
+	reviewerTypes     = []string{"User", "Team"}
+)
+
+// Columns of the fields validation errors refer to
+var secretFieldCols = map[string]int{
+	fieldLevel:        secretLevelCol,
+	fieldType:         secretTypeCol,
+	fieldName:         secretNameCol,
+	fieldValue:        secretValueCol,
+	fieldVisibility:   secretAccessCol,
+	fieldRepositories: secretRepoNamesCol,
+	fieldRepoIDs:      secretRepoIDsCol,
+	fieldEnvironment:  secretEnvironmentCol,
+}
+
+var variableFieldCols = map[string]int{
+	fieldLevel:        variableLevelCol,
+	fieldName:         variableNameCol,
+	fieldValue:        variableValueCol,
+	fieldVisibility:   variableAccessCol,
+	fieldRepositories: variableRepoNamesCol,
+	fieldRepoIDs:      variableRepoIDsCol,
+	fieldEnvironment:  variableEnvironmentCol,
+}
+
+// RowError is an error in a row of an input file. Rows are numbered from
+// the first row after the header, as in the results file.
+type RowError struct {
+	Row    int
+	Column string
+	Err    error
+}
+
+func (e *RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+	}
+	return fmt.Sprintf("row %d, column %s: %v", e.Row, e.Column, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// csvTable reads the rows of an input file through the columns found in its
+// header, so columns may be in any order and unknown columns are ignored.
+// Blank rows are left out, but each row keeps its number in the file.
+type csvTable struct {
+	schema  []schemaColumn
+	header  []string
+	indexes []int
+	rows    [][]string
+	numbers []int
+	// document is set for the entries of a JSON or YAML file, whose fields
+	// have no spreadsheet letter.
+	document bool
+}
+
+func newCSVTable(records [][]string, schema []schemaColumn) (*csvTable, error) {
+	if len(records) == 0 {
+		return nil, errors.New("the file is empty, a header row is required")
+	}
+	table := &csvTable{schema: schema, header: records[0], indexes: make([]int, len(schema))}
+	for i := range table.indexes {
+		table.indexes[i] = -1
+	}
+	found := 0
+	for i, column := range records[0] {
+		field := schemaField(schema, column)
+		if field < 0 {
+			zap.S().Debugf("Ignoring unknown column %q", column)
+			continue
+		}
+		if table.indexes[field] >= 0 {
+			return nil, fmt.Errorf("column %s appears more than once in the header", schema[field].header)
+		}
+		table.indexes[field] = i
+		found++
+	}
+	if found == 0 {
+		zap.S().Debugf("No known columns found in the header, reading columns in the order written by export")
+		for i := range table.indexes {
+			table.indexes[i] = i
+		}
+	}
+	var missing []string
+	for field, column := range schema {
+		if column.required && table.indexes[field] < 0 {
+			missing = append(missing, column.header)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the header is missing the required columns: %s", strings.Join(missing, ", "))
+	}
+	for i, row := range records[1:] {
+		if !blankRow(row) {
+			table.rows = append(table.rows, row)
+			table.numbers = append(table.numbers, i+1)
+		}
+	}
+	return table, nil
+}
+
+// schemaField returns the field of schema a header names, or -1. Headers
+// match regardless of case, spaces, dashes and underscores.
+func schemaField(schema []schemaColumn, column string) int {
+	column = strings.ReplaceAll(normalizeColumn(column), "_", "")
+	for field, schemaColumn := range schema {
+		if column == strings.ToLower(schemaColumn.header) {
+			return field
+		}
+		for _, name := range schemaColumn.names {
+			if column == name {
+				return field
+			}
+		}
+	}
+	return -1
+}
+
+func blankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the field of a row without surrounding spaces, or an empty
+// string when the file has no such column or the row is short.
+func (t *csvTable) value(row []string, field int) string {
+	return strings.TrimSpace(t.raw(row, field))
+}
+
+// raw returns a field of a row as written, for values where surrounding
+// spaces may be meaningful.
+func (t *csvTable) raw(row []string, field int) string {
+	index := t.indexes[field]
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+// list returns a field of a row split on semicolons.
+func (t *csvTable) list(row []string, field int) []string {
+	values := strings.Split(t.value(row, field), ";")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// rowError returns an error for a field of a row, naming the column as it
+// appears in the header along with its spreadsheet letter.
+func (t *csvTable) rowError(row int, field int, err error) *RowError {
+	index := t.indexes[field]
+	if index < 0 || index >= len(t.header) {
+		return &RowError{Row: row, Column: t.schema[field].header, Err: err}
+	}
+	if t.document {
+		return &RowError{Row: row, Column: t.header[index], Err: err}
+	}
+	return &RowError{Row: row, Column: fmt.Sprintf("%s (%s)", t.header[index], columnLetter(index)), Err: err}
+}
+
+// fieldRowError returns the validation error of a row, naming the column of
+// the field it refers to, or nil.
+func (t *csvTable) fieldRowError(row int, err error, fieldCols map[string]int) *RowError {
+	if err == nil {
+		return nil
+	}
+	if field, ok := errorField(err); ok {
+		if col, ok := fieldCols[field]; ok {
+			return t.rowError(row, col, err)
+		}
+	}
+	return &RowError{Row: row, Err: err}
+}
+
+// columnLetter returns the spreadsheet letter of a zero based column index.
+func columnLetter(index int) string {
+	letter := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		letter = string(rune('A'+(index-1)%26)) + letter
+	}
+	return letter
+}
+
+// ParseSecretRecords reads secrets from CSV records, locating each column by
+// its header. Every row is validated, and should any be invalid the errors
+// of all of them are returned together, each naming its row and column,
+// along with the valid rows.
+func ParseSecretRecords(records [][]string) ([]data.ImportedSecret, error) {
+	table, err := newCSVTable(records, secretSchema)
+	if err != nil {
+		return nil, err
+	}
+	return table.secrets(validateSecretSettings)
+}
+
+// secrets reads every row of the table as a secret, checking each with
+// validate.
+func (t *csvTable) secrets(validate func(data.ImportedSecret) error) ([]data.ImportedSecret, error) {
+	secrets := make([]data.ImportedSecret, 0, len(t.rows))
+	var errs []error
+	for i, row := range t.rows {
+		secret := t.secret(row)
+		secret.Row = t.numbers[i]
+		normalizeSecret(&secret)
+		if rowErr := t.fieldRowError(secret.Row, validate(secret), secretFieldCols); rowErr != nil {
+			errs = append(errs, rowErr)
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, errors.Join(errs...)
+}
+
+func (t *csvTable) secret(row []string) data.ImportedSecret {
+	return data.ImportedSecret{
+		Level:           t.value(row, secretLevelCol),
+		Type:            t.value(row, secretTypeCol),
+		Name:            t.value(row, secretNameCol),
+		Value:           t.raw(row, secretValueCol),
+		Access:          t.value(row, secretAccessCol),
+		RepositoryNames: t.list(row, secretRepoNamesCol),
+		RepositoryIDs:   t.list(row, secretRepoIDsCol),
+		EnvironmentName: t.value(row, secretEnvironmentCol),
+	}
+}
+
+// ParseVariableRecords reads variables from CSV records in the same way as
+// ParseSecretRecords.
+func ParseVariableRecords(records [][]string) ([]data.ImportedVariable, error) {
+	table, err := newCSVTable(records, variableSchema)
+	if err != nil {
+		return nil, err
+	}
+	return table.variables(validateVariableSettings)
+}
+
+// variables reads every row of the table as a variable, checking each with
+// validate.
+func (t *csvTable) variables(validate func(data.ImportedVariable) error) ([]data.ImportedVariable, error) {
+	variables := make([]data.ImportedVariable, 0, len(t.rows))
+	var errs []error
+	for i, row := range t.rows {
+		variable := t.variable(row)
+		variable.Row = t.numbers[i]
+		normalizeVariable(&variable)
+		if rowErr := t.fieldRowError(variable.Row, validate(variable), variableFieldCols); rowErr != nil {
+			errs = append(errs, rowErr)
+			continue
+		}
+		variables = append(variables, variable)
+	}
+	return variables, errors.Join(errs...)
+}
+
+func (t *csvTable) variable(row []string) data.ImportedVariable {
+	return data.ImportedVariable{
+		Level:            t.value(row, variableLevelCol),
+		Name:             t.value(row, variableNameCol),
+		Value:            t.raw(row, variableValueCol),
+		Visibility:       t.value(row, variableAccessCol),
+		SelectedRepos:    t.list(row, variableRepoNamesCol),
+		SelectedReposIDs: t.list(row, variableRepoIDsCol),
+		EnvironmentName:  t.value(row, variableEnvironmentCol),
+	}
+}
+
+// ParseEnvironmentRecords reads environments from CSV records, locating
+// each column by its header. Every row is validated, and should any be
+// invalid the errors of all of them are returned together, each naming its
+// row and column, along with the valid rows.
+func ParseEnvironmentRecords(records [][]string) ([]data.ImportedEnvironment, error) {
+	table, err := newCSVTable(records, environmentSchema)
+	if err != nil {
+		return nil, err
+	}
+	environments := make([]data.ImportedEnvironment, 0, len(table.rows))
+	var errs []error
+	for i, row := range table.rows {
+		environment, rowErr := table.environment(row, table.numbers[i])
+		if rowErr != nil {
+			errs = append(errs, rowErr)
+			continue
+		}
+		environments = append(environments, environment)
+	}
+	return environments, errors.Join(errs...)
+}
+
+func (t *csvTable) environment(row []string, number int) (data.ImportedEnvironment, *RowError) {
+	environment := data.ImportedEnvironment{
+		RepositoryName: t.value(row, environmentRepoCol),
+		Name:           t.value(row, environmentNameCol),
+		Reviewers:      nonEmpty(t.list(row, environmentReviewersCol)),
+		BranchPolicies: nonEmpty(t.list(row, environmentBranchPoliciesCol)),
+		Row:            number,
+	}
+	if environment.RepositoryName == "" {
+		return environment, t.rowError(number, environmentRepoCol, errors.New("a repository name is required"))
+	}
+	if environment.Name == "" {
+		return environment, t.rowError(number, environmentNameCol, errors.New("an environment name is required"))
+	}
+
+	if value := t.value(row, environmentWaitTimerCol); value != "" {
+		waitTimer, err := strconv.Atoi(value)
+		if err != nil || waitTimer < 0 || waitTimer > maxWaitTimer {
+			return environment, t.rowError(number, environmentWaitTimerCol, fmt.Errorf("invalid wait timer %q, expected a number of minutes from 0 to %d", value, maxWaitTimer))
+		}
+		environment.WaitTimer = waitTimer
+	}
+	var err error
+	if environment.PreventSelfReview, err = parseBoolField(t.value(row, environmentPreventSelfReviewCol)); err != nil {
+		return environment, t.rowError(number, environmentPreventSelfReviewCol, err)
+	}
+	if environment.CanAdminsBypass, err = parseBoolField(t.value(row, environmentAdminsBypassCol)); err != nil {
+		return environment, t.rowError(number, environmentAdminsBypassCol, err)
+	}
+	for i, reviewer := range environment.Reviewers {
+		reviewerType, identifier := ParseEnvironmentReviewer(reviewer)
+		reviewerType, ok := canonical(reviewerType, reviewerTypes)
+		if !ok || identifier == "" {
+			return environment, t.rowError(number, environmentReviewersCol, fmt.Errorf("invalid reviewer %q, expected User:login or Team:slug", reviewer))
+		}
+		environment.Reviewers[i] = reviewerType + ":" + identifier
+	}
+
+	environment.DeploymentBranchPolicy = "all"
+	if value := t.value(row, environmentBranchPolicyCol); value != "" {
+		policy, ok := canonical(value, branchPolicyTypes)
+		if !ok {
+			return environment, t.rowError(number, environmentBranchPolicyCol, fmt.Errorf("unknown deployment branch policy %q, expected all, protected or custom", value))
+		}
+		environment.DeploymentBranchPolicy = policy
+	}
+	return environment, nil
+}
+
+// parseBoolField reads a true or false field, where a blank field is false.
+func parseBoolField(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q, expected true or false", value)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSecretRecordsReorderedColumns(t *testing.T) {
+	records := [][]string{
+		{"Notes", "SecretName", "EnvironmentName", "SecretLevel", "SecretValue", "SecretType", "RepositoryNames", "SecretAccess"},
+		{"rotated in May", "DB_PASSWORD", "production", "environment", " keep spaces ", "actions", "api", "EnvironmentOnly"},
+		{"", "NPM_TOKEN", "", "Organization", "npm", "Dependabot", "api; web", "Selected"},
+		{"", "", "", "", "", "", "", ""},
+		{"", "SHORT", "", "Repository", "value", "Codespaces", "docs"},
+	}
+
+	secrets, err := ParseSecretRecords(records)
+	if err != nil {
+		t.Fatalf("ParseSecretRecords() error = %v", err)
+	}
+	if len(secrets) != 3 {
+		t.Fatalf("Expected 3 secrets, blank rows left out, got %+v", secrets)
+	}
+
+	env := secrets[0]
+	if env.Level != "Environment" || env.Type != "Actions" || env.Name != "DB_PASSWORD" || env.EnvironmentName != "production" || env.RepositoryNames[0] != "api" {
+		t.Errorf("Unexpected environment secret %+v", env)
+	}
+	if env.Value != " keep spaces " {
+		t.Errorf("Expected the value to be kept as written, got %q", env.Value)
+	}
+	org := secrets[1]
+	if org.Access != "selected" || strings.Join(org.RepositoryNames, ",") != "api,web" {
+		t.Errorf("Unexpected organization secret %+v", org)
+	}
+	if len(org.RepositoryIDs) != 1 || org.RepositoryIDs[0] != "" {
+		t.Errorf("Expected no repository IDs without the column, got %v", org.RepositoryIDs)
+	}
+	if secrets[2].Name != "SHORT" || secrets[2].EnvironmentName != "" {
+		t.Errorf("Expected a short row to be read, got %+v", secrets[2])
+	}
+}
+
+func TestParseRecordsBlankRowNumbers(t *testing.T) {
+	records := [][]string{
+		{"SecretLevel", "SecretType", "SecretName", "SecretValue", "SecretAccess", "RepositoryNames", "RepositoryIDs", "EnvironmentName"},
+		{"Organization", "Actions", "FIRST", "value", "all", "", "", ""},
+		{"", "", "", "", "", "", "", ""},
+		{},
+		{"Organization", "Actions", "SECOND", "value", "all", "", "", ""},
+		{"Organization", "Actions", "BAD_ACCESS", "value", "scoped", "", "", ""},
+	}
+	secrets, err := ParseSecretRecords(records)
+	if err == nil || !strings.HasPrefix(err.Error(), "row 5, column SecretAccess (E)") {
+		t.Errorf("Expected the error to name row 5, got %v", err)
+	}
+	if len(secrets) != 2 || secrets[0].Row != 1 || secrets[1].Row != 4 {
+		t.Errorf("Expected the secrets to keep rows 1 and 4, got %+v", secrets)
+	}
+
+	variables, err := ParseVariableRecords([][]string{
+		{"VariableLevel", "VariableName", "VariableValue", "VariableAccess"},
+		{},
+		{"Organization", "REGION", "eu", "all"},
+	})
+	if err != nil || len(variables) != 1 || variables[0].Row != 2 {
+		t.Errorf("Expected the variable to keep row 2, got %+v, %v", variables, err)
+	}
+}
+
+func TestParseSecretRecordsValidation(t *testing.T) {
+	records := [][]string{
+		{"SecretLevel", "SecretType", "SecretName", "SecretValue", "SecretAccess", "RepositoryNames", "RepositoryIDs", "EnvironmentName"},
+		{"Organization", "Actions", "VALID", "value", "all", "", "", ""},
+		{"Org", "Actions", "BAD_LEVEL", "value", "all", "", "", ""},
+		{"Organization", "Packages", "BAD_TYPE", "value", "all", "", "", ""},
+		{"Organization", "Actions", "BAD_ACCESS", "value", "scoped", "", "", ""},
+		{"Organization", "Actions", "NO_REPOS", "value", "selected", "", "", ""},
+		{"Organization", "Actions", "IDS_ONLY", "value", "selected", "", "1;2", ""},
+		{"Repository", "Actions", "NO_REPO", "value", "RepoOnly", "", "", ""},
+		{"Environment", "Dependabot", "ENV_TYPE", "value", "EnvironmentOnly", "api", "", "prod"},
+		{"Environment", "Actions", "NO_ENV", "value", "EnvironmentOnly", "api", "", ""},
+		{"Repository", "Actions"},
+	}
+
+	secrets, err := ParseSecretRecords(records)
+	if len(secrets) != 2 || secrets[0].Name != "VALID" || secrets[1].Name != "IDS_ONLY" {
+		t.Errorf("Expected only the valid secrets to be returned, got %+v", secrets)
+	}
+	expected := []string{
+		`row 2, column SecretLevel (A): unknown secret level "Org"`,
+		`row 3, column SecretType (B): unknown secret type "Packages"`,
+		`row 4, column SecretAccess (E): unknown visibility "scoped"`,
+		`row 5, column RepositoryNames (F): selected visibility requires at least one repository`,
+		`row 7, column RepositoryNames (F): a repository name is required for a Repository level secret`,
+		`row 8, column SecretType (B): environment secrets are only supported for Actions`,
+		`row 9, column EnvironmentName (H): an environment name is required`,
+		`row 10, column SecretName (C): a secret name is required`,
+	}
+	if err == nil {
+		t.Fatal("Expected validation errors, got nil")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d errors, got:\n%v", len(expected), err)
+	}
+	for i, want := range expected {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Errorf("Error %d: expected %q, got %q", i, want, lines[i])
+		}
+	}
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) || rowErr.Row != 2 {
+		t.Errorf("Expected a RowError for row 2, got %v", err)
+	}
+}
+
+func TestParseSecretRecordsHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		records [][]string
+		wantErr string
+	}{
+		{"empty", nil, "a header row is required"},
+		{"missing required", [][]string{{"SecretLevel", "SecretName", "SecretValue"}}, "missing the required columns: SecretType"},
+		{"duplicate", [][]string{{"SecretLevel", "SecretType", "SecretName", "Level"}}, "column SecretLevel appears more than once"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSecretRecords(tt.records)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseSecretRecordsAliases(t *testing.T) {
+	records := [][]string{
+		{"level", "app", "Secret Name", "value", "visibility", "repository-names", "repository_ids", "environment"},
+		{"Organization", "Codespaces", "TOKEN", "value", "private", "", "", ""},
+	}
+	secrets, err := ParseSecretRecords(records)
+	if err != nil {
+		t.Fatalf("ParseSecretRecords() error = %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].Type != "Codespaces" || secrets[0].Access != "private" {
+		t.Errorf("Unexpected secrets %+v", secrets)
+	}
+}
+
+func TestParseSecretRecordsPositional(t *testing.T) {
+	records := [][]string{
+		{"Level", "Kind", "Key"},
+		{"Organization", "Actions", "TOKEN", "value", "all", "", "", ""},
+	}
+	if _, err := ParseSecretRecords(records); err == nil {
+		t.Error("Expected a partly recognized header to require its missing columns")
+	}
+
+	records[0] = []string{"Scope", "Kind", "Key", "Secret", "Who", "Repos?", "IDs?", "Env?"}
+	secrets, err := ParseSecretRecords(records)
+	if err != nil {
+		t.Fatalf("ParseSecretRecords() error = %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].Name != "TOKEN" || secrets[0].Value != "value" {
+		t.Errorf("Expected columns in the order written by export, got %+v", secrets)
+	}
+}
+
+func TestParseVariableRecords(t *testing.T) {
+	records := [][]string{
+		{"VariableName", "VariableLevel", "VariableValue", "RepositoryNames", "VariableAccess", "Owner"},
+		{"REGION", "organization", "eu", "", "ALL", "platform-team"},
+		{"THEME", "Repository", "dark", "docs"},
+		{"", "Organization", "value", "", "all"},
+		{"LEVEL", "Environment", "debug", "api", "EnvironmentOnly"},
+		{"SCOPED", "Organization", "value", "", "selected"},
+	}
+
+	variables, err := ParseVariableRecords(records)
+	if len(variables) != 2 {
+		t.Fatalf("Expected 2 valid variables, got %+v", variables)
+	}
+	if variables[0].Level != "Organization" || variables[0].Visibility != "all" || variables[0].Value != "eu" {
+		t.Errorf("Unexpected organization variable %+v", variables[0])
+	}
+	if variables[1].Name != "THEME" || variables[1].SelectedRepos[0] != "docs" {
+		t.Errorf("Unexpected repository variable %+v", variables[1])
+	}
+
+	expected := []string{
+		"row 3, column VariableName (A): a variable name is required",
+		"row 4, column EnvironmentName: an environment name is required for an Environment level variable",
+		"row 5, column RepositoryNames (D): selected visibility requires at least one repository",
+	}
+	if err == nil {
+		t.Fatal("Expected validation errors, got nil")
+	}
+	if err.Error() != strings.Join(expected, "\n") {
+		t.Errorf("Expected errors:\n%s\ngot:\n%v", strings.Join(expected, "\n"), err)
+	}
+}
+
+func TestColumnLetter(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 7: "H", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnLetter(index); got != want {
+			t.Errorf("columnLetter(%d) = %q, want %q", index, got, want)
+		}
+	}
+}
+
+func TestParseEnvironmentRecords(t *testing.T) {
+	records := [][]string{
+		{"RepositoryName", "EnvironmentName", "WaitTimer", "PreventSelfReview", "Reviewers", "DeploymentBranchPolicy", "BranchPolicies", "CanAdminsBypass"},
+		{"repo1", "production", "30", "true", "User:octocat;team:platform", "Custom", "branch:main;tag:v*", "false"},
+		{"", "", "", "", "", "", "", ""},
+		{"repo1", "staging", "", "", "", "", "", ""},
+	}
+	environments, err := ParseEnvironmentRecords(records)
+	if err != nil {
+		t.Fatalf("ParseEnvironmentRecords() error = %v", err)
+	}
+	if len(environments) != 2 {
+		t.Fatalf("Expected 2 environments, got %d", len(environments))
+	}
+
+	production := environments[0]
+	if production.WaitTimer != 30 || !production.PreventSelfReview || production.CanAdminsBypass {
+		t.Errorf("Unexpected production settings: %+v", production)
+	}
+	if strings.Join(production.Reviewers, ",") != "User:octocat,Team:platform" {
+		t.Errorf("Expected two reviewers, got %v", production.Reviewers)
+	}
+	if production.DeploymentBranchPolicy != "custom" || len(production.BranchPolicies) != 2 {
+		t.Errorf("Expected custom branch policies, got %s %v", production.DeploymentBranchPolicy, production.BranchPolicies)
+	}
+
+	staging := environments[1]
+	if staging.Row != 3 {
+		t.Errorf("Expected staging to keep row 3 past the blank row, got %d", staging.Row)
+	}
+	if staging.DeploymentBranchPolicy != "all" || staging.Reviewers != nil {
+		t.Errorf("Expected default settings for staging, got %+v", staging)
+	}
+}
+
+func TestParseEnvironmentRecordsInvalid(t *testing.T) {
+	records := [][]string{
+		{"RepositoryName", "EnvironmentName", "WaitTimer", "PreventSelfReview", "Reviewers", "DeploymentBranchPolicy", "BranchPolicies", "CanAdminsBypass"},
+		{"", "missing-repo", "", "", "", "", "", ""},
+		{"repo1", "timer", "soon", "", "", "", "", ""},
+		{"repo1", "long-timer", "50000", "", "", "", "", ""},
+		{"repo1", "self-review", "", "ture", "", "", "", ""},
+		{"repo1", "reviewer", "", "", "Group:admins", "", "", ""},
+		{"repo1", "policy", "", "", "", "tags", "", ""},
+		{"repo1", "bypass", "", "", "", "", "", "yes please"},
+		{"repo1", "valid", "5", "false", "", "protected", "", "true"},
+	}
+	environments, err := ParseEnvironmentRecords(records)
+	if len(environments) != 1 || environments[0].Name != "valid" {
+		t.Errorf("Expected only the valid row to be returned, got %+v", environments)
+	}
+	want := []string{
+		`row 1, column RepositoryName (A): a repository name is required`,
+		`row 2, column WaitTimer (C): invalid wait timer "soon", expected a number of minutes from 0 to 43200`,
+		`row 3, column WaitTimer (C): invalid wait timer "50000"`,
+		`row 4, column PreventSelfReview (D): invalid value "ture", expected true or false`,
+		`row 5, column Reviewers (E): invalid reviewer "Group:admins", expected User:login or Team:slug`,
+		`row 6, column DeploymentBranchPolicy (F): unknown deployment branch policy "tags"`,
+		`row 7, column CanAdminsBypass (H): invalid value "yes please", expected true or false`,
+	}
+	if err == nil {
+		t.Fatal("Expected row errors")
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("Expected error to contain %q, got:\n%v", w, err)
+		}
+	}
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) {
+		t.Errorf("Expected RowErrors, got %T", err)
+	}
+}
@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
+)
+
+// SealSecrets encrypts the value of each secret with the public key of its
+// level and type in owner, so it can be written later without the value.
+// The returned errors line up with secrets.
+func (g *APIGetter) SealSecrets(owner string, secrets []data.ImportedSecret, concurrency int) ([]data.SealedSecret, []error) {
+	sealed := make([]data.SealedSecret, len(secrets))
+	errs := make([]error, len(secrets))
+	RunConcurrently(concurrency, len(secrets), func(index int) {
+		secret := secrets[index]
+		if err := ValidateImportedSecret(secret); err != nil {
+			errs[index] = err
+			return
+		}
+		zap.S().Debugf("Sealing %s level %s secret %s", secret.Level, secret.Type, secret.Name)
+		publicKey, err := g.SecretPublicKey(owner, secret)
+		if err != nil {
+			errs[index] = fmt.Errorf("failed to get the public key for %s: %w", secretTarget(owner, secret), err)
+			return
+		}
+		encryptedSecret, err := g.encryptSecretValue(publicKey, secret)
+		if err != nil {
+			errs[index] = err
+			return
+		}
+		sealed[index] = data.SealedSecret{
+			Level:           secret.Level,
+			Type:            secret.Type,
+			Name:            secret.Name,
+			Access:          secret.Access,
+			RepositoryNames: nonEmpty(secret.RepositoryNames),
+			RepositoryIDs:   nonEmpty(secret.RepositoryIDs),
+			EnvironmentName: secret.EnvironmentName,
+			KeyID:           publicKey.KeyID,
+			EncryptedValue:  encryptedSecret,
+		}
+	})
+	return sealed, errs
+}
+
+// WriteSealedBundle writes a bundle of sealed secrets as indented JSON,
+// readable only by the user as it names the secrets of the organization.
+func WriteSealedBundle(fileName string, bundle *data.SealedBundle) error {
+	content, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, append(content, '\n'), 0600)
+}
+
+// ReadSealedBundle reads a bundle written by WriteSealedBundle, checking
+// each secret has a scope, a key ID and an encrypted value.
+func ReadSealedBundle(fileName string) (*data.SealedBundle, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var bundle data.SealedBundle
+	if err := json.Unmarshal(content, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to read sealed bundle %s: %w", fileName, err)
+	}
+
+	var errs []error
+	for i, sealed := range bundle.Secrets {
+		if err := validateSealedSecret(sealed); err != nil {
+			errs = append(errs, fmt.Errorf("secret %d, %s: %w", i+1, sealed.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid sealed bundle %s:\n%w", fileName, errors.Join(errs...))
+	}
+	return &bundle, nil
+}
+
+func validateSealedSecret(sealed data.SealedSecret) error {
+	secret := sealedSecretScope(sealed)
+	if err := ValidateSecretScope(secret); err != nil {
+		return err
+	}
+	if secret.Level == "Organization" {
+		if err := validateOrgVisibility(secret.Access, secret.RepositoryNames, secret.RepositoryIDs); err != nil {
+			return err
+		}
+	}
+	if sealed.KeyID == "" || sealed.EncryptedValue == "" {
+		return errors.New("a key_id and encrypted_value are required")
+	}
+	return nil
+}
+
+// sealedSecretScope returns a sealed secret as a secret without a value,
+// for the functions shared with create.
+func sealedSecretScope(sealed data.SealedSecret) data.ImportedSecret {
+	return data.ImportedSecret{
+		Level:           sealed.Level,
+		Type:            sealed.Type,
+		Name:            sealed.Name,
+		Access:          sealed.Access,
+		RepositoryNames: sealed.RepositoryNames,
+		RepositoryIDs:   sealed.RepositoryIDs,
+		EnvironmentName: sealed.EnvironmentName,
+	}
+}
+
+// CheckSealedKeys compares the key each secret was sealed with to the current
+// public key of its level and type in owner. A secret sealed with another key
+// can't be decrypted by GitHub, so the error names each rotated key and the
+// secrets sealed with it. The keys are read past the cache, as a key cached
+// by this getter may have been rotated since.
+func (g *APIGetter) CheckSealedKeys(owner string, secrets []data.SealedSecret) error {
+	var scopes []string
+	currentKeys := map[string]string{}
+	stale := map[string][]string{}
+	sealedKeys := map[string]string{}
+	for _, sealed := range secrets {
+		secret := sealedSecretScope(sealed)
+		scope := fmt.Sprintf("%s %s secrets of %s", secret.Level, secret.Type, secretTarget(owner, secret))
+		currentKey, ok := currentKeys[scope]
+		if !ok {
+			zap.S().Debugf("Checking the public key of %s", scope)
+			publicKey, err := g.readSecretPublicKey(owner, secret)
+			if err != nil {
+				return fmt.Errorf("failed to get the public key of %s: %w", scope, err)
+			}
+			currentKey = publicKey.KeyID
+			currentKeys[scope] = currentKey
+		}
+		if sealed.KeyID == currentKey {
+			continue
+		}
+		if _, ok := stale[scope]; !ok {
+			scopes = append(scopes, scope)
+		}
+		stale[scope] = append(stale[scope], sealed.Name)
+		sealedKeys[scope] = sealed.KeyID
+	}
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		lines = append(lines, fmt.Sprintf("%s: sealed with key %s, the current key is %s, for %s",
+			scope, sealedKeys[scope], currentKeys[scope], strings.Join(stale[scope], ", ")))
+	}
+	return fmt.Errorf("public keys were rotated after the bundle was sealed, so nothing was applied:\n%s\nseal the secrets again with `gh seva secrets seal`",
+		strings.Join(lines, "\n"))
+}
+
+// ApplySealedSecrets writes each sealed secret to owner, scoping selected
+// organization secrets to the IDs of their repositories in owner, and
+// returns a result for each numbered by its place in the bundle.
+func (g *APIGetter) ApplySealedSecrets(owner string, secrets []data.SealedSecret, concurrency int) []data.RowResult {
+	scoped := make([]data.ImportedSecret, len(secrets))
+	for i, sealed := range secrets {
+		scoped[i] = sealedSecretScope(sealed)
+	}
+	zap.S().Debugf("Resolving selected repositories in %s", owner)
+	resolveErrors := g.ResolveSecretRepos(owner, scoped)
+
+	results := make([]data.RowResult, len(secrets))
+	RunConcurrently(concurrency, len(secrets), func(index int) {
+		secret := scoped[index]
+		err := resolveErrors[index]
+		if err == nil {
+			err = g.putEncryptedSecret(owner, secret, secrets[index].KeyID, secrets[index].EncryptedValue)
+		}
+		if err != nil {
+			zap.S().Errorf("Error arose applying sealed secret %s: %v", secret.Name, err)
+		}
+		results[index] = NewRowResult(index+1, secret.Level, secret.Type, secret.Name, secretTarget(owner, secret), err)
+	})
+	return results
+}
+
+func secretTarget(owner string, secret data.ImportedSecret) string {
+	return Target(owner, secret.Level, firstName(secret.RepositoryNames), secret.EnvironmentName)
+}
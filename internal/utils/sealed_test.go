@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/katiem0/gh-seva/internal/data"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// sealTestServer serves a public key for the organization and for each
+// repository, keyed by path, and records the body of each secret written.
+type sealTestServer struct {
+	keyIDs  map[string]string
+	public  [32]byte
+	private [32]byte
+
+	mu     sync.Mutex
+	writes map[string]map[string]interface{}
+}
+
+func newSealTestServer(t *testing.T) *sealTestServer {
+	t.Helper()
+	public, private, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return &sealTestServer{
+		keyIDs: map[string]string{
+			"orgs/test-org/actions/secrets/public-key":         "org-key-1",
+			"repos/test-org/api/actions/secrets/public-key":    "api-key-1",
+			"repos/test-org/web/dependabot/secrets/public-key": "web-key-1",
+		},
+		public:  *public,
+		private: *private,
+		writes:  map[string]map[string]interface{}{},
+	}
+}
+
+func (s *sealTestServer) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if r.Method == http.MethodGet {
+			keyID, ok := s.keyIDs[path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"key_id":"` + keyID + `","key":"` + base64.StdEncoding.EncodeToString(s.public[:]) + `"}`))
+			return
+		}
+		if r.Method != http.MethodPut {
+			t.Errorf("Unexpected %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]interface{}
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &body)
+		s.mu.Lock()
+		s.writes[path] = body
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (s *sealTestServer) decrypt(t *testing.T, encryptedValue string) string {
+	t.Helper()
+	encrypted, err := base64.StdEncoding.DecodeString(encryptedValue)
+	if err != nil {
+		t.Fatalf("Failed to decode encrypted value: %v", err)
+	}
+	decrypted, ok := box.OpenAnonymous(nil, encrypted, &s.public, &s.private)
+	if !ok {
+		t.Fatalf("Failed to decrypt %q", encryptedValue)
+	}
+	return string(decrypted)
+}
+
+func sealTestSecrets() []data.ImportedSecret {
+	return []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "ORG_TOKEN", Value: "org-value", Access: "private"},
+		{Level: "Repository", Type: "Actions", Name: "API_TOKEN", Value: "api-value", RepositoryNames: []string{"api"}},
+		{Level: "Repository", Type: "Dependabot", Name: "WEB_TOKEN", Value: "web-value", RepositoryNames: []string{"web"}},
+	}
+}
+
+func TestSealSecretsRoundTrip(t *testing.T) {
+	server := newSealTestServer(t)
+	g := newFakeAPIGetter(t, server.handler(t))
+
+	sealed, errs := g.SealSecrets("test-org", sealTestSecrets(), 2)
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("SealSecrets() secret %d error = %v", i, err)
+		}
+	}
+	if len(server.writes) != 0 {
+		t.Fatalf("Expected sealing to write nothing, got %v", server.writes)
+	}
+	wantKeys := []string{"org-key-1", "api-key-1", "web-key-1"}
+	for i, secret := range sealed {
+		if secret.KeyID != wantKeys[i] {
+			t.Errorf("Expected %s to be sealed with %s, got %s", secret.Name, wantKeys[i], secret.KeyID)
+		}
+		if strings.Contains(secret.EncryptedValue, "value") {
+			t.Errorf("Expected %s to hold only ciphertext, got %q", secret.Name, secret.EncryptedValue)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "sealed.json")
+	bundle := &data.SealedBundle{Organization: "test-org", Hostname: "github.com", SealedAt: time.Now().UTC(), Secrets: sealed}
+	if err := WriteSealedBundle(file, bundle); err != nil {
+		t.Fatalf("WriteSealedBundle() error = %v", err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("Failed to stat bundle: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the bundle to be readable only by the user, got %v", info.Mode().Perm())
+	}
+	content, _ := os.ReadFile(file)
+	if strings.Contains(string(content), "org-value") || strings.Contains(string(content), "\"value\"") {
+		t.Errorf("Expected the bundle to hold no plaintext, got %s", content)
+	}
+	read, err := ReadSealedBundle(file)
+	if err != nil {
+		t.Fatalf("ReadSealedBundle() error = %v", err)
+	}
+
+	if err := g.CheckSealedKeys("test-org", read.Secrets); err != nil {
+		t.Fatalf("CheckSealedKeys() error = %v", err)
+	}
+	results := g.ApplySealedSecrets("test-org", read.Secrets, 2)
+	if failed := CountFailedResults(results); failed != 0 {
+		t.Fatalf("Expected every secret to be applied, got %+v", results)
+	}
+	wantWrites := map[string]string{
+		"orgs/test-org/actions/secrets/ORG_TOKEN":         "org-value",
+		"repos/test-org/api/actions/secrets/API_TOKEN":    "api-value",
+		"repos/test-org/web/dependabot/secrets/WEB_TOKEN": "web-value",
+	}
+	for path, value := range wantWrites {
+		body, ok := server.writes[path]
+		if !ok {
+			t.Errorf("Expected a write to %s, got %v", path, server.writes)
+			continue
+		}
+		if got := server.decrypt(t, body["encrypted_value"].(string)); got != value {
+			t.Errorf("Expected %s to decrypt to %q, got %q", path, value, got)
+		}
+	}
+	if server.writes["orgs/test-org/actions/secrets/ORG_TOKEN"]["visibility"] != "private" {
+		t.Errorf("Expected the organization secret to keep its visibility, got %v", server.writes["orgs/test-org/actions/secrets/ORG_TOKEN"])
+	}
+}
+
+func TestSealSecretsRepositoryIDsRoundTrip(t *testing.T) {
+	server := newSealTestServer(t)
+	g := newFakeAPIGetter(t, server.handler(t))
+
+	secrets := []data.ImportedSecret{
+		{Level: "Organization", Type: "Actions", Name: "ORG_TOKEN", Value: "org-value", Access: "selected", RepositoryNames: []string{""}, RepositoryIDs: []string{"12", "34"}},
+	}
+	sealed, errs := g.SealSecrets("test-org", secrets, 1)
+	if errs[0] != nil {
+		t.Fatalf("SealSecrets() error = %v", errs[0])
+	}
+
+	file := filepath.Join(t.TempDir(), "sealed.json")
+	if err := WriteSealedBundle(file, &data.SealedBundle{Organization: "test-org", Secrets: sealed}); err != nil {
+		t.Fatalf("WriteSealedBundle() error = %v", err)
+	}
+	read, err := ReadSealedBundle(file)
+	if err != nil {
+		t.Fatalf("ReadSealedBundle() error = %v", err)
+	}
+	if got := strings.Join(read.Secrets[0].RepositoryIDs, ","); got != "12,34" {
+		t.Errorf("Expected the repository IDs to be kept, got %q", got)
+	}
+	if len(read.Secrets[0].RepositoryNames) != 0 {
+		t.Errorf("Expected no blank repository names, got %v", read.Secrets[0].RepositoryNames)
+	}
+}
+
+func TestSealSecretsErrors(t *testing.T) {
+	server := newSealTestServer(t)
+	g := newFakeAPIGetter(t, server.handler(t))
+	secrets := []data.ImportedSecret{
+		{Level: "Repository", Type: "Actions", Name: "MISSING", Value: "value", RepositoryNames: []string{"missing"}},
+		{Level: "Repository", Type: "Actions", Name: "UNSET", Value: "env:SEVA_TEST_UNSET", RepositoryNames: []string{"api"}},
+		{Level: "Repository", Type: "Actions", Name: "OK", Value: "value", RepositoryNames: []string{"api"}},
+	}
+
+	_, errs := g.SealSecrets("test-org", secrets, 1)
+	if errs[0] == nil || !strings.HasPrefix(errs[0].Error(), "failed to get the public key for test-org/missing:") {
+		t.Errorf("Expected a public key error, got %v", errs[0])
+	}
+	if errs[1] == nil || errs[1].Error() != "failed to resolve the value of secret UNSET: environment variable SEVA_TEST_UNSET is not set" {
+		t.Errorf("Expected a resolve error, got %v", errs[1])
+	}
+	if errs[2] != nil {
+		t.Errorf("Expected OK to be sealed, got %v", errs[2])
+	}
+}
+
+func TestCheckSealedKeysRotated(t *testing.T) {
+	server := newSealTestServer(t)
+	g := newFakeAPIGetter(t, server.handler(t))
+	sealed, errs := g.SealSecrets("test-org", sealTestSecrets(), 1)
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("SealSecrets() error = %v", err)
+		}
+	}
+	sealed = append(sealed, data.SealedSecret{
+		Level: "Repository", Type: "Actions", Name: "OTHER_TOKEN", RepositoryNames: []string{"api"},
+		KeyID: "api-key-1", EncryptedValue: sealed[1].EncryptedValue,
+	})
+
+	server.keyIDs["repos/test-org/api/actions/secrets/public-key"] = "api-key-2"
+	err := g.CheckSealedKeys("test-org", sealed)
+	want := "public keys were rotated after the bundle was sealed, so nothing was applied:\n" +
+		"Repository Actions secrets of test-org/api: sealed with key api-key-1, the current key is api-key-2, for API_TOKEN, OTHER_TOKEN\n" +
+		"seal the secrets again with `gh seva secrets seal`"
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected error:\n%s\ngot:\n%v", want, err)
+	}
+	if len(server.writes) != 0 {
+		t.Errorf("Expected nothing to be written, got %v", server.writes)
+	}
+}
+
+func TestReadSealedBundleInvalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "not JSON",
+			content: "secrets: []",
+			wantErr: "failed to read sealed bundle",
+		},
+		{
+			name:    "missing key",
+			content: `{"secrets":[{"level":"Repository","type":"Actions","name":"TOKEN","selected_repositories":["api"],"encrypted_value":"abc"}]}`,
+			wantErr: "secret 1, TOKEN: a key_id and encrypted_value are required",
+		},
+		{
+			name:    "missing repository",
+			content: `{"secrets":[{"level":"Repository","type":"Actions","name":"TOKEN","key_id":"1","encrypted_value":"abc"}]}`,
+			wantErr: "secret 1, TOKEN:",
+		},
+		{
+			name:    "invalid visibility",
+			content: `{"secrets":[{"level":"Organization","type":"Actions","name":"TOKEN","visibility":"everyone","key_id":"1","encrypted_value":"abc"}]}`,
+			wantErr: "secret 1, TOKEN:",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(dir, "bundle"+jsonInt(i)+".json")
+			if err := os.WriteFile(file, []byte(tc.content), 0600); err != nil {
+				t.Fatalf("Failed to write bundle: %v", err)
+			}
+			_, err := ReadSealedBundle(file)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -3,117 +3,138 @@ package utils
 import (
 	"fmt"
 	"io"
-	"log"
 
 	"go.uber.org/zap"
 )
 
-func (g *APIGetter) GetOrgActionSecrets(owner string) ([]byte, error) {
+func (g *GitHubGetter) GetOrgActionSecrets(owner string) ([]byte, error) {
 	url := fmt.Sprintf("orgs/%s/actions/secrets", owner)
 
-	resp, err := g.restClient.Request("GET", url, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	return getAllPages(&g.restClient, url, "secrets", maxPerPage)
 }
 
-func (g *APIGetter) GetRepoActionSecrets(owner string, repo string) ([]byte, error) {
+func (g *GitHubGetter) GetRepoActionSecrets(owner string, repo string) ([]byte, error) {
 	url := fmt.Sprintf("repos/%s/%s/actions/secrets", owner, repo)
 
-	resp, err := g.restClient.Request("GET", url, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	return getAllPages(&g.restClient, url, "secrets", maxPerPage)
 }
 
-func (g *APIGetter) GetScopedOrgActionSecrets(owner string, secret string) ([]byte, error) {
+func (g *GitHubGetter) GetScopedOrgActionSecrets(owner string, secret string) ([]byte, error) {
 	url := fmt.Sprintf("orgs/%s/actions/secrets/%s/repositories", owner, secret)
 
-	resp, err := g.restClient.Request("GET", url, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	return getAllPages(&g.restClient, url, "repositories", maxPerPage)
 }
 
-func (g *APIGetter) GetOrgActionPublicKey(owner string) ([]byte, error) {
+func (g *GitHubGetter) GetOrgActionPublicKey(owner string) ([]byte, error) {
 	url := fmt.Sprintf("orgs/%s/actions/secrets/public-key", owner)
 	zap.S().Debugf("Getting public-key for %v", url)
-	resp, err := g.restClient.Request("GET", url, nil)
+	resp, err := doRequest(&g.restClient, "GET", url, nil)
 	if err != nil {
-		log.Printf("Body read error, %v", err)
+		return nil, fmt.Errorf("failed to get public key from %s: %w", url, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Body read error, %v", err)
-	}
-	return responseData, err
+	return io.ReadAll(resp.Body)
 }
 
-func (g *APIGetter) GetRepoActionPublicKey(owner string, repo string) ([]byte, error) {
+func (g *GitHubGetter) GetRepoActionPublicKey(owner string, repo string) ([]byte, error) {
 	url := fmt.Sprintf("repos/%s/%s/actions/secrets/public-key", owner, repo)
 	zap.S().Debugf("Getting public-key for %v", url)
-	resp, err := g.restClient.Request("GET", url, nil)
+	resp, err := doRequest(&g.restClient, "GET", url, nil)
 	if err != nil {
-		log.Printf("Body read error, %v", err)
+		return nil, fmt.Errorf("failed to get public key from %s: %w", url, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Body read error, %v", err)
-	}
-	return responseData, err
+	return io.ReadAll(resp.Body)
 }
 
-func (g *APIGetter) CreateOrgActionSecret(owner string, secret string, data io.Reader) error {
+func (g *GitHubGetter) CreateOrgActionSecret(owner string, secret string, data io.Reader) error {
 	url := fmt.Sprintf("orgs/%s/actions/secrets/%s", owner, secret)
 
-	resp, err := g.restClient.Request("PUT", url, data)
+	resp, err := doRequest(&g.restClient, "PUT", url, data)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	return err
+	return nil
 }
 
-func (g *APIGetter) CreateRepoActionSecret(owner string, repo string, secret string, data io.Reader) error {
+func (g *GitHubGetter) CreateRepoActionSecret(owner string, repo string, secret string, data io.Reader) error {
 	url := fmt.Sprintf("repos/%s/%s/actions/secrets/%s", owner, repo, secret)
 
-	resp, err := g.restClient.Request("PUT", url, data)
+	resp, err := doRequest(&g.restClient, "PUT", url, data)
+	if err != nil {
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (g *GitHubGetter) GetEnvironmentActionSecrets(owner string, repo string, environment string) ([]byte, error) {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/secrets", owner, repo, escapeEnvironment(environment))
+
+	return getAllPages(&g.restClient, url, "secrets", maxPerPage)
+}
+
+func (g *GitHubGetter) GetEnvironmentActionPublicKey(owner string, repo string, environment string) ([]byte, error) {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/secrets/public-key", owner, repo, escapeEnvironment(environment))
+	zap.S().Debugf("Getting public-key for %v", url)
+	resp, err := doRequest(&g.restClient, "GET", url, nil)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to get public key for environment %s: %w", environment, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	return err
+	return io.ReadAll(resp.Body)
+}
+
+func (g *GitHubGetter) CreateEnvironmentActionSecret(owner string, repo string, environment string, secret string, data io.Reader) error {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/secrets/%s", owner, repo, escapeEnvironment(environment), secret)
+
+	resp, err := doRequest(&g.restClient, "PUT", url, data)
+	if err != nil {
+		return fmt.Errorf("failed to create environment secret %s: %w", secret, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (g *GitHubGetter) DeleteOrgActionSecret(owner string, secret string) error {
+	url := fmt.Sprintf("orgs/%s/actions/secrets/%s", owner, secret)
+
+	return g.deleteResource(url)
+}
+
+func (g *GitHubGetter) DeleteRepoActionSecret(owner string, repo string, secret string) error {
+	url := fmt.Sprintf("repos/%s/%s/actions/secrets/%s", owner, repo, secret)
+
+	return g.deleteResource(url)
+}
+
+func (g *GitHubGetter) DeleteEnvironmentActionSecret(owner string, repo string, environment string, secret string) error {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/secrets/%s", owner, repo, escapeEnvironment(environment), secret)
+
+	return g.deleteResource(url)
 }
@@ -352,11 +352,11 @@ func TestCreateOrgActionSecretScenarios(t *testing.T) {
 	zap.ReplaceGlobals(logger)
 
 	testCases := []struct {
-		name        string
-		secretName  string
-		secretData  string
-		statusCode  int
-		shouldPanic bool
+		name       string
+		secretName string
+		secretData string
+		statusCode int
+		wantErr    bool
 	}{
 		{"Success", "TEST_SECRET", `{"encrypted_value":"encrypted","key_id":"123"}`, 201, false},
 		{"Invalid Data", "BAD_SECRET", `invalid`, 400, true},
@@ -388,17 +388,12 @@ func TestCreateOrgActionSecretScenarios(t *testing.T) {
 
 			getter := newAPIGetterWithMockREST(mockClient)
 
-			if tc.shouldPanic {
-				defer func() {
-					if r := recover(); r == nil {
-						t.Error("Expected panic from log.Fatal")
-					}
-				}()
-			}
-
 			err := getter.CreateOrgActionSecret("test-org", tc.secretName, bytes.NewReader([]byte(tc.secretData)))
 
-			if !tc.shouldPanic && err != nil {
+			if tc.wantErr && err == nil {
+				t.Error("Expected error but got nil")
+			}
+			if !tc.wantErr && err != nil {
 				t.Errorf("Unexpected error: %v", err)
 			}
 		})
@@ -3,117 +3,96 @@ package utils
 import (
 	"fmt"
 	"io"
-	"log"
 
 	"go.uber.org/zap"
 )
 
-func (g *APIGetter) GetOrgCodespacesSecrets(owner string) ([]byte, error) {
+func (g *GitHubGetter) GetOrgCodespacesSecrets(owner string) ([]byte, error) {
 	url := fmt.Sprintf("orgs/%s/codespaces/secrets", owner)
 
-	resp, err := g.restClient.Request("GET", url, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	return getAllPages(&g.restClient, url, "secrets", maxPerPage)
 }
 
-func (g *APIGetter) GetRepoCodespacesSecrets(owner string, repo string) ([]byte, error) {
+func (g *GitHubGetter) GetRepoCodespacesSecrets(owner string, repo string) ([]byte, error) {
 	url := fmt.Sprintf("repos/%s/%s/codespaces/secrets", owner, repo)
 
-	resp, err := g.restClient.Request("GET", url, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	return getAllPages(&g.restClient, url, "secrets", maxPerPage)
 }
 
-func (g *APIGetter) GetScopedOrgCodespacesSecrets(owner string, secret string) ([]byte, error) {
+func (g *GitHubGetter) GetScopedOrgCodespacesSecrets(owner string, secret string) ([]byte, error) {
 	url := fmt.Sprintf("orgs/%s/codespaces/secrets/%s/repositories", owner, secret)
 
-	resp, err := g.restClient.Request("GET", url, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	return getAllPages(&g.restClient, url, "repositories", maxPerPage)
 }
 
-func (g *APIGetter) GetOrgCodespacesPublicKey(owner string) ([]byte, error) {
+func (g *GitHubGetter) GetOrgCodespacesPublicKey(owner string) ([]byte, error) {
 	url := fmt.Sprintf("orgs/%s/codespaces/secrets/public-key", owner)
 	zap.S().Debugf("Getting public-key for %v", url)
-	resp, err := g.restClient.Request("GET", url, nil)
+	resp, err := doRequest(&g.restClient, "GET", url, nil)
 	if err != nil {
-		log.Printf("Body read error, %v", err)
+		return nil, fmt.Errorf("failed to get public key from %s: %w", url, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Body read error, %v", err)
-	}
-	return responseData, err
+	return io.ReadAll(resp.Body)
 }
 
-func (g *APIGetter) GetRepoCodespacesPublicKey(owner string, repo string) ([]byte, error) {
+func (g *GitHubGetter) GetRepoCodespacesPublicKey(owner string, repo string) ([]byte, error) {
 	url := fmt.Sprintf("repos/%s/%s/codespaces/secrets/public-key", owner, repo)
 	zap.S().Debugf("Getting public-key for %v", url)
-	resp, err := g.restClient.Request("GET", url, nil)
+	resp, err := doRequest(&g.restClient, "GET", url, nil)
 	if err != nil {
-		log.Printf("Body read error, %v", err)
+		return nil, fmt.Errorf("failed to get public key from %s: %w", url, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Body read error, %v", err)
-	}
-	return responseData, err
+	return io.ReadAll(resp.Body)
 }
 
-func (g *APIGetter) CreateOrgCodespacesSecret(owner string, secret string, data io.Reader) error {
+func (g *GitHubGetter) CreateOrgCodespacesSecret(owner string, secret string, data io.Reader) error {
 	url := fmt.Sprintf("orgs/%s/codespaces/secrets/%s", owner, secret)
 
-	resp, err := g.restClient.Request("PUT", url, data)
+	resp, err := doRequest(&g.restClient, "PUT", url, data)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	return err
+	return nil
 }
 
-func (g *APIGetter) CreateRepoCodespacesSecret(owner string, repo string, secret string, data io.Reader) error {
+func (g *GitHubGetter) CreateRepoCodespacesSecret(owner string, repo string, secret string, data io.Reader) error {
 	url := fmt.Sprintf("repos/%s/%s/codespaces/secrets/%s", owner, repo, secret)
 
-	resp, err := g.restClient.Request("PUT", url, data)
+	resp, err := doRequest(&g.restClient, "PUT", url, data)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("failed to create secret %s: %w", secret, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	return err
+	return nil
+}
+
+func (g *GitHubGetter) DeleteOrgCodespacesSecret(owner string, secret string) error {
+	url := fmt.Sprintf("orgs/%s/codespaces/secrets/%s", owner, secret)
+
+	return g.deleteResource(url)
+}
+
+func (g *GitHubGetter) DeleteRepoCodespacesSecret(owner string, repo string, secret string) error {
+	url := fmt.Sprintf("repos/%s/%s/codespaces/secrets/%s", owner, repo, secret)
+
+	return g.deleteResource(url)
 }
@@ -162,14 +162,10 @@ func TestCreateOrgCodespacesSecretInvalidData(t *testing.T) {
 	getter := newAPIGetterWithMockREST(mockClient)
 
 	t.Run("invalid data error", func(t *testing.T) {
-		defer func() {
-			if r := recover(); r == nil {
-				t.Error("Expected panic from log.Fatal")
-			}
-		}()
-
 		err := getter.CreateOrgCodespacesSecret("test-org", "TEST_SECRET", bytes.NewReader([]byte("invalid")))
-		_ = err // This line won't be reached due to panic
+		if err == nil {
+			t.Error("Expected error but got nil")
+		}
 	})
 }
 
@@ -241,9 +237,9 @@ func TestCreateRepoCodespacesSecretStatusCodes(t *testing.T) {
 	zap.ReplaceGlobals(logger)
 
 	testCases := []struct {
-		name        string
-		statusCode  int
-		shouldPanic bool
+		name       string
+		statusCode int
+		wantErr    bool
 	}{
 		{"Success Created", 201, false},
 		{"Success OK", 200, false},
@@ -267,17 +263,12 @@ func TestCreateRepoCodespacesSecretStatusCodes(t *testing.T) {
 
 			getter := newAPIGetterWithMockREST(mockClient)
 
-			if tc.shouldPanic {
-				defer func() {
-					if r := recover(); r == nil {
-						t.Error("Expected panic from log.Fatal")
-					}
-				}()
-			}
-
 			err := getter.CreateRepoCodespacesSecret("test-org", "test-repo", "SECRET", bytes.NewReader([]byte("{}")))
 
-			if !tc.shouldPanic && err != nil {
+			if tc.wantErr && err == nil {
+				t.Error("Expected error but got nil")
+			}
+			if !tc.wantErr && err != nil {
 				t.Errorf("Unexpected error: %v", err)
 			}
 		})
@@ -1,28 +1,24 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"strconv"
-	"strings"
 
 	data "github.com/katiem0/gh-seva/internal/data"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/nacl/box"
 )
 
+// CreateSecretsList converts CSV records to secrets, leaving out and logging
+// any invalid row. ParseSecretRecords reports the invalid rows instead.
 func (g *APIGetter) CreateSecretsList(filedata [][]string) []data.ImportedSecret {
-	// convert csv lines to array of structs
-	var importSecretList []data.ImportedSecret
-	var secret data.ImportedSecret
-	for _, each := range filedata[1:] {
-		secret.Level = each[0]
-		secret.Type = each[1]
-		secret.Name = each[2]
-		secret.Value = each[3]
-		secret.Access = each[4]
-		secret.RepositoryNames = strings.Split(each[5], ";")
-		secret.RepositoryIDs = strings.Split(each[6], ";")
-		importSecretList = append(importSecretList, secret)
+	importSecretList, err := ParseSecretRecords(filedata)
+	if err != nil {
+		zap.S().Warnf("Skipping invalid secrets: %v", err)
 	}
 	return importSecretList
 }
@@ -46,18 +42,151 @@ func (g *APIGetter) EncryptSecret(publicKey string, secret string) (string, erro
 	return encryptedValue, nil
 }
 
-func CreateSelectedOrgSecretData(secret data.ImportedSecret, keyID string, encryptedValue string) *data.CreateOrgSecret {
-	secretArray := make([]int, len(secret.RepositoryIDs))
-	for i := range secretArray {
-		secretArray[i], _ = strconv.Atoi(secret.RepositoryIDs[i])
+// CreateImportedSecret encrypts a secret read from a file with the public key
+// of its level and type, then creates it, or replaces it when it exists.
+// Selected organization secrets are scoped to their RepositoryIDs, so these
+// are resolved in owner beforehand. Should GitHub refuse the value because
+// the key was rotated since it was cached, the secret is encrypted with the
+// current key and written once more.
+func (g *APIGetter) CreateImportedSecret(owner string, secret data.ImportedSecret) error {
+	if err := ValidateSecretScope(secret); err != nil {
+		return err
+	}
+	zap.S().Debugf("Encrypting %s level %s secret %s", secret.Level, secret.Type, secret.Name)
+	publicKey, err := g.SecretPublicKey(owner, secret)
+	if err != nil {
+		return err
+	}
+	encryptedSecret, err := g.encryptSecretValue(publicKey, secret)
+	if err != nil {
+		return err
+	}
+	err = g.putEncryptedSecret(owner, secret, publicKey.KeyID, encryptedSecret)
+	if err == nil || !isRejectedKey(err) {
+		return err
+	}
+
+	g.keys.invalidate(publicKeyScope(owner, secret), publicKey.KeyID)
+	currentKey, keyErr := g.SecretPublicKey(owner, secret)
+	if keyErr != nil || currentKey.KeyID == publicKey.KeyID {
+		return err
+	}
+	zap.S().Debugf("Public key %s was rotated to %s, encrypting secret %s again", publicKey.KeyID, currentKey.KeyID, secret.Name)
+	encryptedSecret, err = g.encryptSecretValue(currentKey, secret)
+	if err != nil {
+		return err
+	}
+	return g.putEncryptedSecret(owner, secret, currentKey.KeyID, encryptedSecret)
+}
+
+// SecretPublicKey returns the public key that secrets of the level and type
+// of secret, in its repository and environment, are encrypted with. Keys are
+// cached, so each is read once by a getter.
+func (g *APIGetter) SecretPublicKey(owner string, secret data.ImportedSecret) (data.PublicKey, error) {
+	return g.keys.get(publicKeyScope(owner, secret), func() (data.PublicKey, error) {
+		return g.readSecretPublicKey(owner, secret)
+	})
+}
+
+func (g *APIGetter) readSecretPublicKey(owner string, secret data.ImportedSecret) (data.PublicKey, error) {
+	repo := firstName(secret.RepositoryNames)
+	var response []byte
+	var err error
+	switch secret.Level + "/" + secret.Type {
+	case "Organization/Actions":
+		response, err = g.GetOrgActionPublicKey(owner)
+	case "Organization/Codespaces":
+		response, err = g.GetOrgCodespacesPublicKey(owner)
+	case "Organization/Dependabot":
+		response, err = g.GetOrgDependabotPublicKey(owner)
+	case "Repository/Actions":
+		response, err = g.GetRepoActionPublicKey(owner, repo)
+	case "Repository/Codespaces":
+		response, err = g.GetRepoCodespacesPublicKey(owner, repo)
+	case "Repository/Dependabot":
+		response, err = g.GetRepoDependabotPublicKey(owner, repo)
+	case "Environment/Actions":
+		response, err = g.GetEnvironmentActionPublicKey(owner, repo, secret.EnvironmentName)
+	}
+	var publicKey data.PublicKey
+	if err != nil {
+		return publicKey, err
+	}
+	if err := json.Unmarshal(response, &publicKey); err != nil {
+		return publicKey, err
+	}
+	return publicKey, nil
+}
+
+// encryptSecretValue resolves the value of secret, should it be a
+// reference, and encrypts it with publicKey.
+func (g *APIGetter) encryptSecretValue(publicKey data.PublicKey, secret data.ImportedSecret) (string, error) {
+	value, err := ResolveSecretValue(secret.Value, g.stores)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the value of secret %s: %w", secret.Name, err)
+	}
+	return g.EncryptSecret(publicKey.Key, value)
+}
+
+// putEncryptedSecret creates, or replaces, secret with a value encrypted
+// with the public key keyID.
+func (g *APIGetter) putEncryptedSecret(owner string, secret data.ImportedSecret, keyID string, encryptedSecret string) error {
+	var secretObject interface{}
+	var err error
+	switch {
+	case secret.Level != "Organization":
+		secretObject = CreateRepoSecretData(keyID, encryptedSecret)
+	case secret.Access != "selected":
+		secretObject = CreateOrgSecretData(secret, keyID, encryptedSecret)
+	case secret.Type == "Dependabot":
+		secretObject, err = CreateOrgDependabotSecretData(secret, keyID, encryptedSecret)
+	default:
+		secretObject, err = CreateSelectedOrgSecretData(secret, keyID, encryptedSecret)
+	}
+	if err != nil {
+		return err
+	}
+	createSecret, err := json.Marshal(secretObject)
+	if err != nil {
+		return err
+	}
+	reader := bytes.NewReader(createSecret)
+
+	repo := firstName(secret.RepositoryNames)
+	zap.S().Debugf("Creating %s level %s secret %s", secret.Level, secret.Type, secret.Name)
+	switch secret.Level + "/" + secret.Type {
+	case "Organization/Actions":
+		err = g.CreateOrgActionSecret(owner, secret.Name, reader)
+	case "Organization/Codespaces":
+		err = g.CreateOrgCodespacesSecret(owner, secret.Name, reader)
+	case "Organization/Dependabot":
+		err = g.CreateOrgDependabotSecret(owner, secret.Name, reader)
+	case "Repository/Actions":
+		err = g.CreateRepoActionSecret(owner, repo, secret.Name, reader)
+	case "Repository/Codespaces":
+		err = g.CreateRepoCodespacesSecret(owner, repo, secret.Name, reader)
+	case "Repository/Dependabot":
+		err = g.CreateRepoDependabotSecret(owner, repo, secret.Name, reader)
+	case "Environment/Actions":
+		err = g.CreateEnvironmentActionSecret(owner, repo, secret.EnvironmentName, secret.Name, reader)
+	}
+	return err
+}
+
+// CreateSelectedOrgSecretData returns the payload of a selected
+// organization secret, failing when a repository ID is not a number.
+func CreateSelectedOrgSecretData(secret data.ImportedSecret, keyID string, encryptedValue string) (*data.CreateOrgSecret, error) {
+	ids, err := parseRepoIDs(secret.RepositoryIDs)
+	if err != nil {
+		return nil, err
 	}
 	s := data.CreateOrgSecret{
 		EncryptedValue: encryptedValue,
 		KeyID:          keyID,
 		Visibility:     secret.Access,
-		SelectedRepos:  secretArray,
+		SelectedRepos:  ids,
 	}
-	return &s
+	return &s, nil
 }
 
 func CreateOrgSecretData(secret data.ImportedSecret, keyID string, encryptedValue string) *data.CreateOrgSecretAll {
@@ -70,15 +199,24 @@ func CreateOrgSecretData(secret data.ImportedSecret, keyID string, encryptedValu
 }
 
 // Separate function to address that the Dependabot Org Secret API
-// is an array of strings instead of an array of integers
-func CreateOrgDependabotSecretData(secret data.ImportedSecret, keyID string, encryptedValue string) *data.CreateOrgDepSecret {
+// is an array of strings instead of an array of integers. The IDs are
+// validated in the same way as for other selected secrets.
+func CreateOrgDependabotSecretData(secret data.ImportedSecret, keyID string, encryptedValue string) (*data.CreateOrgDepSecret, error) {
+	ids, err := parseRepoIDs(secret.RepositoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]string, len(ids))
+	for i, id := range ids {
+		selected[i] = strconv.Itoa(id)
+	}
 	s := data.CreateOrgDepSecret{
 		EncryptedValue: encryptedValue,
 		KeyID:          keyID,
 		Visibility:     secret.Access,
-		SelectedRepos:  secret.RepositoryIDs,
+		SelectedRepos:  selected,
 	}
-	return &s
+	return &s, nil
 }
 
 func CreateRepoSecretData(keyID string, encryptedValue string) *data.CreateRepoSecret {
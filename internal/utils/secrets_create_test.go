@@ -1,10 +1,18 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/katiem0/gh-seva/internal/data"
+	"golang.org/x/crypto/nacl/box"
 )
 
 func TestCreateSecretsList(t *testing.T) {
@@ -86,7 +94,10 @@ func TestCreateSelectedOrgSecretData(t *testing.T) {
 	encryptedValue := "encrypted-secret-value"
 
 	// Execute
-	result := CreateSelectedOrgSecretData(secret, keyID, encryptedValue)
+	result, err := CreateSelectedOrgSecretData(secret, keyID, encryptedValue)
+	if err != nil {
+		t.Fatalf("CreateSelectedOrgSecretData() error = %v", err)
+	}
 
 	// Verify
 	expected := &data.CreateOrgSecret{
@@ -184,6 +195,50 @@ func strToInt(s string) (int, error) {
 	return strconv.Atoi(s)
 }
 
+func TestCreateSelectedOrgSecretDataRepositoryIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []string
+		want    []int
+		wantErr string
+	}{
+		{name: "blank", ids: []string{""}, want: nil},
+		{name: "blank among IDs", ids: []string{"1234", "", "5678"}, want: []int{1234, 5678}},
+		{name: "not a number", ids: []string{"1234", "repo-a"}, wantErr: `invalid repository ID "repo-a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := data.ImportedSecret{Name: "TEST_SECRET", Access: "selected", RepositoryIDs: tt.ids}
+
+			result, err := CreateSelectedOrgSecretData(secret, "key", "value")
+			dependabot, depErr := CreateOrgDependabotSecretData(secret, "key", "value")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				if depErr == nil || depErr.Error() != tt.wantErr {
+					t.Errorf("Expected Dependabot error %q, got %v", tt.wantErr, depErr)
+				}
+				return
+			}
+			if err != nil || depErr != nil {
+				t.Fatalf("Unexpected errors %v, %v", err, depErr)
+			}
+			if !reflect.DeepEqual(result.SelectedRepos, tt.want) {
+				t.Errorf("Expected SelectedRepos %v, got %v", tt.want, result.SelectedRepos)
+			}
+			if len(dependabot.SelectedRepos) != len(tt.want) {
+				t.Errorf("Expected %d Dependabot SelectedRepos, got %v", len(tt.want), dependabot.SelectedRepos)
+			}
+			for _, id := range dependabot.SelectedRepos {
+				if id == "" {
+					t.Errorf("Expected no blank Dependabot repository IDs, got %q", dependabot.SelectedRepos)
+				}
+			}
+		})
+	}
+}
+
 func TestCreateOrgSecretData(t *testing.T) {
 	// Setup
 	secret := data.ImportedSecret{
@@ -228,7 +283,10 @@ func TestCreateOrgDependabotSecretData(t *testing.T) {
 	encryptedValue := "encrypted-secret-value"
 
 	// Execute
-	result := CreateOrgDependabotSecretData(secret, keyID, encryptedValue)
+	result, err := CreateOrgDependabotSecretData(secret, keyID, encryptedValue)
+	if err != nil {
+		t.Fatalf("CreateOrgDependabotSecretData() error = %v", err)
+	}
 
 	// Verify
 	expected := &data.CreateOrgDepSecret{
@@ -284,3 +342,129 @@ func TestCreateRepoSecretData(t *testing.T) {
 		t.Errorf("Expected KeyID %s, got %s", expected.KeyID, result.KeyID)
 	}
 }
+
+func TestCreateSecretsListEnvironment(t *testing.T) {
+	g := &APIGetter{}
+	filedata := [][]string{
+		{"SecretLevel", "SecretType", "SecretName", "SecretValue", "SecretAccess", "RepositoryNames", "RepositoryIDs", "EnvironmentName"},
+		{"Environment", "Actions", "ENV_SECRET", "secret1", "EnvironmentOnly", "repo1", "1234", "production"},
+		{"Repository", "Actions", "REPO_SECRET", "secret2", "RepoOnly", "repo1", "1234", ""},
+	}
+
+	result := g.CreateSecretsList(filedata)
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 secrets, got %d", len(result))
+	}
+	if result[0].Level != "Environment" || result[0].EnvironmentName != "production" {
+		t.Errorf("Expected Environment secret for 'production', got %s/%s", result[0].Level, result[0].EnvironmentName)
+	}
+	if result[1].EnvironmentName != "" {
+		t.Errorf("Expected empty EnvironmentName for repository secret, got %s", result[1].EnvironmentName)
+	}
+}
+
+func TestCreateImportedSecret(t *testing.T) {
+	publicKey, _, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyResponse := `{"key_id":"key-1","key":"` + base64.StdEncoding.EncodeToString(publicKey[:]) + `"}`
+
+	testCases := []struct {
+		name     string
+		secret   data.ImportedSecret
+		wantPath string
+		wantIDs  interface{}
+	}{
+		{
+			"selected codespaces secret",
+			data.ImportedSecret{Level: "Organization", Type: "Codespaces", Name: "TOKEN", Value: "v", Access: "selected", RepositoryIDs: []string{"7"}},
+			"/orgs/test-org/codespaces/secrets/TOKEN",
+			[]interface{}{float64(7)},
+		},
+		{
+			"selected dependabot secret",
+			data.ImportedSecret{Level: "Organization", Type: "Dependabot", Name: "TOKEN", Value: "v", Access: "selected", RepositoryIDs: []string{"7"}},
+			"/orgs/test-org/dependabot/secrets/TOKEN",
+			[]interface{}{"7"},
+		},
+		{
+			"environment secret",
+			data.ImportedSecret{Level: "Environment", Type: "Actions", Name: "TOKEN", Value: "v", RepositoryNames: []string{"api"}, EnvironmentName: "prod"},
+			"/repos/test-org/api/environments/prod/secrets/TOKEN",
+			nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var path string
+			var body map[string]interface{}
+			g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "/public-key") {
+					_, _ = w.Write([]byte(keyResponse))
+					return
+				}
+				path = r.URL.Path
+				raw, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(raw, &body)
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			if err := g.CreateImportedSecret("test-org", tc.secret); err != nil {
+				t.Fatalf("CreateImportedSecret() error = %v", err)
+			}
+			if path != tc.wantPath {
+				t.Errorf("Expected PUT %s, got %s", tc.wantPath, path)
+			}
+			if body["key_id"] != "key-1" || body["encrypted_value"] == "" {
+				t.Errorf("Unexpected payload %v", body)
+			}
+			if !reflect.DeepEqual(body["selected_repository_ids"], tc.wantIDs) {
+				t.Errorf("Expected selected repositories %v, got %v", tc.wantIDs, body["selected_repository_ids"])
+			}
+		})
+	}
+}
+
+func TestCreateImportedSecretValueReference(t *testing.T) {
+	publicKey, privateKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyResponse := `{"key_id":"key-1","key":"` + base64.StdEncoding.EncodeToString(publicKey[:]) + `"}`
+	var puts int
+	var body map[string]interface{}
+	g := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/public-key") {
+			_, _ = w.Write([]byte(keyResponse))
+			return
+		}
+		puts++
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	t.Setenv("SEVA_TEST_TOKEN", "from-env")
+	secret := data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "TOKEN", Value: "env:SEVA_TEST_TOKEN", RepositoryNames: []string{"api"}}
+
+	if err := g.CreateImportedSecret("test-org", secret); err != nil {
+		t.Fatalf("CreateImportedSecret() error = %v", err)
+	}
+	encrypted, _ := base64.StdEncoding.DecodeString(body["encrypted_value"].(string))
+	decrypted, ok := box.OpenAnonymous(nil, encrypted, publicKey, privateKey)
+	if !ok || string(decrypted) != "from-env" {
+		t.Errorf("Expected the value of the environment variable to be encrypted, got %q", decrypted)
+	}
+
+	secret.Value = "env:SEVA_TEST_UNSET"
+	err = g.CreateImportedSecret("test-org", secret)
+	if err == nil || err.Error() != "failed to resolve the value of secret TOKEN: environment variable SEVA_TEST_UNSET is not set" {
+		t.Errorf("Expected a resolve error, got %v", err)
+	}
+	if puts != 1 {
+		t.Errorf("Expected nothing to be written for an unresolved value, got %d writes", puts)
+	}
+}
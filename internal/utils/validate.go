@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+var levels = []string{"Organization", "Repository", "Environment"}
+
+var secretTypes = []string{"Actions", "Codespaces", "Dependabot"}
+
+var orgVisibilities = []string{"all", "private", "selected"}
+
+// Fields of a secret or variable a validation error can refer to, so the
+// error of a row of a file names its column.
+const (
+	fieldLevel        = "level"
+	fieldType         = "type"
+	fieldName         = "name"
+	fieldValue        = "value"
+	fieldVisibility   = "visibility"
+	fieldRepositories = "repositories"
+	fieldRepoIDs      = "repository_ids"
+	fieldEnvironment  = "environment"
+)
+
+// fieldError is a validation error in one field of a secret or variable.
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+func invalidField(field string, format string, args ...interface{}) error {
+	return &fieldError{field: field, err: fmt.Errorf(format, args...)}
+}
+
+// canonical returns the known value matching value regardless of case.
+func canonical(value string, known []string) (string, bool) {
+	for _, k := range known {
+		if strings.EqualFold(value, k) {
+			return k, true
+		}
+	}
+	return value, false
+}
+
+// normalizeSecret corrects the case of the level, type and visibility of a
+// secret read from a file, leaving unknown values to be reported.
+func normalizeSecret(secret *data.ImportedSecret) {
+	secret.Level, _ = canonical(secret.Level, levels)
+	secret.Type, _ = canonical(secret.Type, secretTypes)
+	if secret.Level == "Organization" {
+		secret.Access, _ = canonical(secret.Access, orgVisibilities)
+	}
+}
+
+// normalizeVariable corrects the case of the level and visibility of a
+// variable read from a file.
+func normalizeVariable(variable *data.ImportedVariable) {
+	variable.Level, _ = canonical(variable.Level, levels)
+	if variable.Level == "Organization" {
+		variable.Visibility, _ = canonical(variable.Visibility, orgVisibilities)
+	}
+}
+
+// ValidateImportedSecret checks a secret read from a file has everything
+// needed to create it at its level.
+func ValidateImportedSecret(secret data.ImportedSecret) error {
+	if err := validateSecretSettings(secret); err != nil {
+		return err
+	}
+	if secret.Value == "" {
+		return invalidField(fieldValue, "a secret value is required")
+	}
+	return nil
+}
+
+// validateSecretSettings checks the scope and visibility of a secret and
+// that a value reference is well formed, without requiring a value, which
+// is all a secrets file or manifest needs to be read.
+func validateSecretSettings(secret data.ImportedSecret) error {
+	if err := ValidateSecretScope(secret); err != nil {
+		return err
+	}
+	if err := ValidateValueReference(secret.Value); err != nil {
+		return &fieldError{field: fieldValue, err: err}
+	}
+	if secret.Level == "Organization" {
+		return validateOrgVisibility(secret.Access, secret.RepositoryNames, secret.RepositoryIDs)
+	}
+	return nil
+}
+
+// ValidateSecretScope checks a secret names a type and level along with the
+// repository and environment that level needs, which is all a delete uses.
+func ValidateSecretScope(secret data.ImportedSecret) error {
+	if secret.Name == "" {
+		return invalidField(fieldName, "a secret name is required")
+	}
+	if !slices.Contains(secretTypes, secret.Type) {
+		return invalidField(fieldType, "unknown secret type %q, expected Actions, Codespaces or Dependabot", secret.Type)
+	}
+	switch secret.Level {
+	case "Organization":
+	case "Repository":
+		if firstName(secret.RepositoryNames) == "" {
+			return invalidField(fieldRepositories, "a repository name is required for a Repository level secret")
+		}
+	case "Environment":
+		if secret.Type != "Actions" {
+			return invalidField(fieldType, "environment secrets are only supported for Actions")
+		}
+		return validateEnvironmentScope("secret", secret.RepositoryNames, secret.EnvironmentName)
+	default:
+		return invalidField(fieldLevel, "unknown secret level %q, expected Organization, Repository or Environment", secret.Level)
+	}
+	return nil
+}
+
+// ValidateImportedVariable checks a variable read from a file has everything
+// needed to create it at its level.
+func ValidateImportedVariable(variable data.ImportedVariable) error {
+	if err := validateVariableSettings(variable); err != nil {
+		return err
+	}
+	if variable.Value == "" {
+		return invalidField(fieldValue, "a variable value is required")
+	}
+	return nil
+}
+
+// validateVariableSettings checks the scope and visibility of a variable,
+// without requiring a value.
+func validateVariableSettings(variable data.ImportedVariable) error {
+	if err := ValidateVariableScope(variable); err != nil {
+		return err
+	}
+	if variable.Level == "Organization" {
+		return validateOrgVisibility(variable.Visibility, variable.SelectedRepos, variable.SelectedReposIDs)
+	}
+	return nil
+}
+
+// ValidateVariableScope checks a variable names a level along with the
+// repository and environment that level needs, which is all a delete uses.
+func ValidateVariableScope(variable data.ImportedVariable) error {
+	if variable.Name == "" {
+		return invalidField(fieldName, "a variable name is required")
+	}
+	switch variable.Level {
+	case "Organization":
+	case "Repository":
+		if firstName(variable.SelectedRepos) == "" {
+			return invalidField(fieldRepositories, "a repository name is required for a Repository level variable")
+		}
+	case "Environment":
+		return validateEnvironmentScope("variable", variable.SelectedRepos, variable.EnvironmentName)
+	default:
+		return invalidField(fieldLevel, "unknown variable level %q, expected Organization, Repository or Environment", variable.Level)
+	}
+	return nil
+}
+
+func validateEnvironmentScope(kind string, repos []string, environment string) error {
+	if firstName(repos) == "" {
+		return invalidField(fieldRepositories, "a repository name is required for an Environment level %s", kind)
+	}
+	if environment == "" {
+		return invalidField(fieldEnvironment, "an environment name is required for an Environment level %s", kind)
+	}
+	return nil
+}
+
+// validateOrgVisibility checks the visibility of an organization secret or
+// variable, and that a selected visibility names at least one repository,
+// by name or ID.
+func validateOrgVisibility(visibility string, names []string, ids []string) error {
+	if !slices.Contains(orgVisibilities, visibility) {
+		return invalidField(fieldVisibility, "unknown visibility %q, expected all, private or selected", visibility)
+	}
+	if visibility == "selected" && len(nonEmpty(names)) == 0 && len(nonEmpty(ids)) == 0 {
+		return invalidField(fieldRepositories, "selected visibility requires at least one repository")
+	}
+	if _, err := parseRepoIDs(ids); err != nil {
+		return &fieldError{field: fieldRepoIDs, err: err}
+	}
+	return nil
+}
+
+// parseRepoIDs converts repository IDs to numbers, leaving out blank IDs.
+func parseRepoIDs(ids []string) ([]int, error) {
+	var parsed []int
+	for _, id := range nonEmpty(ids) {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository ID %q", id)
+		}
+		parsed = append(parsed, n)
+	}
+	return parsed, nil
+}
+
+// errorField returns the field a validation error refers to, if any.
+func errorField(err error) (string, bool) {
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		return fieldErr.field, true
+	}
+	return "", false
+}
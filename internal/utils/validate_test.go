@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/data"
+)
+
+func TestValidateImportedSecret(t *testing.T) {
+	valid := data.ImportedSecret{Level: "Repository", Type: "Actions", Name: "TOKEN", Value: "value", Access: "RepoOnly", RepositoryNames: []string{"repo"}}
+
+	testCases := []struct {
+		name    string
+		modify  func(s *data.ImportedSecret)
+		wantErr string
+	}{
+		{"valid repository secret", func(s *data.ImportedSecret) {}, ""},
+		{"missing name", func(s *data.ImportedSecret) { s.Name = "" }, "name is required"},
+		{"unknown type", func(s *data.ImportedSecret) { s.Type = "Pages" }, "unknown secret type"},
+		{"missing value", func(s *data.ImportedSecret) { s.Value = "" }, "value is required"},
+		{"unknown level", func(s *data.ImportedSecret) { s.Level = "Enterprise" }, "unknown secret level"},
+		{"missing repository", func(s *data.ImportedSecret) { s.RepositoryNames = []string{""} }, "repository name is required"},
+		{"unknown visibility", func(s *data.ImportedSecret) { s.Level = "Organization"; s.Access = "internal" }, "unknown visibility"},
+		{"selected without repositories", func(s *data.ImportedSecret) {
+			s.Level = "Organization"
+			s.Access = "selected"
+			s.RepositoryNames = []string{""}
+		}, "at least one repository"},
+		{"environment dependabot secret", func(s *data.ImportedSecret) {
+			s.Level = "Environment"
+			s.Type = "Dependabot"
+			s.EnvironmentName = "prod"
+		}, "only supported for Actions"},
+		{"environment without name", func(s *data.ImportedSecret) { s.Level = "Environment" }, "an environment name is required"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret := valid
+			tc.modify(&secret)
+			err := ValidateImportedSecret(secret)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateImportedVariable(t *testing.T) {
+	testCases := []struct {
+		name     string
+		variable data.ImportedVariable
+		wantErr  bool
+	}{
+		{"organization", data.ImportedVariable{Level: "Organization", Name: "REGION", Value: "us", Visibility: "all"}, false},
+		{"repository", data.ImportedVariable{Level: "Repository", Name: "REGION", Value: "us", SelectedRepos: []string{"repo"}}, false},
+		{"environment", data.ImportedVariable{Level: "Environment", Name: "REGION", Value: "us", SelectedRepos: []string{"repo"}, EnvironmentName: "prod"}, false},
+		{"missing value", data.ImportedVariable{Level: "Organization", Name: "REGION", Visibility: "all"}, true},
+		{"missing environment", data.ImportedVariable{Level: "Environment", Name: "REGION", Value: "us", SelectedRepos: []string{"repo"}}, true},
+		{"unknown level", data.ImportedVariable{Level: "Org", Name: "REGION", Value: "us"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateImportedVariable(tc.variable)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidationErrorFields(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		wantField string
+	}{
+		{"secret type", ValidateSecretScope(data.ImportedSecret{Level: "Organization", Type: "Pages", Name: "TOKEN"}), fieldType},
+		{"secret value", ValidateImportedSecret(data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "TOKEN", Access: "all"}), fieldValue},
+		{"secret reference", validateSecretSettings(data.ImportedSecret{Level: "Organization", Type: "Actions", Name: "TOKEN", Value: "env:", Access: "all"}), fieldValue},
+		{"variable environment", ValidateVariableScope(data.ImportedVariable{Level: "Environment", Name: "REGION", SelectedRepos: []string{"api"}}), fieldEnvironment},
+		{"variable visibility", validateVariableSettings(data.ImportedVariable{Level: "Organization", Name: "REGION", Visibility: "internal"}), fieldVisibility},
+		{"variable repository id", validateVariableSettings(data.ImportedVariable{Level: "Organization", Name: "REGION", Visibility: "selected", SelectedReposIDs: []string{"12", "api"}}), fieldRepoIDs},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			field, ok := errorField(tc.err)
+			if !ok || field != tc.wantField {
+				t.Errorf("Expected an error in %s, got %q from %v", tc.wantField, field, tc.err)
+			}
+		})
+	}
+}
+
+func TestNormalizeSecret(t *testing.T) {
+	secret := data.ImportedSecret{Level: "organization", Type: "DEPENDABOT", Access: "Selected"}
+	normalizeSecret(&secret)
+	if secret.Level != "Organization" || secret.Type != "Dependabot" || secret.Access != "selected" {
+		t.Errorf("Expected known values in their case, got %+v", secret)
+	}
+	variable := data.ImportedVariable{Level: "repository", Visibility: "RepoOnly"}
+	normalizeVariable(&variable)
+	if variable.Level != "Repository" || variable.Visibility != "RepoOnly" {
+		t.Errorf("Expected the level corrected and the visibility kept, got %+v", variable)
+	}
+}
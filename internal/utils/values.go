@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/katiem0/gh-seva/internal/providers"
+)
+
+// Prefixes of a secret value that refer to where the value is kept rather
+// than holding it, so files can be committed without plaintext secrets.
+// valueLiteral escapes a value that would otherwise be read as a reference.
+const (
+	valueRefEnv   = "env:"
+	valueRefFile  = "file:"
+	valueRefExec  = "exec:"
+	valueRefStore = "store:"
+	valueLiteral  = "literal:"
+)
+
+// LiteralValue escapes value so it is used as is, even should it start
+// with the prefix of a reference. Values supplied rather than read from a
+// file, such as from the environment, are escaped so they are never
+// resolved.
+func LiteralValue(value string) string {
+	return valueLiteral + value
+}
+
+// ValidateValueReference checks that a secret value which is a reference
+// names an environment variable, file, command or secret store entry,
+// without resolving it.
+func ValidateValueReference(value string) error {
+	prefix, ref, ok := splitValueReference(value)
+	if !ok {
+		return nil
+	}
+	if strings.TrimSpace(ref) == "" {
+		return fmt.Errorf("value reference %q is missing what it refers to", prefix)
+	}
+	switch prefix {
+	case valueRefStore:
+		_, _, _, err := providers.ParseReference(ref)
+		return err
+	case valueRefExec:
+		_, err := splitCommandLine(ref)
+		return err
+	}
+	return nil
+}
+
+// ResolveSecretValue returns the value of a secret, reading it from the
+// environment variable, file, command output or entry of one of stores it
+// refers to when the value is a reference, or without the prefix when it is
+// escaped as a literal. A single trailing newline is trimmed from files and
+// command output. Errors never include the value.
+func ResolveSecretValue(value string, stores *providers.Registry) (string, error) {
+	if literal, ok := strings.CutPrefix(value, valueLiteral); ok {
+		return literal, nil
+	}
+	prefix, ref, ok := splitValueReference(value)
+	if !ok {
+		return value, nil
+	}
+	if err := ValidateValueReference(value); err != nil {
+		return "", err
+	}
+	ref = strings.TrimSpace(ref)
+
+	switch prefix {
+	case valueRefStore:
+		return stores.Lookup(ref)
+	case valueRefEnv:
+		resolved, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref)
+		}
+		return resolved, nil
+	case valueRefFile:
+		content, err := os.ReadFile(ref)
+		if err != nil {
+			return "", fmt.Errorf("failed to read value file: %w", err)
+		}
+		return trimTrailingNewline(string(content)), nil
+	default:
+		args, err := splitCommandLine(ref)
+		if err != nil {
+			return "", err
+		}
+		command := exec.Command(args[0], args[1:]...)
+		command.Stderr = os.Stderr
+		output, err := command.Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return "", fmt.Errorf("command %s failed: %s", args[0], exitErr.ProcessState)
+			}
+			return "", fmt.Errorf("command %s failed: %w", args[0], err)
+		}
+		return trimTrailingNewline(string(output)), nil
+	}
+}
+
+func splitValueReference(value string) (string, string, bool) {
+	for _, prefix := range []string{valueRefEnv, valueRefFile, valueRefExec, valueRefStore} {
+		if ref, ok := strings.CutPrefix(value, prefix); ok {
+			return prefix, ref, true
+		}
+	}
+	return "", "", false
+}
+
+// splitCommandLine splits the command of an exec: reference into its
+// arguments on spaces, as a shell would, so an argument can be quoted with
+// single or double quotes, or have a character escaped with a backslash.
+// Variables, globs and other shell syntax are not expanded.
+func splitCommandLine(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("command of exec: reference has an unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("value reference %q is missing what it refers to", valueRefExec)
+	}
+	return args, nil
+}
+
+func trimTrailingNewline(value string) string {
+	value = strings.TrimSuffix(value, "\n")
+	return strings.TrimSuffix(value, "\r")
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/katiem0/gh-seva/internal/providers"
+)
+
+func TestResolveSecretValue(t *testing.T) {
+	t.Setenv("SEVA_TEST_VALUE", "from-env")
+	fileName := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(fileName, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("Failed to write value file: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"literal", "s3cr3t", "s3cr3t", ""},
+		{"literal with colon", "user:pass", "user:pass", ""},
+		{"environment variable", "env:SEVA_TEST_VALUE", "from-env", ""},
+		{"unset environment variable", "env:SEVA_TEST_UNSET", "", "environment variable SEVA_TEST_UNSET is not set"},
+		{"file", "file:" + fileName, "from-file", ""},
+		{"missing file", "file:" + fileName + ".missing", "", "failed to read value file"},
+		{"command", "exec:echo from-exec", "from-exec", ""},
+		{"quoted command", `exec:printf '%s|%s' "two words" a\ b`, "two words|a b", ""},
+		{"unterminated quote", `exec:echo "from-exec`, "", "unterminated quote"},
+		{"escaped literal", "literal:exec:echo from-exec", "exec:echo from-exec", ""},
+		{"escaped plain literal", LiteralValue("s3cr3t"), "s3cr3t", ""},
+		{"failing command", "exec:false", "", "command false failed: exit status 1"},
+		{"empty reference", "exec: ", "", `value reference "exec:" is missing what it refers to`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ResolveSecretValue(tc.value, nil)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveSecretValue() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateValueReference(t *testing.T) {
+	for _, value := range []string{"s3cr3t", "env:TOKEN", "file:/run/secrets/token", "exec:vault kv get -field=token secret/ci", "literal:env:"} {
+		if err := ValidateValueReference(value); err != nil {
+			t.Errorf("ValidateValueReference(%q) error = %v", value, err)
+		}
+	}
+	for _, value := range []string{"env:", "file:  ", "exec:", "exec:echo 'token"} {
+		if err := ValidateValueReference(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestParseSecretRecordsValueReference(t *testing.T) {
+	records := [][]string{
+		{"SecretLevel", "SecretType", "SecretName", "SecretValue", "SecretAccess", "RepositoryNames", "RepositoryIDs", "EnvironmentName"},
+		{"Organization", "Actions", "NPM_TOKEN", "env:NPM_TOKEN", "all", "", "", ""},
+		{"Organization", "Actions", "DEPLOY_KEY", "file:", "all", "", "", ""},
+	}
+	_, err := ParseSecretRecords(records)
+	if err == nil || !strings.Contains(err.Error(), `row 2, column SecretValue (D): value reference "file:" is missing what it refers to`) {
+		t.Errorf("Expected an error for the empty reference, got %v", err)
+	}
+}
+
+type staticStore map[string]string
+
+func (s staticStore) Lookup(key string, field string) (string, error) {
+	return s[key+"#"+field], nil
+}
+
+func TestResolveSecretValueStore(t *testing.T) {
+	stores, err := providers.NewRegistry(nil)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	stores.Register("vault", staticStore{"ci/npm#token": "npm-value"})
+
+	value, err := ResolveSecretValue("store:vault:ci/npm#token", stores)
+	if err != nil || value != "npm-value" {
+		t.Errorf("Expected npm-value, got %q, %v", value, err)
+	}
+	if _, err := ResolveSecretValue("store:vault:ci/npm#token", nil); err == nil || !strings.Contains(err.Error(), "use --providers-config") {
+		t.Errorf("Expected an error without providers, got %v", err)
+	}
+	if err := ValidateValueReference("store:ci/npm"); err == nil || !strings.Contains(err.Error(), `must be of the form provider:key#field`) {
+		t.Errorf("Expected an error for a reference without a provider, got %v", err)
+	}
+}
@@ -2,48 +2,46 @@ package utils
 
 import (
 	"fmt"
-	"io"
-	"log"
 )
 
-func (g *APIGetter) GetOrgActionVariables(owner string) ([]byte, error) {
+func (g *GitHubGetter) GetOrgActionVariables(owner string) ([]byte, error) {
 	url := fmt.Sprintf("orgs/%s/actions/variables", owner)
 
-	resp, err := g.restClient.Request("GET", url, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	return getAllPages(&g.restClient, url, "variables", maxVariablesPerPage)
 }
 
-func (g *APIGetter) GetRepoActionVariables(owner string, repo string) ([]byte, error) {
+func (g *GitHubGetter) GetRepoActionVariables(owner string, repo string) ([]byte, error) {
 	url := fmt.Sprintf("repos/%s/%s/actions/variables", owner, repo)
 
-	resp, err := g.restClient.Request("GET", url, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	return getAllPages(&g.restClient, url, "variables", maxVariablesPerPage)
 }
 
-func (g *APIGetter) GetScopedOrgActionVariables(owner string, secret string) ([]byte, error) {
+func (g *GitHubGetter) GetScopedOrgActionVariables(owner string, secret string) ([]byte, error) {
 	url := fmt.Sprintf("orgs/%s/actions/variables/%s/repositories", owner, secret)
 
-	resp, err := g.restClient.Request("GET", url, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	return getAllPages(&g.restClient, url, "repositories", maxPerPage)
+}
+
+func (g *GitHubGetter) GetEnvironmentActionVariables(owner string, repo string, environment string) ([]byte, error) {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/variables", owner, repo, escapeEnvironment(environment))
+
+	return getAllPages(&g.restClient, url, "variables", maxVariablesPerPage)
+}
+
+func (g *GitHubGetter) DeleteOrganizationVariable(owner string, variable string) error {
+	url := fmt.Sprintf("orgs/%s/actions/variables/%s", owner, variable)
+
+	return g.deleteResource(url)
+}
+
+func (g *GitHubGetter) DeleteRepoVariable(owner string, repo string, variable string) error {
+	url := fmt.Sprintf("repos/%s/%s/actions/variables/%s", owner, repo, variable)
+
+	return g.deleteResource(url)
+}
+
+func (g *GitHubGetter) DeleteEnvironmentVariable(owner string, repo string, environment string, variable string) error {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/variables/%s", owner, repo, escapeEnvironment(environment), variable)
+
+	return g.deleteResource(url)
 }
@@ -144,27 +144,6 @@ func TestGetScopedOrgActionVariablesMock(t *testing.T) {
 	}
 }
 
-func TestGetSourceOrganizationVariablesError(t *testing.T) {
-	testGetSourceOrganizationVariables := func(owner string, g interface{}) ([]byte, error) {
-		// This is a simplified version that just returns an error
-		return nil, fmt.Errorf("mock error for source API request")
-	}
-
-	// Execute - call our local test function instead of the one from another file
-	_, err := testGetSourceOrganizationVariables("test-org", nil)
-
-	// Verify
-	if err == nil {
-		t.Errorf("Expected error, got nil")
-	}
-
-	// Check that error message is as expected
-	expectedErrMsg := "mock error for source API request"
-	if err.Error() != expectedErrMsg {
-		t.Errorf("Expected error message '%s', got '%s'", expectedErrMsg, err.Error())
-	}
-}
-
 // Test multiple variables in the response
 func TestOrgActionVariablesWithMultipleEntries(t *testing.T) {
 	// Setup
@@ -515,3 +494,42 @@ func TestConcurrentVariableAccess(t *testing.T) {
 		<-done
 	}
 }
+
+// Test environment variable endpoints escape the environment name
+func TestEnvironmentVariablePaths(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	zap.ReplaceGlobals(logger)
+
+	var capturedPaths []string
+	mockClient := &mockRESTClient{
+		RequestFunc: func(method string, path string, body io.Reader) (*http.Response, error) {
+			capturedPaths = append(capturedPaths, method+" "+path)
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"total_count":0,"variables":[]}`))),
+			}, nil
+		},
+	}
+
+	getter := newAPIGetterWithMockREST(mockClient)
+
+	if _, err := getter.GetEnvironmentActionVariables("test-org", "test-repo", "prod/eu"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := getter.CreateEnvironmentVariable("test-org", "test-repo", "prod/eu", bytes.NewReader([]byte("{}"))); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	expectedPaths := []string{
+		"GET repos/test-org/test-repo/environments/prod%2Feu/variables",
+		"POST repos/test-org/test-repo/environments/prod%2Feu/variables",
+	}
+	if len(capturedPaths) != len(expectedPaths) {
+		t.Fatalf("Expected %d requests, got %d", len(expectedPaths), len(capturedPaths))
+	}
+	for i, expected := range expectedPaths {
+		if capturedPaths[i] != expected {
+			t.Errorf("Expected request %s, got %s", expected, capturedPaths[i])
+		}
+	}
+}
@@ -1,154 +1,214 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
-	"log"
-	"strconv"
-	"strings"
 
 	"github.com/katiem0/gh-seva/internal/data"
 	"go.uber.org/zap"
 )
 
-func (g *APIGetter) CreateVariableList(filedata [][]string) []data.ImportedVariable {
-	var variableList []data.ImportedVariable
+// Policies for creating a variable that already exists
+const (
+	ConflictSkip   = "skip"
+	ConflictUpdate = "update"
+	ConflictFail   = "fail"
+)
 
+// CreateVariableList converts CSV records to variables, leaving out and
+// logging any invalid row. ParseVariableRecords reports the invalid rows
+// instead.
+func (g *APIGetter) CreateVariableList(filedata [][]string) []data.ImportedVariable {
 	if len(filedata) <= 1 {
 		zap.S().Warn("Empty variable data provided")
-		return variableList
+		return nil
 	}
 
-	for _, each := range filedata[1:] {
-		// Skip if we don't have at least name, level, and value
-		if len(each) < 3 {
-			zap.S().Warn("Skipping row with insufficient fields")
-			continue
-		}
-
-		variable := data.ImportedVariable{
-			Level: each[0],
-			Name:  each[1],
-			Value: each[2],
-		}
-
-		// Validate required fields
-		if variable.Name == "" {
-			zap.S().Warn("Skipping variable with empty name")
-			continue
-		}
-
-		// Handle optional fields
-		if len(each) > 3 {
-			variable.Visibility = each[3]
-		}
-
-		// Handle repository data
-		if len(each) > 4 {
-			variable.SelectedRepos = strings.Split(each[4], ";")
-		}
-
-		if len(each) > 5 {
-			variable.SelectedReposIDs = strings.Split(each[5], ";")
-		}
-
+	variableList, err := ParseVariableRecords(filedata)
+	if err != nil {
+		zap.S().Warnf("Skipping invalid variables: %v", err)
+	}
+	for _, variable := range variableList {
 		zap.S().Debugf("Processed variable: %s/%s", variable.Level, variable.Name)
-		variableList = append(variableList, variable)
 	}
-
 	return variableList
 }
 
-func GetSourceOrganizationVariables(owner string, g *sourceAPIGetter) ([]byte, error) {
+func (g *GitHubGetter) CreateOrganizationVariable(owner string, data io.Reader) error {
 	url := fmt.Sprintf("orgs/%s/actions/variables", owner)
-	zap.S().Debugf("Reading in variables from %v", url)
-	resp, err := g.restClient.Request("GET", url, nil)
+
+	resp, err := doRequest(&g.restClient, "POST", url, data)
 	if err != nil {
-		log.Printf("Body read error, %v", err)
+		zap.S().Errorf("Error making request to create organization variable: %v", err)
+		return fmt.Errorf("failed to create organization variable: %w", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Body read error, %v", err)
+
+	// Check response status code
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error: status=%d, body=%s", resp.StatusCode, string(body))
 	}
-	return responseData, err
+
+	return nil
 }
 
-func GetScopedSourceOrgActionVariables(owner string, secret string, g *sourceAPIGetter) ([]byte, error) {
-	url := fmt.Sprintf("orgs/%s/actions/variables/%s/repositories", owner, secret)
+func (g *GitHubGetter) CreateRepoVariable(owner string, repo string, data io.Reader) error {
+	url := fmt.Sprintf("repos/%s/%s/actions/variables", owner, repo)
 
-	resp, err := g.restClient.Request("GET", url, nil)
+	resp, err := doRequest(&g.restClient, "POST", url, data)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("failed to create repository variable: %w", err)
 	}
-	responseData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return responseData, err
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			zap.S().Errorf("Error closing response body: %v", err)
+		}
+	}()
+	return nil
 }
 
-func (g *APIGetter) CreateOrganizationVariable(owner string, data io.Reader) error {
-	url := fmt.Sprintf("orgs/%s/actions/variables", owner)
+func (g *GitHubGetter) CreateEnvironmentVariable(owner string, repo string, environment string, data io.Reader) error {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/variables", owner, repo, escapeEnvironment(environment))
 
-	resp, err := g.restClient.Request("POST", url, data)
+	resp, err := doRequest(&g.restClient, "POST", url, data)
 	if err != nil {
-		zap.S().Errorf("Error making request to create organization variable: %v", err)
-		return fmt.Errorf("failed to create organization variable: %w", err)
+		zap.S().Errorf("Error making request to create environment variable: %v", err)
+		return fmt.Errorf("failed to create environment variable: %w", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
+	return nil
+}
 
-	// Check response status code
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error: status=%d, body=%s", resp.StatusCode, string(body))
-	}
+func (g *GitHubGetter) UpdateOrganizationVariable(owner string, variable string, data io.Reader) error {
+	url := fmt.Sprintf("orgs/%s/actions/variables/%s", owner, variable)
 
-	return nil
+	return g.updateVariable(url, variable, data)
 }
 
-func (g *APIGetter) CreateRepoVariable(owner string, repo string, data io.Reader) error {
-	url := fmt.Sprintf("repos/%s/%s/actions/variables", owner, repo)
+func (g *GitHubGetter) UpdateRepoVariable(owner string, repo string, variable string, data io.Reader) error {
+	url := fmt.Sprintf("repos/%s/%s/actions/variables/%s", owner, repo, variable)
+
+	return g.updateVariable(url, variable, data)
+}
 
-	resp, err := g.restClient.Request("POST", url, data)
+func (g *GitHubGetter) UpdateEnvironmentVariable(owner string, repo string, environment string, variable string, data io.Reader) error {
+	url := fmt.Sprintf("repos/%s/%s/environments/%s/variables/%s", owner, repo, escapeEnvironment(environment), variable)
+
+	return g.updateVariable(url, variable, data)
+}
+
+func (g *GitHubGetter) updateVariable(url string, variable string, data io.Reader) error {
+	resp, err := doRequest(&g.restClient, "PATCH", url, data)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("failed to update variable %s: %w", variable, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			zap.S().Errorf("Error closing response body: %v", err)
 		}
 	}()
-	return err
+	return nil
 }
 
-func CreateSelectedOrgVariableData(variable data.ImportedVariable) *data.CreateOrgVariable {
-	var validIDs []int
+// CreateImportedVariable creates a variable read from a file at its level.
+// Selected organization variables are scoped to their SelectedReposIDs, so
+// these are resolved in owner beforehand.
+func (g *APIGetter) CreateImportedVariable(owner string, variable data.ImportedVariable) error {
+	payload, err := importedVariablePayload(variable)
+	if err != nil {
+		return err
+	}
+	repo := firstName(variable.SelectedRepos)
+	switch variable.Level {
+	case "Organization":
+		return g.CreateOrganizationVariable(owner, bytes.NewReader(payload))
+	case "Repository":
+		return g.CreateRepoVariable(owner, repo, bytes.NewReader(payload))
+	default:
+		return g.CreateEnvironmentVariable(owner, repo, variable.EnvironmentName, bytes.NewReader(payload))
+	}
+}
+
+// UpdateImportedVariable replaces the value, and for an organization variable
+// the visibility and selected repositories, of an existing variable.
+func (g *APIGetter) UpdateImportedVariable(owner string, variable data.ImportedVariable) error {
+	payload, err := importedVariablePayload(variable)
+	if err != nil {
+		return err
+	}
+	repo := firstName(variable.SelectedRepos)
+	switch variable.Level {
+	case "Organization":
+		return g.UpdateOrganizationVariable(owner, variable.Name, bytes.NewReader(payload))
+	case "Repository":
+		return g.UpdateRepoVariable(owner, repo, variable.Name, bytes.NewReader(payload))
+	default:
+		return g.UpdateEnvironmentVariable(owner, repo, variable.EnvironmentName, variable.Name, bytes.NewReader(payload))
+	}
+}
 
-	for _, idStr := range variable.SelectedReposIDs {
-		id, err := strconv.Atoi(idStr)
+// WriteImportedVariable creates a variable read from a file and, when it
+// already exists, follows the onConflict policy to update it, skip it with
+// a SkipError, or report the conflict as an error.
+func (g *APIGetter) WriteImportedVariable(owner string, variable data.ImportedVariable, onConflict string) error {
+	zap.S().Debugf("Creating %s level variable %s", variable.Level, variable.Name)
+	err := g.CreateImportedVariable(owner, variable)
+	if err == nil || !IsConflict(err) {
+		return err
+	}
+	switch onConflict {
+	case ConflictSkip:
+		zap.S().Infof("Variable %s already exists, skipping", variable.Name)
+		return &SkipError{Reason: fmt.Sprintf("variable %s already exists", variable.Name)}
+	case ConflictFail:
+		return fmt.Errorf("variable %s already exists", variable.Name)
+	}
+	zap.S().Debugf("Variable %s already exists, updating", variable.Name)
+	return g.UpdateImportedVariable(owner, variable)
+}
+
+func importedVariablePayload(variable data.ImportedVariable) ([]byte, error) {
+	if err := ValidateVariableScope(variable); err != nil {
+		return nil, err
+	}
+	switch {
+	case variable.Level != "Organization":
+		return json.Marshal(CreateRepoVariableData(variable))
+	case variable.Visibility == "selected":
+		payload, err := CreateSelectedOrgVariableData(variable)
 		if err != nil {
-			zap.S().Warnf("Invalid repository ID '%s' will be skipped", idStr)
-			continue
+			return nil, err
 		}
-		validIDs = append(validIDs, id)
+		return json.Marshal(payload)
 	}
+	return json.Marshal(CreateOrgVariableData(variable))
+}
 
+// CreateSelectedOrgVariableData returns the payload of a selected
+// organization variable, failing when a repository ID is not a number.
+func CreateSelectedOrgVariableData(variable data.ImportedVariable) (*data.CreateOrgVariable, error) {
+	ids, err := parseRepoIDs(variable.SelectedReposIDs)
+	if err != nil {
+		return nil, err
+	}
 	s := data.CreateOrgVariable{
 		Name:             variable.Name,
 		Value:            variable.Value,
 		Visibility:       variable.Visibility,
-		SelectedReposIDs: validIDs,
+		SelectedReposIDs: ids,
 	}
-	return &s
+	return &s, nil
 }
 
 func CreateOrgVariableData(variable data.ImportedVariable) *data.CreateVariableAll {
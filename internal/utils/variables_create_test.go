@@ -3,6 +3,7 @@ package utils
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -145,7 +146,10 @@ func TestCreateSelectedOrgVariableData(t *testing.T) {
 	}
 
 	// Execute
-	result := CreateSelectedOrgVariableData(variable)
+	result, err := CreateSelectedOrgVariableData(variable)
+	if err != nil {
+		t.Fatalf("CreateSelectedOrgVariableData() error = %v", err)
+	}
 
 	// Verify
 	expected := &data.CreateOrgVariable{
@@ -189,19 +193,14 @@ func TestCreateSelectedOrgVariableDataWithInvalidIDs(t *testing.T) {
 	}
 
 	// Execute
-	result := CreateSelectedOrgVariableData(variable)
+	result, err := CreateSelectedOrgVariableData(variable)
 
-	// Verify - only valid IDs should be included
-	if len(result.SelectedReposIDs) != 2 {
-		t.Errorf("Expected 2 valid IDs, got %d", len(result.SelectedReposIDs))
+	// Verify - an invalid ID fails the variable rather than being dropped
+	if err == nil || err.Error() != `invalid repository ID "invalid"` {
+		t.Errorf("Expected an invalid repository ID error, got %v", err)
 	}
-
-	// Check specific IDs
-	validIDs := map[int]bool{1234: true, 5678: true}
-	for _, id := range result.SelectedReposIDs {
-		if !validIDs[id] {
-			t.Errorf("Unexpected ID in result: %d", id)
-		}
+	if result != nil {
+		t.Errorf("Expected no payload, got %v", result)
 	}
 }
 
@@ -292,45 +291,6 @@ func TestCreateOrgSourceVariableData(t *testing.T) {
 	}
 }
 
-type mockSourceAPIGetter struct {
-	VariablesData      []byte
-	ScopedVariableData []byte
-	ShouldReturnError  bool
-}
-
-func GetSourceOrganizationVariablesTest(owner string, g interface{}) ([]byte, error) {
-	// Cast the interface to our mock type
-	mockGetter, ok := g.(*mockSourceAPIGetter)
-	if !ok {
-		return nil, fmt.Errorf("invalid getter type")
-	}
-
-	// Return the mock data
-	return mockGetter.VariablesData, nil
-}
-
-func GetScopedSourceOrgActionVariablesTest(owner string, secret string, g interface{}) ([]byte, error) {
-	// Cast the interface to our mock type
-	mockGetter, ok := g.(*mockSourceAPIGetter)
-	if !ok {
-		return nil, fmt.Errorf("invalid getter type")
-	}
-
-	// Return the mock data
-	return mockGetter.ScopedVariableData, nil
-}
-
-func (m *mockSourceAPIGetter) Request(method string, url string, body io.Reader) (*mockResponse, error) {
-	if m.ShouldReturnError {
-		return nil, fmt.Errorf("mock error for source API request")
-	}
-
-	if strings.Contains(url, "repositories") {
-		return &mockResponse{Body: io.NopCloser(bytes.NewReader(m.ScopedVariableData))}, nil
-	}
-	return &mockResponse{Body: io.NopCloser(bytes.NewReader(m.VariablesData))}, nil
-}
-
 type MockRESTClient struct {
 	StatusCode int
 	Response   []byte
@@ -363,100 +323,6 @@ func (m MockRESTClient) GraphQL(query string, variables map[string]interface{},
 	return nil
 }
 
-type mockResponse struct {
-	Body io.ReadCloser
-}
-
-func TestGetSourceOrganizationVariables(t *testing.T) {
-	// Setup
-	expectedResponse := []byte(`{"total_count":1,"variables":[{"name":"TEST_VAR","value":"test-value","visibility":"all"}]}`)
-	mockGetter := &mockSourceAPIGetter{
-		VariablesData: expectedResponse,
-	}
-
-	// Execute
-	response, err := GetSourceOrganizationVariablesTest("test-org", mockGetter)
-
-	// Verify
-	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
-	}
-
-	if string(response) != string(expectedResponse) {
-		t.Errorf("Expected response %s, got %s", string(expectedResponse), string(response))
-	}
-
-	// Test parsing of the response
-	var variablesResponse data.VariableResponse
-	err = json.Unmarshal(response, &variablesResponse)
-	if err != nil {
-		t.Errorf("Failed to unmarshal response: %v", err)
-	}
-
-	if variablesResponse.TotalCount != 1 {
-		t.Errorf("Expected total count 1, got %d", variablesResponse.TotalCount)
-	}
-
-	if len(variablesResponse.Variables) != 1 {
-		t.Errorf("Expected 1 variable, got %d", len(variablesResponse.Variables))
-	}
-
-	if variablesResponse.Variables[0].Name != "TEST_VAR" {
-		t.Errorf("Expected variable name TEST_VAR, got %s", variablesResponse.Variables[0].Name)
-	}
-
-	if variablesResponse.Variables[0].Value != "test-value" {
-		t.Errorf("Expected variable value test-value, got %s", variablesResponse.Variables[0].Value)
-	}
-
-	if variablesResponse.Variables[0].Visibility != "all" {
-		t.Errorf("Expected variable visibility all, got %s", variablesResponse.Variables[0].Visibility)
-	}
-}
-
-func TestGetScopedSourceOrgActionVariables(t *testing.T) {
-	// Setup
-	expectedResponse := []byte(`{"total_count":1,"repositories":[{"id":12345,"name":"test-repo"}]}`)
-	mockGetter := &mockSourceAPIGetter{
-		ScopedVariableData: expectedResponse,
-	}
-
-	// Execute
-	response, err := GetScopedSourceOrgActionVariablesTest("test-org", "TEST_VAR", mockGetter)
-
-	// Verify
-	if err != nil {
-		t.Errorf("Expected no error, got %v", err)
-	}
-
-	if string(response) != string(expectedResponse) {
-		t.Errorf("Expected response %s, got %s", string(expectedResponse), string(response))
-	}
-
-	// Test parsing of the response
-	var scopedResponse data.ScopedResponse
-	err = json.Unmarshal(response, &scopedResponse)
-	if err != nil {
-		t.Errorf("Failed to unmarshal response: %v", err)
-	}
-
-	if scopedResponse.TotalCount != 1 {
-		t.Errorf("Expected total count 1, got %d", scopedResponse.TotalCount)
-	}
-
-	if len(scopedResponse.Repositories) != 1 {
-		t.Errorf("Expected 1 repository, got %d", len(scopedResponse.Repositories))
-	}
-
-	if scopedResponse.Repositories[0].ID != 12345 {
-		t.Errorf("Expected repository ID 12345, got %d", scopedResponse.Repositories[0].ID)
-	}
-
-	if scopedResponse.Repositories[0].Name != "test-repo" {
-		t.Errorf("Expected repository name test-repo, got %s", scopedResponse.Repositories[0].Name)
-	}
-}
-
 // Test the API methods that need a REST client
 func TestCreateOrganizationVariable(t *testing.T) {
 	// Setup - using mock
@@ -623,3 +489,143 @@ func TestCreateVariableListInvalidData(t *testing.T) {
 		t.Errorf("Expected empty result for invalid input, got %d items", len(result))
 	}
 }
+
+func TestCreateVariableListEnvironment(t *testing.T) {
+	g := &APIGetter{}
+	filedata := [][]string{
+		{"VariableLevel", "VariableName", "VariableValue", "VariableAccess", "RepositoryNames", "RepositoryIDs", "EnvironmentName"},
+		{"Environment", "ENV_VAR", "value1", "EnvironmentOnly", "repo1", "1234", "staging"},
+		{"Repository", "REPO_VAR", "value2", "RepoOnly", "repo1", "1234", ""},
+	}
+
+	result := g.CreateVariableList(filedata)
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 variables, got %d", len(result))
+	}
+	if result[0].Level != "Environment" || result[0].EnvironmentName != "staging" {
+		t.Errorf("Expected Environment variable for 'staging', got %s/%s", result[0].Level, result[0].EnvironmentName)
+	}
+	if result[1].EnvironmentName != "" {
+		t.Errorf("Expected empty EnvironmentName for repository variable, got %s", result[1].EnvironmentName)
+	}
+}
+
+func TestCreateEnvironmentVariableMockError(t *testing.T) {
+	mockGetter := NewMockAPIGetter()
+	mockGetter.ShouldReturnError = true
+
+	err := mockGetter.CreateEnvironmentVariable("test-org", "test-repo", "staging", nil)
+	if err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
+
+func TestUpdateVariablePaths(t *testing.T) {
+	var method, path, body string
+	getter := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.EscapedPath(), string(payload)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	testCases := []struct {
+		name         string
+		update       func() error
+		expectedPath string
+	}{
+		{"organization", func() error {
+			return getter.UpdateOrganizationVariable("test-org", "REGION", strings.NewReader(`{"value":"eu"}`))
+		}, "/orgs/test-org/actions/variables/REGION"},
+		{"repository", func() error {
+			return getter.UpdateRepoVariable("test-org", "test-repo", "REGION", strings.NewReader(`{"value":"eu"}`))
+		}, "/repos/test-org/test-repo/actions/variables/REGION"},
+		{"environment", func() error {
+			return getter.UpdateEnvironmentVariable("test-org", "test-repo", "prod eu", "REGION", strings.NewReader(`{"value":"eu"}`))
+		}, "/repos/test-org/test-repo/environments/prod%20eu/variables/REGION"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.update(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if method != "PATCH" {
+				t.Errorf("Expected PATCH, got %s", method)
+			}
+			if path != tc.expectedPath {
+				t.Errorf("Expected path %s, got %s", tc.expectedPath, path)
+			}
+			if body != `{"value":"eu"}` {
+				t.Errorf("Expected body to be sent, got %s", body)
+			}
+		})
+	}
+}
+
+func TestCreateVariableConflict(t *testing.T) {
+	getter := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"message":"Already exists - Variable already exists"}`))
+	})
+
+	err := getter.CreateRepoVariable("test-org", "test-repo", strings.NewReader(`{"name":"REGION","value":"eu"}`))
+	if !IsConflict(err) {
+		t.Errorf("Expected a conflict error, got %v", err)
+	}
+	if IsConflict(fmt.Errorf("network error")) {
+		t.Error("Expected a plain error not to be a conflict")
+	}
+}
+
+func TestWriteImportedVariable(t *testing.T) {
+	variable := data.ImportedVariable{Level: "Repository", Name: "REGION", Value: "eu", SelectedRepos: []string{"test-repo"}}
+
+	testCases := []struct {
+		name       string
+		createCode int
+		onConflict string
+		wantUpdate bool
+		wantSkip   bool
+		wantErr    bool
+	}{
+		{"created", http.StatusCreated, ConflictFail, false, false, false},
+		{"conflict updated", http.StatusConflict, ConflictUpdate, true, false, false},
+		{"conflict skipped", http.StatusConflict, ConflictSkip, false, true, true},
+		{"conflict fails", http.StatusConflict, ConflictFail, false, false, true},
+		{"other error", http.StatusForbidden, ConflictUpdate, false, false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var updatePath, updateBody string
+			getter := newFakeAPIGetter(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.Method == http.MethodPatch {
+					body, _ := io.ReadAll(r.Body)
+					updatePath, updateBody = r.URL.Path, string(body)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				w.WriteHeader(tc.createCode)
+				_, _ = w.Write([]byte(`{"message":"status"}`))
+			})
+
+			err := getter.WriteImportedVariable("test-org", variable, tc.onConflict)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			var skipErr *SkipError
+			if errors.As(err, &skipErr) != tc.wantSkip {
+				t.Errorf("Expected skip %v, got %v", tc.wantSkip, err)
+			}
+			if (updatePath != "") != tc.wantUpdate {
+				t.Errorf("Expected update %v, got path %q", tc.wantUpdate, updatePath)
+			}
+			if tc.wantUpdate && (updatePath != "/repos/test-org/test-repo/actions/variables/REGION" || updateBody != `{"name":"REGION","value":"eu"}`) {
+				t.Errorf("Unexpected update of %s with %s", updatePath, updateBody)
+			}
+		})
+	}
+}